@@ -0,0 +1,319 @@
+//go:generate mockery
+package chaospg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrInjected = errors.New("chaospg: injected failure")
+
+// Pool is satisfied by elephant databases and by pools of clusterpg and shardedpg.
+type Pool interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+type Config struct {
+	rules []*Rule
+	rand  *rand.Rand
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+type Option func(cfg *Config)
+
+func WithRules(rules ...*Rule) Option {
+	return func(cfg *Config) {
+		cfg.rules = append(cfg.rules, rules...)
+	}
+}
+
+// WithSeed makes probabilistic rules reproducible.
+func WithSeed(seed uint64) Option {
+	return func(cfg *Config) {
+		cfg.rand = rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // faults don't need crypto random.
+	}
+}
+
+// Chaos wraps pool and injects faults of rules into its operations. Every matched rule fires: latencies add up and
+// the first error is returned.
+type Chaos struct {
+	pool Pool
+	mu   sync.Mutex
+	cfg  Config
+}
+
+func New(pool Pool, opts ...Option) *Chaos {
+	cfg := Config{
+		rand:  rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), //nolint:gosec // faults don't need crypto random.
+		sleep: sleep,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Chaos{pool: pool, cfg: cfg}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Add appends rules, for example to switch scenario in the middle of test.
+func (c *Chaos) Add(rules ...*Rule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.rules = append(c.cfg.rules, rules...)
+}
+
+// Reset removes all rules.
+func (c *Chaos) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.rules = nil
+}
+
+// Fired returns how many times rule fired.
+func (c *Chaos) Fired(rule *Rule) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return rule.fired
+}
+
+func (c *Chaos) fault(kind op, sql string) fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out fault
+	for _, rule := range c.cfg.rules {
+		if !rule.match(kind, sql) {
+			continue
+		}
+		rule.matched++
+		if rule.matched <= rule.skip || rule.times > 0 && rule.fired >= rule.times {
+			continue
+		}
+		if rule.probability < 1 && c.cfg.rand.Float64() >= rule.probability {
+			continue
+		}
+		rule.fired++
+		out.latency += rule.latency
+		if rule.partial && !out.partial {
+			out.partial, out.afterRows = true, rule.afterRows
+		}
+		if out.err == nil {
+			out.err = rule.err
+		}
+	}
+	if out.partial && out.err == nil {
+		out.err = ErrInjected
+	}
+	return out
+}
+
+// inject delays operation and returns error of fault, except of error for partial rows.
+func (c *Chaos) inject(ctx context.Context, kind op, sql string) (fault, error) {
+	f := c.fault(kind, sql)
+	if f.latency > 0 {
+		if err := c.cfg.sleep(ctx, f.latency); err != nil {
+			return f, err
+		}
+	}
+	if f.partial {
+		return f, nil
+	}
+	return f, f.err
+}
+
+func (c *Chaos) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if _, err := c.inject(ctx, opBegin, ""); err != nil {
+		return nil, err
+	}
+	tx, err := c.pool.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.wrapTx(tx), nil
+}
+
+func (c *Chaos) Begin(ctx context.Context) (pgx.Tx, error) {
+	if _, err := c.inject(ctx, opBegin, ""); err != nil {
+		return nil, err
+	}
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.wrapTx(tx), nil
+}
+
+// Acquire passes dedicated connection of pool as is, faults aren't injected into it.
+func (c *Chaos) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	pool, ok := c.pool.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	return pool.Acquire(ctx)
+}
+
+// inTx reports, that context has wrapped transaction, which injects faults into statements routed to it by pool.
+func (c *Chaos) inTx(ctx context.Context) bool {
+	tx, _ := pgcontext.TransactionFrom(ctx)
+	return c.owns(tx)
+}
+
+func (c *Chaos) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	if c.inTx(ctx) {
+		return c.pool.Query(ctx, query, args...)
+	}
+	return c.query(ctx, c.pool, query, args)
+}
+
+func (c *Chaos) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	if c.inTx(ctx) {
+		return c.pool.QueryRow(ctx, query, args...)
+	}
+	return c.queryRow(ctx, c.pool, query, args)
+}
+
+func (c *Chaos) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	if c.inTx(ctx) {
+		return c.pool.Exec(ctx, query, args...)
+	}
+	return c.exec(ctx, c.pool, query, args)
+}
+
+func (c *Chaos) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if c.inTx(ctx) {
+		return c.pool.SendBatch(ctx, b)
+	}
+	return c.sendBatch(ctx, c.pool, b)
+}
+
+func (c *Chaos) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	if c.inTx(ctx) {
+		return c.pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+	return c.copyFrom(ctx, c.pool, tableName, columnNames, rowSrc)
+}
+
+func (c *Chaos) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	if _, err := c.inject(ctx, opStatement, query); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return c.pool.CopyTo(ctx, w, query)
+}
+
+// Transactional injects faults into statements of fn, which run through transaction from context. Commit failure
+// is injected by rollback of transaction after fn.
+func (c *Chaos) Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error) {
+	if tx, _ := pgcontext.TransactionFrom(ctx); !c.owns(tx) {
+		if _, err := c.inject(ctx, opBegin, ""); err != nil {
+			return err
+		}
+	}
+	return c.pool.Transactional(ctx, func(ctx context.Context) error {
+		tx, ok := pgcontext.TransactionFrom(ctx)
+		if c.owns(tx) {
+			// savepoint of wrapped transaction injects faults itself.
+			return fn(ctx)
+		}
+		if ok {
+			ctx = pgcontext.With(ctx, pgcontext.WithTransaction(c.wrapTx(tx)))
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if _, err := c.inject(ctx, opCommit, ""); err != nil {
+			return fmt.Errorf("can't commit transaction: %w", err)
+		}
+		return nil
+	})
+}
+
+// db is the part of pool and transaction, which runs statements.
+type db interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+func (c *Chaos) query(ctx context.Context, db db, query string, args []any) (pgx.Rows, error) {
+	f, err := c.inject(ctx, opStatement, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil || !f.partial {
+		return rows, err
+	}
+	return &partialRows{Rows: rows, left: f.afterRows, err: f.err}, nil
+}
+
+func (c *Chaos) queryRow(ctx context.Context, db db, query string, args []any) pgx.Row {
+	f, err := c.inject(ctx, opStatement, query)
+	if err == nil && f.partial && f.afterRows == 0 {
+		err = f.err
+	}
+	if err != nil {
+		return failedRow{err: err}
+	}
+	return db.QueryRow(ctx, query, args...)
+}
+
+func (c *Chaos) exec(ctx context.Context, db db, query string, args []any) (pgconn.CommandTag, error) {
+	if _, err := c.inject(ctx, opStatement, query); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return db.Exec(ctx, query, args...)
+}
+
+// sendBatch fails the whole batch, when fault is injected into any of queued queries.
+func (c *Chaos) sendBatch(ctx context.Context, db db, b *pgx.Batch) pgx.BatchResults {
+	for _, qq := range b.QueuedQueries {
+		if _, err := c.inject(ctx, opStatement, qq.SQL); err != nil {
+			return failedBatchResults{err: err}
+		}
+	}
+	return db.SendBatch(ctx, b)
+}
+
+func (c *Chaos) copyFrom(
+	ctx context.Context, db db, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	if _, err := c.inject(ctx, opStatement, "COPY "+tableName.Sanitize()); err != nil {
+		return 0, err
+	}
+	return db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
@@ -0,0 +1,447 @@
+package chaospg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/elephanttest"
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func sqlState(t *testing.T, err error) string {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	return pgErr.Code
+}
+
+func TestChaos_Rules(t *testing.T) {
+	t.Run("should be able to fail scripted attempt", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`INSERT`).Times(2)
+		rule := Statements(`INSERT`).SQLState("40001").Skip(1).Times(1)
+		sut := New(fake, WithRules(rule))
+		ctx := context.Background()
+
+		_, err := sut.Exec(ctx, "INSERT")
+		require.NoError(t, err)
+		_, err = sut.Exec(ctx, "INSERT")
+		assert.Equal(t, "40001", sqlState(t, err))
+		_, err = sut.Exec(ctx, "INSERT")
+		require.NoError(t, err)
+		assert.Equal(t, 1, sut.Fired(rule))
+	})
+
+	t.Run("should be able to fire rules with probability reproducibly", func(t *testing.T) {
+		run := func() []bool {
+			pool := NewMockPool(t)
+			pool.EXPECT().Exec(mock.Anything, "SELECT").Return(pgconn.CommandTag{}, nil).Maybe()
+			sut := New(pool, WithSeed(42), WithRules(Statements(`SELECT`).Fail(ErrInjected).Probability(0.5)))
+			out := make([]bool, 0, 100)
+			for range 100 {
+				_, err := sut.Exec(context.Background(), "SELECT")
+				out = append(out, err != nil)
+			}
+			return out
+		}
+		first := run()
+		assert.Equal(t, first, run())
+		assert.Contains(t, first, true)
+		assert.Contains(t, first, false)
+	})
+
+	t.Run("should be able to skip rules of other statements", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`)
+		rule := Statements(`INSERT`).Fail(ErrInjected)
+		sut := New(fake, WithRules(rule, Begin().Fail(ErrInjected)))
+
+		_, err := sut.Exec(context.Background(), "SELECT")
+		require.NoError(t, err)
+		assert.Zero(t, sut.Fired(rule))
+	})
+
+	t.Run("should be able to add latencies and return the first error", func(t *testing.T) {
+		expErr := newError()
+		var slept []time.Duration
+		sut := New(NewMockPool(t), WithRules(
+			Statements(`SELECT`).Latency(time.Second),
+			Statements(`SELECT`).Latency(time.Second).Fail(expErr),
+			Statements(`SELECT`).SQLState("40001"),
+		))
+		sut.cfg.sleep = func(_ context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}
+
+		_, err := sut.Exec(context.Background(), "SELECT")
+		require.ErrorIs(t, err, expErr)
+		assert.Equal(t, []time.Duration{2 * time.Second}, slept)
+	})
+
+	t.Run("should be able to fail at done context while delaying", func(t *testing.T) {
+		sut := New(NewMockPool(t), WithRules(Statements(`SELECT`).Latency(time.Hour)))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := sut.Query(ctx, "SELECT")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("should be able to add and reset rules", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`)
+		sut := New(fake)
+		sut.Add(Statements(`SELECT`).Fail(ErrInjected))
+
+		_, err := sut.Exec(context.Background(), "SELECT")
+		require.ErrorIs(t, err, ErrInjected)
+		sut.Reset()
+		_, err = sut.Exec(context.Background(), "SELECT")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to describe rules", func(t *testing.T) {
+		assert.Equal(t, "statements /^SELECT/", Statements(`^SELECT`).String())
+		assert.Equal(t, "begin", Begin().String())
+		assert.Equal(t, "commit", Commit().String())
+	})
+}
+
+func TestSleep(t *testing.T) {
+	t.Run("should be able to sleep", func(t *testing.T) {
+		require.NoError(t, sleep(context.Background(), time.Millisecond))
+	})
+	t.Run("should be able to stop at done context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, sleep(ctx, time.Hour), context.Canceled)
+	})
+}
+
+func TestChaos_Rows(t *testing.T) {
+	rows := [][]any{{1}, {2}, {3}}
+
+	t.Run("should be able to cut rows", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, rows...).Times(2)
+		sut := New(fake, WithRules(Statements(`SELECT`).AfterRows(1)))
+
+		got, err := sut.Query(context.Background(), "SELECT")
+		require.NoError(t, err)
+		require.True(t, got.Next())
+		assert.False(t, got.Next())
+		assert.False(t, got.Next())
+		require.ErrorIs(t, got.Err(), ErrInjected)
+
+		sut.Reset()
+		sut.Add(Statements(`SELECT`).AfterRows(5).SQLState("08006"))
+		got, err = sut.Query(context.Background(), "SELECT")
+		require.NoError(t, err)
+		ids, err := pgx.CollectRows(got, pgx.RowTo[int])
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, ids)
+	})
+
+	t.Run("should be able to cut rows with error of rule", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, rows...)
+		sut := New(fake, WithRules(Statements(`SELECT`).AfterRows(0), Statements(`SELECT`).SQLState("08006")))
+
+		got, err := sut.Query(context.Background(), "SELECT")
+		require.NoError(t, err)
+		_, err = pgx.CollectRows(got, pgx.RowTo[int])
+		assert.Equal(t, "08006", sqlState(t, err))
+	})
+
+	t.Run("should be able to pass error of query", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().Query(mock.Anything, "SELECT").Return(nil, expErr)
+		sut := New(pool, WithRules(Statements(`SELECT`).AfterRows(1)))
+
+		_, err := sut.Query(context.Background(), "SELECT")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail row", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, rows...)
+		sut := New(fake, WithRules(Statements(`SELECT 1`).AfterRows(0), Statements(`SELECT 2`).AfterRows(1)))
+
+		var id int
+		require.ErrorIs(t, sut.QueryRow(context.Background(), "SELECT 1").Scan(&id), ErrInjected)
+		require.NoError(t, sut.QueryRow(context.Background(), "SELECT 2").Scan(&id))
+		assert.Equal(t, 1, id)
+	})
+}
+
+func TestChaos_Tx(t *testing.T) {
+	t.Run("should be able to fail begin", func(t *testing.T) {
+		sut := New(NewMockPool(t), WithRules(Begin().Fail(ErrInjected)))
+
+		_, err := sut.Begin(context.Background())
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = sut.BeginTx(context.Background(), pgx.TxOptions{})
+		require.ErrorIs(t, err, ErrInjected)
+		err = sut.Transactional(context.Background(), func(context.Context) error {
+			t.Fatal("fn must not be called")
+			return nil
+		})
+		require.ErrorIs(t, err, ErrInjected)
+	})
+
+	t.Run("should be able to pass error of begin", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().Begin(mock.Anything).Return(nil, expErr)
+		pool.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(nil, expErr)
+		sut := New(pool)
+
+		_, err := sut.Begin(context.Background())
+		require.ErrorIs(t, err, expErr)
+		_, err = sut.BeginTx(context.Background(), pgx.TxOptions{})
+		require.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail commit and roll transaction back", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`INSERT`)
+		sut := New(fake, WithRules(Commit().SQLState("40001")))
+		ctx := context.Background()
+
+		tx, err := sut.BeginTx(ctx, pgx.TxOptions{})
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "INSERT")
+		require.NoError(t, err)
+		assert.Equal(t, "40001", sqlState(t, tx.Commit(ctx)))
+		assert.Equal(t, []string{"BEGIN", "INSERT", "ROLLBACK"}, boundaries(fake))
+	})
+
+	t.Run("should be able to commit savepoint", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		sut := New(fake)
+		ctx := context.Background()
+
+		tx, err := sut.Begin(ctx)
+		require.NoError(t, err)
+		nested, err := tx.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, nested.Commit(ctx))
+		require.NoError(t, tx.Commit(ctx))
+		assert.Equal(t, []string{"BEGIN", "SAVEPOINT", "RELEASE SAVEPOINT", "COMMIT"}, boundaries(fake))
+	})
+
+	t.Run("should be able to fail savepoint", func(t *testing.T) {
+		expErr := newError()
+		inner := NewMockTx(t)
+		inner.EXPECT().Begin(mock.Anything).Return(nil, expErr).Once()
+		pool := NewMockPool(t)
+		pool.EXPECT().Begin(mock.Anything).Return(inner, nil)
+		rule := Begin().Skip(1).Fail(ErrInjected)
+		sut := New(pool, WithRules(rule))
+		ctx := context.Background()
+
+		tx, err := sut.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Begin(ctx)
+		require.ErrorIs(t, err, ErrInjected)
+		sut.Reset()
+		_, err = tx.Begin(ctx)
+		require.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to inject faults into statements of transactional", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, []any{1})
+		fake.Expect(`COPY`)
+		sut := New(fake, WithRules(Statements(`INSERT`).Fail(ErrInjected)))
+		ctx := context.Background()
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			tx, _ := pgcontext.TransactionFrom(ctx)
+			var id int
+			require.NoError(t, tx.QueryRow(ctx, "SELECT").Scan(&id))
+			_, err := tx.CopyFrom(ctx, pgx.Identifier{"users"}, nil, pgx.CopyFromRows(nil))
+			require.NoError(t, err)
+			results := tx.SendBatch(ctx, batchOf("INSERT"))
+			require.ErrorIs(t, results.Close(), ErrInjected)
+			_, err = tx.Exec(ctx, "INSERT")
+			return err
+		})
+		require.ErrorIs(t, err, ErrInjected)
+		assert.Equal(t, []string{"BEGIN", "SELECT", `COPY "users" (  ) FROM STDIN`, "ROLLBACK"}, boundaries(fake))
+	})
+
+	t.Run("should be able to inject faults once into statements routed by pool to transaction", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`INSERT`)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, []any{1}).Times(2)
+		fake.Expect(`UPDATE`)
+		fake.Expect(`COPY`)
+		rule := Statements(`.`)
+		sut := New(fake, WithRules(rule))
+
+		err := sut.Transactional(context.Background(), func(ctx context.Context) error {
+			_, err := sut.Exec(ctx, "INSERT")
+			require.NoError(t, err)
+			assert.Equal(t, 1, sut.Fired(rule))
+
+			rows, err := sut.Query(ctx, "SELECT")
+			require.NoError(t, err)
+			rows.Close()
+			var id int
+			require.NoError(t, sut.QueryRow(ctx, "SELECT").Scan(&id))
+			require.NoError(t, sut.SendBatch(ctx, batchOf("UPDATE")).Close())
+			_, err = sut.CopyFrom(ctx, pgx.Identifier{"users"}, nil, pgx.CopyFromRows(nil))
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 5, sut.Fired(rule))
+	})
+
+	t.Run("should be able to fail commit of transactional", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`)
+		sut := New(fake, WithRules(Commit().Fail(ErrInjected)))
+
+		err := sut.Transactional(context.Background(), func(ctx context.Context) error {
+			tx, _ := pgcontext.TransactionFrom(ctx)
+			rows, err := tx.Query(ctx, "SELECT")
+			if err != nil {
+				return err
+			}
+			rows.Close()
+			return nil
+		})
+		require.ErrorIs(t, err, ErrInjected)
+		assert.Equal(t, []string{"BEGIN", "SELECT", "ROLLBACK"}, boundaries(fake))
+	})
+
+	t.Run("should be able to fail commit of nested transactional", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		rule := Commit().Fail(ErrInjected)
+		sut := New(fake, WithRules(rule))
+		ctx := context.Background()
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return sut.Transactional(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, ErrInjected)
+		assert.Equal(t, 1, sut.Fired(rule))
+		assert.Equal(t, []string{"BEGIN", "SAVEPOINT", "ROLLBACK TO SAVEPOINT", "ROLLBACK"}, boundaries(fake))
+	})
+
+	t.Run("should be able to run transactional with foreign transaction", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+				return fn(ctx)
+			})
+		sut := New(pool)
+
+		require.NoError(t, sut.Transactional(context.Background(), func(ctx context.Context) error {
+			_, ok := pgcontext.TransactionFrom(ctx)
+			assert.False(t, ok)
+			return nil
+		}))
+	})
+
+	t.Run("should be able to wrap transaction once", func(t *testing.T) {
+		sut := New(NewMockPool(t))
+		tx := sut.wrapTx(NewMockTx(t))
+		assert.Same(t, tx, sut.wrapTx(tx))
+		assert.NotSame(t, tx, New(NewMockPool(t)).wrapTx(tx))
+	})
+}
+
+func boundaries(fake *elephanttest.Fake) []string {
+	var out []string
+	for _, call := range fake.Calls() {
+		out = append(out, call.SQL)
+	}
+	return out
+}
+
+func batchOf(queries ...string) *pgx.Batch {
+	batch := &pgx.Batch{}
+	for _, query := range queries {
+		batch.Queue(query)
+	}
+	return batch
+}
+
+type acquirerPool struct {
+	*MockPool
+	conn *pgxpool.Conn
+}
+
+func (p acquirerPool) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return p.conn, nil
+}
+
+func TestChaos_Statements(t *testing.T) {
+	t.Run("should be able to fail batch", func(t *testing.T) {
+		sut := New(NewMockPool(t), WithRules(Statements(`DELETE`).SQLState("40P01")))
+
+		results := sut.SendBatch(context.Background(), batchOf("SELECT", "DELETE"))
+		_, err := results.Exec()
+		assert.Equal(t, "40P01", sqlState(t, err))
+		_, err = results.Query()
+		require.Error(t, err)
+		require.Error(t, results.QueryRow().Scan())
+		require.Error(t, results.Close())
+	})
+
+	t.Run("should be able to pass batch", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`SELECT`)
+		sut := New(fake, WithRules(Statements(`DELETE`).Fail(ErrInjected)))
+
+		require.NoError(t, sut.SendBatch(context.Background(), batchOf("SELECT")).Close())
+	})
+
+	t.Run("should be able to fail copy", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`COPY "orders"`)
+		fake.Expect(`COPY orders TO`)
+		sut := New(fake, WithRules(Statements(`^COPY "users"`).Fail(ErrInjected), Statements(`TO STDOUT`).Fail(ErrInjected)))
+		ctx := context.Background()
+
+		_, err := sut.CopyFrom(ctx, pgx.Identifier{"users"}, nil, pgx.CopyFromRows(nil))
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = sut.CopyFrom(ctx, pgx.Identifier{"orders"}, nil, pgx.CopyFromRows(nil))
+		require.NoError(t, err)
+		_, err = sut.CopyTo(ctx, &bytes.Buffer{}, "COPY users TO STDOUT")
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = sut.CopyTo(ctx, &bytes.Buffer{}, "COPY orders TO STDIN")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to acquire connection of pool", func(t *testing.T) {
+		_, err := New(NewMockPool(t)).Acquire(context.Background())
+		require.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+
+		conn := &pgxpool.Conn{}
+		got, err := New(acquirerPool{MockPool: NewMockPool(t), conn: conn}).Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Same(t, conn, got)
+	})
+}
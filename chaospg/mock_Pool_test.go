@@ -0,0 +1,569 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package chaospg
+
+import (
+	context "context"
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// MockPool is an autogenerated mock type for the Pool type
+type MockPool struct {
+	mock.Mock
+}
+
+type MockPool_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockPool) EXPECT() *MockPool_Expecter {
+	return &MockPool_Expecter{mock: &_m.Mock}
+}
+
+// Begin provides a mock function with given fields: ctx
+func (_m *MockPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Begin")
+	}
+
+	var r0 pgx.Tx
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (pgx.Tx, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) pgx.Tx); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Tx)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_Begin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Begin'
+type MockPool_Begin_Call struct {
+	*mock.Call
+}
+
+// Begin is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockPool_Expecter) Begin(ctx interface{}) *MockPool_Begin_Call {
+	return &MockPool_Begin_Call{Call: _e.mock.On("Begin", ctx)}
+}
+
+func (_c *MockPool_Begin_Call) Run(run func(ctx context.Context)) *MockPool_Begin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockPool_Begin_Call) Return(_a0 pgx.Tx, _a1 error) *MockPool_Begin_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_Begin_Call) RunAndReturn(run func(context.Context) (pgx.Tx, error)) *MockPool_Begin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// BeginTx provides a mock function with given fields: ctx, opts
+func (_m *MockPool) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	ret := _m.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BeginTx")
+	}
+
+	var r0 pgx.Tx
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.TxOptions) (pgx.Tx, error)); ok {
+		return rf(ctx, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.TxOptions) pgx.Tx); ok {
+		r0 = rf(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Tx)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.TxOptions) error); ok {
+		r1 = rf(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_BeginTx_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BeginTx'
+type MockPool_BeginTx_Call struct {
+	*mock.Call
+}
+
+// BeginTx is a helper method to define mock.On call
+//   - ctx context.Context
+//   - opts pgx.TxOptions
+func (_e *MockPool_Expecter) BeginTx(ctx interface{}, opts interface{}) *MockPool_BeginTx_Call {
+	return &MockPool_BeginTx_Call{Call: _e.mock.On("BeginTx", ctx, opts)}
+}
+
+func (_c *MockPool_BeginTx_Call) Run(run func(ctx context.Context, opts pgx.TxOptions)) *MockPool_BeginTx_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.TxOptions))
+	})
+	return _c
+}
+
+func (_c *MockPool_BeginTx_Call) Return(_a0 pgx.Tx, _a1 error) *MockPool_BeginTx_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_BeginTx_Call) RunAndReturn(run func(context.Context, pgx.TxOptions) (pgx.Tx, error)) *MockPool_BeginTx_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CopyFrom provides a mock function with given fields: ctx, tableName, columnNames, rowSrc
+func (_m *MockPool) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ret := _m.Called(ctx, tableName, columnNames, rowSrc)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyFrom")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error)); ok {
+		return rf(ctx, tableName, columnNames, rowSrc)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) int64); ok {
+		r0 = rf(ctx, tableName, columnNames, rowSrc)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) error); ok {
+		r1 = rf(ctx, tableName, columnNames, rowSrc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_CopyFrom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyFrom'
+type MockPool_CopyFrom_Call struct {
+	*mock.Call
+}
+
+// CopyFrom is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tableName pgx.Identifier
+//   - columnNames []string
+//   - rowSrc pgx.CopyFromSource
+func (_e *MockPool_Expecter) CopyFrom(ctx interface{}, tableName interface{}, columnNames interface{}, rowSrc interface{}) *MockPool_CopyFrom_Call {
+	return &MockPool_CopyFrom_Call{Call: _e.mock.On("CopyFrom", ctx, tableName, columnNames, rowSrc)}
+}
+
+func (_c *MockPool_CopyFrom_Call) Run(run func(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource)) *MockPool_CopyFrom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Identifier), args[2].([]string), args[3].(pgx.CopyFromSource))
+	})
+	return _c
+}
+
+func (_c *MockPool_CopyFrom_Call) Return(_a0 int64, _a1 error) *MockPool_CopyFrom_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_CopyFrom_Call) RunAndReturn(run func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error)) *MockPool_CopyFrom_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CopyTo provides a mock function with given fields: ctx, w, query
+func (_m *MockPool) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	ret := _m.Called(ctx, w, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyTo")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer, string) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, w, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer, string) pgconn.CommandTag); ok {
+		r0 = rf(ctx, w, query)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, io.Writer, string) error); ok {
+		r1 = rf(ctx, w, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_CopyTo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyTo'
+type MockPool_CopyTo_Call struct {
+	*mock.Call
+}
+
+// CopyTo is a helper method to define mock.On call
+//   - ctx context.Context
+//   - w io.Writer
+//   - query string
+func (_e *MockPool_Expecter) CopyTo(ctx interface{}, w interface{}, query interface{}) *MockPool_CopyTo_Call {
+	return &MockPool_CopyTo_Call{Call: _e.mock.On("CopyTo", ctx, w, query)}
+}
+
+func (_c *MockPool_CopyTo_Call) Run(run func(ctx context.Context, w io.Writer, query string)) *MockPool_CopyTo_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(io.Writer), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockPool_CopyTo_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockPool_CopyTo_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_CopyTo_Call) RunAndReturn(run func(context.Context, io.Writer, string) (pgconn.CommandTag, error)) *MockPool_CopyTo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Exec provides a mock function with given fields: ctx, query, args
+func (_m *MockPool) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exec")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, query, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgconn.CommandTag); ok {
+		r0 = rf(ctx, query, args...)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...interface{}) error); ok {
+		r1 = rf(ctx, query, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
+type MockPool_Exec_Call struct {
+	*mock.Call
+}
+
+// Exec is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - args ...interface{}
+func (_e *MockPool_Expecter) Exec(ctx interface{}, query interface{}, args ...interface{}) *MockPool_Exec_Call {
+	return &MockPool_Exec_Call{Call: _e.mock.On("Exec",
+		append([]interface{}{ctx, query}, args...)...)}
+}
+
+func (_c *MockPool_Exec_Call) Run(run func(ctx context.Context, query string, args ...interface{})) *MockPool_Exec_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPool_Exec_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockPool_Exec_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_Exec_Call) RunAndReturn(run func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)) *MockPool_Exec_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Query provides a mock function with given fields: ctx, query, args
+func (_m *MockPool) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Query")
+	}
+
+	var r0 pgx.Rows
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) (pgx.Rows, error)); ok {
+		return rf(ctx, query, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgx.Rows); ok {
+		r0 = rf(ctx, query, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Rows)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...interface{}) error); ok {
+		r1 = rf(ctx, query, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_Query_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Query'
+type MockPool_Query_Call struct {
+	*mock.Call
+}
+
+// Query is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - args ...interface{}
+func (_e *MockPool_Expecter) Query(ctx interface{}, query interface{}, args ...interface{}) *MockPool_Query_Call {
+	return &MockPool_Query_Call{Call: _e.mock.On("Query",
+		append([]interface{}{ctx, query}, args...)...)}
+}
+
+func (_c *MockPool_Query_Call) Run(run func(ctx context.Context, query string, args ...interface{})) *MockPool_Query_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPool_Query_Call) Return(_a0 pgx.Rows, _a1 error) *MockPool_Query_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_Query_Call) RunAndReturn(run func(context.Context, string, ...interface{}) (pgx.Rows, error)) *MockPool_Query_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryRow provides a mock function with given fields: ctx, query, args
+func (_m *MockPool) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryRow")
+	}
+
+	var r0 pgx.Row
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgx.Row); ok {
+		r0 = rf(ctx, query, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Row)
+		}
+	}
+
+	return r0
+}
+
+// MockPool_QueryRow_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryRow'
+type MockPool_QueryRow_Call struct {
+	*mock.Call
+}
+
+// QueryRow is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - args ...interface{}
+func (_e *MockPool_Expecter) QueryRow(ctx interface{}, query interface{}, args ...interface{}) *MockPool_QueryRow_Call {
+	return &MockPool_QueryRow_Call{Call: _e.mock.On("QueryRow",
+		append([]interface{}{ctx, query}, args...)...)}
+}
+
+func (_c *MockPool_QueryRow_Call) Run(run func(ctx context.Context, query string, args ...interface{})) *MockPool_QueryRow_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockPool_QueryRow_Call) Return(_a0 pgx.Row) *MockPool_QueryRow_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPool_QueryRow_Call) RunAndReturn(run func(context.Context, string, ...interface{}) pgx.Row) *MockPool_QueryRow_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendBatch provides a mock function with given fields: ctx, b
+func (_m *MockPool) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	ret := _m.Called(ctx, b)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendBatch")
+	}
+
+	var r0 pgx.BatchResults
+	if rf, ok := ret.Get(0).(func(context.Context, *pgx.Batch) pgx.BatchResults); ok {
+		r0 = rf(ctx, b)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.BatchResults)
+		}
+	}
+
+	return r0
+}
+
+// MockPool_SendBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendBatch'
+type MockPool_SendBatch_Call struct {
+	*mock.Call
+}
+
+// SendBatch is a helper method to define mock.On call
+//   - ctx context.Context
+//   - b *pgx.Batch
+func (_e *MockPool_Expecter) SendBatch(ctx interface{}, b interface{}) *MockPool_SendBatch_Call {
+	return &MockPool_SendBatch_Call{Call: _e.mock.On("SendBatch", ctx, b)}
+}
+
+func (_c *MockPool_SendBatch_Call) Run(run func(ctx context.Context, b *pgx.Batch)) *MockPool_SendBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*pgx.Batch))
+	})
+	return _c
+}
+
+func (_c *MockPool_SendBatch_Call) Return(_a0 pgx.BatchResults) *MockPool_SendBatch_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPool_SendBatch_Call) RunAndReturn(run func(context.Context, *pgx.Batch) pgx.BatchResults) *MockPool_SendBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Transactional provides a mock function with given fields: ctx, fn
+func (_m *MockPool) Transactional(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transactional")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPool_Transactional_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Transactional'
+type MockPool_Transactional_Call struct {
+	*mock.Call
+}
+
+// Transactional is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(context.Context) error
+func (_e *MockPool_Expecter) Transactional(ctx interface{}, fn interface{}) *MockPool_Transactional_Call {
+	return &MockPool_Transactional_Call{Call: _e.mock.On("Transactional", ctx, fn)}
+}
+
+func (_c *MockPool_Transactional_Call) Run(run func(ctx context.Context, fn func(context.Context) error)) *MockPool_Transactional_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(func(context.Context) error))
+	})
+	return _c
+}
+
+func (_c *MockPool_Transactional_Call) Return(out error) *MockPool_Transactional_Call {
+	_c.Call.Return(out)
+	return _c
+}
+
+func (_c *MockPool_Transactional_Call) RunAndReturn(run func(context.Context, func(context.Context) error) error) *MockPool_Transactional_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockPool creates a new instance of MockPool. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPool(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPool {
+	mock := &MockPool{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,59 @@
+package chaospg
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// partialRows breaks stream of rows, as if connection is lost in the middle of it.
+type partialRows struct {
+	pgx.Rows
+	left int
+	err  error
+	cut  bool
+}
+
+func (r *partialRows) Next() bool {
+	if r.left == 0 {
+		r.cut = true
+		r.Rows.Close()
+		return false
+	}
+	r.left--
+	return r.Rows.Next()
+}
+
+func (r *partialRows) Err() error {
+	if r.cut {
+		return r.err
+	}
+	return r.Rows.Err()
+}
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(_ ...any) error {
+	return r.err
+}
+
+type failedBatchResults struct {
+	err error
+}
+
+func (r failedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r failedBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r failedBatchResults) QueryRow() pgx.Row {
+	return failedRow(r)
+}
+
+func (r failedBatchResults) Close() error {
+	return r.err
+}
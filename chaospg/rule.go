@@ -0,0 +1,117 @@
+package chaospg
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type op uint8
+
+const (
+	opStatement op = iota
+	opBegin
+	opCommit
+)
+
+// Rule describes fault, which is injected into matched operations. Rule fires every time by default.
+type Rule struct {
+	op          op
+	pattern     *regexp.Regexp
+	latency     time.Duration
+	err         error
+	afterRows   int
+	partial     bool
+	probability float64
+	skip        int
+	times       int
+	matched     int
+	fired       int
+}
+
+// Statements matches Query, QueryRow, Exec, CopyTo and queued queries of batch by regular expression pattern.
+// CopyFrom is matched as COPY "table".
+func Statements(pattern string) *Rule {
+	return &Rule{op: opStatement, pattern: regexp.MustCompile(pattern), probability: 1}
+}
+
+// Begin matches start of transaction and savepoint.
+func Begin() *Rule {
+	return &Rule{op: opBegin, probability: 1}
+}
+
+// Commit matches commit of transaction and release of savepoint. Injected commit failure rolls transaction back.
+func Commit() *Rule {
+	return &Rule{op: opCommit, probability: 1}
+}
+
+// Latency delays operation, or fails it with context error, when context is done earlier.
+func (r *Rule) Latency(d time.Duration) *Rule {
+	r.latency = d
+	return r
+}
+
+// Fail fails operation with err, for example with connection error.
+func (r *Rule) Fail(err error) *Rule {
+	r.err = err
+	return r
+}
+
+// SQLState fails operation with postgres error of code.
+func (r *Rule) SQLState(code string) *Rule {
+	r.err = &pgconn.PgError{Severity: "ERROR", Code: code, Message: "chaospg: injected " + code}
+	return r
+}
+
+// AfterRows cuts rows of query after n rows, then rows fail with error of rule or ErrInjected.
+func (r *Rule) AfterRows(n int) *Rule {
+	r.afterRows = n
+	r.partial = true
+	return r
+}
+
+// Probability fires rule with probability p from 0 to 1.
+func (r *Rule) Probability(p float64) *Rule {
+	r.probability = p
+	return r
+}
+
+// Skip lets first n matched operations pass, for example to fail the second attempt only.
+func (r *Rule) Skip(n int) *Rule {
+	r.skip = n
+	return r
+}
+
+// Times limits how many times rule fires.
+func (r *Rule) Times(n int) *Rule {
+	r.times = n
+	return r
+}
+
+func (r *Rule) String() string {
+	switch r.op {
+	case opBegin:
+		return "begin"
+	case opCommit:
+		return "commit"
+	default:
+		return fmt.Sprintf("statements /%s/", r.pattern)
+	}
+}
+
+func (r *Rule) match(kind op, sql string) bool {
+	if r.op != kind {
+		return false
+	}
+	return kind != opStatement || r.pattern.MatchString(sql)
+}
+
+// fault is combined effect of fired rules.
+type fault struct {
+	latency   time.Duration
+	err       error
+	afterRows int
+	partial   bool
+}
@@ -0,0 +1,68 @@
+package chaospg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// chaosTx injects faults into statements, savepoints and commit of transaction.
+type chaosTx struct {
+	pgx.Tx
+	chaos *Chaos
+}
+
+func (c *Chaos) wrapTx(tx pgx.Tx) pgx.Tx {
+	if c.owns(tx) {
+		return tx
+	}
+	return &chaosTx{Tx: tx, chaos: c}
+}
+
+func (c *Chaos) owns(tx pgx.Tx) bool {
+	wrapped, ok := tx.(*chaosTx)
+	return ok && wrapped.chaos == c
+}
+
+func (tx *chaosTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if _, err := tx.chaos.inject(ctx, opBegin, ""); err != nil {
+		return nil, err
+	}
+	nested, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tx.chaos.wrapTx(nested), nil
+}
+
+func (tx *chaosTx) Commit(ctx context.Context) error {
+	if _, err := tx.chaos.inject(ctx, opCommit, ""); err != nil {
+		_ = tx.Tx.Rollback(ctx)
+		return fmt.Errorf("can't commit transaction: %w", err)
+	}
+	return tx.Tx.Commit(ctx)
+}
+
+func (tx *chaosTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return tx.chaos.query(ctx, tx.Tx, sql, args)
+}
+
+func (tx *chaosTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return tx.chaos.queryRow(ctx, tx.Tx, sql, args)
+}
+
+func (tx *chaosTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return tx.chaos.exec(ctx, tx.Tx, sql, arguments)
+}
+
+func (tx *chaosTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return tx.chaos.sendBatch(ctx, tx.Tx, b)
+}
+
+func (tx *chaosTx) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return tx.chaos.copyFrom(ctx, tx.Tx, tableName, columnNames, rowSrc)
+}
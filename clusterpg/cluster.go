@@ -5,13 +5,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
 
 	"github.com/godepo/elephant/internal/cluster"
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var ErrInvalidClusterConfiguration = errors.New("invalid cluster configuration")
+var (
+	ErrInvalidClusterConfiguration = errors.New("invalid cluster configuration")
+	ErrNoFollowers                 = cluster.ErrNoFollowers
+)
 
 type Pool interface {
 	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
@@ -19,15 +28,34 @@ type Pool interface {
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
 	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
 }
 
+// Cluster is database of leader and followers, which Builder builds. Nodes may omit Acquire, Shutdown and Health:
+// Acquire then fails with elephant.ErrAcquireNotSupported, nodes are closed instead of shutdown and probed by query.
+type Cluster interface {
+	Pool
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+	Shutdown(ctx context.Context) error
+	ShutdownProgress() pgdrain.Progress
+	Ping(ctx context.Context, rules ...pghealth.Rule) error
+	Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report
+	// SwapFollowers atomically replaces followers, queries in flight finish on previous followers, which are shut down
+	// or closed, once they are drained, unless they stay in cluster.
+	SwapFollowers(ctx context.Context, followers []Pool) error
+}
+
 type ConstructDB func() (Pool, error)
 
 type Builder interface {
 	Leader(fn ConstructDB) Builder
 	Follower(fns ...ConstructDB) Builder
-	Go() (Pool, error)
+	// CircuitBreaker guards leader and every follower by circuit breaker, followers with open circuit are skipped.
+	CircuitBreaker(cfg pgbreaker.Config) Builder
+	Go() (Cluster, error)
 }
 
 func New() Builder {
@@ -41,6 +69,7 @@ func New() Builder {
 type builder struct {
 	leaderConstructor     ConstructDB
 	followersConstructors []ConstructDB
+	opts                  []cluster.Option
 }
 
 func (b builder) Leader(fn ConstructDB) Builder {
@@ -56,7 +85,12 @@ func (b builder) Follower(fns ...ConstructDB) Builder {
 	return b
 }
 
-func (b builder) Go() (Pool, error) {
+func (b builder) CircuitBreaker(cfg pgbreaker.Config) Builder {
+	b.opts = append(slices.Clip(b.opts), cluster.WithCircuitBreaker(cfg))
+	return b
+}
+
+func (b builder) Go() (Cluster, error) {
 	if len(b.followersConstructors) == 0 {
 		return nil, fmt.Errorf("%w: at least one folower constructor is required", ErrInvalidClusterConfiguration)
 	}
@@ -74,5 +108,17 @@ func (b builder) Go() (Pool, error) {
 		fellows = append(fellows, follower)
 	}
 
-	return cluster.New(leader, fellows), nil
+	return swappable{Cluster: cluster.New(leader, fellows, b.opts...)}, nil
+}
+
+type swappable struct {
+	*cluster.Cluster
+}
+
+func (c swappable) SwapFollowers(ctx context.Context, followers []Pool) error {
+	fellows := make([]cluster.Pool, 0, len(followers))
+	for _, follower := range followers {
+		fellows = append(fellows, follower)
+	}
+	return c.Cluster.SwapFollowers(ctx, fellows)
 }
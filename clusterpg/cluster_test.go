@@ -2,10 +2,17 @@ package clusterpg
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
 
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
 	"github.com/godepo/groat"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -173,3 +180,68 @@ func TestNew(t *testing.T) {
 			Go()
 	})
 }
+
+func TestBuilder_CircuitBreaker(t *testing.T) {
+	t.Run("should be able to guard followers by circuit breaker", func(t *testing.T) {
+		ctx := context.Background()
+		follower := NewMockPool(t)
+		follower.EXPECT().Exec(ctx, testQuery).
+			Return(pgconn.CommandTag{}, &net.OpError{Op: "dial", Err: errors.New("connection refused")}).Once()
+		cls, err := New().
+			Leader(func() (Pool, error) { return NewMockPool(t), nil }).
+			Follower(func() (Pool, error) { return follower, nil }).
+			CircuitBreaker(pgbreaker.Config{ConsecutiveFailures: 1}).
+			Go()
+		require.NoError(t, err)
+		_, err = cls.Exec(ctx, testQuery)
+		require.Error(t, err)
+
+		_, err = cls.Exec(ctx, testQuery)
+
+		assert.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+	})
+}
+
+func TestCluster_SwapFollowers(t *testing.T) {
+	t.Run("should be able to route reads to swapped followers", func(t *testing.T) {
+		ctx := context.Background()
+		previous, next := NewMockPool(t), NewMockPool(t)
+		next.EXPECT().Exec(ctx, testQuery).Return(pgconn.CommandTag{}, nil)
+		cls, err := New().
+			Leader(func() (Pool, error) { return NewMockPool(t), nil }).
+			Follower(func() (Pool, error) { return previous, nil }).
+			Go()
+		require.NoError(t, err)
+
+		require.NoError(t, cls.SwapFollowers(ctx, []Pool{next}))
+
+		_, err = cls.Exec(ctx, testQuery)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should be able to reject empty followers", func(t *testing.T) {
+		cls, err := New().
+			Leader(func() (Pool, error) { return NewMockPool(t), nil }).
+			Follower(func() (Pool, error) { return NewMockPool(t), nil }).
+			Go()
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, cls.SwapFollowers(context.Background(), nil), ErrNoFollowers)
+	})
+}
+
+func TestCluster_OptionalNodeMethods(t *testing.T) {
+	t.Run("should be able to build cluster of nodes without acquire and shutdown", func(t *testing.T) {
+		ctx := context.Background()
+		cls, err := New().
+			Leader(func() (Pool, error) { return NewMockPool(t), nil }).
+			Follower(func() (Pool, error) { return NewMockPool(t), nil }).
+			Go()
+		require.NoError(t, err)
+
+		_, err = cls.Acquire(ctx)
+		assert.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+		require.NoError(t, cls.Shutdown(ctx))
+		assert.Equal(t, pgdrain.StageClosed, cls.ShutdownProgress().Stage)
+	})
+}
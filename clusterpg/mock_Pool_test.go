@@ -4,10 +4,12 @@ package clusterpg
 
 import (
 	context "context"
+	io "io"
 
-	pgconn "github.com/jackc/pgx/v5/pgconn"
 	mock "github.com/stretchr/testify/mock"
 
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+
 	pgx "github.com/jackc/pgx/v5"
 )
 
@@ -141,6 +143,123 @@ func (_c *MockPool_BeginTx_Call) RunAndReturn(run func(context.Context, pgx.TxOp
 	return _c
 }
 
+// CopyFrom provides a mock function with given fields: ctx, tableName, columnNames, rowSrc
+func (_m *MockPool) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ret := _m.Called(ctx, tableName, columnNames, rowSrc)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyFrom")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error)); ok {
+		return rf(ctx, tableName, columnNames, rowSrc)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) int64); ok {
+		r0 = rf(ctx, tableName, columnNames, rowSrc)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) error); ok {
+		r1 = rf(ctx, tableName, columnNames, rowSrc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_CopyFrom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyFrom'
+type MockPool_CopyFrom_Call struct {
+	*mock.Call
+}
+
+// CopyFrom is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tableName pgx.Identifier
+//   - columnNames []string
+//   - rowSrc pgx.CopyFromSource
+func (_e *MockPool_Expecter) CopyFrom(ctx interface{}, tableName interface{}, columnNames interface{}, rowSrc interface{}) *MockPool_CopyFrom_Call {
+	return &MockPool_CopyFrom_Call{Call: _e.mock.On("CopyFrom", ctx, tableName, columnNames, rowSrc)}
+}
+
+func (_c *MockPool_CopyFrom_Call) Run(run func(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource)) *MockPool_CopyFrom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Identifier), args[2].([]string), args[3].(pgx.CopyFromSource))
+	})
+	return _c
+}
+
+func (_c *MockPool_CopyFrom_Call) Return(_a0 int64, _a1 error) *MockPool_CopyFrom_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_CopyFrom_Call) RunAndReturn(run func(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error)) *MockPool_CopyFrom_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CopyTo provides a mock function with given fields: ctx, w, query
+func (_m *MockPool) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	ret := _m.Called(ctx, w, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyTo")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer, string) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, w, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer, string) pgconn.CommandTag); ok {
+		r0 = rf(ctx, w, query)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, io.Writer, string) error); ok {
+		r1 = rf(ctx, w, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPool_CopyTo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyTo'
+type MockPool_CopyTo_Call struct {
+	*mock.Call
+}
+
+// CopyTo is a helper method to define mock.On call
+//   - ctx context.Context
+//   - w io.Writer
+//   - query string
+func (_e *MockPool_Expecter) CopyTo(ctx interface{}, w interface{}, query interface{}) *MockPool_CopyTo_Call {
+	return &MockPool_CopyTo_Call{Call: _e.mock.On("CopyTo", ctx, w, query)}
+}
+
+func (_c *MockPool_CopyTo_Call) Run(run func(ctx context.Context, w io.Writer, query string)) *MockPool_CopyTo_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(io.Writer), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockPool_CopyTo_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockPool_CopyTo_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPool_CopyTo_Call) RunAndReturn(run func(context.Context, io.Writer, string) (pgconn.CommandTag, error)) *MockPool_CopyTo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Exec provides a mock function with given fields: ctx, query, args
 func (_m *MockPool) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
 	var _ca []interface{}
@@ -339,6 +458,55 @@ func (_c *MockPool_QueryRow_Call) RunAndReturn(run func(context.Context, string,
 	return _c
 }
 
+// SendBatch provides a mock function with given fields: ctx, b
+func (_m *MockPool) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	ret := _m.Called(ctx, b)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendBatch")
+	}
+
+	var r0 pgx.BatchResults
+	if rf, ok := ret.Get(0).(func(context.Context, *pgx.Batch) pgx.BatchResults); ok {
+		r0 = rf(ctx, b)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.BatchResults)
+		}
+	}
+
+	return r0
+}
+
+// MockPool_SendBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendBatch'
+type MockPool_SendBatch_Call struct {
+	*mock.Call
+}
+
+// SendBatch is a helper method to define mock.On call
+//   - ctx context.Context
+//   - b *pgx.Batch
+func (_e *MockPool_Expecter) SendBatch(ctx interface{}, b interface{}) *MockPool_SendBatch_Call {
+	return &MockPool_SendBatch_Call{Call: _e.mock.On("SendBatch", ctx, b)}
+}
+
+func (_c *MockPool_SendBatch_Call) Run(run func(ctx context.Context, b *pgx.Batch)) *MockPool_SendBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*pgx.Batch))
+	})
+	return _c
+}
+
+func (_c *MockPool_SendBatch_Call) Return(_a0 pgx.BatchResults) *MockPool_SendBatch_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPool_SendBatch_Call) RunAndReturn(run func(context.Context, *pgx.Batch) pgx.BatchResults) *MockPool_SendBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Transactional provides a mock function with given fields: ctx, fn
 func (_m *MockPool) Transactional(ctx context.Context, fn func(context.Context) error) error {
 	ret := _m.Called(ctx, fn)
@@ -0,0 +1,256 @@
+package configpg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidConfig = errors.New("configpg: invalid config")
+
+type Layout string
+
+const (
+	LayoutSingle  Layout = "single"
+	LayoutCluster Layout = "cluster"
+	LayoutSharded Layout = "sharded"
+)
+
+// Names of load balancers of followers and shard pickers.
+const (
+	BalancerRoundRobin = "round_robin"
+	BalancerRandom     = "random"
+	PickerHash         = "hash"
+)
+
+// Config describes topology by DSNs. Layout is inferred, when empty: sharded with shards, cluster with followers,
+// single otherwise. Leader and Followers describe single node or cluster, every shard is single node or cluster.
+type Config struct {
+	Layout    Layout   `yaml:"layout"`
+	Leader    string   `yaml:"leader"`
+	Followers []string `yaml:"followers"`
+	Shards    []Shard  `yaml:"shards"`
+	Pool      Pool     `yaml:"pool"`
+	Balancer  string   `yaml:"balancer"`
+	Picker    string   `yaml:"picker"`
+	Timeouts  Timeouts `yaml:"timeouts"`
+	// CircuitBreaker guards nodes of clusters and shards by circuit breakers, when it's set, even empty.
+	CircuitBreaker *CircuitBreaker `yaml:"circuit_breaker"`
+	// Lazy skips ping of every pool at construction, so nodes are connected on first use.
+	Lazy bool `yaml:"lazy"`
+}
+
+type Shard struct {
+	Leader    string   `yaml:"leader"`
+	Followers []string `yaml:"followers"`
+}
+
+// Pool settings are applied to every pgx pool, zero values keep defaults of pgxpool.
+type Pool struct {
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+}
+
+// Timeouts are applied to connections of every pool, statement and lock timeouts are defaults of session, which
+// elephant.WithStatementTimeout and elephant.WithLockTimeout override. Errors of exceeded session defaults are
+// returned as is, only timeouts from context are mapped into elephant.ErrStatementTimeout and elephant.ErrLockTimeout.
+type Timeouts struct {
+	Connect   time.Duration `yaml:"connect"`
+	Statement time.Duration `yaml:"statement"`
+	Lock      time.Duration `yaml:"lock"`
+}
+
+// CircuitBreaker settings, zero values keep defaults of elephant.CircuitBreaker.
+type CircuitBreaker struct {
+	ConsecutiveFailures int           `yaml:"consecutive_failures"`
+	FailureRate         float64       `yaml:"failure_rate"`
+	Window              int           `yaml:"window"`
+	OpenTimeout         time.Duration `yaml:"open_timeout"`
+}
+
+// FromYAML reads config from YAML document.
+func FromYAML(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("%w: can't read yaml: %w", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+// FromEnv reads config from environment variables with prefix, for example with prefix DB:
+//
+//	DB_LAYOUT, DB_LEADER, DB_FOLLOWERS (comma separated), DB_SHARD_0_LEADER, DB_SHARD_0_FOLLOWERS, ...
+//	DB_POOL_MAX_CONNS, DB_POOL_MIN_CONNS, DB_POOL_MAX_CONN_LIFETIME, DB_POOL_MAX_CONN_IDLE_TIME,
+//	DB_POOL_HEALTH_CHECK_PERIOD, DB_BALANCER, DB_PICKER, DB_CONNECT_TIMEOUT, DB_STATEMENT_TIMEOUT, DB_LOCK_TIMEOUT,
+//	DB_LAZY, DB_CIRCUIT_BREAKER (bool), DB_CIRCUIT_BREAKER_CONSECUTIVE_FAILURES, DB_CIRCUIT_BREAKER_FAILURE_RATE,
+//	DB_CIRCUIT_BREAKER_WINDOW, DB_CIRCUIT_BREAKER_OPEN_TIMEOUT
+//
+// Shards are read from index 0 until the first one without leader.
+func FromEnv(prefix string) (Config, error) {
+	env := envReader{prefix: prefix}
+	cfg := Config{
+		Layout:    Layout(env.string("LAYOUT")),
+		Leader:    env.string("LEADER"),
+		Followers: env.list("FOLLOWERS"),
+		Pool: Pool{
+			MaxConns:          env.int32("POOL_MAX_CONNS"),
+			MinConns:          env.int32("POOL_MIN_CONNS"),
+			MaxConnLifetime:   env.duration("POOL_MAX_CONN_LIFETIME"),
+			MaxConnIdleTime:   env.duration("POOL_MAX_CONN_IDLE_TIME"),
+			HealthCheckPeriod: env.duration("POOL_HEALTH_CHECK_PERIOD"),
+		},
+		Balancer: env.string("BALANCER"),
+		Picker:   env.string("PICKER"),
+		Timeouts: Timeouts{
+			Connect:   env.duration("CONNECT_TIMEOUT"),
+			Statement: env.duration("STATEMENT_TIMEOUT"),
+			Lock:      env.duration("LOCK_TIMEOUT"),
+		},
+		Lazy: env.bool("LAZY"),
+	}
+	if env.bool("CIRCUIT_BREAKER") {
+		cfg.CircuitBreaker = &CircuitBreaker{
+			ConsecutiveFailures: int(env.int32("CIRCUIT_BREAKER_CONSECUTIVE_FAILURES")),
+			FailureRate:         env.float64("CIRCUIT_BREAKER_FAILURE_RATE"),
+			Window:              int(env.int32("CIRCUIT_BREAKER_WINDOW")),
+			OpenTimeout:         env.duration("CIRCUIT_BREAKER_OPEN_TIMEOUT"),
+		}
+	}
+	for i := 0; ; i++ {
+		leader := env.string(fmt.Sprintf("SHARD_%d_LEADER", i))
+		if leader == "" {
+			break
+		}
+		cfg.Shards = append(cfg.Shards, Shard{
+			Leader:    leader,
+			Followers: env.list(fmt.Sprintf("SHARD_%d_FOLLOWERS", i)),
+		})
+	}
+	if err := errors.Join(env.errs...); err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+type envReader struct {
+	prefix string
+	errs   []error
+}
+
+func (e *envReader) string(name string) string {
+	return strings.TrimSpace(os.Getenv(e.prefix + "_" + name))
+}
+
+func (e *envReader) list(name string) []string {
+	var out []string
+	for _, item := range strings.Split(e.string(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func (e *envReader) int32(name string) int32 {
+	value := e.string(name)
+	if value == "" {
+		return 0
+	}
+	out, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s_%s: %w", e.prefix, name, err))
+	}
+	return int32(out)
+}
+
+func (e *envReader) float64(name string) float64 {
+	value := e.string(name)
+	if value == "" {
+		return 0
+	}
+	out, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s_%s: %w", e.prefix, name, err))
+	}
+	return out
+}
+
+func (e *envReader) duration(name string) time.Duration {
+	value := e.string(name)
+	if value == "" {
+		return 0
+	}
+	out, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s_%s: %w", e.prefix, name, err))
+	}
+	return out
+}
+
+func (e *envReader) bool(name string) bool {
+	value := e.string(name)
+	if value == "" {
+		return false
+	}
+	out, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s_%s: %w", e.prefix, name, err))
+	}
+	return out
+}
+
+// layout returns layout of config, after its validation.
+func (cfg Config) layout() (Layout, error) {
+	layout := cfg.Layout
+	if layout == "" {
+		switch {
+		case len(cfg.Shards) > 0:
+			layout = LayoutSharded
+		case len(cfg.Followers) > 0:
+			layout = LayoutCluster
+		default:
+			layout = LayoutSingle
+		}
+	}
+	switch layout {
+	case LayoutSingle, LayoutCluster:
+		if cfg.Leader == "" {
+			return "", fmt.Errorf("%w: leader is required for %s layout", ErrInvalidConfig, layout)
+		}
+		if len(cfg.Shards) > 0 {
+			return "", fmt.Errorf("%w: shards aren't allowed for %s layout", ErrInvalidConfig, layout)
+		}
+		if layout == LayoutSingle && len(cfg.Followers) > 0 {
+			return "", fmt.Errorf("%w: followers aren't allowed for single layout", ErrInvalidConfig)
+		}
+		if layout == LayoutCluster && len(cfg.Followers) == 0 {
+			return "", fmt.Errorf("%w: at least one follower is required for cluster layout", ErrInvalidConfig)
+		}
+	case LayoutSharded:
+		if cfg.Leader != "" || len(cfg.Followers) > 0 {
+			return "", fmt.Errorf("%w: leader and followers of sharded layout belong to shards", ErrInvalidConfig)
+		}
+		if len(cfg.Shards) == 0 {
+			return "", fmt.Errorf("%w: at least one shard is required for sharded layout", ErrInvalidConfig)
+		}
+		for i, shard := range cfg.Shards {
+			if shard.Leader == "" {
+				return "", fmt.Errorf("%w: leader is required for shard [%d]", ErrInvalidConfig, i)
+			}
+		}
+	default:
+		return "", fmt.Errorf("%w: unknown layout %q", ErrInvalidConfig, layout)
+	}
+	return layout, nil
+}
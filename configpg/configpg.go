@@ -0,0 +1,274 @@
+package configpg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/internal/cluster"
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
+	"github.com/godepo/elephant/internal/regular"
+	"github.com/godepo/elephant/internal/sharded"
+	"github.com/godepo/elephant/shardedpg"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB is database of any layout, which owns its pgx pools, so Shutdown closes them.
+type DB interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+	Shutdown(ctx context.Context) error
+	ShutdownProgress() elephant.ShutdownProgress
+	Ping(ctx context.Context, rules ...elephant.HealthRule) error
+	Health(ctx context.Context, rules ...elephant.HealthRule) elephant.HealthReport
+}
+
+type Options struct {
+	picker    shardedpg.ShardPicker
+	configure func(cfg *pgxpool.Config)
+	onError   func(err error)
+}
+
+type Option func(opts *Options)
+
+// WithPicker sets shard picker, which takes precedence over picker of config.
+func WithPicker(picker shardedpg.ShardPicker) Option {
+	return func(opts *Options) {
+		opts.picker = picker
+	}
+}
+
+// WithPoolConfig changes config of every pgx pool after settings of config are applied, for example to set tracer.
+func WithPoolConfig(fn func(cfg *pgxpool.Config)) Option {
+	return func(opts *Options) {
+		opts.configure = fn
+	}
+}
+
+// WithErrorHandler sets handler of errors, which Watch meets on loading config and reconfiguration.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(opts *Options) {
+		opts.onError = fn
+	}
+}
+
+// New opens pgx pools of every node described by cfg and builds database of its layout. Pools are pinged, unless
+// config is lazy. When any step fails, pools already opened are closed.
+func New(ctx context.Context, cfg Config, opts ...Option) (DB, error) {
+	b := newBuild(cfg, opts)
+	db, err := b.db(ctx)
+	if err != nil {
+		b.close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type build struct {
+	cfg     Config
+	opts    Options
+	pools   []*pgxpool.Pool
+	swapped bool
+}
+
+func newBuild(cfg Config, opts []Option) *build {
+	b := &build{cfg: cfg}
+	for _, opt := range opts {
+		opt(&b.opts)
+	}
+	return b
+}
+
+func (b *build) db(ctx context.Context) (DB, error) {
+	layout, err := b.cfg.layout()
+	if err != nil {
+		return nil, err
+	}
+	balancer, err := b.balancer()
+	if err != nil {
+		return nil, err
+	}
+	switch layout {
+	case LayoutSingle:
+		return b.single(ctx, "leader", b.cfg.Leader)
+	case LayoutCluster:
+		return b.cluster(ctx, "", b.cfg.Leader, b.cfg.Followers, balancer)
+	}
+
+	picker, err := b.picker()
+	if err != nil {
+		return nil, err
+	}
+	shards, err := b.shards(ctx, balancer)
+	if err != nil {
+		return nil, err
+	}
+	var opts []sharded.Option
+	if breaker := b.breaker(); breaker != nil {
+		opts = append(opts, sharded.WithCircuitBreaker(*breaker))
+	}
+	return sharded.New(shards, sharded.Picker(picker), opts...), nil
+}
+
+func (b *build) shards(ctx context.Context, balancer cluster.LoadBalancer) ([]sharded.Pool, error) {
+	shards := make([]sharded.Pool, 0, len(b.cfg.Shards))
+	for i, shard := range b.cfg.Shards {
+		prefix := "shard [" + strconv.Itoa(i) + "] "
+		var db sharded.Pool
+		var err error
+		if len(shard.Followers) == 0 {
+			db, err = b.single(ctx, prefix+"leader", shard.Leader)
+		} else {
+			db, err = b.cluster(ctx, prefix, shard.Leader, shard.Followers, balancer)
+		}
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, db)
+	}
+	return shards, nil
+}
+
+func (b *build) single(ctx context.Context, name, dsn string) (*regular.Instance, error) {
+	pool, err := b.open(ctx, name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return regular.New(pool), nil
+}
+
+// breaker returns config of circuit breakers, nil is returned, when they are disabled.
+func (b *build) breaker() *pgbreaker.Config {
+	settings := b.cfg.CircuitBreaker
+	if settings == nil {
+		return nil
+	}
+	return &pgbreaker.Config{
+		ConsecutiveFailures: settings.ConsecutiveFailures,
+		FailureRate:         settings.FailureRate,
+		Window:              settings.Window,
+		OpenTimeout:         settings.OpenTimeout,
+	}
+}
+
+func (b *build) cluster(
+	ctx context.Context, prefix, leaderDSN string, followerDSNs []string, balancer cluster.LoadBalancer,
+) (*cluster.Cluster, error) {
+	leader, err := b.single(ctx, prefix+"leader", leaderDSN)
+	if err != nil {
+		return nil, err
+	}
+	fellows, err := b.followers(ctx, prefix, followerDSNs)
+	if err != nil {
+		return nil, err
+	}
+	opts := []cluster.Option{cluster.WithLoadBalancer(balancer), cluster.WithName(prefix)}
+	if breaker := b.breaker(); breaker != nil {
+		opts = append(opts, cluster.WithCircuitBreaker(*breaker))
+	}
+	return cluster.New(leader, fellows, opts...), nil
+}
+
+func (b *build) followers(ctx context.Context, prefix string, dsns []string) ([]cluster.Pool, error) {
+	fellows := make([]cluster.Pool, 0, len(dsns))
+	for i, dsn := range dsns {
+		follower, err := b.single(ctx, fmt.Sprintf("%sfollower [%d]", prefix, i), dsn)
+		if err != nil {
+			return nil, err
+		}
+		fellows = append(fellows, follower)
+	}
+	return fellows, nil
+}
+
+func (b *build) close() {
+	for _, pool := range b.pools {
+		pool.Close()
+	}
+}
+
+func (b *build) balancer() (cluster.LoadBalancer, error) {
+	switch b.cfg.Balancer {
+	case "", BalancerRoundRobin:
+		return cluster.DefaultLoadBalancer(), nil
+	case BalancerRandom:
+		return cluster.RandomLoadBalancer(), nil
+	}
+	return nil, fmt.Errorf("%w: unknown balancer %q", ErrInvalidConfig, b.cfg.Balancer)
+}
+
+func (b *build) picker() (shardedpg.ShardPicker, error) {
+	if b.opts.picker != nil {
+		return b.opts.picker, nil
+	}
+	switch b.cfg.Picker {
+	case "", PickerHash:
+		return shardedpg.HashPicker(uint(len(b.cfg.Shards))), nil
+	}
+	return nil, fmt.Errorf("%w: unknown picker %q", ErrInvalidConfig, b.cfg.Picker)
+}
+
+// open creates pgx pool of node with settings of config and pings it, unless config is lazy.
+func (b *build) open(ctx context.Context, name, dsn string) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't parse dsn of %s: %w", ErrInvalidConfig, name, err)
+	}
+	b.apply(poolCfg)
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't open pool of %s: %w", name, err)
+	}
+	b.pools = append(b.pools, pool)
+	if b.cfg.Lazy {
+		return pool, nil
+	}
+	if err := pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("can't ping %s: %w", name, err)
+	}
+	return pool, nil
+}
+
+func (b *build) apply(poolCfg *pgxpool.Config) {
+	settings := b.cfg.Pool
+	if settings.MaxConns > 0 {
+		poolCfg.MaxConns = settings.MaxConns
+	}
+	if settings.MinConns > 0 {
+		poolCfg.MinConns = settings.MinConns
+	}
+	if settings.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = settings.MaxConnLifetime
+	}
+	if settings.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = settings.MaxConnIdleTime
+	}
+	if settings.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = settings.HealthCheckPeriod
+	}
+	timeouts := b.cfg.Timeouts
+	if timeouts.Connect > 0 {
+		poolCfg.ConnConfig.ConnectTimeout = timeouts.Connect
+	}
+	if timeouts.Statement > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeouts.Statement.Milliseconds(), 10)
+	}
+	if timeouts.Lock > 0 {
+		poolCfg.ConnConfig.RuntimeParams["lock_timeout"] = strconv.FormatInt(timeouts.Lock.Milliseconds(), 10)
+	}
+	if b.opts.configure != nil {
+		b.opts.configure(poolCfg)
+	}
+}
@@ -0,0 +1,376 @@
+package configpg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/internal/cluster"
+	"github.com/godepo/elephant/internal/regular"
+	"github.com/godepo/elephant/internal/sharded"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/puddle/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	leaderDSN   = "postgres://user@leader.local:5432/db"
+	followerDSN = "postgres://user@follower.local:5432/db"
+)
+
+func TestFromYAML(t *testing.T) {
+	t.Run("should be able to read sharded layout", func(t *testing.T) {
+		cfg, err := FromYAML(strings.NewReader(`
+layout: sharded
+shards:
+  - leader: postgres://shard0
+  - leader: postgres://shard1
+    followers: [postgres://shard1-replica]
+pool:
+  max_conns: 10
+  max_conn_lifetime: 1h
+balancer: random
+picker: hash
+timeouts:
+  connect: 5s
+  statement: 2s
+circuit_breaker:
+  consecutive_failures: 3
+  failure_rate: 0.5
+  window: 10
+  open_timeout: 30s
+lazy: true
+`))
+		require.NoError(t, err)
+		assert.Equal(t, Config{
+			Layout: LayoutSharded,
+			Shards: []Shard{
+				{Leader: "postgres://shard0"},
+				{Leader: "postgres://shard1", Followers: []string{"postgres://shard1-replica"}},
+			},
+			Pool:     Pool{MaxConns: 10, MaxConnLifetime: time.Hour},
+			Balancer: BalancerRandom,
+			Picker:   PickerHash,
+			Timeouts: Timeouts{Connect: 5 * time.Second, Statement: 2 * time.Second},
+			CircuitBreaker: &CircuitBreaker{
+				ConsecutiveFailures: 3, FailureRate: 0.5, Window: 10, OpenTimeout: 30 * time.Second,
+			},
+			Lazy: true,
+		}, cfg)
+	})
+
+	t.Run("should be able to fail at unknown field", func(t *testing.T) {
+		_, err := FromYAML(strings.NewReader("leaders: postgres://leader"))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Run("should be able to read config", func(t *testing.T) {
+		t.Setenv("DB_LEADER", leaderDSN)
+		t.Setenv("DB_FOLLOWERS", followerDSN+", "+followerDSN+",")
+		t.Setenv("DB_SHARD_0_LEADER", "postgres://shard0")
+		t.Setenv("DB_SHARD_0_FOLLOWERS", "postgres://shard0-replica")
+		t.Setenv("DB_SHARD_1_LEADER", "postgres://shard1")
+		t.Setenv("DB_POOL_MAX_CONNS", "8")
+		t.Setenv("DB_POOL_MIN_CONNS", "2")
+		t.Setenv("DB_POOL_MAX_CONN_LIFETIME", "1h")
+		t.Setenv("DB_POOL_MAX_CONN_IDLE_TIME", "10m")
+		t.Setenv("DB_POOL_HEALTH_CHECK_PERIOD", "30s")
+		t.Setenv("DB_BALANCER", BalancerRandom)
+		t.Setenv("DB_PICKER", PickerHash)
+		t.Setenv("DB_CONNECT_TIMEOUT", "3s")
+		t.Setenv("DB_STATEMENT_TIMEOUT", "2s")
+		t.Setenv("DB_LOCK_TIMEOUT", "1s")
+		t.Setenv("DB_LAZY", "true")
+		t.Setenv("DB_CIRCUIT_BREAKER", "true")
+		t.Setenv("DB_CIRCUIT_BREAKER_CONSECUTIVE_FAILURES", "3")
+		t.Setenv("DB_CIRCUIT_BREAKER_FAILURE_RATE", "0.5")
+		t.Setenv("DB_CIRCUIT_BREAKER_WINDOW", "10")
+		t.Setenv("DB_CIRCUIT_BREAKER_OPEN_TIMEOUT", "30s")
+
+		cfg, err := FromEnv("DB")
+
+		require.NoError(t, err)
+		assert.Equal(t, Config{
+			Leader:    leaderDSN,
+			Followers: []string{followerDSN, followerDSN},
+			Shards: []Shard{
+				{Leader: "postgres://shard0", Followers: []string{"postgres://shard0-replica"}},
+				{Leader: "postgres://shard1"},
+			},
+			Pool: Pool{
+				MaxConns:          8,
+				MinConns:          2,
+				MaxConnLifetime:   time.Hour,
+				MaxConnIdleTime:   10 * time.Minute,
+				HealthCheckPeriod: 30 * time.Second,
+			},
+			Balancer: BalancerRandom,
+			Picker:   PickerHash,
+			Timeouts: Timeouts{Connect: 3 * time.Second, Statement: 2 * time.Second, Lock: time.Second},
+			CircuitBreaker: &CircuitBreaker{
+				ConsecutiveFailures: 3, FailureRate: 0.5, Window: 10, OpenTimeout: 30 * time.Second,
+			},
+			Lazy: true,
+		}, cfg)
+	})
+
+	t.Run("should be able to read empty config", func(t *testing.T) {
+		cfg, err := FromEnv("DB")
+
+		require.NoError(t, err)
+		assert.Equal(t, Config{}, cfg)
+	})
+
+	t.Run("should be able to enable circuit breaker with defaults", func(t *testing.T) {
+		t.Setenv("DB_CIRCUIT_BREAKER", "true")
+
+		cfg, err := FromEnv("DB")
+
+		require.NoError(t, err)
+		assert.Equal(t, Config{CircuitBreaker: &CircuitBreaker{}}, cfg)
+	})
+
+	t.Run("should be able to fail at malformed values", func(t *testing.T) {
+		t.Setenv("DB_POOL_MAX_CONNS", "many")
+		t.Setenv("DB_CONNECT_TIMEOUT", "soon")
+		t.Setenv("DB_LAZY", "sometimes")
+		t.Setenv("DB_CIRCUIT_BREAKER", "true")
+		t.Setenv("DB_CIRCUIT_BREAKER_FAILURE_RATE", "half")
+
+		_, err := FromEnv("DB")
+
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.Contains(t, err.Error(), "DB_CIRCUIT_BREAKER_FAILURE_RATE")
+		assert.Contains(t, err.Error(), "DB_POOL_MAX_CONNS")
+		assert.Contains(t, err.Error(), "DB_CONNECT_TIMEOUT")
+		assert.Contains(t, err.Error(), "DB_LAZY")
+	})
+}
+
+func TestConfig_layout(t *testing.T) {
+	shards := []Shard{{Leader: leaderDSN}}
+	for name, tc := range map[string]struct {
+		cfg Config
+		exp Layout
+	}{
+		"single":          {cfg: Config{Leader: leaderDSN}, exp: LayoutSingle},
+		"cluster":         {cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN}}, exp: LayoutCluster},
+		"sharded":         {cfg: Config{Shards: shards}, exp: LayoutSharded},
+		"explicit single": {cfg: Config{Layout: LayoutSingle, Leader: leaderDSN}, exp: LayoutSingle},
+	} {
+		t.Run("should be able to infer "+name, func(t *testing.T) {
+			layout, err := tc.cfg.layout()
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, layout)
+		})
+	}
+
+	for name, cfg := range map[string]Config{
+		"single without leader":    {Layout: LayoutSingle},
+		"single with shards":       {Layout: LayoutSingle, Leader: leaderDSN, Shards: shards},
+		"single with followers":    {Layout: LayoutSingle, Leader: leaderDSN, Followers: []string{followerDSN}},
+		"cluster without follower": {Layout: LayoutCluster, Leader: leaderDSN},
+		"sharded with leader":      {Leader: leaderDSN, Shards: shards},
+		"sharded without shards":   {Layout: LayoutSharded},
+		"shard without leader":     {Shards: []Shard{{Followers: []string{followerDSN}}}},
+		"unknown layout":           {Layout: "ring", Leader: leaderDSN},
+	} {
+		t.Run("should be able to reject "+name, func(t *testing.T) {
+			_, err := cfg.layout()
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should be able to build single node", func(t *testing.T) {
+		db, err := New(ctx, Config{Leader: leaderDSN, Lazy: true})
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+
+		assert.IsType(t, &regular.Instance{}, db)
+	})
+
+	t.Run("should be able to build cluster", func(t *testing.T) {
+		db, err := New(ctx, Config{
+			Leader: leaderDSN, Followers: []string{followerDSN}, Balancer: BalancerRandom, Lazy: true,
+		})
+		require.NoError(t, err)
+
+		assert.IsType(t, &cluster.Cluster{}, db)
+		require.NoError(t, db.Shutdown(ctx))
+		assert.Equal(t, elephant.StageClosed, db.ShutdownProgress().Stage)
+	})
+
+	t.Run("should be able to build sharded database of single nodes and clusters", func(t *testing.T) {
+		var picked string
+		db, err := New(ctx, Config{
+			Shards: []Shard{{Leader: leaderDSN}, {Leader: leaderDSN, Followers: []string{followerDSN}}},
+			Lazy:   true,
+		}, WithPicker(func(_ context.Context, key string) uint {
+			picked = key
+			return 1
+		}))
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+
+		require.IsType(t, &sharded.Hive{}, db)
+		report := db.Health(elephant.With(ctx, elephant.WithShardingKey("tenant")))
+		require.Len(t, report.Nodes, 3)
+		assert.Equal(t, elephant.RoleSingle, report.Nodes[0].Role)
+		assert.Equal(t, elephant.RoleLeader, report.Nodes[1].Role)
+		assert.Equal(t, elephant.RoleFollower, report.Nodes[2].Role)
+		assert.Equal(t, "leader.local:5432", report.Nodes[1].Address)
+
+		_, _ = db.Acquire(elephant.With(ctx, elephant.WithShardingKey("tenant")))
+		assert.Equal(t, "tenant", picked)
+	})
+
+	t.Run("should be able to pick shards by hash", func(t *testing.T) {
+		db, err := New(ctx, Config{Shards: []Shard{{Leader: leaderDSN}}, Picker: PickerHash, Lazy: true})
+		require.NoError(t, err)
+
+		require.NoError(t, db.Shutdown(ctx))
+	})
+
+	t.Run("should be able to guard nodes by circuit breakers", func(t *testing.T) {
+		refusedDSN := "postgres://user@127.0.0.1:1/db"
+		breaker := &CircuitBreaker{ConsecutiveFailures: 1, OpenTimeout: time.Minute}
+		for _, tc := range []struct {
+			cfg  Config
+			ctx  context.Context
+			node string
+		}{
+			{
+				cfg:  Config{Leader: leaderDSN, Followers: []string{refusedDSN}},
+				ctx:  ctx,
+				node: "follower [0]",
+			},
+			{
+				cfg:  Config{Shards: []Shard{{Leader: refusedDSN}}},
+				ctx:  elephant.With(ctx, elephant.WithShardID(0)),
+				node: "shard [0]",
+			},
+			{
+				cfg:  Config{Shards: []Shard{{Leader: leaderDSN}, {Leader: leaderDSN, Followers: []string{refusedDSN}}}},
+				ctx:  elephant.With(ctx, elephant.WithShardID(1)),
+				node: "shard [1] follower [0]",
+			},
+		} {
+			tc.cfg.CircuitBreaker, tc.cfg.Lazy = breaker, true
+			db, err := New(ctx, tc.cfg)
+			require.NoError(t, err)
+			_, err = db.Exec(tc.ctx, "SELECT 1")
+			require.Error(t, err)
+
+			_, err = db.Exec(tc.ctx, "SELECT 1")
+
+			var openErr *elephant.CircuitOpenError
+			require.ErrorAs(t, err, &openErr)
+			assert.Equal(t, tc.node, openErr.Node)
+			require.NoError(t, db.Shutdown(ctx))
+		}
+	})
+
+	t.Run("should be able to fail at invalid config", func(t *testing.T) {
+		_, err := New(ctx, Config{})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("should be able to fail at unknown balancer", func(t *testing.T) {
+		_, err := New(ctx, Config{Leader: leaderDSN, Balancer: "least_lag"})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("should be able to fail at unknown picker", func(t *testing.T) {
+		_, err := New(ctx, Config{Shards: []Shard{{Leader: leaderDSN}}, Picker: "range"})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	for name, cfg := range map[string]Config{
+		"leader":            {Leader: "postgres://%"},
+		"follower":          {Leader: leaderDSN, Followers: []string{followerDSN, "postgres://%"}},
+		"leader of shard":   {Shards: []Shard{{Leader: leaderDSN}, {Leader: "postgres://%"}}},
+		"follower of shard": {Shards: []Shard{{Leader: leaderDSN, Followers: []string{"postgres://%"}}}},
+		"leader of cluster": {Leader: "postgres://%", Followers: []string{followerDSN}},
+		"shard after cluster": {Shards: []Shard{
+			{Leader: leaderDSN, Followers: []string{followerDSN}},
+			{Leader: "postgres://%"},
+		}},
+	} {
+		t.Run("should be able to fail at malformed dsn of "+name, func(t *testing.T) {
+			cfg.Lazy = true
+			_, err := New(ctx, cfg)
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+		})
+	}
+
+	t.Run("should be able to fail, when pool can't be opened", func(t *testing.T) {
+		_, err := New(ctx, Config{Leader: leaderDSN, Lazy: true}, WithPoolConfig(func(cfg *pgxpool.Config) {
+			cfg.MaxConns = 0
+		}))
+		assert.ErrorContains(t, err, "can't open pool of leader")
+	})
+
+	t.Run("should be able to fail, when node isn't reachable", func(t *testing.T) {
+		_, err := New(ctx, Config{
+			Leader:   "postgres://user@127.0.0.1:1/db",
+			Timeouts: Timeouts{Connect: time.Second},
+		})
+		assert.ErrorContains(t, err, "can't ping leader")
+	})
+}
+
+func TestBuild_close(t *testing.T) {
+	b := &build{cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN, "postgres://%"}, Lazy: true}}
+
+	_, err := b.db(context.Background())
+	require.Error(t, err)
+	require.Len(t, b.pools, 2)
+
+	b.close()
+	for _, pool := range b.pools {
+		_, err := pool.Acquire(context.Background())
+		assert.True(t, errors.Is(err, puddle.ErrClosedPool))
+	}
+}
+
+func TestBuild_open(t *testing.T) {
+	b := &build{cfg: Config{
+		Pool: Pool{
+			MaxConns:          8,
+			MinConns:          1,
+			MaxConnLifetime:   time.Hour,
+			MaxConnIdleTime:   time.Minute,
+			HealthCheckPeriod: time.Second,
+		},
+		Timeouts: Timeouts{Connect: 3 * time.Second, Statement: 2 * time.Second, Lock: time.Second},
+		Lazy:     true,
+	}}
+	b.opts.configure = func(cfg *pgxpool.Config) {
+		cfg.ConnConfig.RuntimeParams["application_name"] = "orders"
+	}
+
+	pool, err := b.open(context.Background(), "leader", leaderDSN)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	cfg := pool.Config()
+	assert.Equal(t, int32(8), cfg.MaxConns)
+	assert.Equal(t, int32(1), cfg.MinConns)
+	assert.Equal(t, time.Hour, cfg.MaxConnLifetime)
+	assert.Equal(t, time.Minute, cfg.MaxConnIdleTime)
+	assert.Equal(t, time.Second, cfg.HealthCheckPeriod)
+	assert.Equal(t, 3*time.Second, cfg.ConnConfig.ConnectTimeout)
+	assert.Equal(t, "2000", cfg.ConnConfig.RuntimeParams["statement_timeout"])
+	assert.Equal(t, "1000", cfg.ConnConfig.RuntimeParams["lock_timeout"])
+	assert.Equal(t, "orders", cfg.ConnConfig.RuntimeParams["application_name"])
+}
@@ -0,0 +1,63 @@
+// Package integration runs configpg against postgres in container, apart from configpg tests, which don't need it.
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/configpg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+// suite provides DSN of postgres in container.
+var suite *integration.Container[Deps, State, string]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, string] {
+	return groat.New[Deps, State, string](t, func(t *testing.T, deps Deps) string {
+		return deps.DB.Config().ConnString()
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, string](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrator(func(context.Context, pgrx.MigratorConfig) error {
+				return nil
+			}),
+		),
+	)
+	os.Exit(suite.Go())
+}
+
+func TestNew_Integration(t *testing.T) {
+	tcs := suite.Case(t)
+	tcs.Go()
+	ctx := context.Background()
+
+	db, err := configpg.New(ctx, configpg.Config{
+		Leader:    tcs.SUT,
+		Followers: []string{tcs.SUT},
+		Timeouts:  configpg.Timeouts{Statement: time.Second},
+	})
+	require.NoError(t, err)
+
+	var timeout string
+	require.NoError(t, db.QueryRow(ctx, "SHOW statement_timeout").Scan(&timeout))
+	assert.Equal(t, "1s", timeout)
+	require.NoError(t, db.Ping(ctx))
+	require.NoError(t, db.Shutdown(ctx))
+}
@@ -0,0 +1,155 @@
+package configpg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/godepo/elephant/internal/cluster"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/sharded"
+	"github.com/godepo/elephant/shardedpg"
+)
+
+// Loader loads current config, for example from file or from remote store.
+type Loader func(ctx context.Context) (Config, error)
+
+// FileLoader loads config from YAML file at path.
+func FileLoader(path string) Loader {
+	return func(context.Context) (Config, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("can't open config: %w", err)
+		}
+		defer f.Close()
+		return FromYAML(f)
+	}
+}
+
+// Reconfigure swaps followers of cluster, or shards of sharded database, which runs with current config, with nodes of
+// next config. Layout and count of shards must be kept. Leader, load balancer, pool settings, timeouts and circuit
+// breaker of cluster, picker and circuit breaker of sharded database can't be changed, config, which changes them, is
+// rejected with ErrInvalidConfig. New pools are opened with settings of next config, queries in flight finish on
+// previous pools, which are closed, once they are drained.
+func Reconfigure(ctx context.Context, db DB, current, next Config, opts ...Option) error {
+	_, err := reconfigure(ctx, db, current, newBuild(next, opts))
+	return err
+}
+
+// reconfigure reports, whether db took new pools, even when previous ones aren't retired cleanly.
+func reconfigure(ctx context.Context, db DB, current Config, b *build) (bool, error) {
+	err := b.swap(ctx, db, current)
+	if err == nil {
+		return true, nil
+	}
+	if b.swapped && !rejected(err) {
+		return true, err
+	}
+	b.close()
+	return false, err
+}
+
+// rejected reports, whether swap failed before new pools were taken.
+func rejected(err error) bool {
+	return errors.Is(err, pgerr.ErrShutdown) ||
+		errors.Is(err, cluster.ErrNoFollowers) ||
+		errors.Is(err, sharded.ErrShardCountMismatch)
+}
+
+func (b *build) swap(ctx context.Context, db DB, current Config) error {
+	layout, err := b.cfg.layout()
+	if err != nil {
+		return err
+	}
+	if changed := b.fixed(layout, current); len(changed) > 0 {
+		return fmt.Errorf("%w: can't change %s at runtime", ErrInvalidConfig, strings.Join(changed, ", "))
+	}
+	if hive, ok := db.(*shardedpg.Hive); ok {
+		db = hive.Hive
+	}
+	switch target := db.(type) {
+	case *cluster.Cluster:
+		if layout != LayoutCluster {
+			return fmt.Errorf("%w: can't reconfigure cluster as %s", ErrInvalidConfig, layout)
+		}
+		fellows, err := b.followers(ctx, "", b.cfg.Followers)
+		if err != nil {
+			return err
+		}
+		b.swapped = true
+		return target.SwapFollowers(ctx, fellows)
+	case *sharded.Hive:
+		if layout != LayoutSharded {
+			return fmt.Errorf("%w: can't reconfigure sharded database as %s", ErrInvalidConfig, layout)
+		}
+		balancer, err := b.balancer()
+		if err != nil {
+			return err
+		}
+		shards, err := b.shards(ctx, balancer)
+		if err != nil {
+			return err
+		}
+		b.swapped = true
+		return target.SwapShards(ctx, shards)
+	}
+	return fmt.Errorf("%w: can't reconfigure %T", ErrInvalidConfig, db)
+}
+
+// fixed returns fields, which swap of layout can't apply and which differ from current config. Every pool of sharded
+// database is opened again, so only settings of hive itself are fixed.
+func (b *build) fixed(layout Layout, current Config) []string {
+	next := b.cfg
+	fields := []struct {
+		name string
+		same bool
+	}{
+		{name: "picker", same: layout != LayoutSharded || next.Picker == current.Picker},
+		{name: "leader", same: layout != LayoutCluster || next.Leader == current.Leader},
+		{name: "balancer", same: layout != LayoutCluster || next.Balancer == current.Balancer},
+		{name: "pool", same: layout != LayoutCluster || next.Pool == current.Pool},
+		{name: "timeouts", same: layout != LayoutCluster || next.Timeouts == current.Timeouts},
+		{name: "circuit_breaker", same: reflect.DeepEqual(next.CircuitBreaker, current.CircuitBreaker)},
+	}
+	var changed []string
+	for _, field := range fields {
+		if !field.same {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+// Watch loads config every interval and reconfigures db, which runs with current config, when loaded config differs
+// from the last applied one, until ctx is done. Errors of loading and of reconfiguration are passed to handler of
+// WithErrorHandler, config, which db didn't take, for example with changed leader, is applied again on next tick.
+func Watch(ctx context.Context, db DB, current Config, load Loader, interval time.Duration, opts ...Option) error {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		next, err := load(ctx)
+		if err == nil && !reflect.DeepEqual(current, next) {
+			var applied bool
+			applied, err = reconfigure(ctx, db, current, newBuild(next, opts))
+			if applied {
+				current = next
+			}
+		}
+		if err != nil && options.onError != nil {
+			options.onError(err)
+		}
+	}
+}
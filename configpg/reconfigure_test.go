@@ -0,0 +1,332 @@
+package configpg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/internal/regular"
+	"github.com/godepo/elephant/internal/sharded"
+	"github.com/godepo/elephant/shardedpg"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const replicaDSN = "postgres://user@replica.local:5432/db"
+
+func addresses(report elephant.HealthReport) []string {
+	out := make([]string, 0, len(report.Nodes))
+	for _, node := range report.Nodes {
+		out = append(out, node.Address)
+	}
+	return out
+}
+
+func TestFileLoader(t *testing.T) {
+	t.Run("should be able to load yaml file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("leader: "+leaderDSN+"\n"), 0o600))
+
+		cfg, err := FileLoader(path)(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, Config{Leader: leaderDSN}, cfg)
+	})
+
+	t.Run("should be able to fail, when file doesn't exist", func(t *testing.T) {
+		_, err := FileLoader(filepath.Join(t.TempDir(), "db.yaml"))(context.Background())
+
+		assert.ErrorContains(t, err, "can't open config")
+	})
+}
+
+func TestReconfigure(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should be able to swap followers of cluster", func(t *testing.T) {
+		cfg := Config{Leader: leaderDSN, Followers: []string{followerDSN}, Lazy: true}
+		db, err := New(ctx, cfg)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+
+		next := cfg
+		next.Followers = []string{replicaDSN, followerDSN}
+		require.NoError(t, Reconfigure(ctx, db, cfg, next))
+
+		assert.Equal(t, []string{"leader.local:5432", "replica.local:5432", "follower.local:5432"},
+			addresses(db.Health(ctx)))
+	})
+
+	t.Run("should be able to swap shards", func(t *testing.T) {
+		cfg := Config{Shards: []Shard{{Leader: leaderDSN}, {Leader: followerDSN}}, Lazy: true}
+		db, err := New(ctx, cfg)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+
+		next := cfg
+		next.Shards = []Shard{cfg.Shards[0], {Leader: replicaDSN, Followers: []string{followerDSN}}}
+		next.Pool = Pool{MaxConns: 4}
+		require.NoError(t, Reconfigure(ctx, db, cfg, next))
+
+		assert.Equal(t, []string{"leader.local:5432", "replica.local:5432", "follower.local:5432"},
+			addresses(db.Health(ctx)))
+	})
+
+	t.Run("should be able to swap shards of hive built by hand", func(t *testing.T) {
+		pool, err := pgxpool.New(ctx, leaderDSN)
+		require.NoError(t, err)
+		hive, err := shardedpg.New(1).
+			Shard(0, regular.New(pool)).
+			Picker(func(context.Context, string) uint { return 0 }).
+			Go()
+		require.NoError(t, err)
+		defer func() { _ = hive.Shutdown(ctx) }()
+
+		require.NoError(t, Reconfigure(ctx, hive, Config{}, Config{Shards: []Shard{{Leader: replicaDSN}}, Lazy: true}))
+
+		assert.Equal(t, []string{"replica.local:5432"}, addresses(hive.Health(ctx)))
+	})
+
+	t.Run("should be able to close new pools, when shards count is changed", func(t *testing.T) {
+		var opened []*pgxpool.Config
+		current := Config{Shards: []Shard{{Leader: leaderDSN}}, Lazy: true}
+		db, err := New(ctx, current)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+		b := newBuild(Config{Shards: []Shard{{Leader: leaderDSN}, {Leader: replicaDSN}}, Lazy: true},
+			[]Option{WithPoolConfig(func(cfg *pgxpool.Config) {
+				opened = append(opened, cfg)
+			})})
+
+		applied, err := reconfigure(ctx, db, current, b)
+
+		require.ErrorIs(t, err, sharded.ErrShardCountMismatch)
+		assert.False(t, applied)
+		assert.Len(t, opened, 2)
+		for _, pool := range b.pools {
+			_, err := pool.Acquire(ctx)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("should be able to keep new pools, when previous ones aren't retired in time", func(t *testing.T) {
+		silent, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer silent.Close()
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, err := silent.Accept()
+			if err == nil {
+				accepted <- conn
+			}
+		}()
+		current := Config{
+			Leader: leaderDSN, Followers: []string{"postgres://user@" + silent.Addr().String() + "/db"}, Lazy: true,
+		}
+		db, err := New(ctx, current)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+		queryCtx, stop := context.WithCancel(ctx)
+		queried := make(chan struct{})
+		go func() {
+			defer close(queried)
+			_, _ = db.Exec(queryCtx, "SELECT 1")
+		}()
+		conn := <-accepted
+		defer conn.Close()
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		applied, err := reconfigure(short, db, current, newBuild(Config{
+			Leader: leaderDSN, Followers: []string{replicaDSN}, Lazy: true,
+		}, nil))
+
+		stop()
+		<-queried
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, applied)
+		assert.Equal(t, []string{"leader.local:5432", "replica.local:5432"}, addresses(db.Health(ctx)))
+	})
+
+	t.Run("should be able to fail after shutdown", func(t *testing.T) {
+		current := Config{Leader: leaderDSN, Followers: []string{followerDSN}, Lazy: true}
+		db, err := New(ctx, current)
+		require.NoError(t, err)
+		require.NoError(t, db.Shutdown(ctx))
+
+		err = Reconfigure(ctx, db, current, Config{Leader: leaderDSN, Followers: []string{replicaDSN}, Lazy: true})
+
+		assert.ErrorIs(t, err, elephant.ErrShutdown)
+	})
+
+	t.Run("should be able to name fields, which can't be changed", func(t *testing.T) {
+		current := Config{Leader: leaderDSN, Followers: []string{followerDSN}, Lazy: true}
+		db, err := New(ctx, current)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(ctx) }()
+		next := current
+		next.Leader, next.Balancer = replicaDSN, BalancerRandom
+
+		err = Reconfigure(ctx, db, current, next)
+
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "can't change leader, balancer at runtime")
+	})
+
+	for name, tc := range map[string]struct {
+		db  Config
+		cfg Config
+	}{
+		"single node": {
+			db:  Config{Leader: leaderDSN},
+			cfg: Config{Leader: replicaDSN},
+		},
+		"cluster as sharded database": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Shards: []Shard{{Leader: leaderDSN}}},
+		},
+		"sharded database as cluster": {
+			db:  Config{Shards: []Shard{{Leader: leaderDSN}}},
+			cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+		},
+		"invalid config": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{},
+		},
+		"unknown balancer": {
+			db:  Config{Shards: []Shard{{Leader: leaderDSN}}},
+			cfg: Config{Shards: []Shard{{Leader: leaderDSN}}, Balancer: "least_lag"},
+		},
+		"malformed dsn of follower": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Leader: leaderDSN, Followers: []string{"postgres://%"}},
+		},
+		"malformed dsn of shard": {
+			db:  Config{Shards: []Shard{{Leader: leaderDSN}}},
+			cfg: Config{Shards: []Shard{{Leader: "postgres://%"}}},
+		},
+		"changed leader of cluster": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Leader: replicaDSN, Followers: []string{followerDSN}},
+		},
+		"changed balancer of cluster": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN}, Balancer: BalancerRandom},
+		},
+		"changed pool settings of cluster": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN}, Pool: Pool{MaxConns: 4}},
+		},
+		"changed timeouts of cluster": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN}, Timeouts: Timeouts{Lock: time.Second}},
+		},
+		"changed circuit breaker": {
+			db:  Config{Leader: leaderDSN, Followers: []string{followerDSN}},
+			cfg: Config{Leader: leaderDSN, Followers: []string{followerDSN}, CircuitBreaker: &CircuitBreaker{}},
+		},
+		"changed picker of sharded database": {
+			db:  Config{Shards: []Shard{{Leader: leaderDSN}}},
+			cfg: Config{Shards: []Shard{{Leader: leaderDSN}}, Picker: "range"},
+		},
+	} {
+		t.Run("should be able to reject "+name, func(t *testing.T) {
+			tc.db.Lazy, tc.cfg.Lazy = true, true
+			db, err := New(ctx, tc.db)
+			require.NoError(t, err)
+			defer func() { _ = db.Shutdown(ctx) }()
+
+			assert.ErrorIs(t, Reconfigure(ctx, db, tc.db, tc.cfg), ErrInvalidConfig)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("should be able to apply changed config and report errors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		current := Config{Leader: leaderDSN, Followers: []string{followerDSN}, Lazy: true}
+		db, err := New(ctx, current)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(context.Background()) }()
+		expErr := errors.New("config isn't available")
+		changed := Config{Leader: leaderDSN, Followers: []string{replicaDSN}, Lazy: true}
+		loads := []func() (Config, error){
+			func() (Config, error) { return current, nil },
+			func() (Config, error) { return Config{}, expErr },
+			func() (Config, error) { return Config{Leader: leaderDSN, Lazy: true}, nil },
+			func() (Config, error) { return changed, nil },
+		}
+		var calls int
+		var errs []error
+		done := make(chan error)
+
+		go func() {
+			done <- Watch(ctx, db, current, func(context.Context) (Config, error) {
+				load := loads[min(calls, len(loads)-1)]
+				calls++
+				if calls == len(loads)+1 {
+					cancel()
+				}
+				return load()
+			}, time.Millisecond, WithErrorHandler(func(err error) {
+				errs = append(errs, err)
+			}))
+		}()
+
+		require.ErrorIs(t, <-done, context.Canceled)
+		require.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[0], expErr)
+		assert.ErrorIs(t, errs[1], ErrInvalidConfig)
+		assert.Equal(t, []string{"leader.local:5432", "replica.local:5432"},
+			addresses(db.Health(context.Background())))
+	})
+
+	t.Run("should be able to report config, which can't be applied, on every tick", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		current := Config{Leader: leaderDSN, Followers: []string{followerDSN}, Lazy: true}
+		db, err := New(context.Background(), current)
+		require.NoError(t, err)
+		defer func() { _ = db.Shutdown(context.Background()) }()
+		changed := Config{Leader: replicaDSN, Followers: []string{replicaDSN}, Lazy: true}
+		var errs []error
+
+		err = Watch(ctx, db, current, func(context.Context) (Config, error) {
+			if len(errs) == 1 {
+				cancel()
+			}
+			return changed, nil
+		}, time.Millisecond, WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}))
+
+		require.ErrorIs(t, err, context.Canceled)
+		require.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[0], ErrInvalidConfig)
+		assert.ErrorIs(t, errs[1], ErrInvalidConfig)
+		assert.Equal(t, []string{"leader.local:5432", "follower.local:5432"},
+			addresses(db.Health(context.Background())))
+	})
+
+	t.Run("should be able to ignore errors without handler", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+
+		err := Watch(ctx, nil, Config{}, func(context.Context) (Config, error) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+			return Config{}, errors.New("config isn't available")
+		}, time.Millisecond)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
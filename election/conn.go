@@ -0,0 +1,54 @@
+package election
+
+import (
+	"context"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const releaseTimeout = 5 * time.Second
+
+// Conn is dedicated connection, which holds leadership lock.
+type Conn interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Release()
+}
+
+type Dialer func(ctx context.Context) (Conn, error)
+
+type Acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// FromPool dials connections from pgxpool.Pool or elephant DB. Cluster gives connection of leader, sharded hive
+// gives connection of shard picked by context of Elector.Run.
+func FromPool(db Acquirer) Dialer {
+	return func(ctx context.Context) (Conn, error) {
+		conn, err := db.Acquire(pgcontext.WithCanWrite(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return poolConn{conn: conn}, nil
+	}
+}
+
+type poolConn struct {
+	conn *pgxpool.Conn
+}
+
+func (c poolConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return c.conn.QueryRow(ctx, sql, args...)
+}
+
+// Release returns connection to pool without advisory locks, or closes it when they can't be released.
+func (c poolConn) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if _, err := c.conn.Exec(ctx, "SELECT pg_advisory_unlock_all()"); err != nil {
+		_ = c.conn.Conn().Close(ctx)
+	}
+	c.conn.Release()
+}
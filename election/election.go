@@ -0,0 +1,177 @@
+//go:generate mockery
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/godepo/elephant"
+)
+
+const (
+	tryLockQuery = "SELECT pg_try_advisory_lock($1)"
+	heldQuery    = `SELECT EXISTS (
+	SELECT 1 FROM pg_locks
+	WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted AND objsubid = 1
+		AND (classid::bigint << 32 | objid::bigint) = $1
+)`
+)
+
+var ErrLeadershipLost = errors.New("election: leadership lock is lost")
+
+type Config struct {
+	renewInterval time.Duration
+	retryInterval time.Duration
+	onError       func(err error)
+}
+
+type Option func(cfg *Config)
+
+// WithRenewInterval sets how often leader checks that connection is alive and still holds the lock.
+func WithRenewInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.renewInterval = interval
+	}
+}
+
+// WithRetryInterval sets how often follower tries to take leadership.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.retryInterval = interval
+	}
+}
+
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onError = fn
+	}
+}
+
+// Elector campaigns for leadership by holding session advisory lock on dedicated connection. Only one elector of
+// the same key leads at a time.
+type Elector struct {
+	dial    Dialer
+	key     elephant.LockKey
+	cfg     Config
+	leader  atomic.Bool
+	changes chan bool
+}
+
+func New(dial Dialer, key elephant.LockKey, opts ...Option) *Elector {
+	cfg := Config{
+		renewInterval: 5 * time.Second,
+		retryInterval: 5 * time.Second,
+		onError:       func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Elector{
+		dial:    dial,
+		key:     key,
+		cfg:     cfg,
+		changes: make(chan bool, 1),
+	}
+}
+
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Changes reports leadership changes. Slow reader misses intermediate changes, but always gets the latest one.
+func (e *Elector) Changes() <-chan bool {
+	return e.changes
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.leader.Store(leader)
+	select {
+	case <-e.changes:
+	default:
+	}
+	e.changes <- leader
+}
+
+// Run campaigns until context is done. While elector leads, lead runs with context, which is canceled when
+// leadership is lost. Lock is released only after lead returns, so lead should stop promptly. When lead returns
+// by itself, elector resigns and campaigns again after retry interval.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	for {
+		err := e.campaign(ctx, lead)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			e.cfg.onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.cfg.retryInterval):
+		}
+	}
+}
+
+func (e *Elector) campaign(ctx context.Context, lead func(ctx context.Context)) error {
+	conn, err := e.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("can't dial election connection: %w", err)
+	}
+	defer conn.Release()
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, tryLockQuery, e.key.ID()).Scan(&acquired); err != nil {
+		return fmt.Errorf("can't try leadership lock: %w", err)
+	}
+	if !acquired {
+		return nil
+	}
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	e.setLeader(true)
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+	defer func() {
+		cancel()
+		e.setLeader(false)
+		<-done
+	}()
+	return e.hold(leadCtx, conn, done)
+}
+
+func (e *Elector) hold(ctx context.Context, conn Conn, done <-chan struct{}) error {
+	ticker := time.NewTicker(e.cfg.renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-done:
+			return nil
+		case <-ticker.C:
+			if err := e.renew(ctx, conn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (e *Elector) renew(ctx context.Context, conn Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.renewInterval)
+	defer cancel()
+	var held bool
+	if err := conn.QueryRow(ctx, heldQuery, e.key.ID()).Scan(&held); err != nil {
+		return fmt.Errorf("%w: %w", ErrLeadershipLost, err)
+	}
+	if !held {
+		return ErrLeadershipLost
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/jackc/pgx/v5"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func boolRow(t *testing.T, value bool, err error) *MockRow {
+	t.Helper()
+	row := NewMockRow(t)
+	row.EXPECT().Scan(mock.Anything).RunAndReturn(func(dest ...any) error {
+		if err != nil {
+			return err
+		}
+		*dest[0].(*bool) = value
+		return nil
+	})
+	return row
+}
+
+func dialer(conns ...Conn) Dialer {
+	return func(context.Context) (Conn, error) {
+		conn := conns[0]
+		if len(conns) > 1 {
+			conns = conns[1:]
+		}
+		return conn, nil
+	}
+}
+
+func errorsChan() (chan error, Option) {
+	errs := make(chan error, 16)
+	return errs, WithErrorHandler(func(err error) {
+		errs <- err
+	})
+}
+
+func expectError(t *testing.T, errs chan error) error {
+	t.Helper()
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(time.Second):
+		require.Fail(t, "error wasn't reported")
+		return nil
+	}
+}
+
+func expectChange(t *testing.T, el *Elector, leader bool) {
+	t.Helper()
+	select {
+	case got := <-el.Changes():
+		assert.Equal(t, leader, got)
+	case <-time.After(time.Second):
+		require.Fail(t, "leadership change wasn't reported")
+	}
+}
+
+func TestElector_Run(t *testing.T) {
+	key := elephant.LockName("cron")
+
+	t.Run("should be able to report dial errors and stop with context", func(t *testing.T) {
+		expErr := newError()
+		errs, onError := errorsChan()
+		ctx, cancel := context.WithCancel(context.Background())
+		el := New(func(context.Context) (Conn, error) {
+			return nil, expErr
+		}, key, onError, WithRetryInterval(time.Hour))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- el.Run(ctx, func(context.Context) {
+				t.Error("must not lead")
+			})
+		}()
+		assert.ErrorIs(t, expectError(t, errs), expErr)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		assert.False(t, el.IsLeader())
+	})
+
+	t.Run("should be able to report errors of lock", func(t *testing.T) {
+		expErr := newError()
+		errs, onError := errorsChan()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		conn := NewMockConn(t)
+		conn.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).Return(boolRow(t, false, expErr)).Once()
+		conn.EXPECT().Release().Return().Once()
+		el := New(dialer(conn), key, onError, WithRetryInterval(time.Hour))
+
+		go func() {
+			_ = el.Run(ctx, func(context.Context) {
+				t.Error("must not lead")
+			})
+		}()
+		assert.ErrorIs(t, expectError(t, errs), expErr)
+	})
+
+	t.Run("should be able to stay follower while lock is taken", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := make(chan struct{}, 16)
+		conn := NewMockConn(t)
+		conn.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).
+			RunAndReturn(func(context.Context, string, ...any) pgx.Row {
+				attempts <- struct{}{}
+				return boolRow(t, false, nil)
+			})
+		conn.EXPECT().Release().Return()
+		el := New(dialer(conn), key, WithRetryInterval(time.Millisecond))
+
+		done := make(chan error, 1)
+		go func() {
+			done <- el.Run(ctx, func(context.Context) {
+				t.Error("must not lead")
+			})
+		}()
+		<-attempts
+		<-attempts
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		assert.False(t, el.IsLeader())
+	})
+
+	t.Run("should be able to lead until context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		conn := NewMockConn(t)
+		conn.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).Return(boolRow(t, true, nil)).Once()
+		conn.EXPECT().QueryRow(mock.Anything, heldQuery, key.ID()).
+			RunAndReturn(func(context.Context, string, ...any) pgx.Row {
+				return boolRow(t, true, nil)
+			}).Maybe()
+		conn.EXPECT().Release().Return().Once()
+		el := New(dialer(conn), key, WithRenewInterval(time.Millisecond))
+
+		leading := make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			done <- el.Run(ctx, func(ctx context.Context) {
+				close(leading)
+				<-ctx.Done()
+			})
+		}()
+		<-leading
+		expectChange(t, el, true)
+		assert.True(t, el.IsLeader())
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		expectChange(t, el, false)
+		assert.False(t, el.IsLeader())
+	})
+
+	t.Run("should be able to cancel leadership, when lock is lost", func(t *testing.T) {
+		errs, onError := errorsChan()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		conn := NewMockConn(t)
+		conn.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).Return(boolRow(t, true, nil)).Once()
+		conn.EXPECT().QueryRow(mock.Anything, heldQuery, key.ID()).Return(boolRow(t, false, nil)).Once()
+		conn.EXPECT().Release().Return().Once()
+		el := New(dialer(conn), key, onError, WithRenewInterval(time.Millisecond), WithRetryInterval(time.Hour))
+
+		lost := make(chan struct{})
+		go func() {
+			_ = el.Run(ctx, func(ctx context.Context) {
+				<-ctx.Done()
+				close(lost)
+			})
+		}()
+		assert.ErrorIs(t, expectError(t, errs), ErrLeadershipLost)
+		<-lost
+		assert.False(t, el.IsLeader())
+	})
+
+	t.Run("should be able to cancel leadership, when connection is lost", func(t *testing.T) {
+		expErr := newError()
+		errs, onError := errorsChan()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		conn := NewMockConn(t)
+		conn.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).Return(boolRow(t, true, nil)).Once()
+		conn.EXPECT().QueryRow(mock.Anything, heldQuery, key.ID()).Return(boolRow(t, false, expErr)).Once()
+		conn.EXPECT().Release().Return().Once()
+		el := New(dialer(conn), key, onError, WithRenewInterval(time.Millisecond), WithRetryInterval(time.Hour))
+
+		go func() {
+			_ = el.Run(ctx, func(ctx context.Context) {
+				<-ctx.Done()
+			})
+		}()
+		err := expectError(t, errs)
+		assert.ErrorIs(t, err, ErrLeadershipLost)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to resign, when lead returns", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		first := NewMockConn(t)
+		first.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).Return(boolRow(t, true, nil)).Once()
+		first.EXPECT().Release().Return().Once()
+		second := NewMockConn(t)
+		second.EXPECT().QueryRow(mock.Anything, tryLockQuery, key.ID()).Return(boolRow(t, false, nil)).Once()
+		second.EXPECT().Release().Run(cancel).Return().Once()
+		el := New(dialer(first, second), key, WithRenewInterval(time.Hour), WithRetryInterval(time.Millisecond))
+
+		terms := 0
+		err := el.Run(ctx, func(context.Context) {
+			terms++
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, terms)
+		assert.False(t, el.IsLeader())
+	})
+}
+
+func TestElector_Changes(t *testing.T) {
+	t.Run("should be able to keep only the latest change", func(t *testing.T) {
+		el := New(nil, elephant.LockID(1))
+		el.setLeader(true)
+		el.setLeader(false)
+		expectChange(t, el, false)
+		select {
+		case <-el.Changes():
+			require.Fail(t, "stale change is delivered")
+		default:
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/election"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keptAcquirer keeps acquired connection, so test reaches it under election connection.
+type keptAcquirer struct {
+	pool *pgxpool.Pool
+	conn *pgxpool.Conn
+}
+
+func (a *keptAcquirer) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := a.pool.Acquire(ctx)
+	a.conn = conn
+	return conn, err
+}
+
+func TestFromPool(t *testing.T) {
+	t.Run("should be able to elect single leader", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		firstCtx, resign := context.WithCancel(ctx)
+
+		first := make(chan struct{})
+		firstDone := make(chan error, 1)
+		go func() {
+			firstDone <- tcs.SUT.Run(firstCtx, func(ctx context.Context) {
+				close(first)
+				<-ctx.Done()
+			})
+		}()
+		<-first
+
+		follower := newElector(tcs.Deps.DB, t.Name())
+		second := make(chan struct{})
+		go func() {
+			_ = follower.Run(ctx, func(ctx context.Context) {
+				close(second)
+				<-ctx.Done()
+			})
+		}()
+		time.Sleep(50 * time.Millisecond)
+		assert.True(t, tcs.SUT.IsLeader())
+		assert.False(t, follower.IsLeader())
+
+		resign()
+		assert.ErrorIs(t, <-firstDone, context.Canceled)
+		select {
+		case <-second:
+		case <-ctx.Done():
+			require.Fail(t, "follower didn't take leadership")
+		}
+		assert.True(t, follower.IsLeader())
+	})
+
+	t.Run("should be able to lose leadership with connection", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		terms := make(chan struct{}, 2)
+		errs := make(chan error, 16)
+		key := elephant.LockName(t.Name())
+		el := election.New(election.FromPool(singlepg.New(tcs.Deps.DB)), key,
+			election.WithRenewInterval(10*time.Millisecond),
+			election.WithRetryInterval(10*time.Millisecond),
+			election.WithErrorHandler(func(err error) {
+				errs <- err
+			}),
+		)
+
+		go func() {
+			_ = el.Run(ctx, func(ctx context.Context) {
+				terms <- struct{}{}
+				<-ctx.Done()
+			})
+		}()
+		<-terms
+		_, err := tcs.Deps.DB.Exec(ctx,
+			`SELECT pg_terminate_backend(pid) FROM pg_locks
+			WHERE locktype = 'advisory' AND (classid::bigint << 32 | objid::bigint) = $1`,
+			key.ID(),
+		)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, <-errs, election.ErrLeadershipLost)
+		select {
+		case <-terms:
+		case <-ctx.Done():
+			require.Fail(t, "leadership wasn't taken again")
+		}
+	})
+
+	t.Run("should be able to close connection which can't release locks", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		pool := &keptAcquirer{pool: tcs.Deps.DB}
+		conn, err := election.FromPool(pool)(context.Background())
+		require.NoError(t, err)
+		raw := pool.conn.Conn()
+		require.NoError(t, raw.Close(context.Background()))
+
+		conn.Release()
+		assert.True(t, raw.IsClosed())
+	})
+}
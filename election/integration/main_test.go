@@ -0,0 +1,50 @@
+// Package integration runs election against postgres in container, apart from election tests, which don't need it.
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/election"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, *election.Elector]
+
+func newElector(db *pgxpool.Pool, name string) *election.Elector {
+	return election.New(election.FromPool(singlepg.New(db)), elephant.LockName(name),
+		election.WithRenewInterval(10*time.Millisecond),
+		election.WithRetryInterval(10*time.Millisecond),
+	)
+}
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, *election.Elector] {
+	return groat.New[Deps, State, *election.Elector](t, func(t *testing.T, deps Deps) *election.Elector {
+		return newElector(deps.DB, t.Name())
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, *election.Elector](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrator(func(context.Context, pgrx.MigratorConfig) error {
+				return nil
+			}),
+		),
+	)
+	os.Exit(suite.Go())
+}
@@ -3,11 +3,107 @@ package elephant
 
 import (
 	"context"
+	"time"
 
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrStatementTimeout = pgerr.ErrStatementTimeout
+	ErrLockTimeout      = pgerr.ErrLockTimeout
+	ErrLockNotAvailable = pgerr.ErrLockNotAvailable
+
+	ErrAcquireNotSupported = pgerr.ErrAcquireNotSupported
+	ErrShutdown            = pgerr.ErrShutdown
+	ErrNotReady            = pghealth.ErrNotReady
+	ErrCircuitOpen         = pgerr.ErrCircuitOpen
+)
+
+// ShutdownProgress is stage of shutdown with count of in-flight transactions.
+type ShutdownProgress = pgdrain.Progress
+
+type ShutdownStage = pgdrain.Stage
+
+const (
+	StageServing  = pgdrain.StageServing
+	StageDraining = pgdrain.StageDraining
+	StageClosing  = pgdrain.StageClosing
+	StageClosed   = pgdrain.StageClosed
+)
+
+// Shutdowner is implemented by databases of singlepg, clusterpg and shardedpg.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+	ShutdownProgress() ShutdownProgress
+}
+
+type (
+	HealthReport = pghealth.Report
+	NodeHealth   = pghealth.Node
+	PoolStats    = pghealth.PoolStats
+	NodeRole     = pghealth.Role
+	// HealthRule decides, whether nodes of single instance or cluster are ready.
+	HealthRule = pghealth.Rule
+)
+
+const (
+	RoleSingle   = pghealth.RoleSingle
+	RoleLeader   = pghealth.RoleLeader
+	RoleFollower = pghealth.RoleFollower
+)
+
+// HealthChecker is implemented by databases of singlepg, clusterpg and shardedpg. Without rules every node must be
+// reachable, sharded database applies rules to every shard.
+type HealthChecker interface {
+	Ping(ctx context.Context, rules ...HealthRule) error
+	Health(ctx context.Context, rules ...HealthRule) HealthReport
+}
+
+type (
+	// CircuitBreaker configures circuit breakers of nodes of clusterpg and shardedpg.
+	CircuitBreaker = pgbreaker.Config
+	// CircuitOpenError names node, which circuit is open, it matches ErrCircuitOpen.
+	CircuitOpenError = pgbreaker.OpenError
+	CircuitState     = pgbreaker.State
+)
+
+const (
+	CircuitClosed   = pgbreaker.StateClosed
+	CircuitOpen     = pgbreaker.StateOpen
+	CircuitHalfOpen = pgbreaker.StateHalfOpen
+)
+
+// NodeFailure is default classifier of circuit breaker: errors of connection and timeouts are failures of node, errors
+// of queries, which node answered, aren't.
+func NodeFailure(err error) bool {
+	return pgbreaker.Failure(err)
+}
+
+// AllReachable requires every node to be reachable.
+func AllReachable() HealthRule {
+	return pghealth.AllReachable()
+}
+
+// LeaderReachable requires reachable leader or single node.
+func LeaderReachable() HealthRule {
+	return pghealth.LeaderReachable()
+}
+
+// MinFollowers requires at least n reachable followers.
+func MinFollowers(n int) HealthRule {
+	return pghealth.MinFollowers(n)
+}
+
+// MaxLag requires replay lag of every reachable node to be at most lag.
+func MaxLag(lag time.Duration) HealthRule {
+	return pghealth.MaxLag(lag)
+}
+
 func With(ctx context.Context, opts ...pgcontext.OptionContext) context.Context {
 	return pgcontext.With(ctx, opts...)
 }
@@ -27,3 +123,29 @@ func WithShardID(id uint) pgcontext.OptionContext {
 func WithShardingKey(key string) pgcontext.OptionContext {
 	return pgcontext.WithShardingKey(key)
 }
+
+// WithStatementTimeout sets statement_timeout for transactions and standalone statements. Standalone statements
+// are wrapped in transaction to apply it.
+func WithStatementTimeout(timeout time.Duration) pgcontext.OptionContext {
+	return pgcontext.WithStatementTimeout(timeout)
+}
+
+// WithLockTimeout sets lock_timeout in the same way as WithStatementTimeout.
+func WithLockTimeout(timeout time.Duration) pgcontext.OptionContext {
+	return pgcontext.WithLockTimeout(timeout)
+}
+
+// WithDeadlineTimeout turns remaining time of context deadline into statement_timeout.
+func WithDeadlineTimeout(ctx context.Context) context.Context {
+	return pgcontext.WithDeadlineTimeout(ctx)
+}
+
+// WithSessionVars sets variables with set_config(name, value, true) in every transaction, so they are available
+// through current_setting(name), for example in row level security policies.
+func WithSessionVars(vars map[string]string) pgcontext.OptionContext {
+	return pgcontext.WithSessionVars(vars)
+}
+
+func WithTenant(tenant string) pgcontext.OptionContext {
+	return pgcontext.WithTenant(tenant)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/jaswdr/faker/v2"
 	"testing"
+	"time"
 
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
 	"github.com/jackc/pgx/v5"
@@ -75,3 +76,59 @@ func TestWithShardingKey(t *testing.T) {
 		assert.Equal(t, expectedKey, key)
 	})
 }
+
+func TestWithStatementTimeout(t *testing.T) {
+	t.Run("should be able to set statement timeout in context", func(t *testing.T) {
+		ctx := With(context.Background(), WithStatementTimeout(time.Second))
+		timeout, ok := pgcontext.StatementTimeoutFrom(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, time.Second, timeout)
+	})
+}
+
+func TestWithLockTimeout(t *testing.T) {
+	t.Run("should be able to set lock timeout in context", func(t *testing.T) {
+		ctx := With(context.Background(), WithLockTimeout(time.Second))
+		timeout, ok := pgcontext.LockTimeoutFrom(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, time.Second, timeout)
+	})
+}
+
+func TestWithDeadlineTimeout(t *testing.T) {
+	t.Run("should be able to set deadline timeout flag in context", func(t *testing.T) {
+		assert.True(t, pgcontext.DeadlineTimeoutFrom(With(context.Background(), WithDeadlineTimeout)))
+	})
+}
+
+func TestWithSessionVars(t *testing.T) {
+	t.Run("should be able to set session vars in context", func(t *testing.T) {
+		expVars := map[string]string{"app.tenant_id": faker.New().RandomStringWithLength(10)}
+		vars, ok := pgcontext.SessionVarsFrom(With(context.Background(), WithSessionVars(expVars)))
+		assert.True(t, ok)
+		assert.Equal(t, expVars, vars)
+	})
+}
+
+func TestWithTenant(t *testing.T) {
+	t.Run("should be able to set tenant in context", func(t *testing.T) {
+		expTenant := faker.New().RandomStringWithLength(10)
+		tenant, ok := pgcontext.TenantFrom(With(context.Background(), WithTenant(expTenant)))
+		assert.True(t, ok)
+		assert.Equal(t, expTenant, tenant)
+	})
+}
+
+func TestHealthRules(t *testing.T) {
+	nodes := []NodeHealth{{Role: RoleLeader, Reachable: true}, {Role: RoleFollower, Lag: time.Second}}
+
+	assert.False(t, AllReachable()(nodes))
+	assert.True(t, LeaderReachable()(nodes))
+	assert.False(t, MinFollowers(1)(nodes))
+	assert.True(t, MaxLag(time.Millisecond)(nodes))
+}
+
+func TestNodeFailure(t *testing.T) {
+	assert.True(t, NodeFailure(context.DeadlineExceeded))
+	assert.False(t, NodeFailure(pgx.ErrNoRows))
+}
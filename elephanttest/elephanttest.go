@@ -0,0 +1,82 @@
+//go:generate mockery
+package elephanttest
+
+import (
+	"context"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const cleanupTimeout = 5 * time.Second
+
+// T is the part of testing.TB, which harness needs.
+type T interface {
+	Helper()
+	Cleanup(fn func())
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Conn is dedicated connection, which holds outer transaction of single test.
+type Conn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Release()
+}
+
+type Dialer func(ctx context.Context) (Conn, error)
+
+type Acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// FromPool dials connections from pgxpool.Pool.
+func FromPool(pool Acquirer) Dialer {
+	return func(ctx context.Context) (Conn, error) {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// Harness runs every test in outer transaction, which is rolled back on test cleanup, so tests don't need to truncate
+// tables.
+type Harness struct {
+	dial Dialer
+}
+
+func New(dial Dialer) *Harness {
+	return &Harness{dial: dial}
+}
+
+// Begin opens outer transaction on dedicated connection, so parallel tests don't see changes of each other. Returned
+// context and DB are bound to the transaction: Transactional and BeginTx start savepoints, statements run in outer
+// transaction even with context without it. Statements of one test must not run concurrently.
+func (h *Harness) Begin(t T) (context.Context, singlepg.DB) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := h.dial(ctx)
+	if err != nil {
+		t.Fatalf("elephanttest: can't acquire connection: %v", err)
+		return nil, nil
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		conn.Release()
+		t.Fatalf("elephanttest: can't begin outer transaction: %v", err)
+		return nil, nil
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		// pool closes connection, which is released in transaction.
+		_ = tx.Rollback(ctx)
+		conn.Release()
+	})
+	return pgcontext.With(ctx, pgcontext.WithTransaction(tx)), singlepg.New(txPool{Tx: tx})
+}
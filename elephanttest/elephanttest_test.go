@@ -0,0 +1,130 @@
+package elephanttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+type fakeT struct {
+	cleanups []func()
+	errors   []string
+	fatal    string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.fatal = fmt.Sprintf(format, args...)
+}
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func dialer(conn Conn) Dialer {
+	return func(context.Context) (Conn, error) {
+		return conn, nil
+	}
+}
+
+func TestFromPool(t *testing.T) {
+	t.Run("should be able to return error of pool", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockAcquirer(t)
+		pool.EXPECT().Acquire(mock.Anything).Return(nil, expErr)
+
+		conn, err := FromPool(pool)(context.Background())
+		assert.ErrorIs(t, err, expErr)
+		assert.Nil(t, conn)
+	})
+}
+
+func TestHarness_Begin(t *testing.T) {
+	t.Run("should be able to run test in outer transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		conn := NewMockConn(t)
+		conn.EXPECT().Begin(mock.Anything).Return(tx, nil)
+		tb := &fakeT{}
+
+		ctx, db := New(dialer(conn)).Begin(tb)
+		require.Empty(t, tb.fatal)
+		got, ok := pgcontext.TransactionFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, tx, got)
+
+		tx.EXPECT().Exec(mock.Anything, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		_, err := db.Exec(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+
+		tx.EXPECT().Rollback(mock.Anything).Return(nil)
+		conn.EXPECT().Release().Return()
+		tb.finish()
+	})
+
+	t.Run("should be able to start savepoints instead of transactions", func(t *testing.T) {
+		savepoint := NewMockTx(t)
+		savepoint.EXPECT().Commit(mock.Anything).Return(nil)
+		savepoint.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+		tx := NewMockTx(t)
+		tx.EXPECT().Begin(mock.Anything).Return(savepoint, nil)
+		conn := NewMockConn(t)
+		conn.EXPECT().Begin(mock.Anything).Return(tx, nil)
+
+		_, db := New(dialer(conn)).Begin(&fakeT{})
+		require.NoError(t, db.Transactional(context.Background(), func(ctx context.Context) error {
+			got, _ := pgcontext.TransactionFrom(ctx)
+			assert.Equal(t, savepoint, got)
+			return nil
+		}))
+	})
+
+	t.Run("should be able to fail test, when connection can't be acquired", func(t *testing.T) {
+		expErr := newError()
+		tb := &fakeT{}
+
+		ctx, db := New(func(context.Context) (Conn, error) {
+			return nil, expErr
+		}).Begin(tb)
+		assert.Contains(t, tb.fatal, expErr.Error())
+		assert.Nil(t, ctx)
+		assert.Nil(t, db)
+	})
+
+	t.Run("should be able to fail test, when transaction can't begin", func(t *testing.T) {
+		expErr := newError()
+		conn := NewMockConn(t)
+		conn.EXPECT().Begin(mock.Anything).Return(nil, expErr)
+		conn.EXPECT().Release().Return()
+		tb := &fakeT{}
+
+		ctx, db := New(dialer(conn)).Begin(tb)
+		assert.Contains(t, tb.fatal, expErr.Error())
+		assert.Nil(t, ctx)
+		assert.Nil(t, db)
+		assert.Empty(t, tb.cleanups)
+	})
+}
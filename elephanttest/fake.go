@@ -0,0 +1,444 @@
+package elephanttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const DefaultNode = "default"
+
+var (
+	ErrUnexpectedQuery = errors.New("elephanttest: unexpected query")
+	ErrUnmet           = errors.New("elephanttest: fake expectations aren't met")
+)
+
+type anyArg struct{}
+
+// AnyArg matches any argument of statement.
+var AnyArg any = anyArg{}
+
+// Call is statement or transaction boundary, which fake received. Boundaries are BEGIN, SAVEPOINT, COMMIT,
+// RELEASE SAVEPOINT, ROLLBACK and ROLLBACK TO SAVEPOINT.
+type Call struct {
+	Node string
+	SQL  string
+	Args []any
+	InTx bool
+}
+
+func (c Call) String() string {
+	if len(c.Args) == 0 {
+		return c.Node + ": " + c.SQL
+	}
+	return fmt.Sprintf("%s: %s %v", c.Node, c.SQL, c.Args)
+}
+
+// Expectation declares statement, which fake should receive, and result of it.
+type Expectation struct {
+	pattern *regexp.Regexp
+	args    []any
+	hasArgs bool
+	node    string
+	columns []string
+	rows    [][]any
+	tag     pgconn.CommandTag
+	err     error
+	times   int
+	calls   int
+}
+
+// WithArgs matches statement arguments, use AnyArg to skip single argument. Arguments aren't matched by default.
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// On matches node, which statement was routed to. Any node is matched by default.
+func (e *Expectation) On(node string) *Expectation {
+	e.node = node
+	return e
+}
+
+func (e *Expectation) Returns(columns []string, rows ...[]any) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// ReturnsTag sets command tag of statement, for example "UPDATE 1".
+func (e *Expectation) ReturnsTag(tag string) *Expectation {
+	e.tag = pgconn.NewCommandTag(tag)
+	return e
+}
+
+func (e *Expectation) ReturnsError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times sets how many statements the expectation matches, once by default.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) match(call Call) bool {
+	if e.calls >= e.times || !e.pattern.MatchString(call.SQL) {
+		return false
+	}
+	if e.node != "" && e.node != call.Node {
+		return false
+	}
+	if !e.hasArgs {
+		return true
+	}
+	if len(e.args) != len(call.Args) {
+		return false
+	}
+	for i, arg := range e.args {
+		if arg != AnyArg && !reflect.DeepEqual(arg, call.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "/%s/", e.pattern)
+	if e.node != "" {
+		fmt.Fprintf(&b, " on %s", e.node)
+	}
+	if e.hasArgs {
+		fmt.Fprintf(&b, " with args %v", e.args)
+	}
+	fmt.Fprintf(&b, ": called %d of %d times", e.calls, e.times)
+	return b.String()
+}
+
+// Fake is in-memory DB, which serves declared expectations and tracks transactions. Fake itself is DB of node
+// DefaultNode. Nodes, taken by Node, are plugged into clusterpg and shardedpg builders to check routing.
+type Fake struct {
+	*FakeNode
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	unexpected   []Call
+	nodes        map[string]*FakeNode
+}
+
+// NewFake returns fake, which reports unmet expectations and unexpected statements on test cleanup.
+func NewFake(t T) *Fake {
+	f := &Fake{nodes: map[string]*FakeNode{}}
+	f.FakeNode = f.Node(DefaultNode)
+	t.Cleanup(func() {
+		if err := f.Verify(); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+	return f
+}
+
+// Node returns DB of node with given name. Node implements singlepg.DB, clusterpg.Pool and shardedpg.Pool.
+func (f *Fake) Node(name string) *FakeNode {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if node, ok := f.nodes[name]; ok {
+		return node
+	}
+	node := &FakeNode{name: name, fake: f}
+	node.db = singlepg.New(nodePool{node: node})
+	f.nodes[name] = node
+	return node
+}
+
+// Expect declares statement, which SQL matches regular expression pattern.
+func (f *Fake) Expect(pattern string) *Expectation {
+	e := &Expectation{pattern: regexp.MustCompile(pattern), times: 1}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expectations = append(f.expectations, e)
+	return e
+}
+
+// Calls returns statements and transaction boundaries in order they were received.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Verify returns ErrUnmet with report of unexpected statements and unmet expectations.
+func (f *Fake) Verify() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var b strings.Builder
+	if len(f.unexpected) > 0 {
+		b.WriteString("\nunexpected queries:")
+		for _, call := range f.unexpected {
+			b.WriteString("\n\t" + call.String())
+		}
+	}
+	var unmet []string
+	for _, e := range f.expectations {
+		if e.calls < e.times {
+			unmet = append(unmet, "\n\t"+e.String())
+		}
+	}
+	if len(unmet) > 0 {
+		b.WriteString("\nunmet expectations:" + strings.Join(unmet, ""))
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:%s", ErrUnmet, b.String())
+}
+
+func (f *Fake) record(call Call) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+// serve matches statement to the first expectation, which isn't exhausted yet.
+func (f *Fake) serve(call Call) (*Expectation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+	for _, e := range f.expectations {
+		if e.match(call) {
+			e.calls++
+			return e, nil
+		}
+	}
+	f.unexpected = append(f.unexpected, call)
+	return nil, fmt.Errorf("%w: %s", ErrUnexpectedQuery, call)
+}
+
+// FakeNode is DB of single fake node.
+type FakeNode struct {
+	name string
+	fake *Fake
+	db   singlepg.DB
+}
+
+func (n *FakeNode) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return n.db.BeginTx(ctx, opts)
+}
+
+func (n *FakeNode) Begin(ctx context.Context) (pgx.Tx, error) {
+	return n.db.Begin(ctx)
+}
+
+func (n *FakeNode) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return n.db.Query(ctx, query, args...)
+}
+
+func (n *FakeNode) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return n.db.QueryRow(ctx, query, args...)
+}
+
+func (n *FakeNode) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return n.db.Exec(ctx, query, args...)
+}
+
+func (n *FakeNode) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return n.db.SendBatch(ctx, b)
+}
+
+func (n *FakeNode) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return n.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// CopyTo writes rows of expectation in COPY text format.
+func (n *FakeNode) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	call := Call{Node: n.name, SQL: query}
+	if tx, ok := pgcontext.TransactionFrom(ctx); ok {
+		if tx, ok := tx.(*fakeTx); ok {
+			call.Node, call.InTx = tx.node.name, true
+		}
+	}
+	e, err := n.fake.serve(call)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if e.err != nil {
+		return pgconn.CommandTag{}, e.err
+	}
+	for _, row := range e.rows {
+		fields := make([]string, 0, len(row))
+		for _, value := range row {
+			if value == nil {
+				fields = append(fields, `\N`)
+				continue
+			}
+			fields = append(fields, fmt.Sprint(value))
+		}
+		if _, err := io.WriteString(w, strings.Join(fields, "\t")+"\n"); err != nil {
+			return pgconn.CommandTag{}, err
+		}
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("COPY %d", len(e.rows))), nil
+}
+
+// Acquire isn't supported, because fake has no connections.
+func (n *FakeNode) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return n.db.Acquire(ctx)
+}
+
+func (n *FakeNode) Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error) {
+	return n.db.Transactional(ctx, fn)
+}
+
+// Shutdown rejects new transactions of node after in-flight ones are finished, fake has no pools to close.
+func (n *FakeNode) Shutdown(ctx context.Context) error {
+	return n.db.Shutdown(ctx)
+}
+
+func (n *FakeNode) ShutdownProgress() pgdrain.Progress {
+	return n.db.ShutdownProgress()
+}
+
+func (n *FakeNode) Ping(ctx context.Context, rules ...pghealth.Rule) error {
+	return n.Health(ctx, rules...).Err()
+}
+
+// Health reports node as reachable without probe statement, so health checks don't meet expectations of fake.
+func (n *FakeNode) Health(_ context.Context, rules ...pghealth.Rule) pghealth.Report {
+	return pghealth.Aggregate([]pghealth.Node{{Role: pghealth.RoleSingle, Address: n.name, Reachable: true}}, rules...)
+}
+
+// nodePool receives statements of node outside of transaction.
+type nodePool struct {
+	node *FakeNode
+}
+
+func (p nodePool) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return p.begin(), nil
+}
+
+func (p nodePool) Begin(context.Context) (pgx.Tx, error) {
+	return p.begin(), nil
+}
+
+func (p nodePool) begin() *fakeTx {
+	p.node.fake.record(Call{Node: p.node.name, SQL: "BEGIN", InTx: true})
+	return &fakeTx{node: p.node}
+}
+
+func (p nodePool) Query(_ context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return p.node.query(query, args, false)
+}
+
+func (p nodePool) QueryRow(_ context.Context, query string, args ...interface{}) pgx.Row {
+	return p.node.queryRow(query, args, false)
+}
+
+func (p nodePool) Exec(_ context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return p.node.exec(query, args, false)
+}
+
+func (p nodePool) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	return p.node.sendBatch(b, false)
+}
+
+func (p nodePool) CopyFrom(
+	_ context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return p.node.copyFrom(tableName, columnNames, rowSrc, false)
+}
+
+func (n *FakeNode) query(query string, args []any, inTx bool) (pgx.Rows, error) {
+	e, err := n.fake.serve(Call{Node: n.name, SQL: query, Args: args, InTx: inTx})
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return newFakeRows(e.columns, e.rows), nil
+}
+
+func (n *FakeNode) queryRow(query string, args []any, inTx bool) pgx.Row {
+	rows, err := n.query(query, args, inTx)
+	return fakeRow{rows: rows, err: err}
+}
+
+func (n *FakeNode) exec(query string, args []any, inTx bool) (pgconn.CommandTag, error) {
+	e, err := n.fake.serve(Call{Node: n.name, SQL: query, Args: args, InTx: inTx})
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if e.err != nil {
+		return pgconn.CommandTag{}, e.err
+	}
+	if e.tag.String() == "" && e.columns != nil {
+		return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", len(e.rows))), nil
+	}
+	return e.tag, nil
+}
+
+func (n *FakeNode) sendBatch(b *pgx.Batch, inTx bool) pgx.BatchResults {
+	results := &fakeBatchResults{batch: b}
+	for _, qq := range b.QueuedQueries {
+		e, err := n.fake.serve(Call{Node: n.name, SQL: qq.SQL, Args: qq.Arguments, InTx: inTx})
+		if err == nil {
+			err = e.err
+		}
+		if err != nil {
+			results.items = append(results.items, batchItem{err: err})
+			continue
+		}
+		results.items = append(results.items, batchItem{expectation: e})
+	}
+	return results
+}
+
+// copyFrom drains rowSrc and matches it as COPY statement, which arguments are rows.
+func (n *FakeNode) copyFrom(
+	tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource, inTx bool,
+) (int64, error) {
+	columns := make([]string, 0, len(columnNames))
+	for _, name := range columnNames {
+		columns = append(columns, pgx.Identifier{name}.Sanitize())
+	}
+	query := fmt.Sprintf("COPY %s ( %s ) FROM STDIN", tableName.Sanitize(), strings.Join(columns, ", "))
+
+	var rows []any
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		rows = append(rows, values)
+	}
+	if err := rowSrc.Err(); err != nil {
+		return 0, err
+	}
+
+	e, err := n.fake.serve(Call{Node: n.name, SQL: query, Args: rows, InTx: inTx})
+	if err != nil {
+		return 0, err
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	return int64(len(rows)), nil
+}
@@ -0,0 +1,209 @@
+package elephanttest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrScan = errors.New("elephanttest: can't scan value")
+
+type fakeRows struct {
+	fields []pgconn.FieldDescription
+	rows   [][]any
+	pos    int
+	err    error
+	closed bool
+}
+
+func newFakeRows(columns []string, rows [][]any) *fakeRows {
+	fields := make([]pgconn.FieldDescription, 0, len(columns))
+	for _, name := range columns {
+		fields = append(fields, pgconn.FieldDescription{Name: name})
+	}
+	return &fakeRows{fields: fields, rows: rows}
+}
+
+func (r *fakeRows) Close() {
+	r.closed = true
+}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+func (r *fakeRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", len(r.rows)))
+}
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	return r.fields
+}
+
+func (r *fakeRows) Next() bool {
+	if r.closed || r.err != nil || r.pos >= len(r.rows) {
+		r.closed = true
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	if len(dest) != len(row) {
+		r.err = fmt.Errorf("%w: %d destinations for %d values", ErrScan, len(dest), len(row))
+		return r.err
+	}
+	for i, value := range row {
+		if err := assign(dest[i], value); err != nil {
+			r.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return append([]any(nil), r.rows[r.pos-1]...), nil
+}
+
+// RawValues is nil, because fake has no wire format of values.
+func (r *fakeRows) RawValues() [][]byte {
+	return nil
+}
+
+func (r *fakeRows) Conn() *pgx.Conn {
+	return nil
+}
+
+type fakeRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		return pgx.ErrNoRows
+	}
+	return r.rows.Scan(dest...)
+}
+
+type batchItem struct {
+	expectation *Expectation
+	err         error
+}
+
+type fakeBatchResults struct {
+	batch *pgx.Batch
+	items []batchItem
+	pos   int
+	err   error
+}
+
+func (r *fakeBatchResults) next() (batchItem, error) {
+	if r.err != nil {
+		return batchItem{}, r.err
+	}
+	if r.pos >= len(r.items) {
+		return batchItem{}, errors.New("elephanttest: no more results in batch")
+	}
+	r.pos++
+	item := r.items[r.pos-1]
+	return item, item.err
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	item, err := r.next()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return item.expectation.tag, nil
+}
+
+func (r *fakeBatchResults) Query() (pgx.Rows, error) {
+	item, err := r.next()
+	if err != nil {
+		return &fakeRows{err: err}, err
+	}
+	return newFakeRows(item.expectation.columns, item.expectation.rows), nil
+}
+
+func (r *fakeBatchResults) QueryRow() pgx.Row {
+	rows, err := r.Query()
+	return fakeRow{rows: rows, err: err}
+}
+
+// Close runs callbacks of queued queries, which results weren't read, as pgx does.
+func (r *fakeBatchResults) Close() error {
+	if r.err != nil {
+		return r.err
+	}
+	var err error
+	for r.pos < len(r.items) {
+		qq := r.batch.QueuedQueries[r.pos]
+		if qq.Fn != nil {
+			err = errors.Join(err, qq.Fn(r))
+			continue
+		}
+		_, itemErr := r.next()
+		err = errors.Join(err, itemErr)
+	}
+	return err
+}
+
+// assign stores value into pointer dest. Numbers are converted to numbers, strings to strings and byte slices.
+func assign(dest, value any) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("%w: destination %T isn't pointer", ErrScan, dest)
+	}
+	target = target.Elem()
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			target.SetZero()
+			return nil
+		default:
+			return fmt.Errorf("%w: NULL into %T", ErrScan, dest)
+		}
+	}
+
+	source := reflect.ValueOf(value)
+	switch {
+	case source.Type().AssignableTo(target.Type()):
+		target.Set(source)
+	case target.Kind() == reflect.Pointer:
+		ptr := reflect.New(target.Type().Elem())
+		if err := assign(ptr.Interface(), value); err != nil {
+			return err
+		}
+		target.Set(ptr)
+	case convertible(source.Type(), target.Type()):
+		target.Set(source.Convert(target.Type()))
+	default:
+		return fmt.Errorf("%w: %T into %T", ErrScan, value, dest)
+	}
+	return nil
+}
+
+func convertible(from, to reflect.Type) bool {
+	textual := func(t reflect.Type) bool {
+		return t.Kind() == reflect.String || t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+	}
+	numeric := func(t reflect.Type) bool {
+		return t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64
+	}
+	return textual(from) && textual(to) || numeric(from) && numeric(to)
+}
@@ -0,0 +1,551 @@
+package elephanttest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/clusterpg"
+	"github.com/godepo/elephant/shardedpg"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID    int64
+	Login string
+	Email *string
+}
+
+func sqls(calls []Call) []string {
+	out := make([]string, 0, len(calls))
+	for _, call := range calls {
+		out = append(out, call.Node+": "+call.SQL)
+	}
+	return out
+}
+
+func TestFake_Query(t *testing.T) {
+	t.Run("should be able to serve rows of expectation", func(t *testing.T) {
+		fake := NewFake(t)
+		email := "admin@example.com"
+		fake.Expect(`SELECT .* FROM users`).WithArgs(int64(1), AnyArg).
+			Returns([]string{"id", "login", "email"}, []any{int32(1), "admin", email}, []any{int64(2), []byte("root"), nil})
+
+		rows, err := fake.Query(context.Background(), "SELECT id, login, email FROM users WHERE id >= $1 AND $2", int64(1), true)
+		require.NoError(t, err)
+		users, err := pgx.CollectRows(rows, pgx.RowToStructByName[user])
+		require.NoError(t, err)
+		assert.Equal(t, []user{{ID: 1, Login: "admin", Email: &email}, {ID: 2, Login: "root"}}, users)
+
+		assert.Equal(t, []Call{{
+			Node: DefaultNode,
+			SQL:  "SELECT id, login, email FROM users WHERE id >= $1 AND $2",
+			Args: []any{int64(1), true},
+		}}, fake.Calls())
+	})
+
+	t.Run("should be able to return error of expectation", func(t *testing.T) {
+		expErr := errors.New("boom")
+		fake := NewFake(t)
+		fake.Expect(`SELECT`).ReturnsError(expErr).Times(2)
+
+		_, err := fake.Query(context.Background(), "SELECT 1")
+		require.ErrorIs(t, err, expErr)
+		require.ErrorIs(t, fake.QueryRow(context.Background(), "SELECT 1").Scan(), expErr)
+	})
+
+	t.Run("should be able to return no rows", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"})
+
+		var id int
+		assert.ErrorIs(t, fake.QueryRow(context.Background(), "SELECT id").Scan(&id), pgx.ErrNoRows)
+	})
+
+	t.Run("should be able to expose values of rows", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, []any{1})
+
+		rows, err := fake.Query(context.Background(), "SELECT id")
+		require.NoError(t, err)
+		defer rows.Close()
+		assert.Equal(t, "SELECT 1", rows.CommandTag().String())
+		assert.Nil(t, rows.Conn())
+		require.True(t, rows.Next())
+		values, err := rows.Values()
+		require.NoError(t, err)
+		assert.Equal(t, []any{1}, values)
+		assert.Nil(t, rows.RawValues())
+	})
+
+	t.Run("should be able to fail scan", func(t *testing.T) {
+		for name, scan := range map[string]func(rows pgx.Rows) error{
+			"count": func(rows pgx.Rows) error {
+				return rows.Scan()
+			},
+			"value": func(rows pgx.Rows) error {
+				var id int
+				return rows.Scan(&id)
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				fake := NewFake(t)
+				fake.Expect(`SELECT`).Returns([]string{"id"}, []any{"one"})
+
+				rows, err := fake.Query(context.Background(), "SELECT id")
+				require.NoError(t, err)
+				require.True(t, rows.Next())
+				require.ErrorIs(t, scan(rows), ErrScan)
+				assert.False(t, rows.Next())
+				assert.ErrorIs(t, rows.Err(), ErrScan)
+			})
+		}
+	})
+}
+
+func TestFake_Exec(t *testing.T) {
+	t.Run("should be able to return tag of expectation", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`UPDATE users`).ReturnsTag("UPDATE 3")
+		fake.Expect(`SELECT`).Returns([]string{"id"}, []any{1}, []any{2})
+		fake.Expect(`VACUUM`)
+
+		tag, err := fake.Exec(context.Background(), "UPDATE users SET active = false")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), tag.RowsAffected())
+		tag, err = fake.Exec(context.Background(), "SELECT id")
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT 2", tag.String())
+		tag, err = fake.Exec(context.Background(), "VACUUM")
+		require.NoError(t, err)
+		assert.Empty(t, tag.String())
+	})
+
+	t.Run("should be able to return error of expectation", func(t *testing.T) {
+		expErr := errors.New("boom")
+		fake := NewFake(t)
+		fake.Expect(`DELETE`).ReturnsError(expErr)
+
+		_, err := fake.Exec(context.Background(), "DELETE FROM users")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to reject unexpected statement", func(t *testing.T) {
+		tb := &fakeT{}
+		fake := NewFake(tb)
+		fake.Expect(`DELETE`).WithArgs(1).On("leader")
+		fake.Expect(`UPDATE`).WithArgs(1, 2)
+
+		_, err := fake.Exec(context.Background(), "DELETE FROM users WHERE id = $1", 1)
+		require.ErrorIs(t, err, ErrUnexpectedQuery)
+		_, err = fake.Exec(context.Background(), "UPDATE users SET id = $1", 1)
+		require.ErrorIs(t, err, ErrUnexpectedQuery)
+		_, err = fake.Exec(context.Background(), "UPDATE users SET id = $1", 2, 3)
+		require.ErrorIs(t, err, ErrUnexpectedQuery)
+		_, err = fake.Query(context.Background(), "SELECT 1")
+		require.ErrorIs(t, err, ErrUnexpectedQuery)
+		require.ErrorIs(t, fake.QueryRow(context.Background(), "SELECT 1").Scan(), ErrUnexpectedQuery)
+
+		err = fake.Verify()
+		require.ErrorIs(t, err, ErrUnmet)
+		assert.Equal(t, `elephanttest: fake expectations aren't met:
+unexpected queries:
+	default: DELETE FROM users WHERE id = $1 [1]
+	default: UPDATE users SET id = $1 [1]
+	default: UPDATE users SET id = $1 [2 3]
+	default: SELECT 1
+	default: SELECT 1
+unmet expectations:
+	/DELETE/ on leader with args [1]: called 0 of 1 times
+	/UPDATE/ with args [1 2]: called 0 of 1 times`, err.Error())
+
+		tb.finish()
+		require.Len(t, tb.errors, 1)
+		assert.Equal(t, err.Error(), tb.errors[0])
+	})
+
+	t.Run("should be able to refuse acquire", func(t *testing.T) {
+		_, err := NewFake(t).Acquire(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestFake_Routing(t *testing.T) {
+	t.Run("should be able to check routing of cluster", func(t *testing.T) {
+		fake := NewFake(t)
+		db, err := clusterpg.New().
+			Leader(func() (clusterpg.Pool, error) { return fake.Node("leader"), nil }).
+			Follower(func() (clusterpg.Pool, error) { return fake.Node("follower"), nil }).
+			Go()
+		require.NoError(t, err)
+		fake.Expect(`SELECT`).On("follower").Returns([]string{"id"}, []any{1})
+		fake.Expect(`INSERT`).On("leader").Times(2)
+
+		ctx := context.Background()
+		var id int
+		require.NoError(t, db.QueryRow(ctx, "SELECT id").Scan(&id))
+		_, err = db.Exec(elephant.WithCanWrite(ctx), "INSERT")
+		require.NoError(t, err)
+		require.NoError(t, db.Transactional(elephant.WithCanWrite(ctx), func(ctx context.Context) error {
+			_, err := db.Exec(ctx, "INSERT")
+			return err
+		}))
+
+		assert.Equal(t, []string{
+			"follower: SELECT id", "leader: INSERT", "leader: BEGIN", "leader: INSERT", "leader: COMMIT",
+		}, sqls(fake.Calls()))
+		assert.True(t, fake.Calls()[3].InTx)
+	})
+
+	t.Run("should be able to check routing of shards", func(t *testing.T) {
+		fake := NewFake(t)
+		db, err := shardedpg.New(2).
+			Picker(func(context.Context, string) uint { return 1 }).
+			Shard(0, fake.Node("shard-0")).
+			Shard(1, fake.Node("shard-1")).
+			Go()
+		require.NoError(t, err)
+		fake.Expect(`DELETE`).On("shard-0")
+		fake.Expect(`DELETE`).On("shard-1")
+
+		ctx := context.Background()
+		_, err = db.Exec(elephant.With(ctx, elephant.WithShardID(0)), "DELETE")
+		require.NoError(t, err)
+		_, err = db.Exec(elephant.With(ctx, elephant.WithShardingKey("key")), "DELETE")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"shard-0: DELETE", "shard-1: DELETE"}, sqls(fake.Calls()))
+	})
+
+	t.Run("should be able to track savepoints", func(t *testing.T) {
+		expErr := errors.New("rollback")
+		fake := NewFake(t)
+		fake.Expect(`INSERT`).Times(2)
+
+		err := fake.Transactional(context.Background(), func(ctx context.Context) error {
+			if _, err := fake.Exec(ctx, "INSERT"); err != nil {
+				return err
+			}
+			require.NoError(t, fake.Transactional(ctx, func(ctx context.Context) error {
+				return nil
+			}))
+			return fake.Transactional(ctx, func(ctx context.Context) error {
+				_, _ = fake.Exec(ctx, "INSERT")
+				return expErr
+			})
+		})
+		require.ErrorIs(t, err, expErr)
+		assert.Equal(t, []string{
+			"default: BEGIN", "default: INSERT",
+			"default: SAVEPOINT", "default: RELEASE SAVEPOINT",
+			"default: SAVEPOINT", "default: INSERT", "default: ROLLBACK TO SAVEPOINT",
+			"default: ROLLBACK",
+		}, sqls(fake.Calls()))
+	})
+
+	t.Run("should be able to shut down cluster of fake nodes", func(t *testing.T) {
+		fake := NewFake(t)
+		db, err := clusterpg.New().
+			Leader(func() (clusterpg.Pool, error) { return fake.Node("leader"), nil }).
+			Follower(func() (clusterpg.Pool, error) { return fake.Node("follower"), nil }).
+			Go()
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		require.NoError(t, db.Shutdown(ctx))
+
+		assert.Equal(t, elephant.StageClosed, db.ShutdownProgress().Stage)
+		assert.Equal(t, elephant.StageClosed, fake.Node("follower").ShutdownProgress().Stage)
+		assert.ErrorIs(t, fake.Node("leader").Transactional(ctx, func(context.Context) error {
+			return nil
+		}), elephant.ErrShutdown)
+	})
+
+	t.Run("should be able to report health of cluster of fake nodes", func(t *testing.T) {
+		fake := NewFake(t)
+		db, err := clusterpg.New().
+			Leader(func() (clusterpg.Pool, error) { return fake.Node("leader"), nil }).
+			Follower(func() (clusterpg.Pool, error) { return fake.Node("follower"), nil }).
+			Go()
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		report := db.Health(ctx)
+
+		require.NoError(t, db.Ping(ctx, elephant.LeaderReachable(), elephant.MinFollowers(1)))
+		assert.True(t, report.Ready)
+		assert.Equal(t, []elephant.NodeHealth{
+			{Role: elephant.RoleLeader, Address: "leader", Reachable: true},
+			{Role: elephant.RoleFollower, Address: "follower", Reachable: true},
+		}, report.Nodes)
+		assert.Empty(t, fake.Calls())
+	})
+
+	t.Run("should be able to return the same node by name", func(t *testing.T) {
+		fake := NewFake(t)
+		assert.Same(t, fake.Node("leader"), fake.Node("leader"))
+		assert.Same(t, fake.FakeNode, fake.Node(DefaultNode))
+	})
+}
+
+func TestFake_Tx(t *testing.T) {
+	t.Run("should be able to run statements in transaction", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`SELECT`).Returns([]string{"id"}, []any{1}).Times(2)
+		fake.Expect(`UPDATE`).Times(2)
+		ctx := context.Background()
+
+		tx, err := fake.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "UPDATE")
+		require.NoError(t, err)
+		rows, err := tx.Query(ctx, "SELECT")
+		require.NoError(t, err)
+		rows.Close()
+		var id int
+		require.NoError(t, tx.QueryRow(ctx, "SELECT").Scan(&id))
+		batch := &pgx.Batch{}
+		batch.Queue("UPDATE")
+		require.NoError(t, tx.SendBatch(ctx, batch).Close())
+		assert.Nil(t, tx.Conn())
+		assert.Equal(t, pgx.LargeObjects{}, tx.LargeObjects())
+		sd, err := tx.Prepare(ctx, "name", "SELECT")
+		require.NoError(t, err)
+		assert.Equal(t, "name", sd.Name)
+		require.NoError(t, tx.Commit(ctx))
+		for _, call := range fake.Calls() {
+			assert.True(t, call.InTx)
+		}
+	})
+
+	t.Run("should be able to refuse statements of closed transaction", func(t *testing.T) {
+		fake := NewFake(t)
+		ctx := context.Background()
+		tx, err := fake.BeginTx(ctx, pgx.TxOptions{})
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback(ctx))
+
+		assert.ErrorIs(t, tx.Rollback(ctx), pgx.ErrTxClosed)
+		_, err = tx.Begin(ctx)
+		require.ErrorIs(t, err, pgx.ErrTxClosed)
+		_, err = tx.Exec(ctx, "SELECT")
+		require.ErrorIs(t, err, pgx.ErrTxClosed)
+		_, err = tx.Query(ctx, "SELECT")
+		require.ErrorIs(t, err, pgx.ErrTxClosed)
+		require.ErrorIs(t, tx.QueryRow(ctx, "SELECT").Scan(), pgx.ErrTxClosed)
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"users"}, nil, pgx.CopyFromRows(nil))
+		require.ErrorIs(t, err, pgx.ErrTxClosed)
+		results := tx.SendBatch(ctx, &pgx.Batch{})
+		_, err = results.Exec()
+		require.ErrorIs(t, err, pgx.ErrTxClosed)
+		require.ErrorIs(t, results.Close(), pgx.ErrTxClosed)
+	})
+}
+
+func TestFake_SendBatch(t *testing.T) {
+	t.Run("should be able to serve queued queries", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`INSERT`).ReturnsTag("INSERT 0 1")
+		fake.Expect(`SELECT id`).Returns([]string{"id"}, []any{1}).Times(2)
+		fake.Expect(`SELECT login`).Returns([]string{"login"}, []any{"admin"})
+
+		var id int
+		batch := &pgx.Batch{}
+		batch.Queue("INSERT")
+		batch.Queue("SELECT id")
+		batch.Queue("SELECT id").QueryRow(func(row pgx.Row) error {
+			return row.Scan(&id)
+		})
+		batch.Queue("SELECT login")
+
+		results := fake.SendBatch(context.Background(), batch)
+		tag, err := results.Exec()
+		require.NoError(t, err)
+		assert.Equal(t, "INSERT 0 1", tag.String())
+		rows, err := results.Query()
+		require.NoError(t, err)
+		rows.Close()
+		require.NoError(t, results.Close())
+		assert.Equal(t, 1, id)
+	})
+
+	t.Run("should be able to return errors of queued queries", func(t *testing.T) {
+		expErr := errors.New("boom")
+		fake := NewFake(&fakeT{})
+		fake.Expect(`SELECT`).ReturnsError(expErr)
+		fake.Expect(`DELETE`).ReturnsError(expErr)
+
+		batch := &pgx.Batch{}
+		batch.Queue("SELECT")
+		batch.Queue("UPDATE")
+		batch.Queue("DELETE").Exec(func(pgconn.CommandTag) error {
+			return nil
+		})
+
+		results := fake.SendBatch(context.Background(), batch)
+		_, err := results.Query()
+		require.ErrorIs(t, err, expErr)
+		require.ErrorIs(t, results.QueryRow().Scan(), ErrUnexpectedQuery)
+		require.ErrorIs(t, results.Close(), expErr)
+		_, err = results.Exec()
+		require.Error(t, err)
+		require.NoError(t, results.Close())
+	})
+}
+
+type copySource struct {
+	pgx.CopyFromSource
+	valuesErr error
+	err       error
+}
+
+func (s copySource) Values() ([]any, error) {
+	if s.valuesErr != nil {
+		return nil, s.valuesErr
+	}
+	return s.CopyFromSource.Values()
+}
+
+func (s copySource) Err() error {
+	return s.err
+}
+
+func TestFake_Copy(t *testing.T) {
+	t.Run("should be able to match rows of copy", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`^COPY "users" \( "id", "login" \) FROM STDIN$`).WithArgs([]any{1, "admin"}, []any{2, "root"})
+
+		n, err := fake.CopyFrom(context.Background(), pgx.Identifier{"users"}, []string{"id", "login"},
+			pgx.CopyFromRows([][]any{{1, "admin"}, {2, "root"}}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+
+	t.Run("should be able to copy in transaction", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`COPY`)
+		require.NoError(t, fake.Transactional(context.Background(), func(ctx context.Context) error {
+			_, err := fake.CopyFrom(ctx, pgx.Identifier{"users"}, nil, pgx.CopyFromRows(nil))
+			return err
+		}))
+		assert.True(t, fake.Calls()[1].InTx)
+	})
+
+	t.Run("should be able to return errors of copy", func(t *testing.T) {
+		expErr := errors.New("boom")
+		fake := NewFake(&fakeT{})
+		fake.Expect(`COPY`).ReturnsError(expErr)
+		ctx := context.Background()
+		rows := func() pgx.CopyFromSource {
+			return pgx.CopyFromRows([][]any{{1}})
+		}
+
+		_, err := fake.CopyFrom(ctx, pgx.Identifier{"users"}, nil, copySource{CopyFromSource: rows(), valuesErr: expErr})
+		require.ErrorIs(t, err, expErr)
+		_, err = fake.CopyFrom(ctx, pgx.Identifier{"users"}, nil, copySource{CopyFromSource: rows(), err: expErr})
+		require.ErrorIs(t, err, expErr)
+		_, err = fake.CopyFrom(ctx, pgx.Identifier{"users"}, nil, rows())
+		require.ErrorIs(t, err, expErr)
+		_, err = fake.CopyFrom(ctx, pgx.Identifier{"users"}, nil, rows())
+		require.ErrorIs(t, err, ErrUnexpectedQuery)
+	})
+
+	t.Run("should be able to write rows of copy to", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`COPY users TO STDOUT`).On("leader").Returns(nil, []any{1, "admin"}, []any{2, nil})
+		leader := fake.Node("leader")
+
+		var out bytes.Buffer
+		tag, err := leader.CopyTo(context.Background(), &out, "COPY users TO STDOUT")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), tag.RowsAffected())
+		assert.Equal(t, "1\tadmin\n2\t\\N\n", out.String())
+	})
+
+	t.Run("should be able to copy to in transaction of node", func(t *testing.T) {
+		fake := NewFake(t)
+		fake.Expect(`COPY`).On("leader")
+		leader := fake.Node("leader")
+
+		require.NoError(t, leader.Transactional(context.Background(), func(ctx context.Context) error {
+			_, err := fake.CopyTo(ctx, &bytes.Buffer{}, "COPY users TO STDOUT")
+			return err
+		}))
+		assert.True(t, fake.Calls()[1].InTx)
+	})
+
+	t.Run("should be able to return errors of copy to", func(t *testing.T) {
+		expErr := errors.New("boom")
+		fake := NewFake(&fakeT{})
+		fake.Expect(`COPY`).ReturnsError(expErr)
+		fake.Expect(`COPY`).Returns(nil, []any{1})
+		ctx := context.Background()
+
+		_, err := fake.CopyTo(ctx, &bytes.Buffer{}, "COPY")
+		require.ErrorIs(t, err, expErr)
+		_, err = fake.CopyTo(ctx, failedWriter{err: expErr}, "COPY")
+		require.ErrorIs(t, err, expErr)
+		_, err = fake.CopyTo(ctx, &bytes.Buffer{}, "COPY")
+		require.ErrorIs(t, err, ErrUnexpectedQuery)
+	})
+}
+
+type failedWriter struct {
+	err error
+}
+
+func (w failedWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestAssign(t *testing.T) {
+	t.Run("should be able to scan into sql.Scanner", func(t *testing.T) {
+		var dest sql.NullInt64
+		require.NoError(t, assign(&dest, int64(1)))
+		assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, dest)
+	})
+
+	t.Run("should be able to convert values", func(t *testing.T) {
+		var (
+			f     float64
+			s     string
+			b     []byte
+			p     *int
+			a     any = 1
+			m         = map[string]int{}
+			slice     = []int{1}
+		)
+		require.NoError(t, assign(&f, 1))
+		require.NoError(t, assign(&s, []byte("text")))
+		require.NoError(t, assign(&b, "bytes"))
+		require.NoError(t, assign(&p, int64(3)))
+		require.NoError(t, assign(&a, nil))
+		require.NoError(t, assign(&m, nil))
+		require.NoError(t, assign(&slice, nil))
+		assert.InDelta(t, 1.0, f, 0)
+		assert.Equal(t, "text", s)
+		assert.Equal(t, []byte("bytes"), b)
+		assert.Equal(t, 3, *p)
+		assert.Nil(t, a)
+		assert.Nil(t, m)
+		assert.Nil(t, slice)
+	})
+
+	t.Run("should be able to reject wrong destinations", func(t *testing.T) {
+		var (
+			n int
+			s string
+			p *int
+		)
+		assert.ErrorIs(t, assign(n, 1), ErrScan)
+		assert.ErrorIs(t, assign(&n, nil), ErrScan)
+		assert.ErrorIs(t, assign(&s, 1), ErrScan)
+		assert.ErrorIs(t, assign(&p, "one"), ErrScan)
+	})
+}
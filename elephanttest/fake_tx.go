@@ -0,0 +1,106 @@
+package elephanttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx tracks transaction boundaries of node, nested transactions are savepoints.
+type fakeTx struct {
+	node   *FakeNode
+	nested bool
+	mu     sync.Mutex
+	closed bool
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	if tx.isClosed() {
+		return nil, pgx.ErrTxClosed
+	}
+	tx.node.fake.record(Call{Node: tx.node.name, SQL: "SAVEPOINT", InTx: true})
+	return &fakeTx{node: tx.node, nested: true}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.nested {
+		return tx.close("RELEASE SAVEPOINT")
+	}
+	return tx.close("COMMIT")
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.nested {
+		return tx.close("ROLLBACK TO SAVEPOINT")
+	}
+	return tx.close("ROLLBACK")
+}
+
+func (tx *fakeTx) close(boundary string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	tx.node.fake.record(Call{Node: tx.node.name, SQL: boundary, InTx: true})
+	return nil
+}
+
+func (tx *fakeTx) isClosed() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.closed
+}
+
+func (tx *fakeTx) CopyFrom(
+	_ context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	if tx.isClosed() {
+		return 0, pgx.ErrTxClosed
+	}
+	return tx.node.copyFrom(tableName, columnNames, rowSrc, true)
+}
+
+func (tx *fakeTx) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	if tx.isClosed() {
+		return &fakeBatchResults{batch: b, err: pgx.ErrTxClosed}
+	}
+	return tx.node.sendBatch(b, true)
+}
+
+func (tx *fakeTx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (tx *fakeTx) Prepare(_ context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	if tx.isClosed() {
+		return pgconn.CommandTag{}, pgx.ErrTxClosed
+	}
+	return tx.node.exec(sql, arguments, true)
+}
+
+func (tx *fakeTx) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx.isClosed() {
+		return nil, pgx.ErrTxClosed
+	}
+	return tx.node.query(sql, args, true)
+}
+
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	if tx.isClosed() {
+		return fakeRow{err: pgx.ErrTxClosed}
+	}
+	return tx.node.queryRow(sql, args, true)
+}
+
+// Conn is nil, because fake has no connections.
+func (tx *fakeTx) Conn() *pgx.Conn {
+	return nil
+}
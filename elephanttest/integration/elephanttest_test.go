@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/elephanttest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHarness_Integration(t *testing.T) {
+	t.Run("should be able to isolate parallel tests and roll them back", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+
+		t.Run("group", func(t *testing.T) {
+			for _, name := range []string{"first", "second"} {
+				t.Run(name, func(t *testing.T) {
+					t.Parallel()
+					ctx, db := tcs.SUT.Begin(t)
+					_, err := db.Exec(ctx, "CREATE TABLE harness_items (name text PRIMARY KEY)")
+					require.NoError(t, err)
+					_, err = db.Exec(ctx, "INSERT INTO harness_items VALUES ($1)", name)
+					require.NoError(t, err)
+
+					expErr := errors.New("rollback")
+					err = db.Transactional(ctx, func(ctx context.Context) error {
+						if _, err := db.Exec(ctx, "INSERT INTO harness_items VALUES ($1)", name+"-nested"); err != nil {
+							return err
+						}
+						return expErr
+					})
+					require.ErrorIs(t, err, expErr)
+
+					var names []string
+					rows, err := db.Query(context.Background(), "SELECT name FROM harness_items")
+					require.NoError(t, err)
+					for rows.Next() {
+						var item string
+						require.NoError(t, rows.Scan(&item))
+						names = append(names, item)
+					}
+					require.NoError(t, rows.Err())
+					assert.Equal(t, []string{name}, names)
+				})
+			}
+		})
+
+		var exists bool
+		require.NoError(t, tcs.Deps.DB.QueryRow(context.Background(),
+			"SELECT to_regclass('harness_items') IS NOT NULL",
+		).Scan(&exists))
+		assert.False(t, exists)
+	})
+}
+
+func TestSnapshot_Integration(t *testing.T) {
+	t.Run("should be able to open copy of seeded template", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		_, err := tcs.Deps.DB.Exec(ctx, "CREATE DATABASE elephanttest_seed")
+		require.NoError(t, err)
+
+		cfg := tcs.Deps.DB.Config()
+		cfg.ConnConfig.Database = "elephanttest_seed"
+		seed, err := pgxpool.NewWithConfig(ctx, cfg)
+		require.NoError(t, err)
+		_, err = seed.Exec(ctx, "CREATE TABLE users (login text); INSERT INTO users VALUES ('admin')")
+		seed.Close()
+		require.NoError(t, err)
+
+		pool := elephanttest.Snapshot(t, tcs.Deps.DB, "elephanttest_seed")
+		ctx, db := elephanttest.New(elephanttest.FromPool(pool)).Begin(t)
+		var login string
+		require.NoError(t, db.QueryRow(ctx, "SELECT login FROM users").Scan(&login))
+		assert.Equal(t, "admin", login)
+	})
+}
@@ -0,0 +1,40 @@
+// Package integration runs elephanttest against postgres in container, apart from its own tests, which don't need it.
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/godepo/elephant/elephanttest"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, *elephanttest.Harness]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, *elephanttest.Harness] {
+	return groat.New[Deps, State, *elephanttest.Harness](t, func(t *testing.T, deps Deps) *elephanttest.Harness {
+		return elephanttest.New(elephanttest.FromPool(deps.DB))
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, *elephanttest.Harness](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrator(func(context.Context, pgrx.MigratorConfig) error {
+				return nil
+			}),
+		),
+	)
+	os.Exit(suite.Go())
+}
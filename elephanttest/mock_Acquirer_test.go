@@ -0,0 +1,96 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package elephanttest
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgxpool "github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MockAcquirer is an autogenerated mock type for the Acquirer type
+type MockAcquirer struct {
+	mock.Mock
+}
+
+type MockAcquirer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockAcquirer) EXPECT() *MockAcquirer_Expecter {
+	return &MockAcquirer_Expecter{mock: &_m.Mock}
+}
+
+// Acquire provides a mock function with given fields: ctx
+func (_m *MockAcquirer) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Acquire")
+	}
+
+	var r0 *pgxpool.Conn
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*pgxpool.Conn, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *pgxpool.Conn); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pgxpool.Conn)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAcquirer_Acquire_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Acquire'
+type MockAcquirer_Acquire_Call struct {
+	*mock.Call
+}
+
+// Acquire is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockAcquirer_Expecter) Acquire(ctx interface{}) *MockAcquirer_Acquire_Call {
+	return &MockAcquirer_Acquire_Call{Call: _e.mock.On("Acquire", ctx)}
+}
+
+func (_c *MockAcquirer_Acquire_Call) Run(run func(ctx context.Context)) *MockAcquirer_Acquire_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockAcquirer_Acquire_Call) Return(_a0 *pgxpool.Conn, _a1 error) *MockAcquirer_Acquire_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAcquirer_Acquire_Call) RunAndReturn(run func(context.Context) (*pgxpool.Conn, error)) *MockAcquirer_Acquire_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockAcquirer creates a new instance of MockAcquirer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockAcquirer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockAcquirer {
+	mock := &MockAcquirer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,155 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package elephanttest
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+
+	pgxpool "github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MockAdmin is an autogenerated mock type for the Admin type
+type MockAdmin struct {
+	mock.Mock
+}
+
+type MockAdmin_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockAdmin) EXPECT() *MockAdmin_Expecter {
+	return &MockAdmin_Expecter{mock: &_m.Mock}
+}
+
+// Config provides a mock function with given fields:
+func (_m *MockAdmin) Config() *pgxpool.Config {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Config")
+	}
+
+	var r0 *pgxpool.Config
+	if rf, ok := ret.Get(0).(func() *pgxpool.Config); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pgxpool.Config)
+		}
+	}
+
+	return r0
+}
+
+// MockAdmin_Config_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Config'
+type MockAdmin_Config_Call struct {
+	*mock.Call
+}
+
+// Config is a helper method to define mock.On call
+func (_e *MockAdmin_Expecter) Config() *MockAdmin_Config_Call {
+	return &MockAdmin_Config_Call{Call: _e.mock.On("Config")}
+}
+
+func (_c *MockAdmin_Config_Call) Run(run func()) *MockAdmin_Config_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockAdmin_Config_Call) Return(_a0 *pgxpool.Config) *MockAdmin_Config_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockAdmin_Config_Call) RunAndReturn(run func() *pgxpool.Config) *MockAdmin_Config_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Exec provides a mock function with given fields: ctx, sql, args
+func (_m *MockAdmin) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, sql)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exec")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, sql, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgconn.CommandTag); ok {
+		r0 = rf(ctx, sql, args...)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...interface{}) error); ok {
+		r1 = rf(ctx, sql, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockAdmin_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
+type MockAdmin_Exec_Call struct {
+	*mock.Call
+}
+
+// Exec is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sql string
+//   - args ...interface{}
+func (_e *MockAdmin_Expecter) Exec(ctx interface{}, sql interface{}, args ...interface{}) *MockAdmin_Exec_Call {
+	return &MockAdmin_Exec_Call{Call: _e.mock.On("Exec",
+		append([]interface{}{ctx, sql}, args...)...)}
+}
+
+func (_c *MockAdmin_Exec_Call) Run(run func(ctx context.Context, sql string, args ...interface{})) *MockAdmin_Exec_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockAdmin_Exec_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockAdmin_Exec_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockAdmin_Exec_Call) RunAndReturn(run func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)) *MockAdmin_Exec_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockAdmin creates a new instance of MockAdmin. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockAdmin(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockAdmin {
+	mock := &MockAdmin{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
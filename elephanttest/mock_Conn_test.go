@@ -0,0 +1,128 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package elephanttest
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// MockConn is an autogenerated mock type for the Conn type
+type MockConn struct {
+	mock.Mock
+}
+
+type MockConn_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockConn) EXPECT() *MockConn_Expecter {
+	return &MockConn_Expecter{mock: &_m.Mock}
+}
+
+// Begin provides a mock function with given fields: ctx
+func (_m *MockConn) Begin(ctx context.Context) (pgx.Tx, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Begin")
+	}
+
+	var r0 pgx.Tx
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (pgx.Tx, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) pgx.Tx); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Tx)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConn_Begin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Begin'
+type MockConn_Begin_Call struct {
+	*mock.Call
+}
+
+// Begin is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockConn_Expecter) Begin(ctx interface{}) *MockConn_Begin_Call {
+	return &MockConn_Begin_Call{Call: _e.mock.On("Begin", ctx)}
+}
+
+func (_c *MockConn_Begin_Call) Run(run func(ctx context.Context)) *MockConn_Begin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockConn_Begin_Call) Return(_a0 pgx.Tx, _a1 error) *MockConn_Begin_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConn_Begin_Call) RunAndReturn(run func(context.Context) (pgx.Tx, error)) *MockConn_Begin_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Release provides a mock function with given fields:
+func (_m *MockConn) Release() {
+	_m.Called()
+}
+
+// MockConn_Release_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Release'
+type MockConn_Release_Call struct {
+	*mock.Call
+}
+
+// Release is a helper method to define mock.On call
+func (_e *MockConn_Expecter) Release() *MockConn_Release_Call {
+	return &MockConn_Release_Call{Call: _e.mock.On("Release")}
+}
+
+func (_c *MockConn_Release_Call) Run(run func()) *MockConn_Release_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockConn_Release_Call) Return() *MockConn_Release_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockConn_Release_Call) RunAndReturn(run func()) *MockConn_Release_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockConn creates a new instance of MockConn. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockConn(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockConn {
+	mock := &MockConn{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
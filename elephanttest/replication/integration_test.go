@@ -0,0 +1,76 @@
+package replication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestFixture_Integration(t *testing.T) {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	fixture := Run(t)
+	ctx := context.Background()
+	_, err := fixture.DB.Exec(elephant.WithCanWrite(ctx), "CREATE TABLE items (name text)")
+	require.NoError(t, err)
+
+	count := func(t *testing.T) int {
+		t.Helper()
+		var n int
+		require.NoError(t, fixture.DB.QueryRow(ctx, "SELECT count(*) FROM items").Scan(&n))
+		return n
+	}
+	insert := func(t *testing.T) {
+		t.Helper()
+		_, err := fixture.DB.Exec(elephant.WithCanWrite(ctx), "INSERT INTO items VALUES ('item')")
+		require.NoError(t, err)
+	}
+
+	t.Run("should be able to read replicated rows from follower", func(t *testing.T) {
+		insert(t)
+		require.NoError(t, fixture.WaitReplay(ctx, 0))
+
+		assert.Equal(t, 1, count(t))
+		var inRecovery bool
+		require.NoError(t, fixture.DB.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery))
+		assert.True(t, inRecovery)
+	})
+
+	t.Run("should be able to pause replay", func(t *testing.T) {
+		require.NoError(t, fixture.PauseReplay(ctx, 0))
+		insert(t)
+		waitCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+		defer cancel()
+		require.Error(t, fixture.WaitReplay(waitCtx, 0))
+		assert.Equal(t, 1, count(t))
+
+		require.NoError(t, fixture.ResumeReplay(ctx, 0))
+		require.NoError(t, fixture.WaitReplay(ctx, 0))
+		assert.Equal(t, 2, count(t))
+	})
+
+	t.Run("should be able to return error of follower position", func(t *testing.T) {
+		pool, err := pgxpool.New(ctx, "postgres://user@127.0.0.1:1/db")
+		require.NoError(t, err)
+		pool.Close()
+		broken := &Fixture{Leader: fixture.Leader, Followers: []*pgxpool.Pool{pool}}
+
+		require.Error(t, broken.WaitReplay(ctx, 0))
+		require.Error(t, broken.PauseReplay(ctx, 0))
+	})
+
+	t.Run("should be able to promote follower", func(t *testing.T) {
+		require.NoError(t, fixture.Promote(ctx, 0))
+
+		_, err := fixture.Followers[0].Exec(ctx, "INSERT INTO items VALUES ('promoted')")
+		require.NoError(t, err)
+		var inRecovery bool
+		require.NoError(t, fixture.Followers[0].QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery))
+		assert.False(t, inRecovery)
+	})
+}
@@ -0,0 +1,331 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/godepo/elephant/clusterpg"
+	"github.com/godepo/elephant/elephanttest"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	port            nat.Port = "5432/tcp"
+	leaderAlias              = "leader"
+	replicationUser          = "replicator"
+	startupTimeout           = 2 * time.Minute
+	replayPoll               = 50 * time.Millisecond
+)
+
+var ErrNoFollower = errors.New("replication: no follower with index")
+
+// leaderInit allows streaming replication for role replicator from any host of network.
+const leaderInit = `#!/bin/bash
+set -e
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-c "CREATE ROLE ` + replicationUser + ` WITH REPLICATION LOGIN PASSWORD '$REPLICATION_PASSWORD'"
+echo "host replication ` + replicationUser + ` all scram-sha-256" >> "$PGDATA/pg_hba.conf"
+`
+
+// followerStart clones leader with pg_basebackup into empty data directory and starts hot standby. Every follower
+// streams from own replication slot, so leader keeps WAL, which follower hasn't received yet.
+const followerStart = `set -e
+mkdir -p "$PGDATA" && chown postgres "$PGDATA" && chmod 700 "$PGDATA"
+until gosu postgres env PGPASSWORD="$REPLICATION_PASSWORD" pg_basebackup -h ` + leaderAlias + ` \
+	-U ` + replicationUser + ` -D "$PGDATA" -R -X stream -C -S "$SLOT"; do
+	sleep 1
+done
+exec gosu postgres postgres -c hot_standby=on
+`
+
+type Config struct {
+	image     string
+	followers int
+	user      string
+	password  string
+	database  string
+}
+
+type Option func(cfg *Config)
+
+func WithImage(image string) Option {
+	return func(cfg *Config) {
+		cfg.image = image
+	}
+}
+
+// WithFollowers sets number of streaming replicas, one by default.
+func WithFollowers(n int) Option {
+	return func(cfg *Config) {
+		cfg.followers = n
+	}
+}
+
+// Fixture is leader and streaming replicas in local containers. DB routes statements to them as clusterpg does.
+type Fixture struct {
+	Leader    *pgxpool.Pool
+	Followers []*pgxpool.Pool
+	DB        clusterpg.Cluster
+
+	containers []container
+	network    remover
+}
+
+type container interface {
+	PortEndpoint(ctx context.Context, port nat.Port, proto string) (string, error)
+	Terminate(ctx context.Context) error
+}
+
+type remover interface {
+	Remove(ctx context.Context) error
+}
+
+// starter runs containers and connects to them, it is replaced in tests.
+type starter struct {
+	network func(ctx context.Context) (remover, error)
+	run     func(ctx context.Context, req testcontainers.ContainerRequest) (container, error)
+	connect func(ctx context.Context, dsn string) (*pgxpool.Pool, error)
+}
+
+func newStarter() starter {
+	return starter{
+		network: func(ctx context.Context) (remover, error) {
+			return network.New(ctx)
+		},
+		run: func(ctx context.Context, req testcontainers.ContainerRequest) (container, error) {
+			return testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+				ContainerRequest: req,
+				Started:          true,
+			})
+		},
+		connect: pgxpool.New,
+	}
+}
+
+// Start runs leader and followers and waits until followers stream from leader.
+func Start(ctx context.Context, opts ...Option) (*Fixture, error) {
+	return newStarter().start(ctx, opts...)
+}
+
+// Run starts fixture for test and stops it on test cleanup.
+func Run(t elephanttest.T, opts ...Option) *Fixture {
+	t.Helper()
+	return run(t, func() (*Fixture, error) {
+		return Start(context.Background(), opts...)
+	})
+}
+
+func run(t elephanttest.T, start func() (*Fixture, error)) *Fixture {
+	t.Helper()
+	fixture, err := start()
+	if err != nil {
+		t.Fatalf("replication: can't start fixture: %v", err)
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := fixture.Close(context.Background()); err != nil {
+			t.Errorf("replication: can't stop fixture: %v", err)
+		}
+	})
+	return fixture
+}
+
+func (s starter) start(ctx context.Context, opts ...Option) (_ *Fixture, out error) {
+	cfg := Config{
+		image:     "docker.io/postgres:16",
+		followers: 1,
+		user:      "postgres",
+		password:  "postgres",
+		database:  "postgres",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	net, err := s.network(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't create network: %w", err)
+	}
+	fixture := &Fixture{network: net}
+	defer func() {
+		if out != nil {
+			out = errors.Join(out, fixture.Close(context.WithoutCancel(ctx)))
+		}
+	}()
+	name := networkName(net)
+
+	leader, err := s.run(ctx, leaderRequest(cfg, name))
+	if err != nil {
+		return nil, fmt.Errorf("can't run leader: %w", err)
+	}
+	fixture.containers = append(fixture.containers, leader)
+	if fixture.Leader, err = s.pool(ctx, cfg, leader); err != nil {
+		return nil, fmt.Errorf("can't connect to leader: %w", err)
+	}
+
+	for i := range cfg.followers {
+		follower, err := s.run(ctx, followerRequest(cfg, name, i))
+		if err != nil {
+			return nil, fmt.Errorf("can't run follower %d: %w", i, err)
+		}
+		fixture.containers = append(fixture.containers, follower)
+		pool, err := s.pool(ctx, cfg, follower)
+		if err != nil {
+			return nil, fmt.Errorf("can't connect to follower %d: %w", i, err)
+		}
+		fixture.Followers = append(fixture.Followers, pool)
+	}
+
+	builder := clusterpg.New().Leader(func() (clusterpg.Pool, error) {
+		return singlepg.New(fixture.Leader), nil
+	})
+	for _, pool := range fixture.Followers {
+		builder = builder.Follower(func() (clusterpg.Pool, error) {
+			return singlepg.New(pool), nil
+		})
+	}
+	if fixture.DB, err = builder.Go(); err != nil {
+		return nil, fmt.Errorf("can't build cluster: %w", err)
+	}
+	return fixture, nil
+}
+
+func networkName(net remover) string {
+	if docker, ok := net.(*testcontainers.DockerNetwork); ok {
+		return docker.Name
+	}
+	return ""
+}
+
+func (s starter) pool(ctx context.Context, cfg Config, c container) (*pgxpool.Pool, error) {
+	endpoint, err := c.PortEndpoint(ctx, port, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.connect(ctx, fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
+		cfg.user, cfg.password, endpoint, cfg.database,
+	))
+}
+
+func env(cfg Config) map[string]string {
+	return map[string]string{
+		"POSTGRES_USER":        cfg.user,
+		"POSTGRES_PASSWORD":    cfg.password,
+		"POSTGRES_DB":          cfg.database,
+		"REPLICATION_PASSWORD": cfg.password,
+	}
+}
+
+func leaderRequest(cfg Config, network string) testcontainers.ContainerRequest {
+	return testcontainers.ContainerRequest{
+		Image:          cfg.image,
+		Env:            env(cfg),
+		ExposedPorts:   []string{string(port)},
+		Networks:       []string{network},
+		NetworkAliases: map[string][]string{network: {leaderAlias}},
+		Cmd:            []string{"postgres", "-c", "wal_level=replica", "-c", "max_wal_senders=10"},
+		Files: []testcontainers.ContainerFile{{
+			Reader:            strings.NewReader(leaderInit),
+			ContainerFilePath: "/docker-entrypoint-initdb.d/replication.sh",
+			FileMode:          0o755,
+		}},
+		WaitingFor: wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).WithStartupTimeout(startupTimeout),
+	}
+}
+
+func followerRequest(cfg Config, network string, i int) testcontainers.ContainerRequest {
+	vars := env(cfg)
+	vars["SLOT"] = fmt.Sprintf("follower_%d", i)
+	return testcontainers.ContainerRequest{
+		Image:        cfg.image,
+		Env:          vars,
+		ExposedPorts: []string{string(port)},
+		Networks:     []string{network},
+		Entrypoint:   []string{"bash", "-c", followerStart},
+		WaitingFor: wait.ForLog("database system is ready to accept read-only connections").
+			WithStartupTimeout(startupTimeout),
+	}
+}
+
+func (f *Fixture) follower(i int) (*pgxpool.Pool, error) {
+	if i < 0 || i >= len(f.Followers) {
+		return nil, fmt.Errorf("%w %d", ErrNoFollower, i)
+	}
+	return f.Followers[i], nil
+}
+
+// PauseReplay stops follower from applying WAL, so it lags behind leader until ResumeReplay.
+func (f *Fixture) PauseReplay(ctx context.Context, i int) error {
+	return f.exec(ctx, i, "SELECT pg_wal_replay_pause()")
+}
+
+func (f *Fixture) ResumeReplay(ctx context.Context, i int) error {
+	return f.exec(ctx, i, "SELECT pg_wal_replay_resume()")
+}
+
+// Promote turns follower into leader, which accepts writes. Fixture doesn't change roles of pools and DB.
+func (f *Fixture) Promote(ctx context.Context, i int) error {
+	return f.exec(ctx, i, "SELECT pg_promote(true)")
+}
+
+func (f *Fixture) exec(ctx context.Context, i int, query string) error {
+	pool, err := f.follower(i)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Exec(ctx, query)
+	return err
+}
+
+// WaitReplay waits until follower applies all changes, which leader has at the moment of call.
+func (f *Fixture) WaitReplay(ctx context.Context, i int) error {
+	pool, err := f.follower(i)
+	if err != nil {
+		return err
+	}
+	var lsn string
+	if err := f.Leader.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return fmt.Errorf("can't get leader position: %w", err)
+	}
+	ticker := time.NewTicker(replayPoll)
+	defer ticker.Stop()
+	for {
+		var replayed bool
+		err := pool.QueryRow(ctx, "SELECT pg_last_wal_replay_lsn() >= $1::pg_lsn", lsn).Scan(&replayed)
+		if err != nil {
+			return fmt.Errorf("can't get follower position: %w", err)
+		}
+		if replayed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes pools and stops containers.
+func (f *Fixture) Close(ctx context.Context) error {
+	if f.Leader != nil {
+		f.Leader.Close()
+	}
+	for _, pool := range f.Followers {
+		pool.Close()
+	}
+	var out error
+	for i := len(f.containers) - 1; i >= 0; i-- {
+		out = errors.Join(out, f.containers[i].Terminate(ctx))
+	}
+	return errors.Join(out, f.network.Remove(ctx))
+}
@@ -0,0 +1,230 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+type fakeT struct {
+	cleanups []func()
+	errors   []string
+	fatal    string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.fatal = fmt.Sprintf(format, args...)
+}
+
+type stubContainer struct {
+	name         string
+	endpointErr  error
+	terminateErr error
+	log          *[]string
+}
+
+func (c stubContainer) PortEndpoint(context.Context, nat.Port, string) (string, error) {
+	return "127.0.0.1:1", c.endpointErr
+}
+
+func (c stubContainer) Terminate(context.Context) error {
+	*c.log = append(*c.log, "terminate "+c.name)
+	return c.terminateErr
+}
+
+type stubNetwork struct {
+	err error
+	log *[]string
+}
+
+func (n stubNetwork) Remove(context.Context) error {
+	*n.log = append(*n.log, "remove network")
+	return n.err
+}
+
+type stub struct {
+	log        []string
+	requests   []testcontainers.ContainerRequest
+	runErr     map[int]error
+	endpoint   map[int]error
+	connectErr map[int]error
+	connects   int
+}
+
+func (s *stub) starter() starter {
+	return starter{
+		network: func(context.Context) (remover, error) {
+			return stubNetwork{log: &s.log}, nil
+		},
+		run: func(_ context.Context, req testcontainers.ContainerRequest) (container, error) {
+			i := len(s.requests)
+			s.requests = append(s.requests, req)
+			if err := s.runErr[i]; err != nil {
+				return nil, err
+			}
+			return stubContainer{name: fmt.Sprint(i), endpointErr: s.endpoint[i], log: &s.log}, nil
+		},
+		connect: func(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+			i := s.connects
+			s.connects++
+			if err := s.connectErr[i]; err != nil {
+				return nil, err
+			}
+			return pgxpool.New(ctx, dsn)
+		},
+	}
+}
+
+func TestStarter(t *testing.T) {
+	t.Run("should be able to start leader and followers", func(t *testing.T) {
+		s := &stub{}
+		fixture, err := s.starter().start(context.Background(), WithFollowers(2), WithImage("postgres:17"))
+		require.NoError(t, err)
+		require.Len(t, s.requests, 3)
+		assert.Equal(t, "postgres:17", s.requests[0].Image)
+		assert.Equal(t, "postgres", fixture.Leader.Config().ConnConfig.User)
+		assert.Len(t, fixture.Followers, 2)
+		assert.NotNil(t, fixture.DB)
+		assert.Equal(t, "follower_1", s.requests[2].Env["SLOT"])
+
+		require.NoError(t, fixture.Close(context.Background()))
+		assert.Equal(t, []string{"terminate 2", "terminate 1", "terminate 0", "remove network"}, s.log)
+	})
+
+	t.Run("should be able to clean up after failure", func(t *testing.T) {
+		expErr := newError()
+		for name, tc := range map[string]struct {
+			stub *stub
+			log  []string
+		}{
+			"leader run":         {stub: &stub{runErr: map[int]error{0: expErr}}, log: []string{"remove network"}},
+			"leader endpoint":    {stub: &stub{endpoint: map[int]error{0: expErr}}, log: []string{"terminate 0", "remove network"}},
+			"leader connect":     {stub: &stub{connectErr: map[int]error{0: expErr}}, log: []string{"terminate 0", "remove network"}},
+			"follower run":       {stub: &stub{runErr: map[int]error{1: expErr}}, log: []string{"terminate 0", "remove network"}},
+			"follower connect":   {stub: &stub{connectErr: map[int]error{1: expErr}}, log: []string{"terminate 1", "terminate 0", "remove network"}},
+			"follower endpoints": {stub: &stub{endpoint: map[int]error{1: expErr}}, log: []string{"terminate 1", "terminate 0", "remove network"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := tc.stub.starter().start(context.Background())
+				require.ErrorIs(t, err, expErr)
+				assert.Equal(t, tc.log, tc.stub.log)
+			})
+		}
+	})
+
+	t.Run("should be able to reject cluster without followers", func(t *testing.T) {
+		_, err := (&stub{}).starter().start(context.Background(), WithFollowers(0))
+		assert.Error(t, err)
+	})
+
+	t.Run("should be able to return error of network", func(t *testing.T) {
+		expErr := newError()
+		s := (&stub{}).starter()
+		s.network = func(context.Context) (remover, error) {
+			return nil, expErr
+		}
+		_, err := s.start(context.Background())
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to return errors of close", func(t *testing.T) {
+		expErr := newError()
+		var log []string
+		fixture := &Fixture{
+			containers: []container{stubContainer{name: "0", terminateErr: expErr, log: &log}},
+			network:    stubNetwork{err: expErr, log: &log},
+		}
+		assert.ErrorIs(t, fixture.Close(context.Background()), expErr)
+		assert.Equal(t, []string{"terminate 0", "remove network"}, log)
+	})
+}
+
+func TestRequests(t *testing.T) {
+	t.Run("should be able to use name of docker network", func(t *testing.T) {
+		assert.Equal(t, "net", networkName(&testcontainers.DockerNetwork{Name: "net"}))
+		assert.Empty(t, networkName(stubNetwork{}))
+	})
+
+	t.Run("should be able to configure leader for replication", func(t *testing.T) {
+		req := leaderRequest(Config{image: "postgres:16", password: "secret"}, "net")
+		assert.Contains(t, req.Cmd, "wal_level=replica")
+		assert.Equal(t, []string{leaderAlias}, req.NetworkAliases["net"])
+		assert.Equal(t, "secret", req.Env["REPLICATION_PASSWORD"])
+		require.Len(t, req.Files, 1)
+	})
+
+	t.Run("should be able to clone leader into follower", func(t *testing.T) {
+		req := followerRequest(Config{image: "postgres:16"}, "net", 3)
+		assert.Equal(t, "follower_3", req.Env["SLOT"])
+		assert.Equal(t, []string{"net"}, req.Networks)
+		assert.Contains(t, req.Entrypoint[2], "pg_basebackup")
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("should be able to fail test", func(t *testing.T) {
+		expErr := newError()
+		tb := &fakeT{}
+		assert.Nil(t, run(tb, func() (*Fixture, error) {
+			return nil, expErr
+		}))
+		assert.Contains(t, tb.fatal, expErr.Error())
+	})
+
+	t.Run("should be able to stop fixture on cleanup", func(t *testing.T) {
+		expErr := newError()
+		var log []string
+		tb := &fakeT{}
+		fixture := run(tb, func() (*Fixture, error) {
+			return &Fixture{network: stubNetwork{err: expErr, log: &log}}, nil
+		})
+		require.NotNil(t, fixture)
+		require.Len(t, tb.cleanups, 1)
+		tb.cleanups[0]()
+		assert.Equal(t, []string{"remove network"}, log)
+		require.Len(t, tb.errors, 1)
+		assert.Contains(t, tb.errors[0], expErr.Error())
+	})
+}
+
+func TestFixture_Follower(t *testing.T) {
+	t.Run("should be able to reject unknown follower", func(t *testing.T) {
+		fixture := &Fixture{}
+		ctx := context.Background()
+		require.ErrorIs(t, fixture.PauseReplay(ctx, 0), ErrNoFollower)
+		require.ErrorIs(t, fixture.ResumeReplay(ctx, -1), ErrNoFollower)
+		require.ErrorIs(t, fixture.Promote(ctx, 1), ErrNoFollower)
+		require.ErrorIs(t, fixture.WaitReplay(ctx, 0), ErrNoFollower)
+	})
+
+	t.Run("should be able to return error of leader position", func(t *testing.T) {
+		pool, err := pgxpool.New(context.Background(), "postgres://user@127.0.0.1:1/db")
+		require.NoError(t, err)
+		pool.Close()
+		fixture := &Fixture{Leader: pool, Followers: []*pgxpool.Pool{pool}}
+
+		assert.Error(t, fixture.WaitReplay(context.Background(), 0))
+	})
+}
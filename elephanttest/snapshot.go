@@ -0,0 +1,52 @@
+package elephanttest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Admin is pool of maintenance database, for example postgres, which creates and drops snapshots.
+type Admin interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Config() *pgxpool.Config
+}
+
+// Snapshot creates database from seeded template and returns pool of it, configured as admin pool. Database is
+// dropped on test cleanup. Template must have no open connections while snapshot is created.
+func Snapshot(t T, admin Admin, template string) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	database := "snapshot_" + hex.EncodeToString(suffix)
+	name := pgx.Identifier{database}.Sanitize()
+
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+name+" TEMPLATE "+pgx.Identifier{template}.Sanitize()); err != nil {
+		t.Fatalf("elephanttest: can't create snapshot of %q: %v", template, err)
+		return nil
+	}
+	drop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		_, _ = admin.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+	}
+
+	cfg := admin.Config()
+	cfg.ConnConfig.Database = database
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		drop()
+		t.Fatalf("elephanttest: can't open snapshot of %q: %v", template, err)
+		return nil
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		drop()
+	})
+	return pool
+}
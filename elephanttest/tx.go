@@ -0,0 +1,17 @@
+package elephanttest
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// txPool runs statements in outer transaction of test and starts savepoints instead of transactions. Options of
+// transaction are ignored, because savepoint can't change them.
+type txPool struct {
+	pgx.Tx
+}
+
+func (p txPool) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return p.Tx.Begin(ctx)
+}
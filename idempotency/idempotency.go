@@ -0,0 +1,217 @@
+//go:generate mockery
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const lockPrefix = "idempotency:"
+
+var (
+	ErrConflict   = errors.New("idempotency: key is already used with another request")
+	ErrInProgress = errors.New("idempotency: request with the same key is in progress")
+)
+
+type DB interface {
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+// Handler does business logic of request and returns response to store.
+type Handler func(ctx context.Context) ([]byte, error)
+
+type Result struct {
+	Response []byte
+	// Replayed is true when response is taken from store instead of calling handler.
+	Replayed bool
+}
+
+type Config struct {
+	table    pgx.Identifier
+	ttl      time.Duration
+	failFast bool
+	shards   []uint
+	onError  func(err error)
+}
+
+type Option func(cfg *Config)
+
+func WithTable(name ...string) Option {
+	return func(cfg *Config) {
+		cfg.table = name
+	}
+}
+
+// WithTTL sets how long response is replayed for the key.
+func WithTTL(ttl time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithFailFast returns ErrInProgress for concurrent duplicate instead of waiting for its result.
+func WithFailFast() Option {
+	return func(cfg *Config) {
+		cfg.failFast = true
+	}
+}
+
+// WithShards makes cleanup collect expired keys of every given shard of sharded.Hive.
+func WithShards(ids ...uint) Option {
+	return func(cfg *Config) {
+		cfg.shards = ids
+	}
+}
+
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onError = fn
+	}
+}
+
+type queries struct {
+	find    string
+	save    string
+	cleanup string
+}
+
+type Store struct {
+	db      DB
+	cfg     Config
+	queries queries
+}
+
+func New(db DB, opts ...Option) *Store {
+	cfg := Config{
+		table:   pgx.Identifier{"idempotency_keys"},
+		ttl:     24 * time.Hour,
+		onError: func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	table := cfg.table.Sanitize()
+	return &Store{
+		db:  db,
+		cfg: cfg,
+		queries: queries{
+			find: fmt.Sprintf(
+				"SELECT request_hash, response FROM %s WHERE key = $1 AND expires_at > now()", table,
+			),
+			// only expired key can exist here, because live key is found under the lock
+			save: fmt.Sprintf(`INSERT INTO %s (key, request_hash, response, expires_at)
+VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, response = EXCLUDED.response,
+created_at = now(), expires_at = EXCLUDED.expires_at`, table),
+			cleanup: fmt.Sprintf("DELETE FROM %s WHERE expires_at <= now()", table),
+		},
+	}
+}
+
+// Do calls handler once per key and stores its response in the same transaction, joining transaction from context.
+// Request with the same key and body gets stored response, with another body it gets ErrConflict. Concurrent
+// duplicate waits until the first request is committed or rolled back. Key is used as sharding key, unless context
+// already points to shard.
+func (s *Store) Do(ctx context.Context, key string, request []byte, handler Handler) (Result, error) {
+	hash := sha256.Sum256(request)
+	lock := elephant.WithAdvisoryXactLock
+	if s.cfg.failFast {
+		lock = elephant.TryWithAdvisoryXactLock
+	}
+
+	var res Result
+	err := lock(route(ctx, key), s.db, elephant.LockName(lockPrefix+key), func(ctx context.Context) error {
+		var storedHash, response []byte
+		err := s.db.QueryRow(ctx, s.queries.find, key).Scan(&storedHash, &response)
+		switch {
+		case err == nil:
+			if !bytes.Equal(storedHash, hash[:]) {
+				return fmt.Errorf("%w: %q", ErrConflict, key)
+			}
+			res = Result{Response: response, Replayed: true}
+			return nil
+		case !errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("can't find idempotency key %q: %w", key, err)
+		}
+
+		response, err = handler(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(ctx, s.queries.save, key, hash[:], response, s.cfg.ttl.Seconds()); err != nil {
+			return fmt.Errorf("can't save idempotency key %q: %w", key, err)
+		}
+		res = Result{Response: response}
+		return nil
+	})
+	if errors.Is(err, elephant.ErrLockNotAcquired) {
+		return Result{}, fmt.Errorf("%w: %q", ErrInProgress, key)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+func route(ctx context.Context, key string) context.Context {
+	_, hasID := pgcontext.ShardIDFrom(ctx)
+	_, hasKey := pgcontext.ShardingKeyFrom(ctx)
+	if hasID || hasKey {
+		return ctx
+	}
+	return pgcontext.With(ctx, pgcontext.WithShardingKey(key))
+}
+
+// Cleanup deletes expired keys and returns number of deleted ones.
+func (s *Store) Cleanup(ctx context.Context) (int64, error) {
+	var deleted int64
+	var errs []error
+	for _, scope := range s.scopes(pgcontext.WithCanWrite(ctx)) {
+		tag, err := s.db.Exec(scope, s.queries.cleanup)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can't cleanup idempotency keys: %w", err))
+			continue
+		}
+		deleted += tag.RowsAffected()
+	}
+	return deleted, errors.Join(errs...)
+}
+
+func (s *Store) scopes(ctx context.Context) []context.Context {
+	if len(s.cfg.shards) == 0 {
+		return []context.Context{ctx}
+	}
+	scopes := make([]context.Context, 0, len(s.cfg.shards))
+	for _, id := range s.cfg.shards {
+		scopes = append(scopes, pgcontext.With(ctx, pgcontext.WithShardID(id)))
+	}
+	return scopes
+}
+
+// Collect runs Cleanup every interval until context is done. Errors are reported to error handler.
+func (s *Store) Collect(ctx context.Context, interval time.Duration) error {
+	for {
+		if _, err := s.Cleanup(ctx); err != nil {
+			s.cfg.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	lockQuery    = "SELECT pg_advisory_xact_lock($1)"
+	tryLockQuery = "SELECT pg_try_advisory_xact_lock($1)"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func row(t *testing.T, err error, values ...any) *MockRow {
+	t.Helper()
+	r := NewMockRow(t)
+	r.EXPECT().Scan(mock.Anything).RunAndReturn(func(dest ...any) error {
+		*dest[0].(*bool) = values[0].(bool)
+		return nil
+	}).Maybe()
+	r.EXPECT().Scan(mock.Anything, mock.Anything).RunAndReturn(func(dest ...any) error {
+		if err != nil {
+			return err
+		}
+		*dest[0].(*[]byte) = values[0].([]byte)
+		*dest[1].(*[]byte) = values[1].([]byte)
+		return nil
+	}).Maybe()
+	return r
+}
+
+func inTransaction(t *testing.T, db *MockDB, key string) {
+	db.EXPECT().Transactional(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			assert.True(t, pgcontext.CanWriteFrom(ctx))
+			shardingKey, _ := pgcontext.ShardingKeyFrom(ctx)
+			assert.Equal(t, key, shardingKey)
+			return fn(ctx)
+		})
+}
+
+func expectLock(db *MockDB, key string) {
+	db.EXPECT().Exec(mock.Anything, lockQuery, elephant.LockName(lockPrefix+key).ID()).
+		Return(pgconn.CommandTag{}, nil)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should be able to build queries for custom table", func(t *testing.T) {
+		sut := New(NewMockDB(t), WithTable("api", "requests"))
+
+		assert.Equal(t,
+			`SELECT request_hash, response FROM "api"."requests" WHERE key = $1 AND expires_at > now()`,
+			sut.queries.find,
+		)
+		assert.Equal(t, `DELETE FROM "api"."requests" WHERE expires_at <= now()`, sut.queries.cleanup)
+	})
+}
+
+func TestStore_Do(t *testing.T) {
+	request := []byte(`{"amount":100}`)
+	hash := sha256.Sum256(request)
+
+	t.Run("should be able to store response of handler", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		expectLock(db, key)
+		sut := New(db, WithTTL(time.Hour))
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, key).Return(row(t, pgx.ErrNoRows))
+		db.EXPECT().Exec(mock.Anything, sut.queries.save, key, hash[:], []byte("created"), float64(3600)).
+			Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+		res, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			return []byte("created"), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Result{Response: []byte("created")}, res)
+	})
+
+	t.Run("should be able to replay stored response", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		expectLock(db, key)
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, key).Return(row(t, nil, hash[:], []byte("created")))
+
+		res, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			t.Fatal("handler must not be called")
+			return nil, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Result{Response: []byte("created"), Replayed: true}, res)
+	})
+
+	t.Run("should be able to reject another request with the same key", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		expectLock(db, key)
+		sut := New(db)
+		other := sha256.Sum256([]byte("other"))
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, key).Return(row(t, nil, other[:], []byte("created")))
+
+		_, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			t.Fatal("handler must not be called")
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("should be able to return error of find", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		expErr := newError()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		expectLock(db, key)
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, key).Return(row(t, expErr))
+
+		_, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			t.Fatal("handler must not be called")
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to return error of handler", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		expErr := newError()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		expectLock(db, key)
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, key).Return(row(t, pgx.ErrNoRows))
+
+		_, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			return nil, expErr
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to return error of save", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		expErr := newError()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		expectLock(db, key)
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, key).Return(row(t, pgx.ErrNoRows))
+		db.EXPECT().Exec(mock.Anything, sut.queries.save, key, hash[:], []byte(nil), mock.Anything).
+			Return(pgconn.CommandTag{}, expErr)
+
+		_, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail fast, when duplicate is in progress", func(t *testing.T) {
+		key := faker.New().UUID().V4()
+		db := NewMockDB(t)
+		inTransaction(t, db, key)
+		db.EXPECT().QueryRow(mock.Anything, tryLockQuery, elephant.LockName(lockPrefix+key).ID()).
+			Return(row(t, nil, false))
+		sut := New(db, WithFailFast())
+
+		_, err := sut.Do(context.Background(), key, request, func(context.Context) ([]byte, error) {
+			t.Fatal("handler must not be called")
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, ErrInProgress)
+	})
+
+	t.Run("should be able to keep shard from context", func(t *testing.T) {
+		db := NewMockDB(t)
+		inTransaction(t, db, "user")
+		expectLock(db, "key")
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.find, "key").Return(row(t, nil, hash[:], []byte(nil)))
+
+		ctx := elephant.With(context.Background(), elephant.WithShardingKey("user"))
+		res, err := sut.Do(ctx, "key", request, func(context.Context) ([]byte, error) {
+			t.Fatal("handler must not be called")
+			return nil, nil
+		})
+		require.NoError(t, err)
+		assert.True(t, res.Replayed)
+	})
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	t.Run("should be able to cleanup every shard", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockDB(t)
+		sut := New(db, WithShards(1, 2, 3))
+		for id, res := range []struct {
+			tag pgconn.CommandTag
+			err error
+		}{
+			{tag: pgconn.NewCommandTag("DELETE 2")},
+			{err: expErr},
+			{tag: pgconn.NewCommandTag("DELETE 3")},
+		} {
+			db.EXPECT().Exec(mock.MatchedBy(func(ctx context.Context) bool {
+				shardID, _ := pgcontext.ShardIDFrom(ctx)
+				return pgcontext.CanWriteFrom(ctx) && shardID == uint(id+1)
+			}), sut.queries.cleanup).Return(res.tag, res.err)
+		}
+
+		deleted, err := sut.Cleanup(context.Background())
+		assert.ErrorIs(t, err, expErr)
+		assert.Equal(t, int64(5), deleted)
+	})
+}
+
+func TestStore_Collect(t *testing.T) {
+	t.Run("should be able to report errors until context is done", func(t *testing.T) {
+		expErr := newError()
+		ctx, cancel := context.WithCancel(context.Background())
+		db := NewMockDB(t)
+		var errs []error
+		sut := New(db, WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+			if len(errs) == 2 {
+				cancel()
+			}
+		}))
+		db.EXPECT().Exec(mock.Anything, sut.queries.cleanup).Return(pgconn.CommandTag{}, expErr)
+
+		assert.ErrorIs(t, sut.Collect(ctx, time.Millisecond), context.Canceled)
+		require.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[0], expErr)
+	})
+}
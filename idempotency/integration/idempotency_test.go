@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/idempotency"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Requests(t *testing.T) {
+	t.Run("should be able to handle request once", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		key := faker.New().UUID().V4()
+		calls := 0
+		handler := func(context.Context) ([]byte, error) {
+			calls++
+			return []byte("created"), nil
+		}
+
+		res, err := tcs.SUT.Do(ctx, key, []byte("request"), handler)
+		require.NoError(t, err)
+		assert.Equal(t, idempotency.Result{Response: []byte("created")}, res)
+
+		res, err = tcs.SUT.Do(ctx, key, []byte("request"), handler)
+		require.NoError(t, err)
+		assert.Equal(t, idempotency.Result{Response: []byte("created"), Replayed: true}, res)
+		assert.Equal(t, 1, calls)
+
+		_, err = tcs.SUT.Do(ctx, key, []byte("another request"), handler)
+		assert.ErrorIs(t, err, idempotency.ErrConflict)
+	})
+
+	t.Run("should be able to forget key of rolled back transaction", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		db := singlepg.New(tcs.Deps.DB)
+		key := faker.New().UUID().V4()
+		expErr := errors.New("rollback")
+
+		err := db.Transactional(ctx, func(ctx context.Context) error {
+			if _, err := tcs.SUT.Do(ctx, key, []byte("request"), func(context.Context) ([]byte, error) {
+				return []byte("created"), nil
+			}); err != nil {
+				return err
+			}
+			return expErr
+		})
+		assert.ErrorIs(t, err, expErr)
+
+		res, err := tcs.SUT.Do(ctx, key, []byte("request"), func(context.Context) ([]byte, error) {
+			return []byte("again"), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, idempotency.Result{Response: []byte("again")}, res)
+	})
+
+	t.Run("should be able to wait for concurrent duplicate", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		key := faker.New().UUID().V4()
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		first := make(chan error, 1)
+		go func() {
+			_, err := tcs.SUT.Do(ctx, key, []byte("request"), func(context.Context) ([]byte, error) {
+				close(started)
+				<-release
+				return []byte("created"), nil
+			})
+			first <- err
+		}()
+		<-started
+
+		_, err := idempotency.New(singlepg.New(tcs.Deps.DB), idempotency.WithFailFast()).
+			Do(ctx, key, []byte("request"), func(context.Context) ([]byte, error) {
+				return nil, errors.New("duplicate must not be handled")
+			})
+		assert.ErrorIs(t, err, idempotency.ErrInProgress)
+
+		second := make(chan idempotency.Result, 1)
+		go func() {
+			res, _ := tcs.SUT.Do(ctx, key, []byte("request"), func(context.Context) ([]byte, error) {
+				return nil, errors.New("duplicate must not be handled")
+			})
+			second <- res
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		require.NoError(t, <-first)
+		assert.Equal(t, idempotency.Result{Response: []byte("created"), Replayed: true}, <-second)
+	})
+
+	t.Run("should be able to expire and collect keys", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		_, err := tcs.Deps.DB.Exec(ctx, "TRUNCATE idempotency_keys")
+		require.NoError(t, err)
+		sut := idempotency.New(singlepg.New(tcs.Deps.DB), idempotency.WithTTL(0))
+		key := faker.New().UUID().V4()
+
+		for _, response := range []string{"first", "second"} {
+			res, err := sut.Do(ctx, key, []byte(response), func(context.Context) ([]byte, error) {
+				return []byte(response), nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, idempotency.Result{Response: []byte(response)}, res)
+		}
+
+		deleted, err := sut.Cleanup(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+}
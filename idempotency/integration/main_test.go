@@ -0,0 +1,38 @@
+// Package integration runs idempotency against postgres in container, apart from its own tests, which don't need it.
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"github.com/godepo/elephant/idempotency"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, *idempotency.Store]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, *idempotency.Store] {
+	return groat.New[Deps, State, *idempotency.Store](t, func(t *testing.T, deps Deps) *idempotency.Store {
+		return idempotency.New(singlepg.New(deps.DB))
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, *idempotency.Store](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrationsPath("../sql"),
+		),
+	)
+	os.Exit(suite.Go())
+}
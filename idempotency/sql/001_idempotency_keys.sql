@@ -0,0 +1,9 @@
+CREATE TABLE idempotency_keys (
+    key          TEXT        PRIMARY KEY,
+    request_hash BYTEA       NOT NULL,
+    response     BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
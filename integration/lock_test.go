@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdvisoryLock_Session(t *testing.T) {
+	t.Run("should be able to hold lock until fn returns", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		key := elephant.LockName(t.Name())
+
+		err := elephant.WithAdvisoryLock(ctx, tcs.SUT, key, func(ctx context.Context) error {
+			err := elephant.TryWithAdvisoryLock(ctx, tcs.SUT, key, func(context.Context) error {
+				t.Fatal("lock must be held")
+				return nil
+			})
+			assert.ErrorIs(t, err, elephant.ErrLockNotAcquired)
+			return elephant.TryWithAdvisoryXactLock(ctx, tcs.SUT, key, func(context.Context) error {
+				t.Fatal("lock must be held")
+				return nil
+			})
+		})
+		assert.ErrorIs(t, err, elephant.ErrLockNotAcquired)
+
+		called := false
+		require.NoError(t, elephant.TryWithAdvisoryLock(ctx, tcs.SUT, key, func(context.Context) error {
+			called = true
+			return nil
+		}))
+		assert.True(t, called)
+	})
+
+	t.Run("should be able to return error of fn and release lock", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		key := elephant.LockID(time.Now().UnixNano())
+		expErr := errors.New("failed")
+
+		err := elephant.WithAdvisoryLock(ctx, tcs.SUT, key, func(context.Context) error {
+			return expErr
+		})
+		assert.ErrorIs(t, err, expErr)
+		require.NoError(t, elephant.TryWithAdvisoryXactLock(ctx, tcs.SUT, key, func(context.Context) error {
+			return nil
+		}))
+	})
+
+	t.Run("should be able to stop waiting for lock with context", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		key := elephant.LockName(t.Name())
+
+		err := elephant.WithAdvisoryXactLock(context.Background(), tcs.SUT, key, func(context.Context) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			return elephant.WithAdvisoryLock(ctx, tcs.SUT, key, func(context.Context) error {
+				t.Fatal("lock must be held")
+				return nil
+			})
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should be able to return error, when lock can't be released", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		key := elephant.LockName(t.Name())
+
+		err := elephant.WithAdvisoryLock(ctx, tcs.SUT, key, func(ctx context.Context) error {
+			_, err := tcs.Deps.DB.Exec(ctx,
+				`SELECT pg_terminate_backend(pid) FROM pg_locks
+				WHERE locktype = 'advisory' AND (classid::bigint << 32 | objid::bigint) = $1`,
+				key.ID(),
+			)
+			return err
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "can't release advisory lock")
+		require.NoError(t, elephant.TryWithAdvisoryLock(ctx, tcs.SUT, key, func(context.Context) error {
+			return nil
+		}))
+	})
+}
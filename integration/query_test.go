@@ -0,0 +1,38 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godepo/elephant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID   int64
+	Name string `db:"login"`
+}
+
+func TestQuery_Mapping(t *testing.T) {
+	t.Run("should be able to map rows of real query", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+
+		users, err := elephant.QueryAll[user](ctx, tcs.SUT,
+			"SELECT id, 'user' || id AS login FROM generate_series(1, $1::int) AS id", 2,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []user{{ID: 1, Name: "user1"}, {ID: 2, Name: "user2"}}, users)
+
+		_, err = elephant.QueryOne[int64](ctx, tcs.SUT, "SELECT 1::bigint WHERE false")
+		assert.ErrorIs(t, err, elephant.ErrNotFound)
+		_, err = elephant.QueryOne[int64](ctx, tcs.SUT, "SELECT generate_series(1, 2)::bigint")
+		assert.ErrorIs(t, err, elephant.ErrTooManyRows)
+
+		affected, err := elephant.ExecAffected(ctx, tcs.SUT, "SELECT generate_series(1, 3)")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), affected)
+	})
+}
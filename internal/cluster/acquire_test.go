@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type acquirePool struct {
+	*MockPool
+	conn *pgxpool.Conn
+}
+
+func (p acquirePool) Acquire(_ context.Context) (*pgxpool.Conn, error) {
+	return p.conn, nil
+}
+
+func TestCluster_Acquire(t *testing.T) {
+	t.Run("should be able to acquire connection from leader", func(t *testing.T) {
+		exp := &pgxpool.Conn{}
+		sut := New(acquirePool{MockPool: NewMockPool(t), conn: exp}, []Pool{NewMockPool(t)})
+
+		conn, err := sut.Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Same(t, exp, conn)
+	})
+
+	t.Run("should be able to fail when leader doesn't support acquire", func(t *testing.T) {
+		sut := New(NewMockPool(t), []Pool{NewMockPool(t)})
+
+		_, err := sut.Acquire(context.Background())
+		assert.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+	})
+}
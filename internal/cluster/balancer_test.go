@@ -71,3 +71,15 @@ func TestDefaultLoadBalancer(t *testing.T) {
 		tc.State.Result = tc.SUT(tc.State.Fellows)
 	})
 }
+
+func TestRandomLoadBalancer(t *testing.T) {
+	t.Run("should be able to return nil without fellows", func(t *testing.T) {
+		assert.Nil(t, RandomLoadBalancer()(nil))
+	})
+
+	t.Run("should be able to pick one of fellows", func(t *testing.T) {
+		fellows := []Pool{NewMockPool(t), NewMockPool(t)}
+
+		assert.Contains(t, fellows, RandomLoadBalancer()(fellows))
+	})
+}
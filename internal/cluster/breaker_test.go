@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNode = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func firstFollower(fellows []Pool) Pool {
+	return fellows[0]
+}
+
+func TestCluster_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	breaker := pgbreaker.Config{ConsecutiveFailures: 1}
+
+	t.Run("should be able to skip follower with open circuit", func(t *testing.T) {
+		broken, healthy := NewMockPool(t), NewMockPool(t)
+		broken.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Once()
+		healthy.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil).Twice()
+		sut := New(NewMockPool(t), []Pool{broken, healthy},
+			WithLoadBalancer(firstFollower), WithCircuitBreaker(breaker))
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+
+		for range 2 {
+			_, err = sut.Exec(ctx, "SELECT 1")
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("should be able to fail fast, when circuits of all followers are open", func(t *testing.T) {
+		follower := NewMockPool(t)
+		follower.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Once()
+		sut := New(NewMockPool(t), []Pool{follower}, WithCircuitBreaker(breaker), WithName("shard [1] "))
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+
+		_, err = sut.Exec(ctx, "SELECT 1")
+
+		require.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+		var openErr *pgbreaker.OpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, "shard [1] follower [0]", openErr.Node)
+	})
+
+	t.Run("should be able to fail fast, when circuit of leader is open", func(t *testing.T) {
+		leader := NewMockPool(t)
+		leader.EXPECT().Begin(ctx).Return(nil, errNode).Once()
+		sut := New(leader, []Pool{NewMockPool(t)}, WithCircuitBreaker(breaker))
+		_, err := sut.Begin(ctx)
+		require.ErrorIs(t, err, errNode)
+
+		_, err = sut.Exec(pgcontext.WithCanWrite(ctx), "SELECT 1")
+
+		var openErr *pgbreaker.OpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, "leader", openErr.Node)
+		_, err = sut.Acquire(ctx)
+		assert.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+	})
+
+	t.Run("should be able to guard new followers after swap", func(t *testing.T) {
+		previous, next := NewMockPool(t), NewMockPool(t)
+		previous.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Once()
+		next.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Once()
+		sut := New(NewMockPool(t), []Pool{previous}, WithCircuitBreaker(breaker))
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+
+		require.NoError(t, sut.SwapFollowers(ctx, []Pool{next}))
+
+		_, err = sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+		_, err = sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+	})
+}
+
+func TestCluster_Guarded(t *testing.T) {
+	assert.False(t, New(NewMockPool(t), []Pool{NewMockPool(t)}).Guarded())
+	assert.True(t, New(NewMockPool(t), []Pool{NewMockPool(t)}, WithCircuitBreaker(pgbreaker.Config{})).Guarded())
+}
@@ -3,10 +3,21 @@ package cluster
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
 
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Pool interface {
@@ -15,6 +26,9 @@ type Pool interface {
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
 	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
 }
 
@@ -22,12 +36,25 @@ type DB interface {
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+var ErrNoFollowers = errors.New("cluster requires at least one follower")
+
+type healthChecker interface {
+	Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report
 }
 
 type LoadBalancer func(fellows []Pool) Pool
 
 type Config struct {
 	loadBalancer LoadBalancer
+	breaker      *pgbreaker.Config
+	name         string
 }
 
 type Option func(opt *Config)
@@ -38,6 +65,21 @@ func WithLoadBalancer(balancer LoadBalancer) Option {
 	}
 }
 
+// WithCircuitBreaker guards leader and every follower by circuit breaker of its node, load balancer skips followers,
+// which circuits are open.
+func WithCircuitBreaker(cfg pgbreaker.Config) Option {
+	return func(opt *Config) {
+		opt.breaker = &cfg
+	}
+}
+
+// WithName sets name of cluster, which prefixes names of its nodes in errors of circuit breaker.
+func WithName(name string) Option {
+	return func(opt *Config) {
+		opt.name = name
+	}
+}
+
 func New(leader Pool, fellows []Pool, opts ...Option) *Cluster {
 	cfg := Config{
 		loadBalancer: DefaultLoadBalancer(),
@@ -47,27 +89,116 @@ func New(leader Pool, fellows []Pool, opts ...Option) *Cluster {
 		opt(&cfg)
 	}
 
-	return &Cluster{
-		leader:  leader,
-		fellows: fellows,
-		cfg:     cfg,
+	cls := &Cluster{
+		leader: leader,
+		writer: cfg.guard(leader, "leader"),
+		cfg:    cfg,
 	}
+	cls.fellows.Store(cfg.fellowship(fellows))
+	return cls
 }
 
 type Cluster struct {
 	leader  Pool
-	fellows []Pool
+	writer  Pool
+	fellows atomic.Pointer[fellowship]
 	cfg     Config
+	gate    pgdrain.Gate
+	swap    sync.Mutex
+}
+
+// fellowship is set of followers, which is retired by swap, once queries in flight on it are done. Guarded are
+// followers behind circuit breakers, they are followers themselves without breaker.
+type fellowship struct {
+	pools   []Pool
+	guarded []Pool
+	gate    pgdrain.Gate
+}
+
+func (cfg Config) guard(pool Pool, node string) Pool {
+	if cfg.breaker == nil {
+		return pool
+	}
+	return pgbreaker.NewGuard(pool, pgbreaker.New(cfg.name+node, *cfg.breaker))
 }
 
-func (cls *Cluster) selector(ctx context.Context) DB {
+func (cfg Config) fellowship(pools []Pool) *fellowship {
+	set := &fellowship{pools: pools, guarded: pools}
+	if cfg.breaker != nil {
+		set.guarded = make([]Pool, 0, len(pools))
+		for i, pool := range pools {
+			set.guarded = append(set.guarded, cfg.guard(pool, fmt.Sprintf("follower [%d]", i)))
+		}
+	}
+	return set
+}
+
+// available returns followers, which circuits let calls through, or every follower, when all circuits are open, so
+// calls fail fast with error of open circuit.
+func (set *fellowship) available() []Pool {
+	ready := make([]Pool, 0, len(set.guarded))
+	for _, pool := range set.guarded {
+		if guard, ok := pool.(*pgbreaker.Guard); !ok || guard.Breaker().Ready() {
+			ready = append(ready, pool)
+		}
+	}
+	if len(ready) == 0 {
+		return set.guarded
+	}
+	return ready
+}
+
+// Guarded reports, whether nodes of cluster are guarded by circuit breakers.
+func (cls *Cluster) Guarded() bool {
+	return cls.cfg.breaker != nil
+}
+
+// followers returns current set of followers, release must be called, when its followers aren't used anymore.
+func (cls *Cluster) followers() (*fellowship, func()) {
+	for {
+		set := cls.fellows.Load()
+		if set.gate.Enter() == nil {
+			return set, set.gate.Leave
+		}
+	}
+}
+
+// follower picks follower of current set by load balancer.
+func (cls *Cluster) follower() (Pool, func()) {
+	set, release := cls.followers()
+	if cls.cfg.breaker == nil {
+		return cls.cfg.loadBalancer(set.pools), release
+	}
+	return cls.cfg.loadBalancer(set.available()), release
+}
+
+func (cls *Cluster) selector(ctx context.Context) (DB, func()) {
 	if tx, ok := pgcontext.TransactionFrom(ctx); ok {
-		return tx
+		return txDB{Tx: tx}, func() {}
 	}
 	if pgcontext.CanWriteFrom(ctx) {
-		return cls.leader
+		return cls.writer, func() {}
 	}
-	return cls.cfg.loadBalancer(cls.fellows)
+	return cls.follower()
+}
+
+// SwapFollowers atomically replaces followers of cluster. Queries in flight finish on previous followers, which are
+// shut down or closed, once they are drained, unless they stay in cluster.
+func (cls *Cluster) SwapFollowers(ctx context.Context, fellows []Pool) error {
+	if len(fellows) == 0 {
+		return ErrNoFollowers
+	}
+	cls.swap.Lock()
+	defer cls.swap.Unlock()
+	if err := cls.gate.Check(); err != nil {
+		return err
+	}
+	previous := cls.fellows.Swap(cls.cfg.fellowship(fellows))
+	retired := pgdrain.Retired(previous.pools, append([]Pool{cls.leader}, fellows...))
+	if err := previous.gate.Shutdown(ctx, retired...); err != nil {
+		return fmt.Errorf("can't retire followers: %w", err)
+	}
+	return nil
 }
 
 func (cls *Cluster) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
@@ -75,7 +206,10 @@ func (cls *Cluster) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, er
 	if ok {
 		return tx.Begin(ctx)
 	}
-	return cls.leader.BeginTx(ctx, opts)
+	if err := cls.gate.Check(); err != nil {
+		return nil, err
+	}
+	return cls.writer.BeginTx(ctx, opts)
 }
 
 func (cls *Cluster) Begin(ctx context.Context) (pgx.Tx, error) {
@@ -83,25 +217,130 @@ func (cls *Cluster) Begin(ctx context.Context) (pgx.Tx, error) {
 	if ok {
 		return tx.Begin(ctx)
 	}
-	return cls.leader.Begin(ctx)
+	if err := cls.gate.Check(); err != nil {
+		return nil, err
+	}
+	return cls.writer.Begin(ctx)
+}
+
+// Shutdown rejects new top-level transactions with pgerr.ErrShutdown, waits for in-flight Transactional calls
+// until ctx is done and shuts down or closes leader and every follower.
+func (cls *Cluster) Shutdown(ctx context.Context) error {
+	cls.swap.Lock()
+	defer cls.swap.Unlock()
+	fellows := cls.fellows.Load().pools
+	pools := make([]any, 0, len(fellows)+1)
+	pools = append(pools, cls.leader)
+	for _, fellow := range fellows {
+		pools = append(pools, fellow)
+	}
+	if err := cls.gate.Shutdown(ctx, pools...); err != nil {
+		return fmt.Errorf("can't shutdown cluster: %w", err)
+	}
+	return nil
+}
+
+// ShutdownProgress reports stage of shutdown and count of in-flight transactions.
+func (cls *Cluster) ShutdownProgress() pgdrain.Progress {
+	return cls.gate.Progress()
+}
+
+// Health checks leader and followers concurrently and aggregates report by rules, every node must be reachable
+// without rules.
+func (cls *Cluster) Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report {
+	set, release := cls.followers()
+	defer release()
+	nodes := make([][]pghealth.Node, len(set.pools)+1)
+	var wg sync.WaitGroup
+	for i, pool := range append([]Pool{cls.leader}, set.pools...) {
+		role := pghealth.RoleFollower
+		if i == 0 {
+			role = pghealth.RoleLeader
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nodes[i] = nodeHealth(ctx, pool, role)
+		}()
+	}
+	wg.Wait()
+	return pghealth.Aggregate(slices.Concat(nodes...), rules...)
+}
+
+// Ping returns pghealth.ErrNotReady with errors of nodes, when cluster isn't ready by rules.
+func (cls *Cluster) Ping(ctx context.Context, rules ...pghealth.Rule) error {
+	return cls.Health(ctx, rules...).Err()
+}
+
+func nodeHealth(ctx context.Context, pool Pool, role pghealth.Role) []pghealth.Node {
+	if checker, ok := pool.(healthChecker); ok {
+		return pghealth.WithRole(checker.Health(ctx), role)
+	}
+	return pghealth.WithRole(pghealth.Report{Nodes: []pghealth.Node{pghealth.Probe(ctx, pool)}}, role)
+}
+
+// Acquire takes dedicated connection from leader.
+func (cls *Cluster) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	leader, ok := cls.writer.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	return leader.Acquire(ctx)
 }
 
 func (cls *Cluster) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
-	return cls.selector(ctx).Query(ctx, query, args...)
+	db, release := cls.selector(ctx)
+	defer release()
+	return db.Query(ctx, query, args...)
 }
 
 func (cls *Cluster) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
-	return cls.selector(ctx).QueryRow(ctx, query, args...)
+	db, release := cls.selector(ctx)
+	defer release()
+	return db.QueryRow(ctx, query, args...)
 }
 
 func (cls *Cluster) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
-	return cls.selector(ctx).Exec(ctx, query, args...)
+	db, release := cls.selector(ctx)
+	defer release()
+	return db.Exec(ctx, query, args...)
+}
+
+func (cls *Cluster) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	db, release := cls.selector(ctx)
+	defer release()
+	return db.SendBatch(ctx, b)
+}
+
+// CopyFrom always loads rows through leader, joining transaction from context if any.
+func (cls *Cluster) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return cls.writer.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (cls *Cluster) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	_, ok := pgcontext.TransactionFrom(ctx)
+	if ok || pgcontext.CanWriteFrom(ctx) {
+		return cls.writer.CopyTo(ctx, w, query)
+	}
+	follower, release := cls.follower()
+	defer release()
+	return follower.CopyTo(ctx, w, query)
 }
 
 func (cls *Cluster) Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error) {
 	_, ok := pgcontext.TransactionFrom(ctx)
+	if !ok {
+		if err := cls.gate.Enter(); err != nil {
+			return err
+		}
+		defer cls.gate.Leave()
+	}
 	if ok || pgcontext.CanWriteFrom(ctx) {
-		return cls.leader.Transactional(ctx, fn)
+		return cls.writer.Transactional(ctx, fn)
 	}
-	return cls.cfg.loadBalancer(cls.fellows).Transactional(ctx, fn)
+	follower, release := cls.follower()
+	defer release()
+	return follower.Transactional(ctx, fn)
 }
@@ -2,10 +2,12 @@ package cluster
 
 import (
 	"context"
+	"io"
 	"testing"
 
 	"github.com/godepo/groat"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jaswdr/faker/v2"
 )
 
@@ -16,13 +18,17 @@ type Deps struct {
 	Faker   faker.Faker
 	Rows    *MockRows
 	Row     *MockRow
+	Batch   *MockBatchResults
 }
 
 type Result struct {
-	Tx    pgx.Tx
-	Error error
-	Rows  pgx.Rows
-	Row   pgx.Row
+	Tx     pgx.Tx
+	Error  error
+	Rows   pgx.Rows
+	Row    pgx.Row
+	Batch  pgx.BatchResults
+	Copied int64
+	Tag    pgconn.CommandTag
 }
 
 type Expect struct {
@@ -33,6 +39,13 @@ type Expect struct {
 	Args      []any
 	Rows      pgx.Rows
 	Row       pgx.Row
+	Batch     *pgx.Batch
+	Results   pgx.BatchResults
+	Table     pgx.Identifier
+	Columns   []string
+	Source    pgx.CopyFromSource
+	Copied    int64
+	Tag       pgconn.CommandTag
 }
 
 type State struct {
@@ -59,6 +72,7 @@ func newTestCase(t *testing.T) testCase {
 		deps.Faker = faker.New()
 		deps.Rows = NewMockRows(t)
 		deps.Row = NewMockRow(t)
+		deps.Batch = NewMockBatchResults(t)
 		return deps
 	})
 
@@ -274,3 +288,85 @@ func TestCluster_Transactional(t *testing.T) {
 		})
 	})
 }
+
+func TestCluster_SendBatch(t *testing.T) {
+	t.Run("should be able to send batch to fellow", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(ArrangeBatch).
+			When(ActSendBatch(runAtFellowSecond)).
+			Then(AssertBatchResults)
+
+		tc.State.Result.Batch = tc.SUT.SendBatch(tc.State.ctx, tc.State.Expect.Batch)
+	})
+
+	t.Run("should be able to send batch to leader", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(InjectCanWrite, ArrangeBatch).
+			When(ActSendBatch(runAtLeader)).
+			Then(AssertBatchResults)
+
+		tc.State.Result.Batch = tc.SUT.SendBatch(tc.State.ctx, tc.State.Expect.Batch)
+	})
+
+	t.Run("should be able to send batch in transaction", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(InjectTxToContext(tc.Deps.Tx), InjectCanWrite, ArrangeBatch).
+			When(ActSendBatch(runAtTx)).
+			Then(AssertBatchResults)
+
+		tc.State.Result.Batch = tc.SUT.SendBatch(tc.State.ctx, tc.State.Expect.Batch)
+	})
+}
+
+func TestCluster_CopyFrom(t *testing.T) {
+	t.Run("should be able to copy to leader", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(ArrangeCopySource).
+			When(ActCopyFrom).
+			Then(AssertCopied)
+
+		tc.State.Result.Copied, tc.State.Result.Error = tc.SUT.CopyFrom(
+			tc.State.ctx, tc.State.Expect.Table, tc.State.Expect.Columns, tc.State.Expect.Source,
+		)
+	})
+
+	t.Run("should be able to copy to leader in transaction", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(InjectTxToContext(tc.Deps.Tx), ArrangeCopySource).
+			When(ActCopyFrom).
+			Then(AssertCopied)
+
+		tc.State.Result.Copied, tc.State.Result.Error = tc.SUT.CopyFrom(
+			tc.State.ctx, tc.State.Expect.Table, tc.State.Expect.Columns, tc.State.Expect.Source,
+		)
+	})
+}
+
+func TestCluster_CopyTo(t *testing.T) {
+	t.Run("should be able to copy from fellow", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(ArrangeQuery).
+			When(ActCopyTo(runAtFellowSecond)).
+			Then(AssertCopyTag)
+
+		tc.State.Result.Tag, tc.State.Result.Error = tc.SUT.CopyTo(tc.State.ctx, io.Discard, tc.State.Expect.Query)
+	})
+
+	t.Run("should be able to copy from leader", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(InjectCanWrite, ArrangeQuery).
+			When(ActCopyTo(runAtLeader)).
+			Then(AssertCopyTag)
+
+		tc.State.Result.Tag, tc.State.Result.Error = tc.SUT.CopyTo(tc.State.ctx, io.Discard, tc.State.Expect.Query)
+	})
+
+	t.Run("should be able to copy from leader in transaction", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.Given(InjectTxToContext(tc.Deps.Tx), ArrangeQuery).
+			When(ActCopyTo(runAtLeader)).
+			Then(AssertCopyTag)
+
+		tc.State.Result.Tag, tc.State.Result.Error = tc.SUT.CopyTo(tc.State.ctx, io.Discard, tc.State.Expect.Query)
+	})
+}
@@ -3,9 +3,11 @@ package cluster
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
 	"github.com/godepo/groat"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -62,6 +64,22 @@ func ArrangeTxOptions(t *testing.T, state State) State {
 	return state
 }
 
+func ArrangeBatch(t *testing.T, state State) State {
+	t.Helper()
+	state.Expect.Batch = &pgx.Batch{}
+	state.Expect.Batch.Queue(state.Faker.RandomStringWithLength(20))
+	return state
+}
+
+func ArrangeCopySource(t *testing.T, state State) State {
+	t.Helper()
+	state.Expect.Table = pgx.Identifier{state.Faker.Lorem().Word()}
+	state.Expect.Columns = []string{state.Faker.Lorem().Word()}
+	state.Expect.Source = pgx.CopyFromRows([][]any{{uuid.NewString()}})
+	state.Expect.Copied = 1
+	return state
+}
+
 func InjectTxToContext(tx pgx.Tx) func(t *testing.T, state State) State {
 	return func(t *testing.T, state State) State {
 		state.ctx = pgcontext.With(state.ctx, pgcontext.WithTransaction(tx))
@@ -153,6 +171,9 @@ func ActQueryRow(fellowNum int) groat.When[Deps, State] {
 				QueryRow(state.ctx, state.Expect.Query, state.Expect.Args...).Return(deps.Row)
 		}
 		state.Expect.Row = deps.Row
+		if fellowNum == runAtTx {
+			state.Expect.Row = pgerr.Row(state.ctx, deps.Row)
+		}
 		return state
 	}
 }
@@ -192,6 +213,41 @@ func ActQueryFailed(fellowNum int) groat.When[Deps, State] {
 	}
 }
 
+func ActCopyFrom(t *testing.T, deps Deps, state State) State {
+	t.Helper()
+	deps.Leader.EXPECT().
+		CopyFrom(state.ctx, state.Expect.Table, state.Expect.Columns, state.Expect.Source).
+		Return(state.Expect.Copied, nil)
+	return state
+}
+
+func ActCopyTo(fellowNum int) groat.When[Deps, State] {
+	return func(t *testing.T, deps Deps, state State) State {
+		state.Expect.Tag = pgconn.NewCommandTag("COPY 1")
+		if fellowNum == runAtLeader {
+			deps.Leader.EXPECT().CopyTo(state.ctx, io.Discard, state.Expect.Query).Return(state.Expect.Tag, nil)
+		} else {
+			deps.Fellows[fellowNum].EXPECT().CopyTo(state.ctx, io.Discard, state.Expect.Query).Return(state.Expect.Tag, nil)
+		}
+		return state
+	}
+}
+
+func ActSendBatch(fellowNum int) groat.When[Deps, State] {
+	return func(t *testing.T, deps Deps, state State) State {
+		switch fellowNum {
+		case runAtTx:
+			deps.Tx.EXPECT().SendBatch(state.ctx, state.Expect.Batch).Return(deps.Batch)
+		case runAtLeader:
+			deps.Leader.EXPECT().SendBatch(state.ctx, state.Expect.Batch).Return(deps.Batch)
+		default:
+			deps.Fellows[fellowNum].EXPECT().SendBatch(state.ctx, state.Expect.Batch).Return(deps.Batch)
+		}
+		state.Expect.Results = deps.Batch
+		return state
+	}
+}
+
 func ActBeginTx(fellowNum int) groat.When[Deps, State] {
 	return func(t *testing.T, deps Deps, state State) State {
 		t.Helper()
@@ -275,3 +331,20 @@ func AssertRow(t *testing.T, state State) {
 	t.Helper()
 	assert.Equal(t, state.Expect.Row, state.Result.Row)
 }
+
+func AssertCopied(t *testing.T, state State) {
+	t.Helper()
+	require.NoError(t, state.Result.Error)
+	assert.Equal(t, state.Expect.Copied, state.Result.Copied)
+}
+
+func AssertCopyTag(t *testing.T, state State) {
+	t.Helper()
+	require.NoError(t, state.Result.Error)
+	assert.Equal(t, state.Expect.Tag, state.Result.Tag)
+}
+
+func AssertBatchResults(t *testing.T, state State) {
+	t.Helper()
+	assert.Equal(t, state.Expect.Results, state.Result.Batch)
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pghealth"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type healthPool struct {
+	*MockPool
+	report pghealth.Report
+}
+
+func (p healthPool) Health(context.Context, ...pghealth.Rule) pghealth.Report {
+	return p.report
+}
+
+func probedPool(t *testing.T, err error) *MockPool {
+	pool := NewMockPool(t)
+	row := NewMockRow(t)
+	pool.EXPECT().QueryRow(mock.Anything, mock.Anything).Return(row)
+	row.EXPECT().Scan(mock.Anything, mock.Anything).Return(err)
+	return pool
+}
+
+func TestCluster_Health(t *testing.T) {
+	leader := healthPool{MockPool: NewMockPool(t), report: pghealth.Report{
+		Ready: true,
+		Nodes: []pghealth.Node{{Role: pghealth.RoleSingle, Address: "leader:5432", Reachable: true}},
+	}}
+
+	t.Run("should be able to report leader and followers", func(t *testing.T) {
+		sut := New(leader, []Pool{probedPool(t, nil)})
+
+		report := sut.Health(context.Background())
+
+		assert.Equal(t, pghealth.Report{Ready: true, Nodes: []pghealth.Node{
+			{Role: pghealth.RoleLeader, Address: "leader:5432", Reachable: true},
+			{Role: pghealth.RoleFollower, Reachable: true},
+		}}, report)
+	})
+
+	t.Run("should be able to be ready by rules with unreachable follower", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(leader, []Pool{probedPool(t, nil), probedPool(t, expErr)})
+
+		require.ErrorIs(t, sut.Ping(context.Background()), expErr)
+		assert.NoError(t, sut.Ping(context.Background(), pghealth.LeaderReachable(), pghealth.MinFollowers(1)))
+	})
+}
@@ -222,6 +222,55 @@ func (_c *MockDB_QueryRow_Call) RunAndReturn(run func(context.Context, string, .
 	return _c
 }
 
+// SendBatch provides a mock function with given fields: ctx, b
+func (_m *MockDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	ret := _m.Called(ctx, b)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendBatch")
+	}
+
+	var r0 pgx.BatchResults
+	if rf, ok := ret.Get(0).(func(context.Context, *pgx.Batch) pgx.BatchResults); ok {
+		r0 = rf(ctx, b)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.BatchResults)
+		}
+	}
+
+	return r0
+}
+
+// MockDB_SendBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendBatch'
+type MockDB_SendBatch_Call struct {
+	*mock.Call
+}
+
+// SendBatch is a helper method to define mock.On call
+//   - ctx context.Context
+//   - b *pgx.Batch
+func (_e *MockDB_Expecter) SendBatch(ctx interface{}, b interface{}) *MockDB_SendBatch_Call {
+	return &MockDB_SendBatch_Call{Call: _e.mock.On("SendBatch", ctx, b)}
+}
+
+func (_c *MockDB_SendBatch_Call) Run(run func(ctx context.Context, b *pgx.Batch)) *MockDB_SendBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*pgx.Batch))
+	})
+	return _c
+}
+
+func (_c *MockDB_SendBatch_Call) Return(_a0 pgx.BatchResults) *MockDB_SendBatch_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockDB_SendBatch_Call) RunAndReturn(run func(context.Context, *pgx.Batch) pgx.BatchResults) *MockDB_SendBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockDB creates a new instance of MockDB. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockDB(t interface {
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type shutdownPool struct {
+	*MockPool
+	err   error
+	calls int
+}
+
+func (p *shutdownPool) Shutdown(context.Context) error {
+	p.calls++
+	return p.err
+}
+
+func TestCluster_Shutdown(t *testing.T) {
+	t.Run("should be able to shutdown leader and every follower", func(t *testing.T) {
+		leader := &shutdownPool{MockPool: NewMockPool(t)}
+		fellows := []*shutdownPool{{MockPool: NewMockPool(t)}, {MockPool: NewMockPool(t)}}
+		sut := New(leader, []Pool{fellows[0], fellows[1]})
+
+		require.NoError(t, sut.Shutdown(context.Background()))
+
+		assert.Equal(t, 1, leader.calls)
+		assert.Equal(t, 1, fellows[0].calls)
+		assert.Equal(t, 1, fellows[1].calls)
+		assert.Equal(t, pgdrain.Progress{Stage: pgdrain.StageClosed}, sut.ShutdownProgress())
+	})
+
+	t.Run("should be able to fail, when follower can't shutdown", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(&shutdownPool{MockPool: NewMockPool(t)}, []Pool{&shutdownPool{MockPool: NewMockPool(t), err: expErr}})
+
+		err := sut.Shutdown(context.Background())
+
+		require.ErrorIs(t, err, expErr)
+		assert.Contains(t, err.Error(), "can't shutdown cluster")
+	})
+
+	t.Run("should be able to reject new top-level transactions", func(t *testing.T) {
+		sut := New(NewMockPool(t), []Pool{NewMockPool(t)})
+		require.NoError(t, sut.Shutdown(context.Background()))
+
+		_, err := sut.Begin(context.Background())
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+		_, err = sut.BeginTx(context.Background(), pgx.TxOptions{})
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+		err = sut.Transactional(context.Background(), func(context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+	})
+
+	t.Run("should be able to let nested transactions of in-flight one in", func(t *testing.T) {
+		leader := NewMockPool(t)
+		tx := NewMockTx(t)
+		nested := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx))
+		tx.EXPECT().Begin(ctx).Return(nested, nil).Times(2)
+		leader.EXPECT().Transactional(ctx, mock.Anything).Return(nil)
+		sut := New(leader, []Pool{NewMockPool(t)})
+		require.NoError(t, sut.gate.Enter())
+		defer sut.gate.Leave()
+		go func() {
+			_ = sut.Shutdown(context.Background())
+		}()
+		require.Eventually(t, func() bool {
+			return sut.ShutdownProgress() == pgdrain.Progress{Stage: pgdrain.StageDraining, InFlight: 1}
+		}, time.Second, time.Millisecond)
+
+		_, err := sut.Begin(ctx)
+		require.NoError(t, err)
+		_, err = sut.BeginTx(ctx, pgx.TxOptions{})
+		require.NoError(t, err)
+		require.NoError(t, sut.Transactional(ctx, func(context.Context) error {
+			return nil
+		}))
+	})
+}
@@ -0,0 +1,125 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCluster_SwapFollowers(t *testing.T) {
+	t.Run("should be able to route reads to new followers and shut down retired ones", func(t *testing.T) {
+		ctx := context.Background()
+		leader := &shutdownPool{MockPool: NewMockPool(t)}
+		kept := &shutdownPool{MockPool: NewMockPool(t)}
+		retired := &shutdownPool{MockPool: NewMockPool(t)}
+		added := &shutdownPool{MockPool: NewMockPool(t)}
+		added.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		sut := New(leader, []Pool{kept, retired}, WithLoadBalancer(func(fellows []Pool) Pool {
+			return fellows[len(fellows)-1]
+		}))
+
+		require.NoError(t, sut.SwapFollowers(ctx, []Pool{kept, leader, added}))
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, retired.calls)
+		assert.Zero(t, kept.calls)
+		assert.Zero(t, leader.calls)
+		assert.Zero(t, added.calls)
+	})
+
+	t.Run("should be able to finish in-flight query on previous followers", func(t *testing.T) {
+		ctx := context.Background()
+		previous := &shutdownPool{MockPool: NewMockPool(t)}
+		next := NewMockPool(t)
+		entered, release := make(chan struct{}), make(chan struct{})
+		previous.EXPECT().Exec(ctx, "SELECT pg_sleep(1)").RunAndReturn(
+			func(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+				close(entered)
+				<-release
+				return pgconn.CommandTag{}, nil
+			},
+		)
+		next.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		sut := New(NewMockPool(t), []Pool{previous})
+
+		done := make(chan error)
+		go func() {
+			_, err := sut.Exec(ctx, "SELECT pg_sleep(1)")
+			done <- err
+		}()
+		<-entered
+		swapped := make(chan error)
+		go func() {
+			swapped <- sut.SwapFollowers(ctx, []Pool{next})
+		}()
+		require.Eventually(t, func() bool {
+			return sut.fellows.Load().pools[0] == Pool(next)
+		}, time.Second, time.Millisecond)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Zero(t, previous.calls)
+
+		close(release)
+		require.NoError(t, <-done)
+		require.NoError(t, <-swapped)
+		assert.Equal(t, 1, previous.calls)
+	})
+
+	t.Run("should be able to fail, when previous followers aren't drained in time", func(t *testing.T) {
+		sut := New(NewMockPool(t), []Pool{NewMockPool(t)})
+		_, release := sut.followers()
+		defer release()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := sut.SwapFollowers(ctx, []Pool{NewMockPool(t)})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "can't retire followers")
+	})
+
+	t.Run("should be able to report error of retired follower", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(NewMockPool(t), []Pool{&shutdownPool{MockPool: NewMockPool(t), err: expErr}})
+
+		assert.ErrorIs(t, sut.SwapFollowers(context.Background(), []Pool{NewMockPool(t)}), expErr)
+	})
+
+	t.Run("should be able to reject empty followers", func(t *testing.T) {
+		sut := New(NewMockPool(t), []Pool{NewMockPool(t)})
+
+		assert.ErrorIs(t, sut.SwapFollowers(context.Background(), nil), ErrNoFollowers)
+	})
+
+	t.Run("should be able to reject swap after shutdown", func(t *testing.T) {
+		sut := New(NewMockPool(t), []Pool{NewMockPool(t)})
+		require.NoError(t, sut.Shutdown(context.Background()))
+
+		assert.ErrorIs(t, sut.SwapFollowers(context.Background(), []Pool{NewMockPool(t)}), pgerr.ErrShutdown)
+	})
+
+	t.Run("should be able to run read transaction on new followers", func(t *testing.T) {
+		ctx := context.Background()
+		next := NewMockPool(t)
+		next.EXPECT().Transactional(ctx, mock.Anything).Return(nil)
+		next.EXPECT().CopyTo(ctx, mock.Anything, "COPY t TO STDOUT").Return(pgconn.CommandTag{}, nil)
+		sut := New(NewMockPool(t), []Pool{NewMockPool(t)})
+		require.NoError(t, sut.SwapFollowers(ctx, []Pool{next}))
+
+		require.NoError(t, sut.Transactional(ctx, func(context.Context) error {
+			return nil
+		}))
+		_, err := sut.CopyTo(ctx, nil, "COPY t TO STDOUT")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// txDB maps errors of transaction from context, as nodes map errors of statements, which they run themselves.
+type txDB struct {
+	pgx.Tx
+}
+
+func (tx txDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := tx.Tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, pgerr.Map(ctx, err)
+	}
+	return rows, nil
+}
+
+func (tx txDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return pgerr.Row(ctx, tx.Tx.QueryRow(ctx, query, args...))
+}
+
+func (tx txDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := tx.Tx.Exec(ctx, query, args...)
+	if err != nil {
+		return pgconn.CommandTag{}, pgerr.Map(ctx, err)
+	}
+	return tag, nil
+}
@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCluster_TxErrors(t *testing.T) {
+	newSUT := func(t *testing.T) (*Cluster, *MockTx, context.Context) {
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx), pgcontext.WithLockTimeout(time.Second))
+		return New(NewMockPool(t), []Pool{NewMockPool(t)}), tx, ctx
+	}
+	lockErr := &pgconn.PgError{Code: "55P03"}
+	query := faker.New().Lorem().Sentence(3)
+
+	t.Run("should be able to map error of query at transaction from context", func(t *testing.T) {
+		sut, tx, ctx := newSUT(t)
+		tx.EXPECT().Query(ctx, query).Return(nil, lockErr)
+
+		rows, err := sut.Query(ctx, query)
+
+		require.ErrorIs(t, err, pgerr.ErrLockTimeout)
+		assert.ErrorAs(t, err, &lockErr)
+		assert.Nil(t, rows)
+	})
+
+	t.Run("should be able to map error of exec at transaction from context", func(t *testing.T) {
+		sut, tx, ctx := newSUT(t)
+		tx.EXPECT().Exec(ctx, query).Return(pgconn.NewCommandTag("UPDATE 1"), lockErr)
+
+		tag, err := sut.Exec(ctx, query)
+
+		require.ErrorIs(t, err, pgerr.ErrLockTimeout)
+		assert.Equal(t, pgconn.CommandTag{}, tag)
+	})
+
+	t.Run("should be able to pass result of exec at transaction from context", func(t *testing.T) {
+		sut, tx, ctx := newSUT(t)
+		tx.EXPECT().Exec(ctx, query).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+		tag, err := sut.Exec(ctx, query)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), tag.RowsAffected())
+	})
+
+	t.Run("should be able to map error of row scan at transaction from context", func(t *testing.T) {
+		sut, tx, ctx := newSUT(t)
+		row := NewMockRow(t)
+		tx.EXPECT().QueryRow(ctx, query).Return(row)
+		row.EXPECT().Scan().Return(lockErr)
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, query).Scan(), pgerr.ErrLockTimeout)
+	})
+
+	t.Run("should be able to pass no rows of row scan at transaction from context", func(t *testing.T) {
+		sut, tx, ctx := newSUT(t)
+		row := NewMockRow(t)
+		tx.EXPECT().QueryRow(ctx, query).Return(row)
+		row.EXPECT().Scan().Return(pgx.ErrNoRows)
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, query).Scan(), pgx.ErrNoRows)
+	})
+}
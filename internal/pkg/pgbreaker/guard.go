@@ -0,0 +1,204 @@
+package pgbreaker
+
+import (
+	"context"
+	"io"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Pool interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// Guard passes calls to pool through circuit breaker of its node. Calls in transaction from context aren't guarded,
+// so transaction in flight isn't broken by circuit, which opened meanwhile.
+type Guard struct {
+	pool    Pool
+	breaker *Breaker
+}
+
+func NewGuard(pool Pool, breaker *Breaker) *Guard {
+	return &Guard{pool: pool, breaker: breaker}
+}
+
+func (g *Guard) Breaker() *Breaker {
+	return g.breaker
+}
+
+func (g *Guard) allow(ctx context.Context) (func(err error), error) {
+	if _, ok := pgcontext.TransactionFrom(ctx); ok {
+		return func(error) {}, nil
+	}
+	return g.breaker.Allow()
+}
+
+func (g *Guard) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := g.pool.BeginTx(ctx, opts)
+	done(err)
+	return tx, err
+}
+
+func (g *Guard) Begin(ctx context.Context) (pgx.Tx, error) {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := g.pool.Begin(ctx)
+	done(err)
+	return tx, err
+}
+
+func (g *Guard) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := g.pool.Query(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+// QueryRow reports outcome of call, when row is scanned, as pgx defers errors of QueryRow to Scan.
+func (g *Guard) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return failedRow{err: err}
+	}
+	return &row{Row: g.pool.QueryRow(ctx, query, args...), done: done}
+}
+
+func (g *Guard) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := g.pool.Exec(ctx, query, args...)
+	done(err)
+	return tag, err
+}
+
+// SendBatch reports outcome of call, when results are closed.
+func (g *Guard) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return failedBatchResults{err: err}
+	}
+	return &batchResults{BatchResults: g.pool.SendBatch(ctx, b), done: done}
+}
+
+func (g *Guard) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := g.pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	done(err)
+	return n, err
+}
+
+func (g *Guard) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := g.pool.CopyTo(ctx, w, query)
+	done(err)
+	return tag, err
+}
+
+func (g *Guard) Transactional(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := g.allow(ctx)
+	if err != nil {
+		return err
+	}
+	err = g.pool.Transactional(ctx, fn)
+	done(err)
+	return err
+}
+
+func (g *Guard) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	pool, ok := g.pool.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	done, err := g.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pool.Acquire(ctx)
+	done(err)
+	return conn, err
+}
+
+type row struct {
+	pgx.Row
+	done func(err error)
+}
+
+func (r *row) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	r.done(err)
+	return err
+}
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(_ ...any) error {
+	return r.err
+}
+
+type batchResults struct {
+	pgx.BatchResults
+	done func(err error)
+}
+
+func (r *batchResults) Close() error {
+	err := r.BatchResults.Close()
+	r.done(err)
+	return err
+}
+
+type failedBatchResults struct {
+	err error
+}
+
+func (r failedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r failedBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r failedBatchResults) QueryRow() pgx.Row {
+	return failedRow(r)
+}
+
+func (r failedBatchResults) Close() error {
+	return r.err
+}
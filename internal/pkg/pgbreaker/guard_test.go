@@ -0,0 +1,180 @@
+package pgbreaker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type acquirePool struct {
+	*MockPool
+	err error
+}
+
+func (p *acquirePool) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return nil, p.err
+}
+
+func newGuard(t *testing.T) (*Guard, *MockPool) {
+	pool := NewMockPool(t)
+	return NewGuard(pool, New("leader", Config{ConsecutiveFailures: 1})), pool
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	rowSrc := pgx.CopyFromRows(nil)
+	calls := map[string]struct {
+		expect func(pool *MockPool)
+		call   func(g *Guard) error
+	}{
+		"BeginTx": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(nil, errNode)
+			},
+			call: func(g *Guard) error {
+				_, err := g.BeginTx(ctx, pgx.TxOptions{})
+				return err
+			},
+		},
+		"Begin": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().Begin(ctx).Return(nil, errNode)
+			},
+			call: func(g *Guard) error {
+				_, err := g.Begin(ctx)
+				return err
+			},
+		},
+		"Query": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().Query(ctx, "SELECT 1").Return(nil, errNode)
+			},
+			call: func(g *Guard) error {
+				_, err := g.Query(ctx, "SELECT 1")
+				return err
+			},
+		},
+		"QueryRow": {
+			expect: func(pool *MockPool) {
+				row := NewMockRow(t)
+				row.EXPECT().Scan(mock.Anything).Return(errNode)
+				pool.EXPECT().QueryRow(ctx, "SELECT 1").Return(row)
+			},
+			call: func(g *Guard) error {
+				var out int
+				return g.QueryRow(ctx, "SELECT 1").Scan(&out)
+			},
+		},
+		"Exec": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode)
+			},
+			call: func(g *Guard) error {
+				_, err := g.Exec(ctx, "SELECT 1")
+				return err
+			},
+		},
+		"SendBatch": {
+			expect: func(pool *MockPool) {
+				results := NewMockBatchResults(t)
+				results.EXPECT().Close().Return(errNode)
+				pool.EXPECT().SendBatch(ctx, &pgx.Batch{}).Return(results)
+			},
+			call: func(g *Guard) error {
+				return g.SendBatch(ctx, &pgx.Batch{}).Close()
+			},
+		},
+		"CopyFrom": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().CopyFrom(ctx, pgx.Identifier{"t"}, []string{"id"}, rowSrc).Return(0, errNode)
+			},
+			call: func(g *Guard) error {
+				_, err := g.CopyFrom(ctx, pgx.Identifier{"t"}, []string{"id"}, rowSrc)
+				return err
+			},
+		},
+		"CopyTo": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().CopyTo(ctx, &bytes.Buffer{}, "COPY t TO STDOUT").Return(pgconn.CommandTag{}, errNode)
+			},
+			call: func(g *Guard) error {
+				_, err := g.CopyTo(ctx, &bytes.Buffer{}, "COPY t TO STDOUT")
+				return err
+			},
+		},
+		"Transactional": {
+			expect: func(pool *MockPool) {
+				pool.EXPECT().Transactional(ctx, mock.Anything).Return(errNode)
+			},
+			call: func(g *Guard) error {
+				return g.Transactional(ctx, func(context.Context) error {
+					return nil
+				})
+			},
+		},
+	}
+	for name, tc := range calls {
+		t.Run("should be able to trip circuit by "+name+" and fail fast", func(t *testing.T) {
+			g, pool := newGuard(t)
+			tc.expect(pool)
+
+			require.ErrorIs(t, tc.call(g), errNode)
+			require.Equal(t, StateOpen, g.Breaker().State())
+
+			assert.ErrorIs(t, tc.call(g), pgerr.ErrCircuitOpen)
+		})
+	}
+
+	t.Run("should be able to fail fast batch results of open circuit", func(t *testing.T) {
+		g, _ := newGuard(t)
+		call(t, g.Breaker(), errNode)
+
+		results := g.SendBatch(ctx, &pgx.Batch{})
+
+		_, err := results.Exec()
+		assert.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+		_, err = results.Query()
+		assert.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+		assert.ErrorIs(t, results.QueryRow().Scan(), pgerr.ErrCircuitOpen)
+		assert.ErrorIs(t, results.Close(), pgerr.ErrCircuitOpen)
+	})
+
+	t.Run("should be able to pass calls in transaction through open circuit", func(t *testing.T) {
+		g, pool := newGuard(t)
+		call(t, g.Breaker(), errNode)
+		txCtx := pgcontext.With(ctx, pgcontext.WithTransaction(NewMockTx(t)))
+		pool.EXPECT().Exec(txCtx, "SELECT 1").Return(pgconn.CommandTag{}, errNode)
+
+		_, err := g.Exec(txCtx, "SELECT 1")
+
+		assert.ErrorIs(t, err, errNode)
+	})
+
+	t.Run("should be able to acquire connection through circuit", func(t *testing.T) {
+		pool := &acquirePool{MockPool: NewMockPool(t), err: errNode}
+		g := NewGuard(pool, New("leader", Config{ConsecutiveFailures: 1}))
+
+		_, err := g.Acquire(ctx)
+		require.ErrorIs(t, err, errNode)
+
+		_, err = g.Acquire(ctx)
+		assert.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+	})
+
+	t.Run("should be able to reject acquire of pool without dedicated connections", func(t *testing.T) {
+		g, _ := newGuard(t)
+
+		_, err := g.Acquire(ctx)
+
+		assert.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+	})
+}
@@ -0,0 +1,255 @@
+//go:generate mockery
+package pgbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultConsecutiveFailures = 5
+	defaultWindow              = 20
+	defaultOpenTimeout         = 10 * time.Second
+)
+
+// State of circuit of node.
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
+// OpenError is returned instead of call to node, which circuit is open, it matches pgerr.ErrCircuitOpen.
+type OpenError struct {
+	Node    string
+	State   State
+	RetryAt time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s: %s until %s", pgerr.ErrCircuitOpen, e.Node, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *OpenError) Unwrap() error {
+	return pgerr.ErrCircuitOpen
+}
+
+// Config of circuit breaker. Circuit opens, when ConsecutiveFailures calls fail in a row, or when share of failures
+// among last Window calls reaches FailureRate, zero threshold is disabled. Without thresholds circuit opens after 5
+// failures in a row. Open circuit lets single probe call through after OpenTimeout, which closes circuit on success.
+type Config struct {
+	ConsecutiveFailures int
+	FailureRate         float64
+	Window              int
+	OpenTimeout         time.Duration
+	// IsFailure decides, whether error of call is failure of node, Failure is used by default.
+	IsFailure func(err error) bool
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.ConsecutiveFailures <= 0 && cfg.FailureRate <= 0 {
+		cfg.ConsecutiveFailures = defaultConsecutiveFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultOpenTimeout
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = Failure
+	}
+	return cfg
+}
+
+// Failure reports errors of connection and timeouts, which mean node is unhealthy, errors of queries, which node
+// answered, don't count.
+func Failure(err error) bool {
+	if err == nil || errors.Is(err, pgerr.ErrCircuitOpen) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &connectErr), errors.As(err, &netErr), pgconn.Timeout(err):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &pgErr):
+		// connection exceptions and shutdown or startup of server.
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P0")
+	}
+	return false
+}
+
+// Breaker is circuit breaker of one node.
+type Breaker struct {
+	node string
+	cfg  Config
+	now  func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64
+	consecutive int
+	outcomes    []bool
+	next        int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	probeAt     time.Time
+}
+
+func New(node string, cfg Config) *Breaker {
+	cfg = cfg.withDefaults()
+	return &Breaker{
+		node:     node,
+		cfg:      cfg,
+		now:      time.Now,
+		outcomes: make([]bool, 0, cfg.Window),
+	}
+}
+
+func (b *Breaker) Node() string {
+	return b.node
+}
+
+// State returns state of circuit, open circuit is half-open, once its timeout passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current(b.now())
+}
+
+// Ready reports, whether call would be let through.
+func (b *Breaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch b.current(now) {
+	case StateClosed:
+		return true
+	case StateHalfOpen:
+		return !b.probing || b.staleProbe(now)
+	}
+	return false
+}
+
+// Allow lets call through or fails with *OpenError, done must be called with error of call, which is let through.
+func (b *Breaker) Allow() (func(err error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch state := b.current(now); state {
+	case StateClosed:
+		return b.once(b.record), nil
+	case StateHalfOpen:
+		if !b.probing || b.staleProbe(now) {
+			b.probing, b.probeAt = true, now
+			return b.once(b.probed), nil
+		}
+		return nil, &OpenError{Node: b.node, State: state, RetryAt: b.probeAt.Add(b.cfg.OpenTimeout)}
+	default:
+		return nil, &OpenError{Node: b.node, State: state, RetryAt: b.openedAt.Add(b.cfg.OpenTimeout)}
+	}
+}
+
+// once binds outcome of call to current generation of circuit and records it once, even when row is scanned twice.
+func (b *Breaker) once(outcome func(generation uint64, failed bool)) func(err error) {
+	generation := b.generation
+	var done sync.Once
+	return func(err error) {
+		done.Do(func() {
+			outcome(generation, b.cfg.IsFailure(err))
+		})
+	}
+}
+
+func (b *Breaker) current(now time.Time) State {
+	if b.state == StateOpen && !now.Before(b.openedAt.Add(b.cfg.OpenTimeout)) {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// staleProbe reports, whether probe is lost, for example row of probe isn't scanned, so new probe is let through.
+func (b *Breaker) staleProbe(now time.Time) bool {
+	return !now.Before(b.probeAt.Add(b.cfg.OpenTimeout))
+}
+
+func (b *Breaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation || b.state != StateClosed {
+		return
+	}
+	if len(b.outcomes) < b.cfg.Window {
+		b.outcomes = append(b.outcomes, failed)
+	} else {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % b.cfg.Window
+	}
+	if failed {
+		b.failures++
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+	consecutive := b.cfg.ConsecutiveFailures > 0 && b.consecutive >= b.cfg.ConsecutiveFailures
+	rate := b.cfg.FailureRate > 0 && len(b.outcomes) == b.cfg.Window &&
+		float64(b.failures)/float64(b.cfg.Window) >= b.cfg.FailureRate
+	if consecutive || rate {
+		b.trip()
+	}
+}
+
+func (b *Breaker) probed(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	if failed {
+		b.trip()
+		return
+	}
+	b.generation++
+	b.state = StateClosed
+	b.probing = false
+}
+
+func (b *Breaker) trip() {
+	b.generation++
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.probing = false
+	b.consecutive, b.failures, b.next = 0, 0, 0
+	b.outcomes = b.outcomes[:0]
+}
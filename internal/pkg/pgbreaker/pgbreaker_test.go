@@ -0,0 +1,207 @@
+package pgbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNode = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newBreaker(cfg Config) (*Breaker, *clock) {
+	c := &clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	b := New("follower [0]", cfg)
+	b.now = c.Now
+	return b, c
+}
+
+func call(t *testing.T, b *Breaker, err error) {
+	t.Helper()
+	done, allowErr := b.Allow()
+	require.NoError(t, allowErr)
+	done(err)
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "closed", StateClosed.String())
+	assert.Equal(t, "open", StateOpen.String())
+	assert.Equal(t, "half-open", StateHalfOpen.String())
+	assert.Equal(t, "state(7)", State(7).String())
+}
+
+func TestFailure(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err    error
+		failed bool
+	}{
+		"nil":                    {err: nil},
+		"no rows":                {err: pgx.ErrNoRows},
+		"canceled":               {err: fmt.Errorf("query: %w", context.Canceled)},
+		"open circuit":           {err: &OpenError{Node: "leader"}},
+		"unique violation":       {err: &pgconn.PgError{Code: "23505"}},
+		"statement timeout":      {err: &pgconn.PgError{Code: "57014"}},
+		"business error":         {err: errors.New(faker.New().RandomStringWithLength(10))},
+		"network error":          {err: errNode, failed: true},
+		"connect error":          {err: &pgconn.ConnectError{Config: &pgconn.Config{}}, failed: true},
+		"deadline":               {err: context.DeadlineExceeded, failed: true},
+		"unexpected eof":         {err: io.ErrUnexpectedEOF, failed: true},
+		"eof":                    {err: io.EOF, failed: true},
+		"connection failure":     {err: &pgconn.PgError{Code: "08006"}, failed: true},
+		"administrative restart": {err: &pgconn.PgError{Code: "57P01"}, failed: true},
+	} {
+		t.Run("should be able to classify "+name, func(t *testing.T) {
+			assert.Equal(t, tc.failed, Failure(tc.err))
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("should be able to trip after consecutive failures", func(t *testing.T) {
+		b, _ := newBreaker(Config{ConsecutiveFailures: 2})
+		call(t, b, errNode)
+		call(t, b, nil)
+		call(t, b, errNode)
+		require.Equal(t, StateClosed, b.State())
+		require.True(t, b.Ready())
+
+		call(t, b, errNode)
+
+		assert.Equal(t, StateOpen, b.State())
+		assert.False(t, b.Ready())
+	})
+
+	t.Run("should be able to trip at failure rate of full window", func(t *testing.T) {
+		b, _ := newBreaker(Config{FailureRate: 0.5, Window: 4})
+		call(t, b, errNode)
+		call(t, b, nil)
+		call(t, b, errNode)
+		require.Equal(t, StateClosed, b.State())
+		call(t, b, nil)
+		require.Equal(t, StateOpen, b.State())
+	})
+
+	t.Run("should be able to slide window of failure rate", func(t *testing.T) {
+		b, _ := newBreaker(Config{FailureRate: 0.75, Window: 4})
+		for _, err := range []error{errNode, nil, nil, errNode, errNode, nil} {
+			call(t, b, err)
+		}
+		require.Equal(t, StateClosed, b.State())
+
+		call(t, b, errNode)
+
+		assert.Equal(t, StateOpen, b.State())
+	})
+
+	t.Run("should be able to fail fast with node of open circuit", func(t *testing.T) {
+		b, c := newBreaker(Config{ConsecutiveFailures: 1, OpenTimeout: time.Minute})
+		call(t, b, errNode)
+
+		_, err := b.Allow()
+
+		require.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+		var openErr *OpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, "follower [0]", openErr.Node)
+		assert.Equal(t, StateOpen, openErr.State)
+		assert.Equal(t, c.now.Add(time.Minute), openErr.RetryAt)
+		assert.Equal(t, "circuit of node is open: follower [0] until 2026-10-19T12:01:00Z", err.Error())
+		assert.Equal(t, "follower [0]", b.Node())
+	})
+
+	t.Run("should be able to close circuit after successful probe", func(t *testing.T) {
+		b, c := newBreaker(Config{ConsecutiveFailures: 1, OpenTimeout: time.Minute})
+		call(t, b, errNode)
+		c.now = c.now.Add(time.Minute)
+		require.Equal(t, StateHalfOpen, b.State())
+		require.True(t, b.Ready())
+
+		done, err := b.Allow()
+		require.NoError(t, err)
+		assert.False(t, b.Ready())
+		_, err = b.Allow()
+		var openErr *OpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, StateHalfOpen, openErr.State)
+		done(nil)
+
+		assert.Equal(t, StateClosed, b.State())
+		call(t, b, nil)
+	})
+
+	t.Run("should be able to open circuit again after failed probe", func(t *testing.T) {
+		b, c := newBreaker(Config{ConsecutiveFailures: 1, OpenTimeout: time.Minute})
+		call(t, b, errNode)
+		c.now = c.now.Add(time.Minute)
+
+		call(t, b, errNode)
+
+		assert.Equal(t, StateOpen, b.State())
+	})
+
+	t.Run("should be able to let new probe through, when probe is lost", func(t *testing.T) {
+		b, c := newBreaker(Config{ConsecutiveFailures: 1, OpenTimeout: time.Minute})
+		call(t, b, errNode)
+		c.now = c.now.Add(time.Minute)
+		lost, err := b.Allow()
+		require.NoError(t, err)
+		c.now = c.now.Add(time.Minute)
+		require.True(t, b.Ready())
+
+		call(t, b, nil)
+		lost(errNode)
+
+		assert.Equal(t, StateClosed, b.State())
+	})
+
+	t.Run("should be able to ignore outcomes of calls of previous generation", func(t *testing.T) {
+		b, _ := newBreaker(Config{ConsecutiveFailures: 1})
+		late, err := b.Allow()
+		require.NoError(t, err)
+		call(t, b, errNode)
+
+		late(nil)
+		late(errNode)
+
+		assert.Equal(t, StateOpen, b.State())
+	})
+
+	t.Run("should be able to use defaults", func(t *testing.T) {
+		b := New("leader", Config{})
+
+		assert.Equal(t, defaultConsecutiveFailures, b.cfg.ConsecutiveFailures)
+		assert.Equal(t, defaultWindow, b.cfg.Window)
+		assert.Equal(t, defaultOpenTimeout, b.cfg.OpenTimeout)
+		assert.True(t, b.cfg.IsFailure(errNode))
+	})
+
+	t.Run("should be able to use custom classifier", func(t *testing.T) {
+		b, _ := newBreaker(Config{ConsecutiveFailures: 1, IsFailure: func(err error) bool {
+			return errors.Is(err, pgx.ErrNoRows)
+		}})
+		call(t, b, errNode)
+		require.Equal(t, StateClosed, b.State())
+
+		call(t, b, pgx.ErrNoRows)
+
+		assert.Equal(t, StateOpen, b.State())
+	})
+}
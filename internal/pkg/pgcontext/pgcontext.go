@@ -3,6 +3,7 @@ package pgcontext
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -16,6 +17,12 @@ const (
 	optTxPassMatcher
 	optShardID
 	optShardingKey
+	optStatementTimeout
+	optLockTimeout
+	optDeadlineTimeout
+	optAppliedSettings
+	optSessionVars
+	optTenant
 )
 
 type OptionContext func(ctx context.Context) context.Context
@@ -97,3 +104,80 @@ func ShardingKeyFrom(ctx context.Context) (string, bool) {
 	res, ok := ctx.Value(optShardingKey).(string)
 	return res, ok
 }
+
+func WithStatementTimeout(timeout time.Duration) OptionContext {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, optStatementTimeout, timeout)
+	}
+}
+
+func StatementTimeoutFrom(ctx context.Context) (time.Duration, bool) {
+	res, ok := ctx.Value(optStatementTimeout).(time.Duration)
+	return res, ok
+}
+
+func WithLockTimeout(timeout time.Duration) OptionContext {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, optLockTimeout, timeout)
+	}
+}
+
+func LockTimeoutFrom(ctx context.Context) (time.Duration, bool) {
+	res, ok := ctx.Value(optLockTimeout).(time.Duration)
+	return res, ok
+}
+
+func WithDeadlineTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, optDeadlineTimeout, true)
+}
+
+func DeadlineTimeoutFrom(ctx context.Context) bool {
+	res, ok := ctx.Value(optDeadlineTimeout).(bool)
+	if !ok {
+		return false
+	}
+	return res
+}
+
+func WithAppliedSettings(settings map[string]string) OptionContext {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, optAppliedSettings, settings)
+	}
+}
+
+func AppliedSettingsFrom(ctx context.Context) (map[string]string, bool) {
+	res, ok := ctx.Value(optAppliedSettings).(map[string]string)
+	return res, ok
+}
+
+// WithSessionVars merges variables with already stored in context ones, so nested units of work can
+// extend or override them.
+func WithSessionVars(vars map[string]string) OptionContext {
+	return func(ctx context.Context) context.Context {
+		parent, _ := SessionVarsFrom(ctx)
+		merged := make(map[string]string, len(parent)+len(vars))
+		for name, value := range parent {
+			merged[name] = value
+		}
+		for name, value := range vars {
+			merged[name] = value
+		}
+		return context.WithValue(ctx, optSessionVars, merged)
+	}
+}
+
+func SessionVarsFrom(ctx context.Context) (map[string]string, bool) {
+	res, ok := ctx.Value(optSessionVars).(map[string]string)
+	return res, ok
+}
+
+func WithTenant(tenant string) OptionContext {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, optTenant, tenant)
+	}
+}
+
+func TenantFrom(ctx context.Context) (string, bool) {
+	res, ok := ctx.Value(optTenant).(string)
+	return res, ok
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -111,3 +112,107 @@ func TestShardingKeyFrom(t *testing.T) {
 		assert.Equal(t, shardingKey, sharding)
 	})
 }
+
+func TestStatementTimeoutFrom(t *testing.T) {
+	t.Run("should be able return false if no statement timeout in context", func(t *testing.T) {
+		timeout, ok := StatementTimeoutFrom(context.Background())
+		assert.Zero(t, timeout)
+		assert.False(t, ok)
+	})
+	t.Run("should be able to set in context and read from it", func(t *testing.T) {
+		expTimeout := time.Duration(faker.New().IntBetween(1, 1000)) * time.Millisecond
+
+		ctx := With(context.Background(), WithStatementTimeout(expTimeout))
+		timeout, ok := StatementTimeoutFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, expTimeout, timeout)
+	})
+}
+
+func TestLockTimeoutFrom(t *testing.T) {
+	t.Run("should be able return false if no lock timeout in context", func(t *testing.T) {
+		timeout, ok := LockTimeoutFrom(context.Background())
+		assert.Zero(t, timeout)
+		assert.False(t, ok)
+	})
+	t.Run("should be able to set in context and read from it", func(t *testing.T) {
+		expTimeout := time.Duration(faker.New().IntBetween(1, 1000)) * time.Millisecond
+
+		ctx := With(context.Background(), WithLockTimeout(expTimeout))
+		timeout, ok := LockTimeoutFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, expTimeout, timeout)
+	})
+}
+
+func TestDeadlineTimeoutFrom(t *testing.T) {
+	t.Run("should be able return false, at empty context", func(t *testing.T) {
+		assert.False(t, DeadlineTimeoutFrom(context.Background()))
+	})
+	t.Run("should be able to return true, when deadline timeout set in context", func(t *testing.T) {
+		assert.True(t, DeadlineTimeoutFrom(With(context.Background(), WithDeadlineTimeout)))
+	})
+}
+
+func TestAppliedSettingsFrom(t *testing.T) {
+	t.Run("should be able return false if no applied settings in context", func(t *testing.T) {
+		settings, ok := AppliedSettingsFrom(context.Background())
+		assert.Nil(t, settings)
+		assert.False(t, ok)
+	})
+	t.Run("should be able to set in context and read from it", func(t *testing.T) {
+		expSettings := map[string]string{"statement_timeout": "10ms"}
+
+		ctx := With(context.Background(), WithAppliedSettings(expSettings))
+		settings, ok := AppliedSettingsFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, expSettings, settings)
+	})
+}
+
+func TestSessionVarsFrom(t *testing.T) {
+	t.Run("should be able return false if no session vars in context", func(t *testing.T) {
+		vars, ok := SessionVarsFrom(context.Background())
+		assert.Nil(t, vars)
+		assert.False(t, ok)
+	})
+	t.Run("should be able to set in context and read from it", func(t *testing.T) {
+		expVars := map[string]string{"app.tenant_id": uuid.NewString()}
+
+		ctx := With(context.Background(), WithSessionVars(expVars))
+		vars, ok := SessionVarsFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, expVars, vars)
+	})
+	t.Run("should be able to merge with parent vars", func(t *testing.T) {
+		tenantID, userID := uuid.NewString(), uuid.NewString()
+		parent := With(context.Background(), WithSessionVars(map[string]string{
+			"app.tenant_id": uuid.NewString(),
+			"app.user_id":   userID,
+		}))
+
+		ctx := With(parent, WithSessionVars(map[string]string{"app.tenant_id": tenantID}))
+		vars, ok := SessionVarsFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{"app.tenant_id": tenantID, "app.user_id": userID}, vars)
+
+		parentVars, _ := SessionVarsFrom(parent)
+		assert.NotEqual(t, tenantID, parentVars["app.tenant_id"])
+	})
+}
+
+func TestTenantFrom(t *testing.T) {
+	t.Run("should be able return false if no tenant in context", func(t *testing.T) {
+		tenant, ok := TenantFrom(context.Background())
+		assert.Empty(t, tenant)
+		assert.False(t, ok)
+	})
+	t.Run("should be able to set in context and read from it", func(t *testing.T) {
+		expTenant := faker.New().RandomStringWithLength(10)
+
+		ctx := With(context.Background(), WithTenant(expTenant))
+		tenant, ok := TenantFrom(ctx)
+		require.True(t, ok)
+		assert.Equal(t, expTenant, tenant)
+	})
+}
@@ -0,0 +1,194 @@
+package pgdrain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+)
+
+// Stage of database lifecycle.
+type Stage int
+
+const (
+	StageServing Stage = iota
+	StageDraining
+	StageClosing
+	StageClosed
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageServing:
+		return "serving"
+	case StageDraining:
+		return "draining"
+	case StageClosing:
+		return "closing"
+	case StageClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("stage(%d)", int(s))
+}
+
+// Progress of shutdown, suitable for readiness probes: database is ready only at StageServing.
+type Progress struct {
+	Stage    Stage
+	InFlight int
+}
+
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+type closer interface {
+	Close()
+}
+
+// Gate counts in-flight transactions and rejects new ones, when shutdown begins.
+type Gate struct {
+	mu       sync.Mutex
+	stage    Stage
+	inFlight int
+	drained  chan struct{}
+	done     chan struct{}
+	err      error
+}
+
+// Check returns ErrShutdown, when gate doesn't accept new transactions.
+func (g *Gate) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stage != StageServing {
+		return pgerr.ErrShutdown
+	}
+	return nil
+}
+
+// Enter registers in-flight transaction, it must be followed by Leave.
+func (g *Gate) Enter() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stage != StageServing {
+		return pgerr.ErrShutdown
+	}
+	g.inFlight++
+	return nil
+}
+
+func (g *Gate) Leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	if g.inFlight == 0 && g.drained != nil {
+		close(g.drained)
+		g.drained = nil
+	}
+}
+
+func (g *Gate) Progress() Progress {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Progress{Stage: g.stage, InFlight: g.inFlight}
+}
+
+func (g *Gate) setStage(stage Stage) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stage = stage
+}
+
+// Shutdown stops accepting of transactions, waits for in-flight ones until ctx is done and closes pools anyway.
+// Pools are shut down, when they support it, or closed. Repeated calls wait for the first one and return its result.
+func (g *Gate) Shutdown(ctx context.Context, pools ...any) error {
+	g.mu.Lock()
+	if g.done != nil {
+		done := g.done
+		g.mu.Unlock()
+		select {
+		case <-done:
+			return g.err
+		case <-ctx.Done():
+			return fmt.Errorf("can't wait for shutdown: %w", ctx.Err())
+		}
+	}
+	g.stage = StageDraining
+	g.done = make(chan struct{})
+	var drained chan struct{}
+	if g.inFlight > 0 {
+		drained = make(chan struct{})
+		g.drained = drained
+	}
+	g.mu.Unlock()
+
+	var errs []error
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf(
+				"can't drain %d in-flight transactions: %w", g.Progress().InFlight, ctx.Err(),
+			))
+		}
+	}
+
+	g.setStage(StageClosing)
+	for i, pool := range pools {
+		if err := closePool(ctx, pool); err != nil {
+			errs = append(errs, fmt.Errorf("can't close pool [%d]: %w", i, err))
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stage = StageClosed
+	g.err = errors.Join(errs...)
+	close(g.done)
+	return g.err
+}
+
+func closePool(ctx context.Context, pool any) error {
+	switch p := pool.(type) {
+	case shutdowner:
+		return p.Shutdown(ctx)
+	case closer:
+		closed := make(chan struct{})
+		go func() {
+			p.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Retired returns pools of previous set, which aren't in current one, so they can be closed after swap of sets.
+func Retired[T any](previous, current []T) []any {
+	out := make([]any, 0, len(previous))
+	for _, pool := range previous {
+		kept := false
+		for _, other := range current {
+			if same(pool, other) {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			out = append(out, pool)
+		}
+	}
+	return out
+}
+
+func same(a, b any) bool {
+	kind := reflect.TypeOf(a)
+	return kind == reflect.TypeOf(b) && kind != nil && kind.Comparable() && a == b
+}
@@ -0,0 +1,176 @@
+package pgdrain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closingPool struct {
+	closed chan struct{}
+	block  chan struct{}
+}
+
+func newClosePool() *closingPool {
+	return &closingPool{closed: make(chan struct{})}
+}
+
+func (p *closingPool) Close() {
+	if p.block != nil {
+		<-p.block
+	}
+	close(p.closed)
+}
+
+type shutdownPool struct {
+	err   error
+	calls int
+}
+
+func (p *shutdownPool) Shutdown(context.Context) error {
+	p.calls++
+	return p.err
+}
+
+func TestStage_String(t *testing.T) {
+	assert.Equal(t, "serving", StageServing.String())
+	assert.Equal(t, "draining", StageDraining.String())
+	assert.Equal(t, "closing", StageClosing.String())
+	assert.Equal(t, "closed", StageClosed.String())
+	assert.Equal(t, "stage(42)", Stage(42).String())
+}
+
+func TestGate(t *testing.T) {
+	t.Run("should be able to let transactions in while serving", func(t *testing.T) {
+		var gate Gate
+
+		require.NoError(t, gate.Check())
+		require.NoError(t, gate.Enter())
+		assert.Equal(t, Progress{Stage: StageServing, InFlight: 1}, gate.Progress())
+
+		gate.Leave()
+		assert.Equal(t, Progress{Stage: StageServing}, gate.Progress())
+	})
+
+	t.Run("should be able to close pools and reject transactions after shutdown", func(t *testing.T) {
+		var gate Gate
+		closer := newClosePool()
+		shutdowner := &shutdownPool{}
+
+		require.NoError(t, gate.Shutdown(context.Background(), closer, shutdowner, struct{}{}))
+
+		<-closer.closed
+		assert.Equal(t, 1, shutdowner.calls)
+		assert.Equal(t, Progress{Stage: StageClosed}, gate.Progress())
+		assert.ErrorIs(t, gate.Check(), pgerr.ErrShutdown)
+		assert.ErrorIs(t, gate.Enter(), pgerr.ErrShutdown)
+	})
+
+	t.Run("should be able to wait for in-flight transactions", func(t *testing.T) {
+		var gate Gate
+		closer := newClosePool()
+		require.NoError(t, gate.Enter())
+
+		done := make(chan error)
+		go func() {
+			done <- gate.Shutdown(context.Background(), closer)
+		}()
+
+		require.Eventually(t, func() bool {
+			return gate.Progress() == Progress{Stage: StageDraining, InFlight: 1}
+		}, time.Second, time.Millisecond)
+		select {
+		case <-closer.closed:
+			t.Fatal("pool is closed before transaction is drained")
+		default:
+		}
+
+		gate.Leave()
+		require.NoError(t, <-done)
+		<-closer.closed
+	})
+
+	t.Run("should be able to close pools, when transactions aren't drained in time", func(t *testing.T) {
+		var gate Gate
+		closer := newClosePool()
+		require.NoError(t, gate.Enter())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := gate.Shutdown(ctx, closer)
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "can't drain 1 in-flight transactions")
+		<-closer.closed
+		assert.Equal(t, Progress{Stage: StageClosed, InFlight: 1}, gate.Progress())
+	})
+
+	t.Run("should be able to fail, when pool isn't closed in time", func(t *testing.T) {
+		var gate Gate
+		closer := newClosePool()
+		closer.block = make(chan struct{})
+		defer close(closer.block)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := gate.Shutdown(ctx, closer)
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "can't close pool [0]")
+	})
+
+	t.Run("should be able to fail, when pool can't shutdown", func(t *testing.T) {
+		var gate Gate
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+
+		err := gate.Shutdown(context.Background(), &shutdownPool{err: expErr})
+
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to return result of first shutdown on repeated call", func(t *testing.T) {
+		var gate Gate
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool := &shutdownPool{err: expErr}
+		require.ErrorIs(t, gate.Shutdown(context.Background(), pool), expErr)
+
+		assert.ErrorIs(t, gate.Shutdown(context.Background(), pool), expErr)
+		assert.Equal(t, 1, pool.calls)
+	})
+
+	t.Run("should be able to stop waiting for first shutdown, when context is done", func(t *testing.T) {
+		var gate Gate
+		require.NoError(t, gate.Enter())
+		go func() {
+			_ = gate.Shutdown(context.Background())
+		}()
+		require.Eventually(t, func() bool {
+			return gate.Progress().Stage == StageDraining
+		}, time.Second, time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, gate.Shutdown(ctx), context.Canceled)
+		gate.Leave()
+	})
+}
+
+type valuePool struct {
+	names []string
+}
+
+func TestRetired(t *testing.T) {
+	kept, retired := newClosePool(), newClosePool()
+
+	assert.Equal(t, []any{retired}, Retired([]*closingPool{kept, retired}, []*closingPool{kept}))
+	assert.Empty(t, Retired([]any{kept}, []any{nil, kept}))
+
+	value := valuePool{names: []string{"replica"}}
+	assert.Equal(t, []any{value}, Retired([]any{value}, []any{value}))
+}
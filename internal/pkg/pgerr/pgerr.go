@@ -0,0 +1,92 @@
+package pgerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	codeQueryCanceled    = "57014"
+	codeLockNotAvailable = "55P03"
+
+	settingStatementTimeout = "statement_timeout"
+	settingLockTimeout      = "lock_timeout"
+)
+
+var (
+	ErrStatementTimeout = errors.New("statement timeout exceeded")
+	ErrLockTimeout      = errors.New("lock timeout exceeded")
+	// ErrLockNotAvailable reports lock, which NOWAIT statement couldn't take at once. Postgres raises it with
+	// the same code as exceeded lock_timeout.
+	ErrLockNotAvailable = errors.New("lock not available")
+
+	ErrAcquireNotSupported = errors.New("pool doesn't support acquiring of dedicated connection")
+	ErrShutdown            = errors.New("database is shut down")
+	ErrCircuitOpen         = errors.New("circuit of node is open")
+)
+
+// Map wraps postgres errors of timeouts into sentinels. Message of error depends on lc_messages of server, so kind
+// of timeout is told by timeouts, which elephant applies from context: canceled statement is timed out, when
+// statement timeout is set and context isn't done, lock isn't available by timeout, when lock timeout is set.
+func Map(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch {
+	case errors.Is(err, ErrStatementTimeout), errors.Is(err, ErrLockTimeout), errors.Is(err, ErrLockNotAvailable):
+		return err
+	case pgErr.Code == codeQueryCanceled && ctx.Err() == nil && statementTimeout(ctx):
+		return fmt.Errorf("%w: %w", ErrStatementTimeout, err)
+	case pgErr.Code == codeLockNotAvailable && lockTimeout(ctx):
+		return fmt.Errorf("%w: %w", ErrLockTimeout, err)
+	case pgErr.Code == codeLockNotAvailable:
+		return fmt.Errorf("%w: %w", ErrLockNotAvailable, err)
+	}
+	return err
+}
+
+func statementTimeout(ctx context.Context) bool {
+	if _, ok := pgcontext.StatementTimeoutFrom(ctx); ok {
+		return true
+	}
+	if _, ok := ctx.Deadline(); ok && pgcontext.DeadlineTimeoutFrom(ctx) {
+		return true
+	}
+	return applied(ctx, settingStatementTimeout)
+}
+
+func lockTimeout(ctx context.Context) bool {
+	if _, ok := pgcontext.LockTimeoutFrom(ctx); ok {
+		return true
+	}
+	return applied(ctx, settingLockTimeout)
+}
+
+func applied(ctx context.Context, name string) bool {
+	settings, _ := pgcontext.AppliedSettingsFrom(ctx)
+	_, ok := settings[name]
+	return ok
+}
+
+// Row maps error of scan in the same way as Map.
+func Row(ctx context.Context, row pgx.Row) pgx.Row {
+	return mappedRow{Row: row, ctx: ctx}
+}
+
+type mappedRow struct {
+	pgx.Row
+	ctx context.Context
+}
+
+func (r mappedRow) Scan(dest ...any) error {
+	return Map(r.ctx, r.Row.Scan(dest...))
+}
@@ -0,0 +1,104 @@
+package pgerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(...any) error {
+	return r.err
+}
+
+func TestMap(t *testing.T) {
+	ctx := context.Background()
+	timed := pgcontext.With(ctx, pgcontext.WithStatementTimeout(time.Second), pgcontext.WithLockTimeout(time.Second))
+
+	t.Run("should be able to return nil at nil error", func(t *testing.T) {
+		assert.NoError(t, Map(ctx, nil))
+	})
+	t.Run("should be able to pass not postgres errors", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		assert.Equal(t, expErr, Map(timed, expErr))
+	})
+	t.Run("should be able to pass unrelated postgres errors", func(t *testing.T) {
+		expErr := &pgconn.PgError{Code: "23505"}
+		assert.Equal(t, expErr, Map(timed, expErr))
+	})
+	t.Run("should be able to pass query canceled without statement timeout", func(t *testing.T) {
+		expErr := &pgconn.PgError{Code: codeQueryCanceled}
+		err := Map(ctx, expErr)
+		assert.Equal(t, expErr, err)
+		assert.NotErrorIs(t, err, ErrStatementTimeout)
+	})
+	t.Run("should be able to pass query canceled by done context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(timed)
+		cancel()
+		expErr := &pgconn.PgError{Code: codeQueryCanceled}
+		assert.Equal(t, expErr, Map(canceled, expErr))
+	})
+	t.Run("should be able to map statement timeout", func(t *testing.T) {
+		pgErr := &pgconn.PgError{Code: codeQueryCanceled, Message: faker.New().Lorem().Sentence(3)}
+		err := Map(timed, fmt.Errorf("wrapped: %w", pgErr))
+		assert.ErrorIs(t, err, ErrStatementTimeout)
+		assert.ErrorAs(t, err, &pgErr)
+	})
+	t.Run("should be able to map statement timeout of deadline", func(t *testing.T) {
+		deadline, cancel := context.WithTimeout(pgcontext.WithDeadlineTimeout(ctx), time.Hour)
+		defer cancel()
+		assert.ErrorIs(t, Map(deadline, &pgconn.PgError{Code: codeQueryCanceled}), ErrStatementTimeout)
+	})
+	t.Run("should be able to map statement timeout of applied settings", func(t *testing.T) {
+		applied := pgcontext.With(ctx, pgcontext.WithAppliedSettings(map[string]string{
+			settingStatementTimeout: "1000ms",
+		}))
+		assert.ErrorIs(t, Map(applied, &pgconn.PgError{Code: codeQueryCanceled}), ErrStatementTimeout)
+	})
+	t.Run("should be able to map lock timeout", func(t *testing.T) {
+		pgErr := &pgconn.PgError{Code: codeLockNotAvailable, Message: faker.New().Lorem().Sentence(3)}
+		err := Map(timed, pgErr)
+		assert.ErrorIs(t, err, ErrLockTimeout)
+		assert.ErrorAs(t, err, &pgErr)
+	})
+	t.Run("should be able to map lock timeout of applied settings", func(t *testing.T) {
+		applied := pgcontext.With(ctx, pgcontext.WithAppliedSettings(map[string]string{
+			settingLockTimeout: "1000ms",
+		}))
+		assert.ErrorIs(t, Map(applied, &pgconn.PgError{Code: codeLockNotAvailable}), ErrLockTimeout)
+	})
+	t.Run("should be able to map lock not available of NOWAIT", func(t *testing.T) {
+		pgErr := &pgconn.PgError{Code: codeLockNotAvailable, Message: `could not obtain lock on row in relation "jobs"`}
+		err := Map(ctx, pgErr)
+		assert.ErrorIs(t, err, ErrLockNotAvailable)
+		assert.NotErrorIs(t, err, ErrLockTimeout)
+		assert.ErrorAs(t, err, &pgErr)
+	})
+	t.Run("should be able to map error only once", func(t *testing.T) {
+		err := Map(timed, &pgconn.PgError{Code: codeLockNotAvailable})
+		assert.Equal(t, err, Map(timed, err))
+	})
+}
+
+func TestRow(t *testing.T) {
+	t.Run("should be able to map error of scan", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithStatementTimeout(time.Second))
+		row := Row(ctx, fakeRow{err: &pgconn.PgError{Code: codeQueryCanceled}})
+		assert.ErrorIs(t, row.Scan(), ErrStatementTimeout)
+	})
+	t.Run("should be able to scan without error", func(t *testing.T) {
+		row := Row(context.Background(), fakeRow{})
+		require.NoError(t, row.Scan())
+	})
+}
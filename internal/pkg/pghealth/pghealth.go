@@ -0,0 +1,219 @@
+package pghealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// probeQuery reports recovery of node and replay lag, which is zero, when replica replayed everything it received.
+const probeQuery = `SELECT pg_is_in_recovery(), CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8, 0)
+END`
+
+var ErrNotReady = errors.New("database isn't ready")
+
+type Role string
+
+const (
+	RoleSingle   Role = "single"
+	RoleLeader   Role = "leader"
+	RoleFollower Role = "follower"
+)
+
+type PoolStats struct {
+	Acquired int32 `json:"acquired"`
+	Idle     int32 `json:"idle"`
+	Total    int32 `json:"total"`
+}
+
+// Node is health of one postgres node. Error is error of this check, LastError is the latest error of any check.
+type Node struct {
+	Role        Role          `json:"role"`
+	Shard       *uint         `json:"shard,omitempty"`
+	Address     string        `json:"address,omitempty"`
+	Reachable   bool          `json:"reachable"`
+	InRecovery  bool          `json:"in_recovery"`
+	Lag         time.Duration `json:"lag"`
+	Pool        *PoolStats    `json:"pool,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+	LastErrorAt *time.Time    `json:"last_error_at,omitempty"`
+	err         error
+}
+
+// Report is health of database topology, Ready is result of aggregation rules.
+type Report struct {
+	Ready bool   `json:"ready"`
+	Nodes []Node `json:"nodes"`
+}
+
+// Err returns ErrNotReady with errors of nodes, when report isn't ready.
+func (r Report) Err() error {
+	if r.Ready {
+		return nil
+	}
+	errs := make([]error, 0, len(r.Nodes))
+	for _, node := range r.Nodes {
+		if node.err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", node.Role, node.Address, node.err))
+		}
+	}
+	if len(errs) == 0 {
+		return ErrNotReady
+	}
+	return fmt.Errorf("%w: %w", ErrNotReady, errors.Join(errs...))
+}
+
+// Rule decides, whether nodes of single instance or cluster are ready.
+type Rule func(nodes []Node) bool
+
+// Aggregate builds report of nodes, which is ready, when every rule holds. Without rules every node must be reachable.
+func Aggregate(nodes []Node, rules ...Rule) Report {
+	if len(rules) == 0 {
+		rules = []Rule{AllReachable()}
+	}
+	report := Report{Ready: true, Nodes: nodes}
+	for _, rule := range rules {
+		if !rule(nodes) {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// Merge joins reports of shards, which is ready, when every shard is ready.
+func Merge(reports ...Report) Report {
+	out := Report{Ready: true}
+	for i, report := range reports {
+		shard := uint(i)
+		for _, node := range report.Nodes {
+			node.Shard = &shard
+			out.Nodes = append(out.Nodes, node)
+		}
+		out.Ready = out.Ready && report.Ready
+	}
+	return out
+}
+
+// WithRole marks nodes of single instance with role in cluster.
+func WithRole(report Report, role Role) []Node {
+	out := make([]Node, 0, len(report.Nodes))
+	for _, node := range report.Nodes {
+		if node.Role == RoleSingle {
+			node.Role = role
+		}
+		out = append(out, node)
+	}
+	return out
+}
+
+func AllReachable() Rule {
+	return func(nodes []Node) bool {
+		for _, node := range nodes {
+			if !node.Reachable {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// LeaderReachable requires reachable leader or single node.
+func LeaderReachable() Rule {
+	return func(nodes []Node) bool {
+		for _, node := range nodes {
+			if node.Role != RoleFollower && node.Reachable {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MinFollowers requires at least n reachable followers.
+func MinFollowers(n int) Rule {
+	return func(nodes []Node) bool {
+		reachable := 0
+		for _, node := range nodes {
+			if node.Role == RoleFollower && node.Reachable {
+				reachable++
+			}
+		}
+		return reachable >= n
+	}
+}
+
+// MaxLag requires replay lag of every reachable node to be at most lag.
+func MaxLag(lag time.Duration) Rule {
+	return func(nodes []Node) bool {
+		for _, node := range nodes {
+			if node.Reachable && node.Lag > lag {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+}
+
+type configurer interface {
+	Config() *pgxpool.Config
+}
+
+type stater interface {
+	Stat() *pgxpool.Stat
+}
+
+// Probe checks node through db. Address and pool stats are reported, when db is pgxpool or alike.
+func Probe(ctx context.Context, db querier) Node {
+	node := Node{Role: RoleSingle}
+	if pool, ok := db.(configurer); ok {
+		cfg := pool.Config().ConnConfig
+		node.Address = net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port)))
+	}
+	if pool, ok := db.(stater); ok {
+		stat := pool.Stat()
+		node.Pool = &PoolStats{Acquired: stat.AcquiredConns(), Idle: stat.IdleConns(), Total: stat.TotalConns()}
+	}
+	var lag float64
+	if err := db.QueryRow(ctx, probeQuery).Scan(&node.InRecovery, &lag); err != nil {
+		node.Error, node.err = err.Error(), err
+		return node
+	}
+	node.Reachable = true
+	node.Lag = time.Duration(lag * float64(time.Second))
+	return node
+}
+
+// LastError keeps the latest error of checks of node.
+type LastError struct {
+	mu  sync.Mutex
+	err string
+	at  time.Time
+}
+
+// Track remembers error of node and reports the latest one.
+func (l *LastError) Track(node Node) Node {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if node.err != nil {
+		l.err, l.at = node.Error, time.Now()
+	}
+	if l.err != "" {
+		at := l.at
+		node.LastError, node.LastErrorAt = l.err, &at
+	}
+	return node
+}
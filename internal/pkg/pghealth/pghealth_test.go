@@ -0,0 +1,167 @@
+package pghealth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scanFunc func(dest ...any) error
+
+func (fn scanFunc) Scan(dest ...any) error {
+	return fn(dest...)
+}
+
+type fakeNode struct {
+	inRecovery bool
+	lag        float64
+	err        error
+}
+
+func (n fakeNode) QueryRow(_ context.Context, query string, _ ...interface{}) pgx.Row {
+	return scanFunc(func(dest ...any) error {
+		if query != probeQuery {
+			return errors.New("unexpected query")
+		}
+		if n.err != nil {
+			return n.err
+		}
+		*dest[0].(*bool), *dest[1].(*float64) = n.inRecovery, n.lag
+		return nil
+	})
+}
+
+type fakePool struct {
+	fakeNode
+	*pgxpool.Pool
+}
+
+func (p fakePool) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return p.fakeNode.QueryRow(ctx, query, args...)
+}
+
+func TestProbe(t *testing.T) {
+	t.Run("should be able to report reachable replica with lag", func(t *testing.T) {
+		node := Probe(context.Background(), fakeNode{inRecovery: true, lag: 1.5})
+
+		assert.Equal(t, Node{Role: RoleSingle, Reachable: true, InRecovery: true, Lag: 1500 * time.Millisecond}, node)
+	})
+
+	t.Run("should be able to report address and stats of pool", func(t *testing.T) {
+		pool, err := pgxpool.New(context.Background(), "postgres://user@db.local:6432/db")
+		require.NoError(t, err)
+		defer pool.Close()
+
+		node := Probe(context.Background(), fakePool{Pool: pool})
+
+		assert.True(t, node.Reachable)
+		assert.Equal(t, "db.local:6432", node.Address)
+		assert.Equal(t, &PoolStats{}, node.Pool)
+	})
+
+	t.Run("should be able to report unreachable node", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+
+		node := Probe(context.Background(), fakeNode{err: expErr})
+
+		assert.False(t, node.Reachable)
+		assert.Equal(t, expErr.Error(), node.Error)
+		assert.ErrorIs(t, node.err, expErr)
+	})
+}
+
+func TestLastError(t *testing.T) {
+	var last LastError
+	assert.Equal(t, Node{Reachable: true}, last.Track(Node{Reachable: true}))
+
+	expErr := errors.New(faker.New().RandomStringWithLength(10))
+	failed := last.Track(Node{Error: expErr.Error(), err: expErr})
+	assert.Equal(t, expErr.Error(), failed.LastError)
+	require.NotNil(t, failed.LastErrorAt)
+
+	recovered := last.Track(Node{Reachable: true})
+	assert.Empty(t, recovered.Error)
+	assert.Equal(t, expErr.Error(), recovered.LastError)
+	assert.Equal(t, failed.LastErrorAt, recovered.LastErrorAt)
+}
+
+func TestRules(t *testing.T) {
+	nodes := []Node{
+		{Role: RoleLeader, Reachable: true},
+		{Role: RoleFollower, Reachable: true, Lag: time.Second},
+		{Role: RoleFollower},
+	}
+
+	assert.False(t, AllReachable()(nodes))
+	assert.True(t, AllReachable()(nodes[:2]))
+	assert.True(t, LeaderReachable()(nodes))
+	assert.False(t, LeaderReachable()(nodes[1:]))
+	assert.True(t, MinFollowers(1)(nodes))
+	assert.False(t, MinFollowers(2)(nodes))
+	assert.True(t, MaxLag(time.Second)(nodes))
+	assert.False(t, MaxLag(time.Millisecond)(nodes))
+}
+
+func TestAggregate(t *testing.T) {
+	t.Run("should be able to require every node reachable without rules", func(t *testing.T) {
+		nodes := []Node{{Role: RoleLeader, Reachable: true}, {Role: RoleFollower}}
+
+		assert.False(t, Aggregate(nodes).Ready)
+		assert.True(t, Aggregate(nodes, LeaderReachable()).Ready)
+		assert.False(t, Aggregate(nodes, LeaderReachable(), MinFollowers(1)).Ready)
+	})
+}
+
+func TestMerge(t *testing.T) {
+	first, second := uint(0), uint(1)
+
+	report := Merge(
+		Report{Ready: true, Nodes: []Node{{Role: RoleSingle, Reachable: true}}},
+		Report{Nodes: []Node{{Role: RoleLeader}, {Role: RoleFollower}}},
+	)
+
+	assert.False(t, report.Ready)
+	assert.Equal(t, []Node{
+		{Role: RoleSingle, Shard: &first, Reachable: true},
+		{Role: RoleLeader, Shard: &second},
+		{Role: RoleFollower, Shard: &second},
+	}, report.Nodes)
+	assert.True(t, Merge(Report{Ready: true}).Ready)
+}
+
+func TestWithRole(t *testing.T) {
+	nodes := WithRole(Report{Nodes: []Node{{Role: RoleSingle}, {Role: RoleFollower}}}, RoleLeader)
+
+	assert.Equal(t, []Node{{Role: RoleLeader}, {Role: RoleFollower}}, nodes)
+}
+
+func TestReport_Err(t *testing.T) {
+	t.Run("should be able to return nil, when report is ready", func(t *testing.T) {
+		assert.NoError(t, Report{Ready: true}.Err())
+	})
+
+	t.Run("should be able to return errors of nodes", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		report := Report{Nodes: []Node{
+			{Role: RoleLeader, Reachable: true},
+			{Role: RoleFollower, Address: "db:5432", err: expErr},
+		}}
+
+		err := report.Err()
+
+		require.ErrorIs(t, err, ErrNotReady)
+		require.ErrorIs(t, err, expErr)
+		assert.Contains(t, err.Error(), "follower db:5432: "+expErr.Error())
+	})
+
+	t.Run("should be able to return not ready, when every node is reachable", func(t *testing.T) {
+		assert.Equal(t, ErrNotReady, Report{Nodes: []Node{{Role: RoleLeader, Reachable: true}}}.Err())
+	})
+}
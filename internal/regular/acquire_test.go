@@ -0,0 +1,43 @@
+package regular
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type acquirePool struct {
+	*MockPool
+	conn *pgxpool.Conn
+	err  error
+}
+
+func (p acquirePool) Acquire(_ context.Context) (*pgxpool.Conn, error) {
+	return p.conn, p.err
+}
+
+func TestInstance_Acquire(t *testing.T) {
+	t.Run("should be able to acquire connection from pool", func(t *testing.T) {
+		exp := &pgxpool.Conn{}
+		conn, err := New(acquirePool{MockPool: NewMockPool(t), conn: exp}).Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Same(t, exp, conn)
+	})
+
+	t.Run("should be able to fail when pool can't acquire", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		_, err := New(acquirePool{MockPool: NewMockPool(t), err: expErr}).Acquire(context.Background())
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when pool doesn't support acquire", func(t *testing.T) {
+		_, err := New(NewMockPool(t)).Acquire(context.Background())
+		assert.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+	})
+}
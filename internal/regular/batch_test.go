@@ -0,0 +1,87 @@
+package regular
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedBatchResults(t *testing.T) {
+	t.Run("should be able to return err from every method", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		results := failedBatchResults{err: expErr}
+
+		_, err := results.Exec()
+		assert.Equal(t, expErr, err)
+		rows, err := results.Query()
+		assert.Nil(t, rows)
+		assert.Equal(t, expErr, err)
+		assert.Equal(t, expErr, results.QueryRow().Scan())
+		assert.Equal(t, expErr, results.Close())
+	})
+}
+
+func TestInstance_SendBatch(t *testing.T) {
+	t.Run("should be able to send batch to pool", func(t *testing.T) {
+		pool := NewMockPool(t)
+		results := NewMockBatchResults(t)
+		batch := &pgx.Batch{}
+		pool.EXPECT().SendBatch(context.Background(), batch).Return(results)
+
+		assert.Equal(t, results, New(pool).SendBatch(context.Background(), batch))
+	})
+
+	t.Run("should be able to send batch in context transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		results := NewMockBatchResults(t)
+		batch := &pgx.Batch{}
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx))
+		tx.EXPECT().SendBatch(ctx, batch).Return(results)
+
+		assert.Equal(t, results, New(NewMockPool(t)).SendBatch(ctx, batch))
+	})
+
+	t.Run("should be able to wrap batch into transaction and commit at close", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		results := NewMockBatchResults(t)
+		batch := &pgx.Batch{}
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().SendBatch(ctx, batch).Return(results)
+		results.EXPECT().Exec().Return(pgconn.CommandTag{}, statementTimeoutErr)
+		results.EXPECT().Close().Return(nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+
+		res := sut.SendBatch(ctx, batch)
+		_, err := res.Exec()
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+		require.NoError(t, res.Close())
+	})
+
+	t.Run("should be able to rollback wrapped batch at close error", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		results := NewMockBatchResults(t)
+		batch := &pgx.Batch{}
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().SendBatch(ctx, batch).Return(results)
+		results.EXPECT().Close().Return(statementTimeoutErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		assert.ErrorIs(t, sut.SendBatch(ctx, batch).Close(), pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to fail when can't begin wrapping transaction", func(t *testing.T) {
+		sut, pool, _, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(nil, expErr)
+
+		assert.ErrorIs(t, sut.SendBatch(ctx, &pgx.Batch{}).Close(), expErr)
+	})
+}
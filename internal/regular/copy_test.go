@@ -0,0 +1,200 @@
+package regular
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const copyToQuery = "COPY users TO STDOUT"
+
+var (
+	copyTable   = pgx.Identifier{"users"}
+	copyColumns = []string{"id", "name"}
+)
+
+func stubCopyTo(ins *Instance, expTx pgx.Tx, err error) {
+	ins.copyTo = func(_ context.Context, tx pgx.Tx, w io.Writer, query string) (pgconn.CommandTag, error) {
+		if tx != expTx || query != copyToQuery {
+			return pgconn.CommandTag{}, errors.New("unexpected copy")
+		}
+		if err != nil {
+			return pgconn.CommandTag{}, err
+		}
+		_, _ = w.Write([]byte("1\tuser\n"))
+		return pgconn.NewCommandTag("COPY 1"), nil
+	}
+}
+
+func TestInstance_CopyFrom(t *testing.T) {
+	src := pgx.CopyFromRows([][]any{{1, "user"}})
+
+	t.Run("should be able to copy to pool", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().CopyFrom(context.Background(), copyTable, copyColumns, src).Return(1, nil)
+
+		n, err := New(pool).CopyFrom(context.Background(), copyTable, copyColumns, src)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("should be able to copy in context transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx))
+		tx.EXPECT().CopyFrom(ctx, copyTable, copyColumns, src).Return(1, nil)
+
+		n, err := New(NewMockPool(t)).CopyFrom(ctx, copyTable, copyColumns, src)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("should be able to pass canceled copy to pool without statement timeout", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().CopyFrom(context.Background(), copyTable, copyColumns, src).Return(0, statementTimeoutErr)
+
+		_, err := New(pool).CopyFrom(context.Background(), copyTable, copyColumns, src)
+		assert.ErrorIs(t, err, statementTimeoutErr)
+		assert.NotErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to wrap copy into transaction", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().CopyFrom(ctx, copyTable, copyColumns, src).Return(1, nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+
+		n, err := sut.CopyFrom(ctx, copyTable, copyColumns, src)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("should be able to rollback wrapped copy at error", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().CopyFrom(ctx, copyTable, copyColumns, src).Return(0, statementTimeoutErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		_, err := sut.CopyFrom(ctx, copyTable, copyColumns, src)
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to fail when can't commit wrapped copy", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().CopyFrom(ctx, copyTable, copyColumns, src).Return(1, nil)
+		tx.EXPECT().Commit(ctx).Return(expErr)
+
+		_, err := sut.CopyFrom(ctx, copyTable, copyColumns, src)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when can't begin wrapping transaction", func(t *testing.T) {
+		sut, pool, _, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(nil, expErr)
+
+		_, err := sut.CopyFrom(ctx, copyTable, copyColumns, src)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestInstance_CopyTo(t *testing.T) {
+	t.Run("should be able to copy in context transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx))
+		sut := New(NewMockPool(t))
+		stubCopyTo(sut, tx, nil)
+		var buf bytes.Buffer
+
+		tag, err := sut.CopyTo(ctx, &buf, copyToQuery)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), tag.RowsAffected())
+		assert.Equal(t, "1\tuser\n", buf.String())
+	})
+
+	t.Run("should be able to map error in context transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx),
+			pgcontext.WithAppliedSettings(map[string]string{settingStatementTimeout: "1000ms"}))
+		sut := New(NewMockPool(t))
+		stubCopyTo(sut, tx, statementTimeoutErr)
+
+		_, err := sut.CopyTo(ctx, io.Discard, copyToQuery)
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to copy in short transaction", func(t *testing.T) {
+		pool := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(pool)
+		stubCopyTo(sut, tx, nil)
+		pool.EXPECT().BeginTx(context.Background(), pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Commit(context.Background()).Return(nil)
+		var buf bytes.Buffer
+
+		tag, err := sut.CopyTo(context.Background(), &buf, copyToQuery)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), tag.RowsAffected())
+		assert.Equal(t, "1\tuser\n", buf.String())
+	})
+
+	t.Run("should be able to fail when can't begin short transaction", func(t *testing.T) {
+		pool := NewMockPool(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().BeginTx(context.Background(), pgx.TxOptions{}).Return(nil, expErr)
+
+		_, err := New(pool).CopyTo(context.Background(), io.Discard, copyToQuery)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to copy in wrapping transaction with settings", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		stubCopyTo(sut, tx, nil)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Commit(ctx).Return(nil)
+
+		_, err := sut.CopyTo(ctx, io.Discard, copyToQuery)
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to rollback at copy error", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		stubCopyTo(sut, tx, statementTimeoutErr)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		_, err := sut.CopyTo(ctx, io.Discard, copyToQuery)
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to fail when can't commit", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		stubCopyTo(sut, tx, nil)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Commit(ctx).Return(expErr)
+
+		_, err := sut.CopyTo(ctx, io.Discard, copyToQuery)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when can't begin wrapping transaction", func(t *testing.T) {
+		sut, pool, _, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(nil, expErr)
+
+		_, err := sut.CopyTo(ctx, io.Discard, copyToQuery)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
@@ -1,8 +1,10 @@
 package regular
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
@@ -107,6 +109,20 @@ func ActInsertRecord(sut *Instance) groat.When[Deps, State] {
 	}
 }
 
+func ActCopyRecord(sut *Instance) groat.When[Deps, State] {
+	return func(t *testing.T, deps Deps, state State) State {
+		n, err := sut.CopyFrom(
+			state.ctx,
+			pgx.Identifier{"regular", "instance"},
+			[]string{"id", "value"},
+			pgx.CopyFromRows([][]any{{state.Record.ID, state.Record.Value}}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+		return state
+	}
+}
+
 func ActQueryRecord(sut *Instance) groat.When[Deps, State] {
 	return func(t *testing.T, deps Deps, state State) State {
 		AssertRecord(t, state.ctx, state, sut)
@@ -195,6 +211,20 @@ func AssertHasRecord(sut *Instance) groat.Then[State] {
 	}
 }
 
+func AssertRecordExported(sut *Instance) groat.Then[State] {
+	return func(t *testing.T, state State) {
+		var buf bytes.Buffer
+		tag, err := sut.CopyTo(
+			context.Background(),
+			&buf,
+			fmt.Sprintf("COPY (SELECT value FROM regular.instance WHERE id = '%s') TO STDOUT", state.Record.ID),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), tag.RowsAffected())
+		assert.Equal(t, state.Record.Value+"\n", buf.String())
+	}
+}
+
 func AssertRecordQueried(sut *Instance) groat.Then[State] {
 	return func(t *testing.T, state State) {
 		rows, err := sut.Query(context.Background(), `SELECT * FROM regular.instance WHERE id = $1`, state.Record.ID)
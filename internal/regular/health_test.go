@@ -0,0 +1,78 @@
+package regular
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pghealth"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstance_Health(t *testing.T) {
+	t.Run("should be able to report health of postgres", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+
+		report := tcs.SUT.Health(context.Background())
+
+		require.True(t, report.Ready)
+		require.Len(t, report.Nodes, 1)
+		assert.True(t, report.Nodes[0].Reachable)
+		assert.False(t, report.Nodes[0].InRecovery)
+		assert.Zero(t, report.Nodes[0].Lag)
+		assert.NotEmpty(t, report.Nodes[0].Address)
+		require.NotNil(t, report.Nodes[0].Pool)
+		assert.Positive(t, report.Nodes[0].Pool.Total)
+	})
+
+	t.Run("should be able to report reachable node", func(t *testing.T) {
+		pool := NewMockPool(t)
+		ctx := context.Background()
+		pool.EXPECT().QueryRow(ctx, mock.Anything).Return(scanFunc(func(dest ...any) error {
+			*dest[0].(*bool) = true
+			return nil
+		}))
+
+		report := New(pool).Health(ctx)
+
+		assert.Equal(t, pghealth.Report{
+			Ready: true,
+			Nodes: []pghealth.Node{{Role: pghealth.RoleSingle, Reachable: true, InRecovery: true}},
+		}, report)
+	})
+
+	t.Run("should be able to keep last error of node", func(t *testing.T) {
+		pool := NewMockPool(t)
+		ctx := context.Background()
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().QueryRow(ctx, mock.Anything).Return(scanFunc(func(...any) error {
+			return expErr
+		})).Once()
+		pool.EXPECT().QueryRow(ctx, mock.Anything).Return(scanFunc(func(...any) error {
+			return nil
+		})).Once()
+		sut := New(pool)
+
+		require.ErrorIs(t, sut.Ping(ctx), expErr)
+
+		report := sut.Health(ctx)
+		require.True(t, report.Ready)
+		assert.Equal(t, expErr.Error(), report.Nodes[0].LastError)
+	})
+
+	t.Run("should be able to aggregate report by rules", func(t *testing.T) {
+		pool := NewMockPool(t)
+		ctx := context.Background()
+		pool.EXPECT().QueryRow(ctx, mock.Anything).Return(scanFunc(func(...any) error {
+			return nil
+		}))
+
+		err := New(pool).Ping(ctx, pghealth.MinFollowers(1))
+
+		assert.Equal(t, pghealth.ErrNotReady, err)
+	})
+}
@@ -5,10 +5,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Pool interface {
@@ -17,6 +22,8 @@ type Pool interface {
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 }
 
 type DB interface {
@@ -24,12 +31,21 @@ type DB interface {
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
 }
 
 type Instance struct {
 	db               Pool
 	selector         func(ctx context.Context) DB
 	txErrPassMatcher func(context.Context, error) bool
+	copyTo           func(ctx context.Context, tx pgx.Tx, w io.Writer, query string) (pgconn.CommandTag, error)
+	gate             pgdrain.Gate
+	lastErr          pghealth.LastError
 }
 
 func New(db Pool) *Instance {
@@ -50,10 +66,16 @@ func New(db Pool) *Instance {
 		}
 		return false
 	}
+	ins.copyTo = func(ctx context.Context, tx pgx.Tx, w io.Writer, query string) (pgconn.CommandTag, error) {
+		return tx.Conn().PgConn().CopyTo(ctx, w, query)
+	}
 	return ins
 }
 
 func (ins *Instance) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := ins.gate.Check(); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
 	tx, err := ins.db.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -62,6 +84,14 @@ func (ins *Instance) Begin(ctx context.Context) (pgx.Tx, error) {
 }
 
 func (ins *Instance) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if err := ins.gate.Check(); err != nil {
+		return nil, fmt.Errorf("can't begin tx at regular instance: %w", err)
+	}
+	return ins.beginTx(ctx, opts)
+}
+
+// beginTx begins transaction regardless of shutdown, for transactions already let in and standalone statements.
+func (ins *Instance) beginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
 	tx, err := ins.db.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("can't begin tx at regular instance: %w", err)
@@ -69,26 +99,202 @@ func (ins *Instance) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, e
 	return tx, nil
 }
 
+type beginnerFunc func(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+
+func (fn beginnerFunc) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return fn(ctx, opts)
+}
+
+// Shutdown rejects new top-level transactions with pgerr.ErrShutdown, waits for in-flight Transactional calls
+// until ctx is done and closes underlying pool.
+func (ins *Instance) Shutdown(ctx context.Context) error {
+	if err := ins.gate.Shutdown(ctx, ins.db); err != nil {
+		return fmt.Errorf("can't shutdown regular instance: %w", err)
+	}
+	return nil
+}
+
+// ShutdownProgress reports stage of shutdown and count of in-flight transactions.
+func (ins *Instance) ShutdownProgress() pgdrain.Progress {
+	return ins.gate.Progress()
+}
+
+// Health checks node of instance and aggregates report by rules, every node must be reachable without rules.
+func (ins *Instance) Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report {
+	return pghealth.Aggregate([]pghealth.Node{ins.lastErr.Track(pghealth.Probe(ctx, ins.db))}, rules...)
+}
+
+// Ping returns pghealth.ErrNotReady with errors of nodes, when instance isn't ready by rules.
+func (ins *Instance) Ping(ctx context.Context, rules ...pghealth.Rule) error {
+	return ins.Health(ctx, rules...).Err()
+}
+
+// Acquire takes dedicated connection from underlying pool, for example to LISTEN on it.
+func (ins *Instance) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	db, ok := ins.db.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't acquire connection at regular instance: %w", err)
+	}
+	return conn, nil
+}
+
 func (ins *Instance) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
-	rows, err := ins.selector(ctx).Query(ctx, query, args...)
+	tx, wrapped, err := ins.standalone(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("can't query regular instance: %w", err)
 	}
+	if wrapped {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, fmt.Errorf("can't query regular instance: %w", pgerr.Map(ctx, err))
+		}
+		return &txRows{Rows: rows, ctx: ctx, tx: tx}, nil
+	}
+
+	rows, err := ins.selector(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("can't query regular instance: %w", pgerr.Map(ctx, err))
+	}
 	return rows, nil
 }
 
 func (ins *Instance) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
-	return ins.selector(ctx).QueryRow(ctx, query, args...)
+	tx, wrapped, err := ins.standalone(ctx)
+	if err != nil {
+		return failedRow{err: fmt.Errorf("can't query regular instance: %w", err)}
+	}
+	if wrapped {
+		return txRow{Row: tx.QueryRow(ctx, query, args...), ctx: ctx, tx: tx}
+	}
+	return pgerr.Row(ctx, ins.selector(ctx).QueryRow(ctx, query, args...))
 }
 
 func (ins *Instance) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
-	tag, err := ins.selector(ctx).Exec(ctx, query, args...)
+	tx, wrapped, err := ins.standalone(ctx)
 	if err != nil {
 		return pgconn.CommandTag{}, fmt.Errorf("can't query regular instance: %w", err)
 	}
+	if wrapped {
+		return execStandalone(ctx, tx, query, args...)
+	}
+
+	tag, err := ins.selector(ctx).Exec(ctx, query, args...)
+	if err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("can't query regular instance: %w", pgerr.Map(ctx, err))
+	}
 	return tag, nil
 }
 
+func (ins *Instance) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	tx, wrapped, err := ins.standalone(ctx)
+	if err != nil {
+		return failedBatchResults{err: fmt.Errorf("can't send batch to regular instance: %w", err)}
+	}
+	if wrapped {
+		return txBatchResults{BatchResults: tx.SendBatch(ctx, b), ctx: ctx, tx: tx}
+	}
+	return ins.selector(ctx).SendBatch(ctx, b)
+}
+
+func (ins *Instance) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	tx, wrapped, err := ins.standalone(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't copy to regular instance: %w", err)
+	}
+	if !wrapped {
+		n, err := ins.selector(ctx).CopyFrom(ctx, tableName, columnNames, rowSrc)
+		if err != nil {
+			return n, fmt.Errorf("can't copy to regular instance: %w", pgerr.Map(ctx, err))
+		}
+		return n, nil
+	}
+
+	n, err := tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return n, fmt.Errorf("can't copy to regular instance: %w", pgerr.Map(ctx, err))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("can't commit standalone copy: %w", pgerr.Map(ctx, err))
+	}
+	return n, nil
+}
+
+// CopyTo streams result of COPY ... TO STDOUT query into w. Outside of transaction it runs in short one,
+// because copy protocol requires dedicated connection.
+func (ins *Instance) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	if tx, ok := pgcontext.TransactionFrom(ctx); ok {
+		tag, err := ins.copyTo(ctx, tx, w, query)
+		if err != nil {
+			return pgconn.CommandTag{}, fmt.Errorf("can't copy from regular instance: %w", pgerr.Map(ctx, err))
+		}
+		return tag, nil
+	}
+
+	tx, wrapped, err := ins.standalone(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("can't copy from regular instance: %w", err)
+	}
+	if !wrapped {
+		opts, _ := pgcontext.TxOptionsFrom(ctx)
+		if tx, err = ins.beginTx(ctx, opts); err != nil {
+			return pgconn.CommandTag{}, fmt.Errorf("can't copy from regular instance: %w", err)
+		}
+	}
+
+	tag, err := ins.copyTo(ctx, tx, w, query)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return pgconn.CommandTag{}, fmt.Errorf("can't copy from regular instance: %w", pgerr.Map(ctx, err))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("can't commit standalone copy: %w", pgerr.Map(ctx, err))
+	}
+	return tag, nil
+}
+
+func execStandalone(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return pgconn.CommandTag{}, fmt.Errorf("can't query regular instance: %w", pgerr.Map(ctx, err))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("can't commit standalone statement: %w", pgerr.Map(ctx, err))
+	}
+	return tag, nil
+}
+
+// standalone begins wrapping transaction for statement outside of transaction, when context requires
+// transaction-local settings such as statement timeout.
+func (ins *Instance) standalone(ctx context.Context) (pgx.Tx, bool, error) {
+	if _, ok := pgcontext.TransactionFrom(ctx); ok {
+		return nil, false, nil
+	}
+	settings := settingsFrom(ctx)
+	if len(settings) == 0 {
+		return nil, false, nil
+	}
+
+	opts, _ := pgcontext.TxOptionsFrom(ctx)
+	tx, err := ins.beginTx(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := applySettings(ctx, tx, nil, settings); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, false, err
+	}
+	return tx, true, nil
+}
+
 func (ins *Instance) nestedTx(ctx context.Context, tx pgx.Tx, fn func(ctx context.Context) error) (out error) {
 	nested, err := tx.Begin(ctx)
 	if err != nil {
@@ -101,7 +307,16 @@ func (ins *Instance) nestedTx(ctx context.Context, tx pgx.Tx, fn func(ctx contex
 		}
 	}()
 
-	nestedCtx := pgcontext.With(ctx, pgcontext.WithTransaction(nested))
+	applied, _ := pgcontext.AppliedSettingsFrom(ctx)
+	settings := settingsFrom(ctx)
+	saved, err := saveSettings(ctx, nested, applied, settings)
+	if err != nil {
+		return err
+	}
+	nestedCtx, err := applySettings(pgcontext.With(ctx, pgcontext.WithTransaction(nested)), nested, applied, settings)
+	if err != nil {
+		return err
+	}
 	err = fn(nestedCtx)
 	if err != nil {
 		if !ins.txErrPassMatcher(ctx, err) {
@@ -112,6 +327,9 @@ func (ins *Instance) nestedTx(ctx context.Context, tx pgx.Tx, fn func(ctx contex
 	if err = nested.Commit(ctx); err != nil {
 		return fmt.Errorf("can't commit nested transaction: %w", err)
 	}
+	if err = restoreSettings(ctx, tx, saved); err != nil {
+		return err
+	}
 	return out
 }
 
@@ -120,15 +338,24 @@ func (ins *Instance) Transactional(ctx context.Context, fn func(ctx context.Cont
 	if ok {
 		return ins.nestedTx(ctx, tx, fn)
 	}
+	if err := ins.gate.Enter(); err != nil {
+		return fmt.Errorf("can't run transaction regular instance: %w", err)
+	}
+	defer ins.gate.Leave()
 
 	var opts pgx.TxOptions
 	if mod, ok := pgcontext.TxOptionsFrom(ctx); ok {
 		opts = mod
 	}
 
-	err := pgx.BeginTxFunc(ctx, ins, opts, func(tx pgx.Tx) error {
-		txCtx := pgcontext.With(ctx, pgcontext.WithTransaction(tx))
-		err := fn(txCtx)
+	err := pgx.BeginTxFunc(ctx, beginnerFunc(ins.beginTx), opts, func(tx pgx.Tx) error {
+		txCtx, err := applySettings(
+			pgcontext.With(ctx, pgcontext.WithTransaction(tx)), tx, nil, settingsFrom(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		err = fn(txCtx)
 		if err != nil {
 			if ins.txErrPassMatcher(ctx, err) {
 				out = err
@@ -139,7 +366,7 @@ func (ins *Instance) Transactional(ctx context.Context, fn func(ctx context.Cont
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("can't run transaction regular instance: %w", err)
+		return fmt.Errorf("can't run transaction regular instance: %w", pgerr.Map(ctx, err))
 	}
 	return out
 }
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
 	"github.com/godepo/groat/integration"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -93,6 +96,31 @@ func TestInstance_BeginTx(t *testing.T) {
 	})
 }
 
+func TestInstance_CopyRoundTrip(t *testing.T) {
+	t.Run("should be able to copy rows in transaction", func(t *testing.T) {
+		tcs := suite.Case(t)
+
+		tcs.Given(ArrangeContext, ArrangeRecord).
+			When(
+				ActBeginTransaction(tcs.SUT),
+				ActCopyRecord(tcs.SUT),
+			).
+			Then(
+				AssertCommitTransaction,
+				AssertHasRecord(tcs.SUT),
+				AssertRecordExported(tcs.SUT),
+			)
+	})
+
+	t.Run("should be able to copy rows without begin transaction", func(t *testing.T) {
+		tcs := suite.Case(t)
+
+		tcs.Given(ArrangeContext, ArrangeRecord).
+			When(ActCopyRecord(tcs.SUT)).
+			Then(AssertHasRecord(tcs.SUT), AssertRecordExported(tcs.SUT))
+	})
+}
+
 func TestInstance_Exec(t *testing.T) {
 	t.Run("should be able to execute statement in transaction", func(t *testing.T) {
 		tcs := suite.Case(t)
@@ -279,6 +307,27 @@ func TestInstance_Transactional(t *testing.T) {
 		})
 	})
 
+	t.Run("should be able to restore override of caller after nested override", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Given(ArrangeContext).Then(AssertNoError)
+
+		tcs.State.Result.Error = tcs.SUT.Transactional(tcs.State.ctx, func(ctx context.Context) error {
+			if _, err := tcs.SUT.Exec(ctx, "SET LOCAL lock_timeout = '5s'"); err != nil {
+				return err
+			}
+			nestedCtx := pgcontext.With(ctx, pgcontext.WithLockTimeout(time.Second))
+			if err := tcs.SUT.Transactional(nestedCtx, func(ctx context.Context) error {
+				return nil
+			}); err != nil {
+				return err
+			}
+			var lockTimeout string
+			require.NoError(t, tcs.SUT.QueryRow(ctx, "SHOW lock_timeout").Scan(&lockTimeout))
+			assert.Equal(t, "5s", lockTimeout)
+			return nil
+		})
+	})
+
 	t.Run("should be able run nested transaction and fail at commit", func(t *testing.T) {
 		tcs := suite.Case(t)
 		tcs.Given(
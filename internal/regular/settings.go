@@ -0,0 +1,123 @@
+package regular
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	settingStatementTimeout = "statement_timeout"
+	settingLockTimeout      = "lock_timeout"
+
+	setConfigQuery      = "SELECT set_config($1, $2, true)"
+	currentSettingQuery = "SELECT current_setting($1, true)"
+)
+
+func settingsFrom(ctx context.Context) map[string]string {
+	vars, _ := pgcontext.SessionVarsFrom(ctx)
+	settings := make(map[string]string, len(vars)+2)
+	for name, value := range vars {
+		settings[name] = value
+	}
+
+	timeout, ok := pgcontext.StatementTimeoutFrom(ctx)
+	if deadline, has := ctx.Deadline(); has && pgcontext.DeadlineTimeoutFrom(ctx) {
+		remaining := max(time.Until(deadline), time.Millisecond)
+		if !ok || remaining < timeout {
+			timeout, ok = remaining, true
+		}
+	}
+	if ok {
+		settings[settingStatementTimeout] = formatMilliseconds(timeout)
+	}
+	if timeout, ok := pgcontext.LockTimeoutFrom(ctx); ok {
+		settings[settingLockTimeout] = formatMilliseconds(timeout)
+	}
+	return settings
+}
+
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+}
+
+// applySettings sets transaction-local configuration parameters which differ from already applied ones and
+// returns context, which remembers the resulting set for nested transactions.
+func applySettings(
+	ctx context.Context,
+	tx pgx.Tx,
+	applied map[string]string,
+	settings map[string]string,
+) (context.Context, error) {
+	names := make([]string, 0, len(settings))
+	for name, value := range settings {
+		if current, ok := applied[name]; ok && current == value {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return ctx, nil
+	}
+	sort.Strings(names)
+
+	merged := make(map[string]string, len(applied)+len(names))
+	for name, value := range applied {
+		merged[name] = value
+	}
+	for _, name := range names {
+		if _, err := tx.Exec(ctx, setConfigQuery, name, settings[name]); err != nil {
+			return ctx, fmt.Errorf("can't apply %s setting: %w", name, err)
+		}
+		merged[name] = settings[name]
+	}
+	return pgcontext.With(ctx, pgcontext.WithAppliedSettings(merged)), nil
+}
+
+// saveSettings remembers values of parameters, which nested transaction is going to change. Values applied by
+// elephant are known, the others are read with current_setting, as they may come from session or from SET LOCAL of
+// caller. NULL stands for parameter, which isn't defined yet.
+func saveSettings(ctx context.Context, tx pgx.Tx, applied, settings map[string]string) (map[string]any, error) {
+	saved := make(map[string]any, len(settings))
+	for name, value := range settings {
+		if current, ok := applied[name]; ok {
+			if current != value {
+				saved[name] = current
+			}
+			continue
+		}
+		var current *string
+		if err := tx.QueryRow(ctx, currentSettingQuery, name).Scan(&current); err != nil {
+			return nil, fmt.Errorf("can't read %s setting: %w", name, err)
+		}
+		switch {
+		case current == nil:
+			saved[name] = nil
+		case *current != value:
+			saved[name] = *current
+		}
+	}
+	return saved, nil
+}
+
+// restoreSettings sets back values saved before nested transaction, as transaction-local values outlive release of
+// savepoint. NULL value makes set_config reset parameter to its default of session.
+func restoreSettings(ctx context.Context, tx pgx.Tx, saved map[string]any) error {
+	names := make([]string, 0, len(saved))
+	for name := range saved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := tx.Exec(ctx, setConfigQuery, name, saved[name]); err != nil {
+			return fmt.Errorf("can't restore %s setting: %w", name, err)
+		}
+	}
+	return nil
+}
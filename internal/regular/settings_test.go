@@ -0,0 +1,584 @@
+package regular
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type scanFunc func(dest ...any) error
+
+func (fn scanFunc) Scan(dest ...any) error {
+	return fn(dest...)
+}
+
+// settingRow returns value of current_setting, nil stands for NULL.
+func settingRow(value any) pgx.Row {
+	return scanFunc(func(dest ...any) error {
+		if value != nil {
+			current := value.(string)
+			*dest[0].(**string) = &current
+		}
+		return nil
+	})
+}
+
+func withLockTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return pgcontext.With(ctx, pgcontext.WithLockTimeout(timeout))
+}
+
+var statementTimeoutErr = &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}
+
+func TestSettingsFrom(t *testing.T) {
+	t.Run("should be able to return empty settings at empty context", func(t *testing.T) {
+		assert.Empty(t, settingsFrom(context.Background()))
+	})
+
+	t.Run("should be able to return explicit timeouts", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithStatementTimeout(time.Second),
+			pgcontext.WithLockTimeout(150*time.Millisecond),
+		)
+		assert.Equal(t, map[string]string{
+			settingStatementTimeout: "1000ms",
+			settingLockTimeout:      "150ms",
+		}, settingsFrom(ctx))
+	})
+
+	t.Run("should be able to return session vars along with timeouts", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithSessionVars(map[string]string{"app.tenant_id": "42"}),
+			pgcontext.WithLockTimeout(time.Second),
+		)
+		assert.Equal(t, map[string]string{
+			"app.tenant_id":    "42",
+			settingLockTimeout: "1000ms",
+		}, settingsFrom(ctx))
+	})
+
+	t.Run("should be able to ignore deadline without deadline timeout option", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		assert.Empty(t, settingsFrom(ctx))
+	})
+
+	t.Run("should be able to take remaining deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		ctx = pgcontext.With(ctx, pgcontext.WithDeadlineTimeout, pgcontext.WithStatementTimeout(time.Hour))
+
+		settings := settingsFrom(ctx)
+		require.Contains(t, settings, settingStatementTimeout)
+		assert.NotEqual(t, "3600000ms", settings[settingStatementTimeout])
+	})
+
+	t.Run("should be able to prefer explicit timeout when it is shorter than deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		ctx = pgcontext.With(ctx, pgcontext.WithDeadlineTimeout, pgcontext.WithStatementTimeout(time.Second))
+
+		assert.Equal(t, map[string]string{settingStatementTimeout: "1000ms"}, settingsFrom(ctx))
+	})
+
+	t.Run("should be able to keep at least one millisecond at expired deadline", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		ctx = pgcontext.With(ctx, pgcontext.WithDeadlineTimeout)
+
+		assert.Equal(t, map[string]string{settingStatementTimeout: "1ms"}, settingsFrom(ctx))
+	})
+}
+
+func TestApplySettings(t *testing.T) {
+	t.Run("should be able to skip already applied settings", func(t *testing.T) {
+		tx := NewMockTx(t)
+		ctx := context.Background()
+		settings := map[string]string{settingStatementTimeout: "10ms"}
+
+		res, err := applySettings(ctx, tx, settings, settings)
+		require.NoError(t, err)
+		assert.Equal(t, ctx, res)
+	})
+
+	t.Run("should be able to apply changed settings and remember them", func(t *testing.T) {
+		tx := NewMockTx(t)
+		ctx := context.Background()
+		tx.EXPECT().Exec(ctx, setConfigQuery, settingLockTimeout, "20ms").Return(pgconn.CommandTag{}, nil)
+
+		res, err := applySettings(ctx, tx,
+			map[string]string{settingStatementTimeout: "10ms"},
+			map[string]string{settingStatementTimeout: "10ms", settingLockTimeout: "20ms"},
+		)
+		require.NoError(t, err)
+		applied, ok := pgcontext.AppliedSettingsFrom(res)
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{settingStatementTimeout: "10ms", settingLockTimeout: "20ms"}, applied)
+	})
+
+	t.Run("should be able to fail when setting rejected", func(t *testing.T) {
+		tx := NewMockTx(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "20ms").
+			Return(pgconn.CommandTag{}, expErr)
+
+		_, err := applySettings(context.Background(), tx, nil, map[string]string{settingLockTimeout: "20ms"})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func newTimeoutInstance(t *testing.T) (*Instance, *MockPool, *MockTx, context.Context) {
+	t.Helper()
+	pool := NewMockPool(t)
+	tx := NewMockTx(t)
+	ctx := pgcontext.With(context.Background(), pgcontext.WithStatementTimeout(time.Second))
+	return New(pool), pool, tx, ctx
+}
+
+func expectStandaloneBegin(ctx context.Context, pool *MockPool, tx *MockTx) {
+	pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+	tx.EXPECT().Exec(mock.Anything, setConfigQuery, settingStatementTimeout, "1000ms").Return(pgconn.CommandTag{}, nil)
+}
+
+func TestInstance_Standalone(t *testing.T) {
+	t.Run("should be able to wrap exec into transaction", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.NewCommandTag("SELECT 1"), nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+
+		tag, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.True(t, tag.Select())
+	})
+
+	t.Run("should be able to map timeout at wrapped exec", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, statementTimeoutErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to fail at commit of wrapped exec", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Commit(ctx).Return(expErr)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when can't begin wrapping transaction", func(t *testing.T) {
+		sut, pool, _, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(nil, expErr).Times(3)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, expErr)
+		_, err = sut.Query(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, expErr)
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), expErr)
+	})
+
+	t.Run("should be able to rollback when can't apply settings", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Exec(ctx, setConfigQuery, settingStatementTimeout, "1000ms").Return(pgconn.CommandTag{}, expErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to wrap query and commit after reading rows", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		rows := NewMockRows(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Query(ctx, "SELECT 1").Return(rows, nil)
+		rows.EXPECT().Next().Return(false)
+		rows.EXPECT().Close().Return()
+		rows.EXPECT().Err().Return(nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+
+		res, err := sut.Query(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.False(t, res.Next())
+		res.Close()
+		assert.NoError(t, res.Err())
+	})
+
+	t.Run("should be able to rollback wrapped query at rows error", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		rows := NewMockRows(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Query(ctx, "SELECT 1").Return(rows, nil)
+		rows.EXPECT().Close().Return()
+		rows.EXPECT().Err().Return(statementTimeoutErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		res, err := sut.Query(ctx, "SELECT 1")
+		require.NoError(t, err)
+		res.Close()
+		assert.ErrorIs(t, res.Err(), pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to report commit error of wrapped query", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		rows := NewMockRows(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Query(ctx, "SELECT 1").Return(rows, nil)
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Next().Return(false).Once()
+		rows.EXPECT().Close().Return()
+		rows.EXPECT().Err().Return(nil)
+		tx.EXPECT().Commit(ctx).Return(expErr)
+
+		res, err := sut.Query(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.True(t, res.Next())
+		assert.False(t, res.Next())
+		assert.ErrorIs(t, res.Err(), expErr)
+	})
+
+	t.Run("should be able to rollback when wrapped query failed", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Query(ctx, "SELECT 1").Return(nil, statementTimeoutErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		_, err := sut.Query(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to wrap query row and commit after scan", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().QueryRow(ctx, "SELECT 1").Return(scanFunc(func(_ ...any) error {
+			return pgx.ErrNoRows
+		}))
+		tx.EXPECT().Commit(ctx).Return(nil)
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), pgx.ErrNoRows)
+	})
+
+	t.Run("should be able to rollback query row at scan error", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().QueryRow(ctx, "SELECT 1").Return(scanFunc(func(_ ...any) error {
+			return statementTimeoutErr
+		}))
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to report commit error of query row", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().QueryRow(ctx, "SELECT 1").Return(scanFunc(func(_ ...any) error {
+			return nil
+		}))
+		tx.EXPECT().Commit(ctx).Return(expErr)
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), expErr)
+	})
+
+	t.Run("should be able to run in context transaction without wrapping", func(t *testing.T) {
+		sut, _, tx, ctx := newTimeoutInstance(t)
+		ctx = pgcontext.With(ctx, pgcontext.WithTransaction(tx))
+		tx.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, statementTimeoutErr)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to map scan error of query row without wrapping", func(t *testing.T) {
+		sut, _, tx, ctx := newTimeoutInstance(t)
+		ctx = pgcontext.With(ctx, pgcontext.WithTransaction(tx))
+		tx.EXPECT().QueryRow(ctx, "SELECT 1").Return(scanFunc(func(_ ...any) error {
+			return statementTimeoutErr
+		}))
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), pgerr.ErrStatementTimeout)
+	})
+}
+
+func TestInstance_TransactionalSettings(t *testing.T) {
+	t.Run("should be able to apply settings after begin", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		expectStandaloneBegin(ctx, pool, tx)
+		tx.EXPECT().Commit(ctx).Return(nil)
+		tx.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			applied, ok := pgcontext.AppliedSettingsFrom(ctx)
+			require.True(t, ok)
+			assert.Equal(t, map[string]string{settingStatementTimeout: "1000ms"}, applied)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to rollback when can't apply settings", func(t *testing.T) {
+		sut, pool, tx, ctx := newTimeoutInstance(t)
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, settingStatementTimeout, "1000ms").
+			Return(pgconn.CommandTag{}, statementTimeoutErr)
+		tx.EXPECT().Rollback(ctx).Return(nil)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+	})
+
+	t.Run("should be able to apply only changed settings in nested transaction", func(t *testing.T) {
+		sut, _, tx, ctx := newTimeoutInstance(t)
+		nested := NewMockTx(t)
+		ctx = pgcontext.With(ctx,
+			pgcontext.WithTransaction(tx),
+			pgcontext.WithAppliedSettings(map[string]string{settingStatementTimeout: "1000ms"}),
+			pgcontext.WithLockTimeout(time.Second),
+		)
+		tx.EXPECT().Begin(ctx).Return(nested, nil)
+		nested.EXPECT().QueryRow(ctx, currentSettingQuery, settingLockTimeout).Return(settingRow("0"))
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "1000ms").
+			Return(pgconn.CommandTag{}, nil)
+		nested.EXPECT().Commit(ctx).Return(nil)
+		nested.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+		tx.EXPECT().Exec(ctx, setConfigQuery, settingLockTimeout, "0").Return(pgconn.CommandTag{}, nil)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to fail nested transaction when can't apply settings", func(t *testing.T) {
+		sut, _, tx, ctx := newTimeoutInstance(t)
+		nested := NewMockTx(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		ctx = pgcontext.With(ctx, pgcontext.WithTransaction(tx))
+		tx.EXPECT().Begin(ctx).Return(nested, nil)
+		nested.EXPECT().QueryRow(ctx, currentSettingQuery, settingStatementTimeout).Return(settingRow("0"))
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, settingStatementTimeout, "1000ms").
+			Return(pgconn.CommandTag{}, expErr)
+		nested.EXPECT().Rollback(ctx).Return(nil)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail nested transaction when can't read current setting", func(t *testing.T) {
+		sut, _, tx, ctx := newTimeoutInstance(t)
+		nested := NewMockTx(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		ctx = pgcontext.With(ctx, pgcontext.WithTransaction(tx))
+		tx.EXPECT().Begin(ctx).Return(nested, nil)
+		nested.EXPECT().QueryRow(ctx, currentSettingQuery, settingStatementTimeout).
+			Return(scanFunc(func(_ ...any) error {
+				return expErr
+			}))
+		nested.EXPECT().Rollback(ctx).Return(nil)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to restore outer override after nested override", func(t *testing.T) {
+		pool := NewMockPool(t)
+		tx := NewMockTx(t)
+		nested := NewMockTx(t)
+		inner := NewMockTx(t)
+		sut := New(pool)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithLockTimeout(time.Second))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "1000ms").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Begin(mock.Anything).Return(nested, nil)
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "2000ms").
+			Return(pgconn.CommandTag{}, nil)
+		nested.EXPECT().Begin(mock.Anything).Return(inner, nil)
+		inner.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "3000ms").
+			Return(pgconn.CommandTag{}, nil)
+		inner.EXPECT().Commit(mock.Anything).Return(nil)
+		inner.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+		restoreNested := nested.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "2000ms").
+			Return(pgconn.CommandTag{}, nil).Call
+		nested.EXPECT().Commit(mock.Anything).Return(nil).NotBefore(restoreNested)
+		nested.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, settingLockTimeout, "1000ms").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+		tx.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return sut.Transactional(withLockTimeout(ctx, 2*time.Second), func(ctx context.Context) error {
+				return sut.Transactional(withLockTimeout(ctx, 3*time.Second), func(ctx context.Context) error {
+					applied, _ := pgcontext.AppliedSettingsFrom(ctx)
+					assert.Equal(t, map[string]string{settingLockTimeout: "3000ms"}, applied)
+					return nil
+				})
+			})
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to restore override of caller after nested override", func(t *testing.T) {
+		sut, _, tx, ctx := newTimeoutInstance(t)
+		nested := NewMockTx(t)
+		ctx = pgcontext.With(ctx, pgcontext.WithTransaction(tx))
+		tx.EXPECT().Begin(ctx).Return(nested, nil)
+		nested.EXPECT().QueryRow(ctx, currentSettingQuery, settingStatementTimeout).Return(settingRow("5s"))
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, settingStatementTimeout, "1000ms").
+			Return(pgconn.CommandTag{}, nil)
+		nested.EXPECT().Commit(ctx).Return(nil)
+		nested.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+		tx.EXPECT().Exec(ctx, setConfigQuery, settingStatementTimeout, "5s").Return(pgconn.CommandTag{}, nil)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestInstance_TransactionalSessionVars(t *testing.T) {
+	t.Run("should be able to apply session vars after begin", func(t *testing.T) {
+		pool := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(pool)
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithSessionVars(map[string]string{"app.tenant_id": "1", "app.user_id": "2"}),
+		)
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		first := tx.EXPECT().Exec(mock.Anything, setConfigQuery, "app.tenant_id", "1").
+			Return(pgconn.CommandTag{}, nil).Call
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, "app.user_id", "2").
+			Return(pgconn.CommandTag{}, nil).NotBefore(first)
+		tx.EXPECT().Commit(ctx).Return(nil)
+		tx.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to re-apply changed session vars in nested transaction", func(t *testing.T) {
+		pool := NewMockPool(t)
+		tx := NewMockTx(t)
+		nested := NewMockTx(t)
+		inner := NewMockTx(t)
+		sut := New(pool)
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithSessionVars(map[string]string{"app.tenant_id": "1", "app.user_id": "2"}),
+		)
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, "app.tenant_id", "1").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, "app.user_id", "2").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Begin(mock.Anything).Return(nested, nil)
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, "app.tenant_id", "3").Return(pgconn.CommandTag{}, nil)
+		nested.EXPECT().Begin(mock.Anything).Return(inner, nil)
+		inner.EXPECT().Commit(mock.Anything).Return(nil)
+		inner.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+		nested.EXPECT().Commit(mock.Anything).Return(nil)
+		nested.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+		tx.EXPECT().Exec(mock.Anything, setConfigQuery, "app.tenant_id", "1").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+		tx.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return sut.Transactional(
+				pgcontext.With(ctx, pgcontext.WithSessionVars(map[string]string{"app.tenant_id": "3"})),
+				func(ctx context.Context) error {
+					return sut.Transactional(ctx, func(ctx context.Context) error {
+						return nil
+					})
+				},
+			)
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestInstance_NestedSessionVarsRestore(t *testing.T) {
+	newNested := func(t *testing.T) (*Instance, *MockTx, *MockTx, context.Context) {
+		tx := NewMockTx(t)
+		nested := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithTransaction(tx),
+			pgcontext.WithAppliedSettings(map[string]string{"app.tenant_id": "1"}),
+			pgcontext.WithSessionVars(map[string]string{"app.tenant_id": "2", "app.user_id": "3"}),
+		)
+		tx.EXPECT().Begin(ctx).Return(nested, nil)
+		nested.EXPECT().QueryRow(ctx, currentSettingQuery, "app.user_id").Return(settingRow(nil))
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, "app.tenant_id", "2").Return(pgconn.CommandTag{}, nil)
+		nested.EXPECT().Exec(mock.Anything, setConfigQuery, "app.user_id", "3").Return(pgconn.CommandTag{}, nil)
+		nested.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+		return New(NewMockPool(t)), tx, nested, ctx
+	}
+
+	t.Run("should be able to restore outer session vars after release of savepoint", func(t *testing.T) {
+		sut, tx, nested, ctx := newNested(t)
+		nested.EXPECT().Commit(ctx).Return(nil)
+		var restored []string
+		tx.EXPECT().Exec(ctx, setConfigQuery, mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, _ string, args ...interface{}) (pgconn.CommandTag, error) {
+				restored = append(restored, fmt.Sprint(args...))
+				return pgconn.CommandTag{}, nil
+			}).Times(2)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			applied, _ := pgcontext.AppliedSettingsFrom(ctx)
+			assert.Equal(t, map[string]string{"app.tenant_id": "2", "app.user_id": "3"}, applied)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.tenant_id1", "app.user_id<nil>"}, restored)
+	})
+
+	t.Run("should be able to skip restore when savepoint isn't released", func(t *testing.T) {
+		sut, _, nested, ctx := newNested(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return expErr
+		})
+		assert.ErrorIs(t, err, expErr)
+		nested.AssertNotCalled(t, "Commit", mock.Anything)
+	})
+
+	t.Run("should be able to fail when can't restore outer session vars", func(t *testing.T) {
+		sut, tx, nested, ctx := newNested(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		nested.EXPECT().Commit(ctx).Return(nil)
+		tx.EXPECT().Exec(ctx, setConfigQuery, "app.tenant_id", "1").Return(pgconn.CommandTag{}, expErr)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
@@ -0,0 +1,122 @@
+package regular
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type closingPool struct {
+	*MockPool
+	closed bool
+}
+
+func (p *closingPool) Close() {
+	p.closed = true
+}
+
+func TestInstance_Shutdown(t *testing.T) {
+	t.Run("should be able to close pool and reject new transactions", func(t *testing.T) {
+		pool := &closingPool{MockPool: NewMockPool(t)}
+		sut := New(pool)
+
+		require.NoError(t, sut.Shutdown(context.Background()))
+		assert.True(t, pool.closed)
+		assert.Equal(t, pgdrain.Progress{Stage: pgdrain.StageClosed}, sut.ShutdownProgress())
+
+		_, err := sut.Begin(context.Background())
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+		_, err = sut.BeginTx(context.Background(), pgx.TxOptions{})
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+		err = sut.Transactional(context.Background(), func(context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+	})
+
+	t.Run("should be able to drain in-flight transaction with nested ones", func(t *testing.T) {
+		pool := &closingPool{MockPool: NewMockPool(t)}
+		tx := NewMockTx(t)
+		nested := NewMockTx(t)
+		ctx := context.Background()
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Begin(mock.Anything).Return(nested, nil)
+		nested.EXPECT().Commit(mock.Anything).Return(nil)
+		nested.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+		tx.EXPECT().Commit(ctx).Return(nil)
+		tx.EXPECT().Rollback(ctx).Return(pgx.ErrTxClosed)
+		sut := New(pool)
+
+		entered, release := make(chan struct{}), make(chan struct{})
+		result := make(chan error)
+		go func() {
+			result <- sut.Transactional(ctx, func(ctx context.Context) error {
+				close(entered)
+				<-release
+				return sut.Transactional(ctx, func(context.Context) error {
+					return nil
+				})
+			})
+		}()
+		<-entered
+
+		shutdown := make(chan error)
+		go func() {
+			shutdown <- sut.Shutdown(context.Background())
+		}()
+		require.Eventually(t, func() bool {
+			return sut.ShutdownProgress() == pgdrain.Progress{Stage: pgdrain.StageDraining, InFlight: 1}
+		}, time.Second, time.Millisecond)
+
+		close(release)
+		require.NoError(t, <-result)
+		require.NoError(t, <-shutdown)
+		assert.True(t, pool.closed)
+	})
+
+	t.Run("should be able to fail, when in-flight transaction isn't drained in time", func(t *testing.T) {
+		pool := &closingPool{MockPool: NewMockPool(t)}
+		sut := New(pool)
+		require.NoError(t, sut.gate.Enter())
+		defer sut.gate.Leave()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := sut.Shutdown(ctx)
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "can't shutdown regular instance")
+	})
+
+	t.Run("should be able to let standalone statements in while draining", func(t *testing.T) {
+		pool := NewMockPool(t)
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithStatementTimeout(time.Second))
+		pool.EXPECT().BeginTx(ctx, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Exec(ctx, setConfigQuery, settingStatementTimeout, "1000ms").
+			Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Commit(ctx).Return(nil)
+		sut := New(pool)
+		require.NoError(t, sut.gate.Enter())
+		defer sut.gate.Leave()
+		go func() {
+			_ = sut.Shutdown(context.Background())
+		}()
+		require.Eventually(t, func() bool {
+			return sut.ShutdownProgress().Stage == pgdrain.StageDraining
+		}, time.Second, time.Millisecond)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.NoError(t, err)
+	})
+}
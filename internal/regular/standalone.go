@@ -0,0 +1,121 @@
+package regular
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(_ ...any) error {
+	return r.err
+}
+
+type failedBatchResults struct {
+	err error
+}
+
+func (r failedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r failedBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r failedBatchResults) QueryRow() pgx.Row {
+	return failedRow(r)
+}
+
+func (r failedBatchResults) Close() error {
+	return r.err
+}
+
+// txRow finishes wrapping transaction of standalone statement right after scanning.
+type txRow struct {
+	pgx.Row
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (r txRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		_ = r.tx.Rollback(r.ctx)
+		return pgerr.Map(r.ctx, err)
+	}
+	if commitErr := r.tx.Commit(r.ctx); commitErr != nil {
+		return pgerr.Map(r.ctx, commitErr)
+	}
+	return err
+}
+
+// txRows finishes wrapping transaction of standalone statement when rows are read or closed.
+type txRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	err  error
+	done bool
+}
+
+func (r *txRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+func (r *txRows) Close() {
+	r.finish()
+}
+
+func (r *txRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return pgerr.Map(r.ctx, r.Rows.Err())
+}
+
+func (r *txRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.Rows.Close()
+	if err := r.Rows.Err(); err != nil {
+		_ = r.tx.Rollback(r.ctx)
+		r.err = pgerr.Map(r.ctx, err)
+		return
+	}
+	if err := r.tx.Commit(r.ctx); err != nil {
+		r.err = pgerr.Map(r.ctx, err)
+	}
+}
+
+// txBatchResults finishes wrapping transaction of standalone batch when results are closed.
+type txBatchResults struct {
+	pgx.BatchResults
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (r txBatchResults) Exec() (pgconn.CommandTag, error) {
+	tag, err := r.BatchResults.Exec()
+	return tag, pgerr.Map(r.ctx, err)
+}
+
+func (r txBatchResults) Close() error {
+	if err := r.BatchResults.Close(); err != nil {
+		_ = r.tx.Rollback(r.ctx)
+		return pgerr.Map(r.ctx, err)
+	}
+	return pgerr.Map(r.ctx, r.tx.Commit(r.ctx))
+}
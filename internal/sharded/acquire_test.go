@@ -0,0 +1,48 @@
+package sharded
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type acquirePool struct {
+	*MockPool
+	conn *pgxpool.Conn
+}
+
+func (p acquirePool) Acquire(_ context.Context) (*pgxpool.Conn, error) {
+	return p.conn, nil
+}
+
+func TestHive_Acquire(t *testing.T) {
+	exp := &pgxpool.Conn{}
+	newHive := func(t *testing.T) *Hive {
+		return New([]Pool{NewMockPool(t), acquirePool{MockPool: NewMockPool(t), conn: exp}}, nil)
+	}
+
+	t.Run("should be able to acquire connection from shard", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(1))
+
+		conn, err := newHive(t).Acquire(ctx)
+		require.NoError(t, err)
+		assert.Same(t, exp, conn)
+	})
+
+	t.Run("should be able to fail when shard doesn't support acquire", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(0))
+
+		_, err := newHive(t).Acquire(ctx)
+		assert.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+	})
+
+	t.Run("should be able to fail when shard can't be picked", func(t *testing.T) {
+		_, err := newHive(t).Acquire(context.Background())
+		assert.ErrorIs(t, err, ErrCouldNotPickShard)
+	})
+}
@@ -0,0 +1,97 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errNode = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestHive_CircuitBreaker(t *testing.T) {
+	breaker := pgbreaker.Config{ConsecutiveFailures: 1}
+
+	t.Run("should be able to fail fast with shard, which circuit is open", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(1))
+		healthy, broken := NewMockPool(t), NewMockPool(t)
+		broken.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Once()
+		healthy.EXPECT().Exec(mock.Anything, "SELECT 1").Return(pgconn.CommandTag{}, nil).Once()
+		sut := New([]Pool{healthy, broken}, nil, WithCircuitBreaker(breaker))
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+
+		_, err = sut.Exec(ctx, "SELECT 1")
+
+		var openErr *pgbreaker.OpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, "shard [1]", openErr.Node)
+		_, err = sut.Exec(pgcontext.With(context.Background(), pgcontext.WithShardID(0)), "SELECT 1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should be able to stop partitioned copy at shard, which circuit is open", func(t *testing.T) {
+		ctx := context.Background()
+		shard := NewMockPool(t)
+		shard.EXPECT().CopyFrom(mock.Anything, pgx.Identifier{"t"}, []string{"id"}, mock.Anything).
+			Return(0, errNode).Once()
+		sut := New([]Pool{shard}, func(context.Context, string) uint {
+			return 0
+		}, WithCircuitBreaker(breaker))
+		key := func([]any) (string, error) {
+			return "key", nil
+		}
+		_, err := sut.CopyFromPartitioned(ctx, pgx.Identifier{"t"}, []string{"id"},
+			pgx.CopyFromRows([][]any{{1}}), key)
+		require.ErrorIs(t, err, errNode)
+
+		_, err = sut.CopyFromPartitioned(ctx, pgx.Identifier{"t"}, []string{"id"},
+			pgx.CopyFromRows([][]any{{1}}), key)
+
+		assert.ErrorIs(t, err, pgerr.ErrCircuitOpen)
+	})
+
+	t.Run("should be able to guard new shards after swap", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(0))
+		previous, next := NewMockPool(t), NewMockPool(t)
+		previous.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Once()
+		next.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil).Once()
+		sut := New([]Pool{previous}, nil, WithCircuitBreaker(breaker))
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+
+		require.NoError(t, sut.SwapShards(ctx, []Pool{next}))
+
+		_, err = sut.Exec(ctx, "SELECT 1")
+		assert.NoError(t, err)
+	})
+}
+
+type guardedPool struct {
+	*MockPool
+}
+
+func (guardedPool) Guarded() bool {
+	return true
+}
+
+func TestHive_CircuitBreaker_guardedShards(t *testing.T) {
+	ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(0))
+	shard := guardedPool{MockPool: NewMockPool(t)}
+	shard.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, errNode).Twice()
+	sut := New([]Pool{shard}, nil, WithCircuitBreaker(pgbreaker.Config{ConsecutiveFailures: 1}))
+
+	for range 2 {
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.ErrorIs(t, err, errNode)
+	}
+}
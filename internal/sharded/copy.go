@@ -0,0 +1,141 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+)
+
+const partitionBuffer = 64
+
+var ErrPartitionedCopyInTransaction = errors.New("partitioned copy can't join transaction of single shard")
+
+// RowKey extracts sharding key from row values of copy source.
+type RowKey func(values []any) (string, error)
+
+// partition streams rows of single shard to its CopyFrom.
+type partition struct {
+	ctx     context.Context
+	rows    chan []any
+	current []any
+	err     error
+}
+
+func newPartition(ctx context.Context) *partition {
+	return &partition{
+		ctx:  ctx,
+		rows: make(chan []any, partitionBuffer),
+	}
+}
+
+func (p *partition) Next() bool {
+	select {
+	case row, ok := <-p.rows:
+		if !ok {
+			return false
+		}
+		p.current = row
+		return true
+	case <-p.ctx.Done():
+		p.err = p.ctx.Err()
+		return false
+	}
+}
+
+func (p *partition) Values() ([]any, error) {
+	return p.current, nil
+}
+
+func (p *partition) Err() error {
+	return p.err
+}
+
+func (p *partition) send(row []any) bool {
+	select {
+	case p.rows <- row:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// CopyFromPartitioned splits rows of source by sharding key and streams every partition to its shard
+// concurrently. Each shard loads its partition atomically, but there is no atomicity across shards.
+func (s *Hive) CopyFromPartitioned(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource, key RowKey,
+) (int64, error) {
+	if _, ok := pgcontext.TransactionFrom(ctx); ok {
+		return 0, ErrPartitionedCopyInTransaction
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	set, release := s.current()
+	defer release()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		total    int64
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	partitions := make(map[uint]*partition)
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			fail(fmt.Errorf("can't read copy source: %w", err))
+			break
+		}
+		shardingKey, err := key(values)
+		if err != nil {
+			fail(fmt.Errorf("can't get sharding key of row: %w", err))
+			break
+		}
+
+		shardID := s.shardPicker(ctx, shardingKey)
+		part, ok := partitions[shardID]
+		if !ok {
+			part = newPartition(ctx)
+			partitions[shardID] = part
+			wg.Add(1)
+			go func(shardID uint) {
+				defer wg.Done()
+				n, err := set.guarded[shardID].CopyFrom(
+					pgcontext.With(ctx, pgcontext.WithShardID(shardID)), tableName, columnNames, part,
+				)
+				if err != nil {
+					fail(fmt.Errorf("can't copy partition to shard %d: %w", shardID, err))
+					return
+				}
+				mu.Lock()
+				total += n
+				mu.Unlock()
+			}(shardID)
+		}
+		if !part.send(append([]any(nil), values...)) {
+			break
+		}
+	}
+	if err := rowSrc.Err(); err != nil {
+		fail(fmt.Errorf("can't read copy source: %w", err))
+	}
+
+	for _, part := range partitions {
+		close(part.rows)
+	}
+	wg.Wait()
+	return total, firstErr
+}
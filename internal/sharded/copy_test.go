@@ -0,0 +1,170 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type brokenSource struct {
+	valuesErr error
+	err       error
+	done      bool
+}
+
+func (s *brokenSource) Next() bool {
+	if s.done {
+		return false
+	}
+	s.done = true
+	return s.valuesErr != nil
+}
+
+func (s *brokenSource) Values() ([]any, error) {
+	return nil, s.valuesErr
+}
+
+func (s *brokenSource) Err() error {
+	return s.err
+}
+
+type partitionedCase struct {
+	hive   *Hive
+	shards []*MockPool
+	mu     sync.Mutex
+	copied map[uint][][]any
+}
+
+func newPartitionedCase(t *testing.T) *partitionedCase {
+	t.Helper()
+	pc := &partitionedCase{copied: make(map[uint][][]any)}
+	pools := make([]Pool, 0, 3)
+	for range 3 {
+		shard := NewMockPool(t)
+		pc.shards = append(pc.shards, shard)
+		pools = append(pools, shard)
+	}
+	pc.hive = New(pools, func(_ context.Context, key string) uint {
+		return uint(key[0]-'a') % 3
+	})
+	return pc
+}
+
+func (pc *partitionedCase) expectCopy(shardID uint, err error) {
+	pc.shards[shardID].EXPECT().
+		CopyFrom(mock.Anything, pgx.Identifier{"users"}, []string{"key", "value"}, mock.Anything).
+		RunAndReturn(func(
+			ctx context.Context, _ pgx.Identifier, _ []string, src pgx.CopyFromSource,
+		) (int64, error) {
+			if id, ok := pgcontext.ShardIDFrom(ctx); !ok || id != shardID {
+				return 0, errors.New("unexpected shard in context")
+			}
+			if err != nil {
+				return 0, err
+			}
+			var n int64
+			for src.Next() {
+				values, _ := src.Values()
+				pc.mu.Lock()
+				pc.copied[shardID] = append(pc.copied[shardID], values)
+				pc.mu.Unlock()
+				n++
+			}
+			return n, src.Err()
+		})
+}
+
+func rowKey(values []any) (string, error) {
+	key, ok := values[0].(string)
+	if !ok {
+		return "", errors.New("key is not a string")
+	}
+	return key, nil
+}
+
+func TestHive_CopyFromPartitioned(t *testing.T) {
+	table := pgx.Identifier{"users"}
+	columns := []string{"key", "value"}
+
+	t.Run("should be able to stream every partition to its shard", func(t *testing.T) {
+		pc := newPartitionedCase(t)
+		pc.expectCopy(0, nil)
+		pc.expectCopy(1, nil)
+		rows := [][]any{{"a", 1}, {"b", 2}, {"d", 3}, {"e", 4}, {"a", 5}}
+
+		n, err := pc.hive.CopyFromPartitioned(context.Background(), table, columns, pgx.CopyFromRows(rows), rowKey)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+		assert.Equal(t, [][]any{{"a", 1}, {"d", 3}, {"a", 5}}, pc.copied[0])
+		assert.Equal(t, [][]any{{"b", 2}, {"e", 4}}, pc.copied[1])
+	})
+
+	t.Run("should be able to reject context transaction", func(t *testing.T) {
+		pc := newPartitionedCase(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(NewMockTx(t)))
+
+		_, err := pc.hive.CopyFromPartitioned(ctx, table, columns, pgx.CopyFromRows(nil), rowKey)
+		assert.ErrorIs(t, err, ErrPartitionedCopyInTransaction)
+	})
+
+	t.Run("should be able to fail when shard copy fails", func(t *testing.T) {
+		pc := newPartitionedCase(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pc.expectCopy(0, expErr)
+		rows := make([][]any, 0, partitionBuffer*2)
+		for range partitionBuffer * 2 {
+			rows = append(rows, []any{"a", 1})
+		}
+
+		_, err := pc.hive.CopyFromPartitioned(context.Background(), table, columns, pgx.CopyFromRows(rows), rowKey)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when key can't be extracted", func(t *testing.T) {
+		pc := newPartitionedCase(t)
+		pc.expectCopy(0, nil)
+		rows := [][]any{{"a", 1}, {2, 2}}
+
+		_, err := pc.hive.CopyFromPartitioned(context.Background(), table, columns, pgx.CopyFromRows(rows), rowKey)
+		assert.ErrorContains(t, err, "key is not a string")
+	})
+
+	t.Run("should be able to fail when source values fail", func(t *testing.T) {
+		pc := newPartitionedCase(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+
+		_, err := pc.hive.CopyFromPartitioned(
+			context.Background(), table, columns, &brokenSource{valuesErr: expErr}, rowKey,
+		)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when source fails", func(t *testing.T) {
+		pc := newPartitionedCase(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+
+		_, err := pc.hive.CopyFromPartitioned(context.Background(), table, columns, &brokenSource{err: expErr}, rowKey)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestPartition(t *testing.T) {
+	t.Run("should be able to stop at canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		part := newPartition(ctx)
+		part.rows = make(chan []any)
+
+		assert.False(t, part.send([]any{1}))
+		assert.False(t, part.Next())
+		assert.ErrorIs(t, part.Err(), context.Canceled)
+	})
+}
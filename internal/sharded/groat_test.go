@@ -3,6 +3,7 @@ package sharded
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
@@ -17,10 +18,13 @@ import (
 )
 
 type Result struct {
-	Tx    pgx.Tx
-	Error error
-	Rows  pgx.Rows
-	Row   pgx.Row
+	Tx     pgx.Tx
+	Error  error
+	Rows   pgx.Rows
+	Row    pgx.Row
+	Batch  pgx.BatchResults
+	Copied int64
+	Tag    pgconn.CommandTag
 }
 
 type Expect struct {
@@ -31,6 +35,13 @@ type Expect struct {
 	Args      []any
 	Rows      pgx.Rows
 	Row       pgx.Row
+	Batch     *pgx.Batch
+	Results   pgx.BatchResults
+	Table     pgx.Identifier
+	Columns   []string
+	Source    pgx.CopyFromSource
+	Copied    int64
+	Tag       pgconn.CommandTag
 }
 
 type State struct {
@@ -54,16 +65,16 @@ func newTestCase(t *testing.T) testCase {
 	tc := groat.New[Deps, State, *Hive](
 		t,
 		func(t *testing.T, deps Deps) *Hive {
-			return &Hive{
-				shards: []Pool{
+			return New(
+				[]Pool{
 					deps.shardMocks[0],
 					deps.shardMocks[1],
 					deps.shardMocks[2],
 				},
-				shardPicker: func(ctx context.Context, key string) uint {
+				func(ctx context.Context, key string) uint {
 					return deps.shardID
 				},
-			}
+			)
 		},
 		func(t *testing.T, deps Deps) Deps {
 			deps.ctx = context.Background()
@@ -140,6 +151,24 @@ func ArrangeRow(t *testing.T, state State) State {
 	return state
 }
 
+func ArrangeCopySource(t *testing.T, state State) State {
+	t.Helper()
+	state.Expect.Table = pgx.Identifier{state.Faker.Lorem().Word()}
+	state.Expect.Columns = []string{state.Faker.Lorem().Word()}
+	state.Expect.Source = pgx.CopyFromRows([][]any{{uuid.NewString()}})
+	state.Expect.Copied = 1
+	state.Expect.Tag = pgconn.NewCommandTag("COPY 1")
+	return state
+}
+
+func ArrangeBatch(t *testing.T, state State) State {
+	t.Helper()
+	state.Expect.Batch = &pgx.Batch{}
+	state.Expect.Batch.Queue(state.Faker.RandomStringWithLength(20))
+	state.Expect.Results = NewMockBatchResults(t)
+	return state
+}
+
 func ArrangeTxOptions(t *testing.T, state State) State {
 	t.Helper()
 	levels := []pgx.TxIsoLevel{pgx.ReadCommitted, pgx.RepeatableRead, pgx.ReadUncommitted, pgx.Serializable}
@@ -219,6 +248,30 @@ func ActExecFailed(t *testing.T, deps Deps, state State) State {
 	return state
 }
 
+func ActSendBatch(t *testing.T, deps Deps, state State) State {
+	t.Helper()
+	deps.shardMocks[state.shardID].EXPECT().
+		SendBatch(state.ctx, state.Expect.Batch).
+		Return(state.Expect.Results)
+	return state
+}
+
+func ActCopyFrom(t *testing.T, deps Deps, state State) State {
+	t.Helper()
+	deps.shardMocks[state.shardID].EXPECT().
+		CopyFrom(state.ctx, state.Expect.Table, state.Expect.Columns, state.Expect.Source).
+		Return(state.Expect.Copied, nil)
+	return state
+}
+
+func ActCopyTo(t *testing.T, deps Deps, state State) State {
+	t.Helper()
+	deps.shardMocks[state.shardID].EXPECT().
+		CopyTo(state.ctx, io.Discard, state.Expect.Query).
+		Return(state.Expect.Tag, nil)
+	return state
+}
+
 func ActTransactional(t *testing.T, deps Deps, state State) State {
 	t.Helper()
 	deps.shardMocks[state.shardID].EXPECT().
@@ -268,3 +321,20 @@ func AssertRow(t *testing.T, state State) {
 	t.Helper()
 	assert.Equal(t, state.Expect.Row, state.Result.Row)
 }
+
+func AssertBatchResults(t *testing.T, state State) {
+	t.Helper()
+	assert.Equal(t, state.Expect.Results, state.Result.Batch)
+}
+
+func AssertCopied(t *testing.T, state State) {
+	t.Helper()
+	require.NoError(t, state.Result.Error)
+	assert.Equal(t, state.Expect.Copied, state.Result.Copied)
+}
+
+func AssertCopyTag(t *testing.T, state State) {
+	t.Helper()
+	require.NoError(t, state.Result.Error)
+	assert.Equal(t, state.Expect.Tag, state.Result.Tag)
+}
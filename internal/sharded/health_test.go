@@ -0,0 +1,62 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pghealth"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type healthPool struct {
+	*MockPool
+	report pghealth.Report
+	rules  []pghealth.Rule
+}
+
+func (p *healthPool) Health(_ context.Context, rules ...pghealth.Rule) pghealth.Report {
+	p.rules = rules
+	return p.report
+}
+
+func TestHive_Health(t *testing.T) {
+	t.Run("should be able to merge reports of shards", func(t *testing.T) {
+		clustered := &healthPool{MockPool: NewMockPool(t), report: pghealth.Report{Ready: true, Nodes: []pghealth.Node{
+			{Role: pghealth.RoleLeader, Reachable: true},
+			{Role: pghealth.RoleFollower},
+		}}}
+		single := NewMockPool(t)
+		row := NewMockRow(t)
+		single.EXPECT().QueryRow(mock.Anything, mock.Anything).Return(row)
+		row.EXPECT().Scan(mock.Anything, mock.Anything).Return(nil)
+		sut := New([]Pool{clustered, single}, nil)
+		first, second := uint(0), uint(1)
+
+		report := sut.Health(context.Background(), pghealth.LeaderReachable())
+
+		assert.Len(t, clustered.rules, 1)
+		assert.Equal(t, pghealth.Report{Ready: true, Nodes: []pghealth.Node{
+			{Role: pghealth.RoleLeader, Shard: &first, Reachable: true},
+			{Role: pghealth.RoleFollower, Shard: &first},
+			{Role: pghealth.RoleSingle, Shard: &second, Reachable: true},
+		}}, report)
+	})
+
+	t.Run("should be able to fail, when any shard isn't ready", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		shard := NewMockPool(t)
+		row := NewMockRow(t)
+		shard.EXPECT().QueryRow(mock.Anything, mock.Anything).Return(row)
+		row.EXPECT().Scan(mock.Anything, mock.Anything).Return(expErr)
+		sut := New([]Pool{&healthPool{MockPool: NewMockPool(t), report: pghealth.Report{Ready: true}}, shard}, nil)
+
+		err := sut.Ping(context.Background())
+
+		require.ErrorIs(t, err, pghealth.ErrNotReady)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
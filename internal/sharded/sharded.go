@@ -3,13 +3,25 @@ package sharded
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
 
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
 	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var ErrCouldNotPickShard = errors.New("could not get shardID or shardingKey from context")
+var (
+	ErrCouldNotPickShard  = errors.New("could not get shardID or shardingKey from context")
+	ErrShardCountMismatch = errors.New("swapped shards must keep count of shards")
+)
 
 type failedRow struct {
 	err error
@@ -19,27 +31,133 @@ func (r failedRow) Scan(_ ...any) error {
 	return r.err
 }
 
+type failedBatchResults struct {
+	err error
+}
+
+func (r failedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r failedBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r failedBatchResults) QueryRow() pgx.Row {
+	return failedRow(r)
+}
+
+func (r failedBatchResults) Close() error {
+	return r.err
+}
+
 type Pool interface {
 	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
 	Begin(ctx context.Context) (pgx.Tx, error)
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
 	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
 }
 
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+type healthChecker interface {
+	Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report
+}
+
 type Picker func(ctx context.Context, key string) uint
 
+// guarded is implemented by shards, which can guard their nodes by circuit breakers themselves, like clusters.
+type guarded interface {
+	Guarded() bool
+}
+
+type Option func(hive *Hive)
+
+// WithCircuitBreaker guards every shard by circuit breaker, so calls to shard, which circuit is open, fail fast.
+// Shards, which guard their nodes themselves, aren't guarded twice, so broken follower doesn't open whole shard.
+func WithCircuitBreaker(cfg pgbreaker.Config) Option {
+	return func(hive *Hive) {
+		hive.breaker = &cfg
+	}
+}
+
 type Hive struct {
-	shards      []Pool
+	shards      atomic.Pointer[shardSet]
 	shardPicker Picker
+	breaker     *pgbreaker.Config
+	gate        pgdrain.Gate
+	swap        sync.Mutex
+}
+
+// shardSet is set of shards, which is retired by swap, once queries in flight on it are done. Guarded are shards
+// behind circuit breakers, they are shards themselves without breaker.
+type shardSet struct {
+	pools   []Pool
+	guarded []Pool
+	gate    pgdrain.Gate
 }
 
-func New(shards []Pool, shardPicker Picker) *Hive {
-	return &Hive{
-		shards:      shards,
+func New(shards []Pool, shardPicker Picker, opts ...Option) *Hive {
+	hive := &Hive{
 		shardPicker: shardPicker,
 	}
+	for _, opt := range opts {
+		opt(hive)
+	}
+	hive.shards.Store(hive.shardSet(shards))
+	return hive
+}
+
+func (s *Hive) shardSet(pools []Pool) *shardSet {
+	set := &shardSet{pools: pools, guarded: pools}
+	if s.breaker != nil {
+		set.guarded = make([]Pool, 0, len(pools))
+		for i, pool := range pools {
+			if shard, ok := pool.(guarded); ok && shard.Guarded() {
+				set.guarded = append(set.guarded, pool)
+				continue
+			}
+			breaker := pgbreaker.New(fmt.Sprintf("shard [%d]", i), *s.breaker)
+			set.guarded = append(set.guarded, pgbreaker.NewGuard(pool, breaker))
+		}
+	}
+	return set
+}
+
+// current returns current set of shards, release must be called, when its shards aren't used anymore.
+func (s *Hive) current() (*shardSet, func()) {
+	for {
+		set := s.shards.Load()
+		if set.gate.Enter() == nil {
+			return set, set.gate.Leave
+		}
+	}
+}
+
+// SwapShards atomically replaces shards of hive, for example to move shard to new host. Count of shards is kept,
+// so shards are picked by the same keys. Queries in flight finish on previous shards, which are shut down or closed,
+// once they are drained, unless they stay in hive.
+func (s *Hive) SwapShards(ctx context.Context, shards []Pool) error {
+	s.swap.Lock()
+	defer s.swap.Unlock()
+	if err := s.gate.Check(); err != nil {
+		return err
+	}
+	if len(shards) != len(s.shards.Load().pools) {
+		return ErrShardCountMismatch
+	}
+	previous := s.shards.Swap(s.shardSet(shards))
+	if err := previous.gate.Shutdown(ctx, pgdrain.Retired(previous.pools, shards)...); err != nil {
+		return fmt.Errorf("can't retire shards: %w", err)
+	}
+	return nil
 }
 
 func (s *Hive) pickShardID(ctx context.Context) (uint, error) {
@@ -52,59 +170,169 @@ func (s *Hive) pickShardID(ctx context.Context) (uint, error) {
 	return 0, ErrCouldNotPickShard
 }
 
-func (s *Hive) getShard(ctx context.Context) (Pool, error) {
+// getShard picks shard of current set, release must be called, when shard isn't used anymore.
+func (s *Hive) getShard(ctx context.Context) (Pool, func(), error) {
 	shardID, err := s.pickShardID(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	return s.shards[shardID], nil
+	set, release := s.current()
+	return set.guarded[shardID], release, nil
 }
 
 func (s *Hive) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
-	shard, err := s.getShard(ctx)
+	if err := s.gate.Check(); err != nil {
+		return nil, err
+	}
+	shard, release, err := s.getShard(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	return shard.BeginTx(ctx, opts)
 }
 
 func (s *Hive) Begin(ctx context.Context) (pgx.Tx, error) {
-	shard, err := s.getShard(ctx)
+	if err := s.gate.Check(); err != nil {
+		return nil, err
+	}
+	shard, release, err := s.getShard(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	return shard.Begin(ctx)
 }
 
+// Shutdown rejects new top-level transactions with pgerr.ErrShutdown, waits for in-flight Transactional calls
+// until ctx is done and shuts down or closes every shard.
+func (s *Hive) Shutdown(ctx context.Context) error {
+	s.swap.Lock()
+	defer s.swap.Unlock()
+	shards := s.shards.Load().pools
+	pools := make([]any, 0, len(shards))
+	for _, shard := range shards {
+		pools = append(pools, shard)
+	}
+	if err := s.gate.Shutdown(ctx, pools...); err != nil {
+		return fmt.Errorf("can't shutdown sharded hive: %w", err)
+	}
+	return nil
+}
+
+// ShutdownProgress reports stage of shutdown and count of in-flight transactions.
+func (s *Hive) ShutdownProgress() pgdrain.Progress {
+	return s.gate.Progress()
+}
+
+// Health checks shards concurrently, rules are applied to nodes of every shard and hive is ready, when every shard
+// is ready.
+func (s *Hive) Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report {
+	set, release := s.current()
+	defer release()
+	reports := make([]pghealth.Report, len(set.pools))
+	var wg sync.WaitGroup
+	for i, shard := range set.pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if checker, ok := shard.(healthChecker); ok {
+				reports[i] = checker.Health(ctx, rules...)
+				return
+			}
+			reports[i] = pghealth.Aggregate([]pghealth.Node{pghealth.Probe(ctx, shard)}, rules...)
+		}()
+	}
+	wg.Wait()
+	return pghealth.Merge(reports...)
+}
+
+// Ping returns pghealth.ErrNotReady with errors of nodes, when any shard isn't ready by rules.
+func (s *Hive) Ping(ctx context.Context, rules ...pghealth.Rule) error {
+	return s.Health(ctx, rules...).Err()
+}
+
+// Acquire takes dedicated connection from shard picked by context.
+func (s *Hive) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	shard, release, err := s.getShard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	db, ok := shard.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	return db.Acquire(ctx)
+}
+
 func (s *Hive) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
-	shard, err := s.getShard(ctx)
+	shard, release, err := s.getShard(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	return shard.Query(ctx, query, args...)
 }
 
 func (s *Hive) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
-	shard, err := s.getShard(ctx)
+	shard, release, err := s.getShard(ctx)
 	if err != nil {
 		return failedRow{err: err}
 	}
+	defer release()
 	return shard.QueryRow(ctx, query, args...)
 }
 
 func (s *Hive) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
-	shard, err := s.getShard(ctx)
+	shard, release, err := s.getShard(ctx)
 	if err != nil {
 		return pgconn.CommandTag{}, err
 	}
+	defer release()
 	return shard.Exec(ctx, query, args...)
 }
 
+func (s *Hive) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	shard, release, err := s.getShard(ctx)
+	if err != nil {
+		return failedBatchResults{err: err}
+	}
+	defer release()
+	return shard.SendBatch(ctx, b)
+}
+
+func (s *Hive) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	shard, release, err := s.getShard(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return shard.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (s *Hive) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	shard, release, err := s.getShard(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+	return shard.CopyTo(ctx, w, query)
+}
+
 func (s *Hive) Transactional(ctx context.Context, fn func(ctx context.Context) error) (err error) {
-	shard, err := s.getShard(ctx)
+	shard, release, err := s.getShard(ctx)
 	if err != nil {
 		return err
 	}
+	defer release()
+	if _, ok := pgcontext.TransactionFrom(ctx); !ok {
+		if err := s.gate.Enter(); err != nil {
+			return err
+		}
+		defer s.gate.Leave()
+	}
 	return shard.Transactional(ctx, fn)
 }
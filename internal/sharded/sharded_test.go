@@ -3,6 +3,7 @@ package sharded
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/jaswdr/faker/v2"
@@ -21,6 +22,21 @@ func TestFailedRow(t *testing.T) {
 	})
 }
 
+func TestFailedBatchResults(t *testing.T) {
+	t.Run("should be able to return err from every method", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		results := failedBatchResults{err: expErr}
+
+		_, err := results.Exec()
+		assert.Equal(t, expErr, err)
+		rows, err := results.Query()
+		assert.Nil(t, rows)
+		assert.Equal(t, expErr, err)
+		assert.Equal(t, expErr, results.QueryRow().Scan())
+		assert.Equal(t, expErr, results.Close())
+	})
+}
+
 func TestNew(t *testing.T) {
 	t.Run("should be able to be able", func(t *testing.T) {
 		mockPool := []Pool{NewMockPool(t), NewMockPool(t), NewMockPool(t)}
@@ -29,7 +45,7 @@ func TestNew(t *testing.T) {
 		}
 		sharded := New(mockPool, shardPicker)
 		require.NotNil(t, sharded)
-		assert.Equal(t, mockPool, sharded.shards)
+		assert.Equal(t, mockPool, sharded.shards.Load().pools)
 		assert.NotNil(t, sharded.shardPicker)
 	})
 }
@@ -438,3 +454,76 @@ func TestSharded_Transactional(t *testing.T) {
 			)
 	})
 }
+
+func TestSharded_SendBatch(t *testing.T) {
+	t.Run("should be able to return error if could not pick shard", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ArrangeBatch).
+			Then(AssertErrorAs(ErrCouldNotPickShard))
+
+		tc.State.Result.Error = tc.SUT.SendBatch(tc.State.ctx, tc.State.Expect.Batch).Close()
+	})
+	t.Run("should be able to get shard by id", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ExtendContextWithShardID, ArrangeBatch).
+			When(ActSendBatch).
+			Then(AssertBatchResults)
+
+		tc.State.Result.Batch = tc.SUT.SendBatch(tc.State.ctx, tc.State.Expect.Batch)
+	})
+	t.Run("should be able to get shard by key", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ExtendContextWithShardingKey, ArrangeBatch).
+			When(ActSendBatch).
+			Then(AssertBatchResults)
+
+		tc.State.Result.Batch = tc.SUT.SendBatch(tc.State.ctx, tc.State.Expect.Batch)
+	})
+}
+
+func TestSharded_CopyFrom(t *testing.T) {
+	t.Run("should be able to return error if could not pick shard", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ArrangeCopySource).
+			Then(AssertErrorAs(ErrCouldNotPickShard))
+
+		_, tc.State.Result.Error = tc.SUT.CopyFrom(
+			tc.State.ctx, tc.State.Expect.Table, tc.State.Expect.Columns, tc.State.Expect.Source,
+		)
+	})
+	t.Run("should be able to get shard by key", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ExtendContextWithShardingKey, ArrangeCopySource).
+			When(ActCopyFrom).
+			Then(AssertCopied)
+
+		tc.State.Result.Copied, tc.State.Result.Error = tc.SUT.CopyFrom(
+			tc.State.ctx, tc.State.Expect.Table, tc.State.Expect.Columns, tc.State.Expect.Source,
+		)
+	})
+}
+
+func TestSharded_CopyTo(t *testing.T) {
+	t.Run("should be able to return error if could not pick shard", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ArrangeQuery).
+			Then(AssertErrorAs(ErrCouldNotPickShard))
+
+		_, tc.State.Result.Error = tc.SUT.CopyTo(tc.State.ctx, io.Discard, tc.State.Expect.Query)
+	})
+	t.Run("should be able to get shard by id", func(t *testing.T) {
+		tc := newTestCase(t)
+		tc.
+			Given(ArrangeContext, ExtendContextWithShardID, ArrangeQuery, ArrangeCopySource).
+			When(ActCopyTo).
+			Then(AssertCopyTag)
+
+		tc.State.Result.Tag, tc.State.Result.Error = tc.SUT.CopyTo(tc.State.ctx, io.Discard, tc.State.Expect.Query)
+	})
+}
@@ -0,0 +1,88 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type shutdownPool struct {
+	*MockPool
+	err   error
+	calls int
+}
+
+func (p *shutdownPool) Shutdown(context.Context) error {
+	p.calls++
+	return p.err
+}
+
+func TestHive_Shutdown(t *testing.T) {
+	t.Run("should be able to shutdown every shard", func(t *testing.T) {
+		shards := []*shutdownPool{{MockPool: NewMockPool(t)}, {MockPool: NewMockPool(t)}}
+		sut := New([]Pool{shards[0], shards[1]}, nil)
+
+		require.NoError(t, sut.Shutdown(context.Background()))
+
+		assert.Equal(t, 1, shards[0].calls)
+		assert.Equal(t, 1, shards[1].calls)
+		assert.Equal(t, pgdrain.Progress{Stage: pgdrain.StageClosed}, sut.ShutdownProgress())
+	})
+
+	t.Run("should be able to fail, when shard can't shutdown", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New([]Pool{&shutdownPool{MockPool: NewMockPool(t), err: expErr}}, nil)
+
+		err := sut.Shutdown(context.Background())
+
+		require.ErrorIs(t, err, expErr)
+		assert.Contains(t, err.Error(), "can't shutdown sharded hive")
+	})
+
+	t.Run("should be able to reject new top-level transactions", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(0))
+		sut := New([]Pool{NewMockPool(t)}, nil)
+		require.NoError(t, sut.Shutdown(context.Background()))
+
+		_, err := sut.Begin(ctx)
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+		_, err = sut.BeginTx(ctx, pgx.TxOptions{})
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+		err = sut.Transactional(ctx, func(context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, pgerr.ErrShutdown)
+	})
+
+	t.Run("should be able to count in-flight transaction and let nested ones in", func(t *testing.T) {
+		shard := NewMockPool(t)
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(0))
+		sut := New([]Pool{shard}, nil)
+		shard.EXPECT().Transactional(ctx, mock.Anything).RunAndReturn(
+			func(ctx context.Context, fn func(ctx context.Context) error) error {
+				assert.Equal(t, 1, sut.ShutdownProgress().InFlight)
+				return fn(pgcontext.With(ctx, pgcontext.WithTransaction(tx)))
+			},
+		)
+		shard.EXPECT().Transactional(mock.Anything, mock.Anything).Return(nil)
+
+		err := sut.Transactional(ctx, func(ctx context.Context) error {
+			return sut.Transactional(ctx, func(context.Context) error {
+				return nil
+			})
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, pgdrain.Progress{Stage: pgdrain.StageServing}, sut.ShutdownProgress())
+	})
+}
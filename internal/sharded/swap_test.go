@@ -0,0 +1,108 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHive_SwapShards(t *testing.T) {
+	t.Run("should be able to route queries to new shards and shut down retired ones", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(1))
+		kept := &shutdownPool{MockPool: NewMockPool(t)}
+		retired := &shutdownPool{MockPool: NewMockPool(t)}
+		added := &shutdownPool{MockPool: NewMockPool(t)}
+		added.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		sut := New([]Pool{kept, retired}, nil)
+
+		require.NoError(t, sut.SwapShards(ctx, []Pool{kept, added}))
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, retired.calls)
+		assert.Zero(t, kept.calls)
+		assert.Zero(t, added.calls)
+	})
+
+	t.Run("should be able to finish in-flight query on previous shards", func(t *testing.T) {
+		ctx := pgcontext.With(context.Background(), pgcontext.WithShardID(0))
+		previous := &shutdownPool{MockPool: NewMockPool(t)}
+		next := NewMockPool(t)
+		entered, release := make(chan struct{}), make(chan struct{})
+		previous.EXPECT().Exec(ctx, "SELECT pg_sleep(1)").RunAndReturn(
+			func(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+				close(entered)
+				<-release
+				return pgconn.CommandTag{}, nil
+			},
+		)
+		next.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		sut := New([]Pool{previous}, nil)
+
+		done := make(chan error)
+		go func() {
+			_, err := sut.Exec(ctx, "SELECT pg_sleep(1)")
+			done <- err
+		}()
+		<-entered
+		swapped := make(chan error)
+		go func() {
+			swapped <- sut.SwapShards(ctx, []Pool{next})
+		}()
+		require.Eventually(t, func() bool {
+			return sut.shards.Load().pools[0] == Pool(next)
+		}, time.Second, time.Millisecond)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Zero(t, previous.calls)
+
+		close(release)
+		require.NoError(t, <-done)
+		require.NoError(t, <-swapped)
+		assert.Equal(t, 1, previous.calls)
+	})
+
+	t.Run("should be able to fail, when previous shards aren't drained in time", func(t *testing.T) {
+		sut := New([]Pool{NewMockPool(t)}, nil)
+		_, release := sut.current()
+		defer release()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := sut.SwapShards(ctx, []Pool{NewMockPool(t)})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "can't retire shards")
+	})
+
+	t.Run("should be able to report error of retired shard", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New([]Pool{&shutdownPool{MockPool: NewMockPool(t), err: expErr}}, nil)
+
+		assert.ErrorIs(t, sut.SwapShards(context.Background(), []Pool{NewMockPool(t)}), expErr)
+	})
+
+	t.Run("should be able to reject changed count of shards", func(t *testing.T) {
+		sut := New([]Pool{NewMockPool(t)}, nil)
+
+		err := sut.SwapShards(context.Background(), []Pool{NewMockPool(t), NewMockPool(t)})
+
+		assert.ErrorIs(t, err, ErrShardCountMismatch)
+	})
+
+	t.Run("should be able to reject swap after shutdown", func(t *testing.T) {
+		sut := New([]Pool{NewMockPool(t)}, nil)
+		require.NoError(t, sut.Shutdown(context.Background()))
+
+		assert.ErrorIs(t, sut.SwapShards(context.Background(), []Pool{NewMockPool(t)}), pgerr.ErrShutdown)
+	})
+}
@@ -0,0 +1,316 @@
+//go:generate mockery
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const settingSearchPath = "search_path"
+
+var (
+	ErrNoTenant      = errors.New("tenant: no tenant in context")
+	ErrInvalidSchema = errors.New("tenant: invalid schema name")
+	ErrTenantSwitch  = errors.New("tenant: can't switch tenant inside transaction")
+
+	schemaPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]{0,62}$`)
+)
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(_ ...any) error {
+	return r.err
+}
+
+type failedBatchResults struct {
+	err error
+}
+
+func (r failedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r failedBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r failedBatchResults) QueryRow() pgx.Row {
+	return failedRow(r)
+}
+
+func (r failedBatchResults) Close() error {
+	return r.err
+}
+
+type Pool interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type SchemaResolver func(ctx context.Context, tenant string) (string, error)
+
+type PoolResolver func(ctx context.Context, tenant string) (Pool, error)
+
+type Config struct {
+	strict         bool
+	shardByTenant  bool
+	sharedSchemas  []string
+	schemaResolver SchemaResolver
+	poolResolver   PoolResolver
+}
+
+type Option func(cfg *Config)
+
+// WithStrict rejects queries without tenant in context.
+func WithStrict() Option {
+	return func(cfg *Config) {
+		cfg.strict = true
+	}
+}
+
+// WithShardingByTenant uses tenant as sharding key, when context has no explicit shard.
+func WithShardingByTenant() Option {
+	return func(cfg *Config) {
+		cfg.shardByTenant = true
+	}
+}
+
+// WithSharedSchemas appends schemas, common for all tenants, to the end of search_path.
+func WithSharedSchemas(schemas ...string) Option {
+	return func(cfg *Config) {
+		cfg.sharedSchemas = append(cfg.sharedSchemas, schemas...)
+	}
+}
+
+func WithSchemaResolver(resolver SchemaResolver) Option {
+	return func(cfg *Config) {
+		cfg.schemaResolver = resolver
+	}
+}
+
+// WithPoolResolver routes statements and transactions, started outside of transaction, to dedicated tenant pool.
+func WithPoolResolver(resolver PoolResolver) Option {
+	return func(cfg *Config) {
+		cfg.poolResolver = resolver
+	}
+}
+
+func New(db Pool, opts ...Option) *Tenancy {
+	cfg := Config{
+		schemaResolver: func(_ context.Context, tenant string) (string, error) {
+			return tenant, nil
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Tenancy{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+type Tenancy struct {
+	db  Pool
+	cfg Config
+}
+
+// scope extends context with tenant search_path and routing, and picks pool for it. Only nested Transactional may
+// switch tenant of transaction from context, as it sets search_path in savepoint, which restores outer search_path on
+// release, other calls in transaction of another tenant, or without tenant search_path, fail with ErrTenantSwitch.
+func (tn *Tenancy) scope(ctx context.Context, nested bool) (context.Context, Pool, error) {
+	tenant, ok := pgcontext.TenantFrom(ctx)
+	if !ok {
+		if tn.cfg.strict {
+			return ctx, nil, ErrNoTenant
+		}
+		return ctx, tn.db, nil
+	}
+
+	searchPath, err := tn.searchPath(ctx, tenant)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if tx, inTx := pgcontext.TransactionFrom(ctx); inTx && !nested && txSearchPath(ctx, tx) != searchPath {
+		return ctx, nil, fmt.Errorf("%w: %q", ErrTenantSwitch, tenant)
+	}
+	ctx = pgcontext.With(ctx, pgcontext.WithSessionVars(map[string]string{settingSearchPath: searchPath}))
+
+	if tn.cfg.shardByTenant {
+		_, hasID := pgcontext.ShardIDFrom(ctx)
+		_, hasKey := pgcontext.ShardingKeyFrom(ctx)
+		if !hasID && !hasKey {
+			ctx = pgcontext.With(ctx, pgcontext.WithShardingKey(tenant))
+		}
+	}
+
+	if _, inTx := pgcontext.TransactionFrom(ctx); inTx || tn.cfg.poolResolver == nil {
+		return ctx, tn.db, nil
+	}
+	pool, err := tn.cfg.poolResolver(ctx, tenant)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("can't resolve pool for tenant %q: %w", tenant, err)
+	}
+	return ctx, pool, nil
+}
+
+// txSearchPath returns search_path of transaction from context: applied by Transactional or set by Begin of tenancy,
+// which transaction doesn't carry applied settings of.
+func txSearchPath(ctx context.Context, tx pgx.Tx) string {
+	applied, _ := pgcontext.AppliedSettingsFrom(ctx)
+	if searchPath, ok := applied[settingSearchPath]; ok {
+		return searchPath
+	}
+	if owned, ok := tx.(*tenantTx); ok {
+		return owned.searchPath
+	}
+	return ""
+}
+
+func (tn *Tenancy) searchPath(ctx context.Context, tenant string) (string, error) {
+	schema, err := tn.cfg.schemaResolver(ctx, tenant)
+	if err != nil {
+		return "", fmt.Errorf("can't resolve schema for tenant %q: %w", tenant, err)
+	}
+
+	schemas := append([]string{schema}, tn.cfg.sharedSchemas...)
+	quoted := make([]string, 0, len(schemas))
+	for _, name := range schemas {
+		if !schemaPattern.MatchString(name) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidSchema, name)
+		}
+		quoted = append(quoted, pgx.Identifier{name}.Sanitize())
+	}
+	return strings.Join(quoted, ", "), nil
+}
+
+func (tn *Tenancy) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tn.prepare(ctx, tx)
+}
+
+func (tn *Tenancy) Begin(ctx context.Context) (pgx.Tx, error) {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tn.prepare(ctx, tx)
+}
+
+// prepare sets search_path for transaction started through Begin, because it bypasses Transactional settings, and
+// returns transaction, which keeps it, so calls of the same tenant pass with transaction put into context.
+func (tn *Tenancy) prepare(ctx context.Context, tx pgx.Tx) (pgx.Tx, error) {
+	vars, ok := pgcontext.SessionVarsFrom(ctx)
+	if !ok {
+		return tx, nil
+	}
+	searchPath, ok := vars[settingSearchPath]
+	if !ok {
+		return tx, nil
+	}
+	if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", settingSearchPath, searchPath); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, fmt.Errorf("can't set tenant search_path: %w", err)
+	}
+	return &tenantTx{Tx: tx, searchPath: searchPath}, nil
+}
+
+// tenantTx is transaction of tenant started through Begin. Its savepoints keep search_path of transaction.
+type tenantTx struct {
+	pgx.Tx
+	searchPath string
+}
+
+func (tx *tenantTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	nested, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantTx{Tx: nested, searchPath: tx.searchPath}, nil
+}
+
+func (tn *Tenancy) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Query(ctx, query, args...)
+}
+
+func (tn *Tenancy) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return failedRow{err: err}
+	}
+	return pool.QueryRow(ctx, query, args...)
+}
+
+func (tn *Tenancy) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pool.Exec(ctx, query, args...)
+}
+
+func (tn *Tenancy) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return failedBatchResults{err: err}
+	}
+	return pool.SendBatch(ctx, b)
+}
+
+func (tn *Tenancy) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	return pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (tn *Tenancy) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	ctx, pool, err := tn.scope(ctx, false)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pool.CopyTo(ctx, w, query)
+}
+
+func (tn *Tenancy) Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error) {
+	ctx, pool, err := tn.scope(ctx, true)
+	if err != nil {
+		return err
+	}
+	return pool.Transactional(ctx, fn)
+}
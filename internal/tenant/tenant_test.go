@@ -0,0 +1,464 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/regular"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func searchPathIs(expected string) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		vars, ok := pgcontext.SessionVarsFrom(ctx)
+		return ok && vars[settingSearchPath] == expected
+	})
+}
+
+func noSearchPath() any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := pgcontext.SessionVarsFrom(ctx)
+		return !ok
+	})
+}
+
+func TestFailedRow(t *testing.T) {
+	t.Run("should be able to scan err", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		assert.Equal(t, expErr, failedRow{err: expErr}.Scan())
+	})
+}
+
+func TestFailedBatchResults(t *testing.T) {
+	t.Run("should be able to return err from every method", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		results := failedBatchResults{err: expErr}
+
+		_, err := results.Exec()
+		assert.Equal(t, expErr, err)
+		rows, err := results.Query()
+		assert.Nil(t, rows)
+		assert.Equal(t, expErr, err)
+		assert.Equal(t, expErr, results.QueryRow().Scan())
+		assert.Equal(t, expErr, results.Close())
+	})
+}
+
+func TestTenancy_Scope(t *testing.T) {
+	t.Run("should be able to pass queries without tenant in non strict mode", func(t *testing.T) {
+		db := NewMockPool(t)
+		sut := New(db)
+		db.EXPECT().Exec(noSearchPath(), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := sut.Exec(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to reject queries without tenant in strict mode", func(t *testing.T) {
+		sut := New(NewMockPool(t), WithStrict())
+		ctx := context.Background()
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrNoTenant)
+		_, err = sut.Query(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrNoTenant)
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), ErrNoTenant)
+		assert.ErrorIs(t, sut.SendBatch(ctx, &pgx.Batch{}).Close(), ErrNoTenant)
+		_, err = sut.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, pgx.CopyFromRows(nil))
+		assert.ErrorIs(t, err, ErrNoTenant)
+		_, err = sut.CopyTo(ctx, io.Discard, "COPY users TO STDOUT")
+		assert.ErrorIs(t, err, ErrNoTenant)
+		_, err = sut.Begin(ctx)
+		assert.ErrorIs(t, err, ErrNoTenant)
+		_, err = sut.BeginTx(ctx, pgx.TxOptions{})
+		assert.ErrorIs(t, err, ErrNoTenant)
+		assert.ErrorIs(t, sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		}), ErrNoTenant)
+	})
+
+	t.Run("should be able to set quoted tenant schema with shared schemas", func(t *testing.T) {
+		db := NewMockPool(t)
+		sut := New(db, WithSharedSchemas("public"))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("Acme"))
+		db.EXPECT().Exec(searchPathIs(`"Acme", "public"`), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to resolve schema by resolver", func(t *testing.T) {
+		db := NewMockPool(t)
+		rows := NewMockRows(t)
+		sut := New(db, WithSchemaResolver(func(_ context.Context, tenant string) (string, error) {
+			return "tenant_" + tenant, nil
+		}))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("42"))
+		db.EXPECT().Query(searchPathIs(`"tenant_42"`), "SELECT 1").Return(rows, nil)
+
+		res, err := sut.Query(ctx, "SELECT 1")
+		require.NoError(t, err)
+		assert.Equal(t, rows, res)
+	})
+
+	t.Run("should be able to fail when schema resolver failed", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(NewMockPool(t), WithSchemaResolver(func(_ context.Context, _ string) (string, error) {
+			return "", expErr
+		}))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("42"))
+
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), expErr)
+	})
+
+	t.Run("should be able to reject invalid schema names", func(t *testing.T) {
+		for _, name := range []string{"", "1tenant", `bad"schema`, "a b", "x;DROP TABLE users"} {
+			sut := New(NewMockPool(t))
+			ctx := pgcontext.With(context.Background(), pgcontext.WithTenant(name))
+
+			_, err := sut.Exec(ctx, "SELECT 1")
+			assert.ErrorIs(t, err, ErrInvalidSchema, name)
+		}
+	})
+
+	t.Run("should be able to reject invalid shared schema names", func(t *testing.T) {
+		sut := New(NewMockPool(t), WithSharedSchemas("public", "bad schema"))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrInvalidSchema)
+	})
+}
+
+func TestTenancy_Sharding(t *testing.T) {
+	t.Run("should be able to use tenant as sharding key", func(t *testing.T) {
+		db := NewMockPool(t)
+		sut := New(db, WithShardingByTenant())
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().Exec(mock.MatchedBy(func(ctx context.Context) bool {
+			key, ok := pgcontext.ShardingKeyFrom(ctx)
+			return ok && key == "acme"
+		}), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to keep explicit shard", func(t *testing.T) {
+		db := NewMockPool(t)
+		sut := New(db, WithShardingByTenant())
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"), pgcontext.WithShardID(1))
+		db.EXPECT().Exec(mock.MatchedBy(func(ctx context.Context) bool {
+			_, ok := pgcontext.ShardingKeyFrom(ctx)
+			return !ok
+		}), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+	})
+}
+
+func TestTenancy_PoolResolver(t *testing.T) {
+	t.Run("should be able to route standalone statements to tenant pool", func(t *testing.T) {
+		db, tenantDB := NewMockPool(t), NewMockPool(t)
+		sut := New(db, WithPoolResolver(func(_ context.Context, tenant string) (Pool, error) {
+			assert.Equal(t, "acme", tenant)
+			return tenantDB, nil
+		}))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		tenantDB.EXPECT().Transactional(searchPathIs(`"acme"`), mock.Anything).Return(nil)
+
+		require.NoError(t, sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		}))
+	})
+
+	t.Run("should be able to stay in context transaction", func(t *testing.T) {
+		db := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(db, WithPoolResolver(func(_ context.Context, _ string) (Pool, error) {
+			return nil, errors.New("should not be called")
+		}))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"), pgcontext.WithTransaction(tx))
+		db.EXPECT().Transactional(searchPathIs(`"acme"`), mock.Anything).Return(nil)
+
+		require.NoError(t, sut.Transactional(ctx, func(ctx context.Context) error {
+			return nil
+		}))
+	})
+
+	t.Run("should be able to fail when pool resolver failed", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(NewMockPool(t), WithPoolResolver(func(_ context.Context, _ string) (Pool, error) {
+			return nil, expErr
+		}))
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestTenancy_Begin(t *testing.T) {
+	t.Run("should be able to set search path after begin", func(t *testing.T) {
+		db := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().Begin(searchPathIs(`"acme"`)).Return(tx, nil)
+		tx.EXPECT().Exec(mock.Anything, mock.Anything, settingSearchPath, `"acme"`).
+			Return(pgconn.CommandTag{}, nil)
+
+		res, err := sut.Begin(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, &tenantTx{Tx: tx, searchPath: `"acme"`}, res)
+	})
+
+	t.Run("should be able to query in transaction of begin put into context", func(t *testing.T) {
+		db := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().Begin(mock.Anything).Return(tx, nil)
+		tx.EXPECT().Exec(mock.Anything, mock.Anything, settingSearchPath, `"acme"`).
+			Return(pgconn.CommandTag{}, nil)
+		res, err := sut.Begin(ctx)
+		require.NoError(t, err)
+		txCtx := pgcontext.With(ctx, pgcontext.WithTransaction(res))
+		db.EXPECT().Query(searchPathIs(`"acme"`), "SELECT 1").Return(nil, nil)
+
+		_, err = sut.Query(txCtx, "SELECT 1")
+		require.NoError(t, err)
+		_, err = sut.Query(pgcontext.With(txCtx, pgcontext.WithTenant("globex")), "SELECT 1")
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+	})
+
+	t.Run("should be able to keep search path in savepoint of begin", func(t *testing.T) {
+		tx, nested := NewMockTx(t), NewMockTx(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := &tenantTx{Tx: tx, searchPath: `"acme"`}
+		tx.EXPECT().Begin(mock.Anything).Return(nested, nil).Once()
+		tx.EXPECT().Begin(mock.Anything).Return(nil, expErr).Once()
+
+		res, err := sut.Begin(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, &tenantTx{Tx: nested, searchPath: `"acme"`}, res)
+		_, err = sut.Begin(context.Background())
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to begin without tenant", func(t *testing.T) {
+		db := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(db)
+		db.EXPECT().BeginTx(noSearchPath(), pgx.TxOptions{}).Return(tx, nil)
+
+		res, err := sut.BeginTx(context.Background(), pgx.TxOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, tx, res)
+	})
+
+	t.Run("should be able to begin with session vars without search path", func(t *testing.T) {
+		db := NewMockPool(t)
+		tx := NewMockTx(t)
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithSessionVars(map[string]string{"app.id": "1"}))
+		db.EXPECT().Begin(ctx).Return(tx, nil)
+
+		res, err := sut.Begin(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, tx, res)
+	})
+
+	t.Run("should be able to rollback when can't set search path", func(t *testing.T) {
+		db := NewMockPool(t)
+		tx := NewMockTx(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(tx, nil)
+		tx.EXPECT().Exec(mock.Anything, mock.Anything, settingSearchPath, `"acme"`).
+			Return(pgconn.CommandTag{}, expErr)
+		tx.EXPECT().Rollback(mock.Anything).Return(nil)
+
+		res, err := sut.BeginTx(ctx, pgx.TxOptions{})
+		assert.ErrorIs(t, err, expErr)
+		assert.Nil(t, res)
+	})
+
+	t.Run("should be able to return begin errors", func(t *testing.T) {
+		db := NewMockPool(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		sut := New(db)
+		db.EXPECT().Begin(mock.Anything).Return(nil, expErr)
+		db.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(nil, expErr)
+
+		_, err := sut.Begin(context.Background())
+		assert.ErrorIs(t, err, expErr)
+		_, err = sut.BeginTx(context.Background(), pgx.TxOptions{})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestTenancy_QueryRow(t *testing.T) {
+	t.Run("should be able to query row with tenant schema", func(t *testing.T) {
+		db := NewMockPool(t)
+		row := NewMockRow(t)
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().QueryRow(searchPathIs(`"acme"`), "SELECT 1").Return(row)
+
+		assert.Equal(t, row, sut.QueryRow(ctx, "SELECT 1"))
+	})
+}
+
+func TestTenancy_SendBatch(t *testing.T) {
+	t.Run("should be able to send batch with tenant schema", func(t *testing.T) {
+		db := NewMockPool(t)
+		results := NewMockBatchResults(t)
+		batch := &pgx.Batch{}
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().SendBatch(searchPathIs(`"acme"`), batch).Return(results)
+
+		assert.Equal(t, results, sut.SendBatch(ctx, batch))
+	})
+}
+
+func TestTenancy_Copy(t *testing.T) {
+	t.Run("should be able to copy from source with tenant schema", func(t *testing.T) {
+		db := NewMockPool(t)
+		src := pgx.CopyFromRows([][]any{{1}})
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().CopyFrom(searchPathIs(`"acme"`), pgx.Identifier{"users"}, []string{"id"}, src).Return(1, nil)
+
+		n, err := sut.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, src)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("should be able to copy to writer with tenant schema", func(t *testing.T) {
+		db := NewMockPool(t)
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		db.EXPECT().CopyTo(searchPathIs(`"acme"`), io.Discard, "COPY users TO STDOUT").
+			Return(pgconn.NewCommandTag("COPY 1"), nil)
+
+		tag, err := sut.CopyTo(ctx, io.Discard, "COPY users TO STDOUT")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), tag.RowsAffected())
+	})
+}
+
+func TestTenancy_Switch(t *testing.T) {
+	t.Run("should be able to reject statements of another tenant inside transaction", func(t *testing.T) {
+		sut := New(NewMockPool(t))
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithTransaction(NewMockTx(t)),
+			pgcontext.WithAppliedSettings(map[string]string{settingSearchPath: `"acme"`}),
+			pgcontext.WithTenant("globex"),
+		)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+		_, err = sut.Query(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+		assert.ErrorIs(t, sut.QueryRow(ctx, "SELECT 1").Scan(), ErrTenantSwitch)
+		assert.ErrorIs(t, sut.SendBatch(ctx, &pgx.Batch{}).Close(), ErrTenantSwitch)
+		_, err = sut.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, pgx.CopyFromRows(nil))
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+		_, err = sut.CopyTo(ctx, io.Discard, "COPY users TO STDOUT")
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+		_, err = sut.Begin(ctx)
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+		_, err = sut.BeginTx(ctx, pgx.TxOptions{})
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+	})
+
+	t.Run("should be able to reject statements of tenant inside external transaction", func(t *testing.T) {
+		sut := New(NewMockPool(t))
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithTransaction(NewMockTx(t)),
+			pgcontext.WithTenant("acme"),
+		)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrTenantSwitch)
+	})
+
+	t.Run("should be able to run statements of same tenant inside transaction", func(t *testing.T) {
+		db := NewMockPool(t)
+		sut := New(db)
+		ctx := pgcontext.With(context.Background(),
+			pgcontext.WithTransaction(NewMockTx(t)),
+			pgcontext.WithAppliedSettings(map[string]string{settingSearchPath: `"acme"`}),
+			pgcontext.WithTenant("acme"),
+		)
+		db.EXPECT().Exec(searchPathIs(`"acme"`), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to restore search path of outer tenant in nested transactions", func(t *testing.T) {
+		pool := NewMockPool(t)
+		sut := New(regular.New(pool))
+		txs := []*MockTx{NewMockTx(t), NewMockTx(t), NewMockTx(t)}
+		var calls []string
+		for i, tx := range txs {
+			tx.EXPECT().Exec(mock.Anything, mock.Anything, settingSearchPath, mock.Anything).
+				RunAndReturn(func(_ context.Context, _ string, args ...interface{}) (pgconn.CommandTag, error) {
+					calls = append(calls, fmt.Sprintf("tx%d %v", i, args[1]))
+					return pgconn.CommandTag{}, nil
+				})
+			tx.EXPECT().Commit(mock.Anything).Return(nil)
+			tx.EXPECT().Rollback(mock.Anything).Return(pgx.ErrTxClosed)
+			if i > 0 {
+				txs[i-1].EXPECT().Begin(mock.Anything).Return(tx, nil)
+			}
+		}
+		pool.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(txs[0], nil)
+		txs[0].EXPECT().Exec(mock.Anything, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		acme := func(ctx context.Context) context.Context {
+			return pgcontext.With(ctx, pgcontext.WithTenant("acme"))
+		}
+		globex := func(ctx context.Context) context.Context {
+			return pgcontext.With(ctx, pgcontext.WithTenant("globex"))
+		}
+
+		err := sut.Transactional(acme(context.Background()), func(ctx context.Context) error {
+			err := sut.Transactional(globex(ctx), func(ctx context.Context) error {
+				return sut.Transactional(acme(ctx), func(context.Context) error {
+					return nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+			_, err = sut.Exec(ctx, "SELECT 1")
+			require.NoError(t, err)
+			_, err = sut.Exec(globex(ctx), "SELECT 1")
+			assert.ErrorIs(t, err, ErrTenantSwitch)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`tx0 "acme"`,
+			`tx1 "globex"`,
+			`tx2 "acme"`,
+			`tx1 "globex"`,
+			`tx0 "acme"`,
+		}, calls)
+	})
+}
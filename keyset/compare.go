@@ -0,0 +1,65 @@
+package keyset
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"time"
+)
+
+func compareOrdered[V cmp.Ordered](a V, b any) (int, error) {
+	other, ok := b.(V)
+	if !ok {
+		return 0, fmt.Errorf("%w: %T and %T", ErrUnsupportedKey, a, b)
+	}
+	return cmp.Compare(a, other), nil
+}
+
+// compare orders values of keys, decoded by pgx from different shards.
+func compare(a, b any) (int, error) {
+	switch a := a.(type) {
+	case int16:
+		return compareOrdered(a, b)
+	case int32:
+		return compareOrdered(a, b)
+	case int64:
+		return compareOrdered(a, b)
+	case float32:
+		return compareOrdered(a, b)
+	case float64:
+		return compareOrdered(a, b)
+	case string:
+		return compareOrdered(a, b)
+	case bool:
+		other, ok := b.(bool)
+		if !ok {
+			break
+		}
+		switch {
+		case a == other:
+			return 0, nil
+		case other:
+			return -1, nil
+		}
+		return 1, nil
+	case time.Time:
+		other, ok := b.(time.Time)
+		if !ok {
+			break
+		}
+		return a.Compare(other), nil
+	case []byte:
+		other, ok := b.([]byte)
+		if !ok {
+			break
+		}
+		return bytes.Compare(a, other), nil
+	case [16]byte:
+		other, ok := b.([16]byte)
+		if !ok {
+			break
+		}
+		return bytes.Compare(a[:], other[:]), nil
+	}
+	return 0, fmt.Errorf("%w: %T and %T", ErrUnsupportedKey, a, b)
+}
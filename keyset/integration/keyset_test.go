@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/keyset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	ID        int64
+	Kind      string
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func TestPaginator_Walk(t *testing.T) {
+	t.Run("should be able to walk all rows in mixed order", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		query := `SELECT id::bigint AS id, CASE WHEN id % 3 = 0 THEN 'a' ELSE 'b' END AS kind,
+	timestamptz '2024-01-01' + (id / 4) * interval '1 hour' AS created_at
+FROM generate_series(1, $1::int) AS id`
+		columns := []keyset.Column{keyset.Asc("kind"), keyset.Desc("created_at"), keyset.Asc("id")}
+
+		expected, err := tcs.SUT.Query(ctx,
+			"SELECT id FROM ("+query+") AS e ORDER BY kind ASC, created_at DESC, id ASC", 20,
+		)
+		require.NoError(t, err)
+		var want []int64
+		for expected.Next() {
+			var id int64
+			require.NoError(t, expected.Scan(&id))
+			want = append(want, id)
+		}
+		require.NoError(t, expected.Err())
+
+		sut := keyset.New[event](query, columns, 3, []byte("secret"))
+		var got []int64
+		cursor := ""
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 10)
+			page, err := sut.Fetch(ctx, tcs.SUT, cursor, 20)
+			require.NoError(t, err)
+			for _, e := range page.Items {
+				got = append(got, e.ID)
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+		assert.Equal(t, want, got)
+	})
+}
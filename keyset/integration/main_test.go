@@ -0,0 +1,40 @@
+// Package integration runs keyset against postgres in container, apart from keyset tests, which don't need it.
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/godepo/elephant/singlepg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, singlepg.DB]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, singlepg.DB] {
+	return groat.New[Deps, State, singlepg.DB](t, func(t *testing.T, deps Deps) singlepg.DB {
+		return singlepg.New(deps.DB)
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, singlepg.DB](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrator(func(context.Context, pgrx.MigratorConfig) error {
+				return nil
+			}),
+		),
+	)
+	os.Exit(suite.Go())
+}
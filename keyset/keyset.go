@@ -0,0 +1,336 @@
+//go:generate mockery
+package keyset
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInvalidCursor  = errors.New("keyset: invalid cursor")
+	ErrNullKey        = errors.New("keyset: sort column is null")
+	ErrMissingColumn  = errors.New("keyset: sort column is missing in result")
+	ErrUnsupportedKey = errors.New("keyset: key can't be compared for merge")
+)
+
+type Querier interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Column is sort column of result. Sort columns together must be unique and not null, so the last one is usually
+// primary key.
+type Column struct {
+	Name string
+	Desc bool
+}
+
+func Asc(name string) Column {
+	return Column{Name: name}
+}
+
+func Desc(name string) Column {
+	return Column{Name: name, Desc: true}
+}
+
+type Page[T any] struct {
+	Items []T
+	// Next is cursor of the next page, empty for the last page.
+	Next string
+}
+
+type Config struct {
+	shards []uint
+}
+
+type Option func(cfg *Config)
+
+// WithShards fetches page from every given shard of sharded.Hive and merges them in global order. Merged keys are
+// compared in Go, so text keys should use "C" collation to match the order of database.
+func WithShards(ids ...uint) Option {
+	return func(cfg *Config) {
+		cfg.shards = ids
+	}
+}
+
+type Paginator[T any] struct {
+	query       string
+	columns     []Column
+	size        int
+	secret      []byte
+	cfg         Config
+	order       string
+	fingerprint []byte
+}
+
+// New paginates result of query by columns. Cursors are signed with secret and bound to query, so client can't
+// forge them or pass cursor of another query.
+func New[T any](query string, columns []Column, size int, secret []byte, opts ...Option) *Paginator[T] {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	order := make([]string, 0, len(columns))
+	for _, col := range columns {
+		direction := "ASC"
+		if col.Desc {
+			direction = "DESC"
+		}
+		order = append(order, pgx.Identifier{col.Name}.Sanitize()+" "+direction)
+	}
+	p := &Paginator[T]{
+		query:   query,
+		columns: columns,
+		size:    size,
+		secret:  secret,
+		cfg:     cfg,
+		order:   strings.Join(order, ", "),
+	}
+	fingerprint := sha256.Sum256([]byte(query + "\x00" + p.order))
+	p.fingerprint = fingerprint[:]
+	return p
+}
+
+// Fetch returns page after cursor, the first one for empty cursor. Args are arguments of base query.
+func (p *Paginator[T]) Fetch(ctx context.Context, db Querier, cursor string, args ...any) (Page[T], error) {
+	var after []string
+	if cursor != "" {
+		var err error
+		if after, err = p.decode(cursor); err != nil {
+			return Page[T]{}, err
+		}
+	}
+	sql, params := p.build(args, after)
+
+	if len(p.cfg.shards) == 0 {
+		entries, err := p.fetch(ctx, db, sql, params)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		return p.page(entries), nil
+	}
+
+	var merged []entry[T]
+	for _, id := range p.cfg.shards {
+		entries, err := p.fetch(pgcontext.With(ctx, pgcontext.WithShardID(id)), db, sql, params)
+		if err != nil {
+			return Page[T]{}, fmt.Errorf("can't fetch page of shard %d: %w", id, err)
+		}
+		merged = append(merged, entries...)
+	}
+	if err := p.merge(merged); err != nil {
+		return Page[T]{}, err
+	}
+	return p.page(merged), nil
+}
+
+// build wraps base query, so predicate and order refer to columns of its result. One extra row shows that there is
+// next page.
+func (p *Paginator[T]) build(args []any, after []string) (string, []any) {
+	var sql strings.Builder
+	sql.WriteString("SELECT * FROM (")
+	sql.WriteString(p.query)
+	sql.WriteString(") AS keyset_page")
+	params := args
+	if after != nil {
+		params = make([]any, 0, len(args)+len(after))
+		params = append(params, args...)
+		for _, key := range after {
+			params = append(params, key)
+		}
+		sql.WriteString(" WHERE ")
+		sql.WriteString(p.predicate(len(args) + 1))
+	}
+	sql.WriteString(" ORDER BY ")
+	sql.WriteString(p.order)
+	sql.WriteString(" LIMIT ")
+	sql.WriteString(strconv.Itoa(p.size + 1))
+	return sql.String(), params
+}
+
+// predicate selects rows after key. Columns of the same direction are compared as row, which can use index,
+// otherwise comparison is expanded column by column.
+func (p *Paginator[T]) predicate(first int) string {
+	names := make([]string, len(p.columns))
+	params := make([]string, len(p.columns))
+	sameDirection := true
+	for i, col := range p.columns {
+		names[i] = pgx.Identifier{col.Name}.Sanitize()
+		params[i] = "$" + strconv.Itoa(first+i)
+		sameDirection = sameDirection && col.Desc == p.columns[0].Desc
+	}
+	if sameDirection {
+		return fmt.Sprintf("(%s) %s (%s)",
+			strings.Join(names, ", "), operator(p.columns[0]), strings.Join(params, ", "),
+		)
+	}
+
+	terms := make([]string, 0, len(p.columns))
+	for i, col := range p.columns {
+		conds := make([]string, 0, i+1)
+		for j := range i {
+			conds = append(conds, names[j]+" = "+params[j])
+		}
+		conds = append(conds, names[i]+" "+operator(col)+" "+params[i])
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+func operator(col Column) string {
+	if col.Desc {
+		return "<"
+	}
+	return ">"
+}
+
+type entry[T any] struct {
+	item T
+	keys []any
+	text []string
+}
+
+func (p *Paginator[T]) fetch(ctx context.Context, db Querier, sql string, params []any) ([]entry[T], error) {
+	rows, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var typeMap *pgtype.Map
+	if conn := rows.Conn(); conn != nil {
+		typeMap = conn.TypeMap()
+	} else {
+		typeMap = pgtype.NewMap()
+	}
+	var indexes []int
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entry[T], error) {
+		fields := row.FieldDescriptions()
+		if indexes == nil {
+			found, err := p.indexes(fields)
+			if err != nil {
+				return entry[T]{}, err
+			}
+			indexes = found
+		}
+		values, err := row.Values()
+		if err != nil {
+			return entry[T]{}, err
+		}
+		e := entry[T]{keys: make([]any, len(indexes)), text: make([]string, len(indexes))}
+		for i, index := range indexes {
+			if values[index] == nil {
+				return entry[T]{}, fmt.Errorf("%w: %s", ErrNullKey, p.columns[i].Name)
+			}
+			text, err := typeMap.Encode(fields[index].DataTypeOID, pgtype.TextFormatCode, values[index], nil)
+			if err != nil {
+				return entry[T]{}, fmt.Errorf("can't encode key %s: %w", p.columns[i].Name, err)
+			}
+			e.keys[i] = values[index]
+			e.text[i] = string(text)
+		}
+		e.item, err = pgx.RowToStructByName[T](row)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't collect page: %w", err)
+	}
+	return entries, nil
+}
+
+func (p *Paginator[T]) indexes(fields []pgconn.FieldDescription) ([]int, error) {
+	indexes := make([]int, 0, len(p.columns))
+	for _, col := range p.columns {
+		index := -1
+		for i, field := range fields {
+			if field.Name == col.Name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, col.Name)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+func (p *Paginator[T]) merge(entries []entry[T]) error {
+	var err error
+	sort.SliceStable(entries, func(i, j int) bool {
+		for k, col := range p.columns {
+			c, cmpErr := compare(entries[i].keys[k], entries[j].keys[k])
+			if cmpErr != nil {
+				err = cmpErr
+				return false
+			}
+			if c != 0 {
+				return (c < 0) != col.Desc
+			}
+		}
+		return false
+	})
+	return err
+}
+
+func (p *Paginator[T]) page(entries []entry[T]) Page[T] {
+	var page Page[T]
+	if len(entries) > p.size {
+		entries = entries[:p.size]
+		page.Next = p.encode(entries[len(entries)-1].text)
+	}
+	page.Items = make([]T, 0, len(entries))
+	for _, e := range entries {
+		page.Items = append(page.Items, e.item)
+	}
+	return page
+}
+
+func (p *Paginator[T]) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(p.fingerprint)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (p *Paginator[T]) encode(keys []string) string {
+	data, _ := json.Marshal(keys) // slice of strings is always encoded.
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(p.sign(data))
+}
+
+func (p *Paginator[T]) decode(cursor string) ([]string, error) {
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	if !hmac.Equal(mac, p.sign(data)) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidCursor)
+	}
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil || len(keys) != len(p.columns) {
+		return nil, fmt.Errorf("%w: malformed keys", ErrInvalidCursor)
+	}
+	return keys, nil
+}
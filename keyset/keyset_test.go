@@ -0,0 +1,298 @@
+package keyset
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const baseQuery = "SELECT id, name FROM users WHERE org_id = $1"
+
+var secret = []byte("secret")
+
+type item struct {
+	ID   int64
+	Name string
+}
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+var fields = []pgconn.FieldDescription{
+	{Name: "id", DataTypeOID: pgtype.Int8OID},
+	{Name: "name", DataTypeOID: pgtype.TextOID},
+}
+
+// rowsOf feeds items as rows of id and name columns.
+func rowsOf(t *testing.T, items ...item) *MockRows {
+	t.Helper()
+	rows := NewMockRows(t)
+	rows.EXPECT().Conn().Return(nil)
+	rows.EXPECT().FieldDescriptions().Return(fields).Maybe()
+	for _, it := range items {
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Values().Return([]any{it.ID, it.Name}, nil).Once()
+		rows.EXPECT().Scan(mock.Anything, mock.Anything).RunAndReturn(func(dest ...any) error {
+			*dest[0].(*int64) = it.ID
+			*dest[1].(*string) = it.Name
+			return nil
+		}).Once()
+	}
+	rows.EXPECT().Next().Return(false).Maybe()
+	rows.EXPECT().Err().Return(nil).Maybe()
+	rows.EXPECT().Close().Return()
+	return rows
+}
+
+func items(ids ...int64) []item {
+	out := make([]item, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, item{ID: id, Name: "user"})
+	}
+	return out
+}
+
+func TestPaginator_build(t *testing.T) {
+	t.Run("should be able to build the first page", func(t *testing.T) {
+		sut := New[item](baseQuery, []Column{Desc("created_at"), Desc("id")}, 10, secret)
+
+		sql, params := sut.build([]any{1}, nil)
+		assert.Equal(t,
+			`SELECT * FROM (`+baseQuery+`) AS keyset_page ORDER BY "created_at" DESC, "id" DESC LIMIT 11`, sql,
+		)
+		assert.Equal(t, []any{1}, params)
+	})
+	t.Run("should be able to compare columns of the same direction as row", func(t *testing.T) {
+		sut := New[item](baseQuery, []Column{Asc("name"), Asc("id")}, 10, secret)
+
+		sql, params := sut.build([]any{1}, []string{"user", "5"})
+		assert.Contains(t, sql, `WHERE ("name", "id") > ($2, $3) ORDER BY "name" ASC, "id" ASC`)
+		assert.Equal(t, []any{1, "user", "5"}, params)
+
+		sut = New[item](baseQuery, []Column{Desc("id")}, 10, secret)
+		sql, _ = sut.build(nil, []string{"5"})
+		assert.Contains(t, sql, `WHERE ("id") < ($1)`)
+	})
+	t.Run("should be able to expand mixed directions", func(t *testing.T) {
+		sut := New[item](baseQuery, []Column{Asc("a"), Desc("b"), Asc("c")}, 10, secret)
+
+		sql, _ := sut.build([]any{1}, []string{"1", "2", "3"})
+		assert.Contains(t, sql,
+			`WHERE (("a" > $2) OR ("a" = $2 AND "b" < $3) OR ("a" = $2 AND "b" = $3 AND "c" > $4))`,
+		)
+	})
+}
+
+func TestPaginator_Fetch(t *testing.T) {
+	columns := []Column{Asc("id")}
+
+	t.Run("should be able to walk pages", func(t *testing.T) {
+		sut := New[item](baseQuery, columns, 2, secret)
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything, 1).Return(rowsOf(t, items(1, 2, 3)...), nil).Once()
+
+		page, err := sut.Fetch(context.Background(), db, "", 1)
+		require.NoError(t, err)
+		assert.Equal(t, items(1, 2), page.Items)
+		require.NotEmpty(t, page.Next)
+
+		db.EXPECT().Query(mock.Anything, mock.MatchedBy(func(sql string) bool {
+			return strings.Contains(sql, `WHERE ("id") > ($2)`)
+		}), 1, "2").Return(rowsOf(t, items(3)...), nil).Once()
+		page, err = sut.Fetch(context.Background(), db, page.Next, 1)
+		require.NoError(t, err)
+		assert.Equal(t, items(3), page.Items)
+		assert.Empty(t, page.Next)
+	})
+
+	t.Run("should be able to reject invalid cursors", func(t *testing.T) {
+		sut := New[item](baseQuery, columns, 2, secret)
+		valid := sut.encode([]string{"2"})
+		encoded, _, _ := strings.Cut(valid, ".")
+
+		for _, cursor := range []string{
+			"no-signature",
+			"!." + strings.Split(valid, ".")[1],
+			encoded + ".!",
+			New[item](baseQuery, columns, 2, []byte("other")).encode([]string{"2"}),
+			New[item]("SELECT 1", columns, 2, secret).encode([]string{"2"}),
+			sut.encode([]string{"2", "3"}),
+		} {
+			_, err := sut.Fetch(context.Background(), NewMockQuerier(t), cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+		}
+	})
+
+	t.Run("should be able to return error of query", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything).Return(nil, expErr)
+
+		_, err := New[item](baseQuery, columns, 2, secret).Fetch(context.Background(), db, "")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail, when sort column is missing", func(t *testing.T) {
+		rows := NewMockRows(t)
+		rows.EXPECT().Conn().Return(nil)
+		rows.EXPECT().FieldDescriptions().Return(fields)
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Close().Return()
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything).Return(rows, nil)
+
+		_, err := New[item](baseQuery, []Column{Asc("created_at")}, 2, secret).Fetch(context.Background(), db, "")
+		assert.ErrorIs(t, err, ErrMissingColumn)
+	})
+
+	t.Run("should be able to fail, when row can't be decoded", func(t *testing.T) {
+		expErr := newError()
+		rows := NewMockRows(t)
+		rows.EXPECT().Conn().Return(nil)
+		rows.EXPECT().FieldDescriptions().Return(fields)
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Values().Return(nil, expErr)
+		rows.EXPECT().Close().Return()
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything).Return(rows, nil)
+
+		_, err := New[item](baseQuery, columns, 2, secret).Fetch(context.Background(), db, "")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail on null and not encodable keys", func(t *testing.T) {
+		for value, expErr := range map[any]error{nil: ErrNullKey, struct{}{}: errors.New("can't encode key")} {
+			rows := NewMockRows(t)
+			rows.EXPECT().Conn().Return(nil)
+			rows.EXPECT().FieldDescriptions().Return(fields)
+			rows.EXPECT().Next().Return(true).Once()
+			rows.EXPECT().Values().Return([]any{value, "name"}, nil)
+			rows.EXPECT().Close().Return()
+			db := NewMockQuerier(t)
+			db.EXPECT().Query(mock.Anything, mock.Anything).Return(rows, nil)
+
+			_, err := New[item](baseQuery, columns, 2, secret).Fetch(context.Background(), db, "")
+			require.Error(t, err)
+			if errors.Is(expErr, ErrNullKey) {
+				assert.ErrorIs(t, err, ErrNullKey)
+				continue
+			}
+			assert.ErrorContains(t, err, expErr.Error())
+		}
+	})
+
+	t.Run("should be able to merge pages of shards", func(t *testing.T) {
+		sut := New[item](baseQuery, []Column{Desc("name"), Asc("id")}, 3, secret, WithShards(1, 2))
+		shard := func(id uint) any {
+			return mock.MatchedBy(func(ctx context.Context) bool {
+				shardID, ok := pgcontext.ShardIDFrom(ctx)
+				return ok && shardID == id
+			})
+		}
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(shard(1), mock.Anything).
+			Return(rowsOf(t, item{ID: 1, Name: "b"}, item{ID: 4, Name: "b"}, item{ID: 2, Name: "a"}), nil)
+		db.EXPECT().Query(shard(2), mock.Anything).
+			Return(rowsOf(t, item{ID: 5, Name: "c"}, item{ID: 3, Name: "b"}), nil)
+
+		page, err := sut.Fetch(context.Background(), db, "")
+		require.NoError(t, err)
+		assert.Equal(t, []item{{ID: 5, Name: "c"}, {ID: 1, Name: "b"}, {ID: 3, Name: "b"}}, page.Items)
+		keys, err := sut.decode(page.Next)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "3"}, keys)
+	})
+
+	t.Run("should be able to return error of shard", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything).Return(nil, expErr)
+
+		_, err := New[item](baseQuery, columns, 2, secret, WithShards(1)).Fetch(context.Background(), db, "")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail merge of not comparable keys", func(t *testing.T) {
+		rows := NewMockRows(t)
+		rows.EXPECT().Conn().Return(nil)
+		rows.EXPECT().FieldDescriptions().Return(fields)
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Values().Return([]any{pgtype.Numeric{}, "name"}, nil)
+		rows.EXPECT().Scan(mock.Anything, mock.Anything).Return(nil)
+		rows.EXPECT().Next().Return(false)
+		rows.EXPECT().Err().Return(nil)
+		rows.EXPECT().Close().Return()
+		db := NewMockQuerier(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything).Return(rows, nil).Once()
+		db.EXPECT().Query(mock.Anything, mock.Anything).Return(rowsOf(t, items(1)...), nil).Once()
+
+		_, err := New[item](baseQuery, []Column{{Name: "id", Desc: true}}, 2, secret, WithShards(1, 2)).
+			Fetch(context.Background(), db, "")
+		assert.ErrorIs(t, err, ErrUnsupportedKey)
+	})
+}
+
+func TestPaginator_merge(t *testing.T) {
+	t.Run("should be able to keep order of equal keys", func(t *testing.T) {
+		sut := New[item](baseQuery, []Column{Asc("id")}, 2, secret)
+		entries := []entry[item]{
+			{item: item{ID: 1, Name: "first"}, keys: []any{int64(1)}},
+			{item: item{ID: 1, Name: "second"}, keys: []any{int64(1)}},
+		}
+
+		require.NoError(t, sut.merge(entries))
+		assert.Equal(t, "first", entries[0].item.Name)
+		assert.Equal(t, "second", entries[1].item.Name)
+	})
+}
+
+func TestCompare(t *testing.T) {
+	t.Run("should be able to compare supported types", func(t *testing.T) {
+		for _, tc := range []struct {
+			a, b any
+			exp  int
+		}{
+			{a: int16(1), b: int16(2), exp: -1},
+			{a: int32(2), b: int32(1), exp: 1},
+			{a: int64(1), b: int64(1), exp: 0},
+			{a: float32(1), b: float32(2), exp: -1},
+			{a: 2.5, b: 1.5, exp: 1},
+			{a: "a", b: "b", exp: -1},
+			{a: true, b: true, exp: 0},
+			{a: false, b: true, exp: -1},
+			{a: true, b: false, exp: 1},
+			{a: []byte{1}, b: []byte{2}, exp: -1},
+			{a: [16]byte{2}, b: [16]byte{1}, exp: 1},
+			{a: time.Unix(1, 0), b: time.Unix(2, 0), exp: -1},
+		} {
+			c, err := compare(tc.a, tc.b)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, c, "%v and %v", tc.a, tc.b)
+		}
+	})
+	t.Run("should be able to fail on different and unsupported types", func(t *testing.T) {
+		for _, b := range []any{int64(1), "a"} {
+			for _, a := range []any{int64(1), true, pgtype.Numeric{}, []byte{}, [16]byte{}, time.Time{}, "a"} {
+				if a == b {
+					continue
+				}
+				_, err := compare(a, b)
+				assert.ErrorIs(t, err, ErrUnsupportedKey)
+			}
+		}
+	})
+}
+
+var _ pgx.Rows = (*MockRows)(nil)
@@ -0,0 +1,150 @@
+package elephant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const unlockTimeout = 5 * time.Second
+
+var ErrLockNotAcquired = errors.New("advisory lock is not acquired")
+
+// LockDB is satisfied by singlepg, clusterpg and shardedpg databases.
+type LockDB interface {
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type lockQuerier interface {
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// LockKey identifies advisory lock in bigint key space.
+type LockKey struct {
+	id   int64
+	name string
+}
+
+func LockID(id int64) LockKey {
+	return LockKey{id: id}
+}
+
+// LockName hashes name with FNV-1a into bigint key space. In sharded topology the lock is taken on the shard of
+// name, unless context already points to shard.
+func LockName(name string) LockKey {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return LockKey{id: int64(h.Sum64()), name: name} //nolint:gosec // overflow is intended, key space is bigint.
+}
+
+func (k LockKey) ID() int64 {
+	return k.id
+}
+
+type lockQueries struct {
+	lock string
+	try  string
+}
+
+var (
+	sessionLock = lockQueries{lock: "SELECT pg_advisory_lock($1)", try: "SELECT pg_try_advisory_lock($1)"}
+	xactLock    = lockQueries{lock: "SELECT pg_advisory_xact_lock($1)", try: "SELECT pg_try_advisory_xact_lock($1)"}
+)
+
+func (q lockQueries) take(ctx context.Context, db lockQuerier, key LockKey, try bool) error {
+	if !try {
+		if _, err := db.Exec(ctx, q.lock, key.id); err != nil {
+			return fmt.Errorf("can't take advisory lock: %w", pgerr.Map(ctx, err))
+		}
+		return nil
+	}
+	var ok bool
+	if err := db.QueryRow(ctx, q.try, key.id).Scan(&ok); err != nil {
+		return fmt.Errorf("can't try advisory lock: %w", pgerr.Map(ctx, err))
+	}
+	if !ok {
+		return ErrLockNotAcquired
+	}
+	return nil
+}
+
+// lockScope routes lock to leader and to the shard of named key.
+func lockScope(ctx context.Context, key LockKey) context.Context {
+	ctx = pgcontext.WithCanWrite(ctx)
+	if key.name == "" {
+		return ctx
+	}
+	_, hasID := pgcontext.ShardIDFrom(ctx)
+	_, hasKey := pgcontext.ShardingKeyFrom(ctx)
+	if !hasID && !hasKey {
+		ctx = pgcontext.With(ctx, pgcontext.WithShardingKey(key.name))
+	}
+	return ctx
+}
+
+// WithAdvisoryXactLock runs fn in transaction holding pg_advisory_xact_lock. It joins transaction from context,
+// then the lock is held until the outer transaction ends.
+func WithAdvisoryXactLock(ctx context.Context, db LockDB, key LockKey, fn func(ctx context.Context) error) error {
+	return withXactLock(ctx, db, key, false, fn)
+}
+
+// TryWithAdvisoryXactLock is WithAdvisoryXactLock, which returns ErrLockNotAcquired instead of waiting for lock.
+func TryWithAdvisoryXactLock(ctx context.Context, db LockDB, key LockKey, fn func(ctx context.Context) error) error {
+	return withXactLock(ctx, db, key, true, fn)
+}
+
+func withXactLock(ctx context.Context, db LockDB, key LockKey, try bool, fn func(ctx context.Context) error) error {
+	return db.Transactional(lockScope(ctx, key), func(ctx context.Context) error {
+		if err := xactLock.take(ctx, db, key, try); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
+
+// WithAdvisoryLock runs fn holding session pg_advisory_lock on dedicated connection, so the lock doesn't depend on
+// transaction from context and is released right after fn returns.
+func WithAdvisoryLock(ctx context.Context, db LockDB, key LockKey, fn func(ctx context.Context) error) error {
+	return withSessionLock(ctx, db, key, false, fn)
+}
+
+// TryWithAdvisoryLock is WithAdvisoryLock, which returns ErrLockNotAcquired instead of waiting for lock.
+func TryWithAdvisoryLock(ctx context.Context, db LockDB, key LockKey, fn func(ctx context.Context) error) error {
+	return withSessionLock(ctx, db, key, true, fn)
+}
+
+func withSessionLock(
+	ctx context.Context, db LockDB, key LockKey, try bool, fn func(ctx context.Context) error,
+) (out error) {
+	conn, err := db.Acquire(lockScope(ctx, key))
+	if err != nil {
+		return fmt.Errorf("can't acquire connection for advisory lock: %w", err)
+	}
+	defer conn.Release()
+
+	if err := sessionLock.take(ctx, conn, key, try); err != nil {
+		return err
+	}
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlockTimeout)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, "SELECT pg_advisory_unlock($1)", key.id); err != nil {
+			// connection can't return to pool holding the lock.
+			_ = conn.Conn().Close(unlockCtx)
+			out = errors.Join(out, fmt.Errorf("can't release advisory lock: %w", err))
+		}
+	}()
+	return fn(ctx)
+}
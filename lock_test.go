@@ -0,0 +1,175 @@
+package elephant
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func tryRow(t *testing.T, acquired bool, err error) *MockRow {
+	t.Helper()
+	row := NewMockRow(t)
+	row.EXPECT().Scan(mock.Anything).RunAndReturn(func(dest ...any) error {
+		if err != nil {
+			return err
+		}
+		*dest[0].(*bool) = acquired
+		return nil
+	})
+	return row
+}
+
+func TestLockName(t *testing.T) {
+	t.Run("should be able to hash name into stable key", func(t *testing.T) {
+		name := faker.New().RandomStringWithLength(10)
+		assert.Equal(t, LockName(name).ID(), LockName(name).ID())
+		assert.NotEqual(t, LockName(name+"x").ID(), LockName(name).ID())
+	})
+	t.Run("should be able to hash known name", func(t *testing.T) {
+		assert.Equal(t, int64(-3750763034362895579), LockName("").ID())
+	})
+	t.Run("should be able to use id as is", func(t *testing.T) {
+		id := faker.New().Int64()
+		assert.Equal(t, id, LockID(id).ID())
+	})
+}
+
+func TestLockScope(t *testing.T) {
+	t.Run("should be able to route named lock to leader and shard of name", func(t *testing.T) {
+		name := faker.New().RandomStringWithLength(10)
+		ctx := lockScope(context.Background(), LockName(name))
+		assert.True(t, pgcontext.CanWriteFrom(ctx))
+		key, ok := pgcontext.ShardingKeyFrom(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, name, key)
+	})
+	t.Run("should be able to keep shard from context", func(t *testing.T) {
+		ctx := lockScope(With(context.Background(), WithShardID(1)), LockName("name"))
+		_, ok := pgcontext.ShardingKeyFrom(ctx)
+		assert.False(t, ok)
+
+		ctx = lockScope(With(context.Background(), WithShardingKey("key")), LockName("name"))
+		key, _ := pgcontext.ShardingKeyFrom(ctx)
+		assert.Equal(t, "key", key)
+	})
+	t.Run("should be able to route numeric lock only to leader", func(t *testing.T) {
+		ctx := lockScope(context.Background(), LockID(1))
+		assert.True(t, pgcontext.CanWriteFrom(ctx))
+		_, ok := pgcontext.ShardingKeyFrom(ctx)
+		assert.False(t, ok)
+	})
+}
+
+func TestWithAdvisoryXactLock(t *testing.T) {
+	key := LockName("jobs")
+
+	inTransaction := func(db *MockLockDB) {
+		db.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+				assert.True(t, pgcontext.CanWriteFrom(ctx))
+				shardingKey, _ := pgcontext.ShardingKeyFrom(ctx)
+				assert.Equal(t, "jobs", shardingKey)
+				return fn(ctx)
+			})
+	}
+
+	t.Run("should be able to run fn holding lock", func(t *testing.T) {
+		db := NewMockLockDB(t)
+		inTransaction(db)
+		db.EXPECT().Exec(mock.Anything, "SELECT pg_advisory_xact_lock($1)", key.ID()).
+			Return(pgconn.CommandTag{}, nil)
+		called := false
+		require.NoError(t, WithAdvisoryXactLock(context.Background(), db, key, func(ctx context.Context) error {
+			called = true
+			return nil
+		}))
+		assert.True(t, called)
+	})
+	t.Run("should be able to return error of fn", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockLockDB(t)
+		inTransaction(db)
+		db.EXPECT().Exec(mock.Anything, "SELECT pg_advisory_xact_lock($1)", key.ID()).
+			Return(pgconn.CommandTag{}, nil)
+		err := WithAdvisoryXactLock(context.Background(), db, key, func(ctx context.Context) error {
+			return expErr
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+	t.Run("should be able to map lock timeout", func(t *testing.T) {
+		db := NewMockLockDB(t)
+		inTransaction(db)
+		db.EXPECT().Exec(mock.Anything, "SELECT pg_advisory_xact_lock($1)", key.ID()).
+			Return(pgconn.CommandTag{}, &pgconn.PgError{Code: "55P03"})
+		ctx := With(context.Background(), WithLockTimeout(time.Second))
+		err := WithAdvisoryXactLock(ctx, db, key, func(ctx context.Context) error {
+			t.Fatal("fn must not be called")
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrLockTimeout)
+	})
+	t.Run("should be able to try lock", func(t *testing.T) {
+		db := NewMockLockDB(t)
+		inTransaction(db)
+		db.EXPECT().QueryRow(mock.Anything, "SELECT pg_try_advisory_xact_lock($1)", key.ID()).
+			Return(tryRow(t, true, nil))
+		called := false
+		require.NoError(t, TryWithAdvisoryXactLock(context.Background(), db, key, func(ctx context.Context) error {
+			called = true
+			return nil
+		}))
+		assert.True(t, called)
+	})
+	t.Run("should be able to return error, when lock is taken", func(t *testing.T) {
+		db := NewMockLockDB(t)
+		inTransaction(db)
+		db.EXPECT().QueryRow(mock.Anything, "SELECT pg_try_advisory_xact_lock($1)", key.ID()).
+			Return(tryRow(t, false, nil))
+		err := TryWithAdvisoryXactLock(context.Background(), db, key, func(ctx context.Context) error {
+			t.Fatal("fn must not be called")
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrLockNotAcquired)
+	})
+	t.Run("should be able to return error of try query", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockLockDB(t)
+		inTransaction(db)
+		db.EXPECT().QueryRow(mock.Anything, "SELECT pg_try_advisory_xact_lock($1)", key.ID()).
+			Return(tryRow(t, false, expErr))
+		err := TryWithAdvisoryXactLock(context.Background(), db, key, func(ctx context.Context) error {
+			t.Fatal("fn must not be called")
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestWithAdvisoryLock(t *testing.T) {
+	t.Run("should be able to return error, when connection can't be acquired", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockLockDB(t)
+		db.EXPECT().Acquire(mock.Anything).RunAndReturn(func(ctx context.Context) (*pgxpool.Conn, error) {
+			assert.True(t, pgcontext.CanWriteFrom(ctx))
+			return nil, expErr
+		})
+		err := WithAdvisoryLock(context.Background(), db, LockID(1), func(ctx context.Context) error {
+			t.Fatal("fn must not be called")
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
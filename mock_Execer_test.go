@@ -0,0 +1,106 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package elephant
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+)
+
+// MockExecer is an autogenerated mock type for the Execer type
+type MockExecer struct {
+	mock.Mock
+}
+
+type MockExecer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockExecer) EXPECT() *MockExecer_Expecter {
+	return &MockExecer_Expecter{mock: &_m.Mock}
+}
+
+// Exec provides a mock function with given fields: ctx, query, args
+func (_m *MockExecer) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exec")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, query, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgconn.CommandTag); ok {
+		r0 = rf(ctx, query, args...)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...interface{}) error); ok {
+		r1 = rf(ctx, query, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockExecer_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
+type MockExecer_Exec_Call struct {
+	*mock.Call
+}
+
+// Exec is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - args ...interface{}
+func (_e *MockExecer_Expecter) Exec(ctx interface{}, query interface{}, args ...interface{}) *MockExecer_Exec_Call {
+	return &MockExecer_Exec_Call{Call: _e.mock.On("Exec",
+		append([]interface{}{ctx, query}, args...)...)}
+}
+
+func (_c *MockExecer_Exec_Call) Run(run func(ctx context.Context, query string, args ...interface{})) *MockExecer_Exec_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockExecer_Exec_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockExecer_Exec_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockExecer_Exec_Call) RunAndReturn(run func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)) *MockExecer_Exec_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockExecer creates a new instance of MockExecer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockExecer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockExecer {
+	mock := &MockExecer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,275 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package elephant
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+
+	pgx "github.com/jackc/pgx/v5"
+
+	pgxpool "github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MockLockDB is an autogenerated mock type for the LockDB type
+type MockLockDB struct {
+	mock.Mock
+}
+
+type MockLockDB_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockLockDB) EXPECT() *MockLockDB_Expecter {
+	return &MockLockDB_Expecter{mock: &_m.Mock}
+}
+
+// Acquire provides a mock function with given fields: ctx
+func (_m *MockLockDB) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Acquire")
+	}
+
+	var r0 *pgxpool.Conn
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*pgxpool.Conn, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *pgxpool.Conn); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pgxpool.Conn)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLockDB_Acquire_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Acquire'
+type MockLockDB_Acquire_Call struct {
+	*mock.Call
+}
+
+// Acquire is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockLockDB_Expecter) Acquire(ctx interface{}) *MockLockDB_Acquire_Call {
+	return &MockLockDB_Acquire_Call{Call: _e.mock.On("Acquire", ctx)}
+}
+
+func (_c *MockLockDB_Acquire_Call) Run(run func(ctx context.Context)) *MockLockDB_Acquire_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockLockDB_Acquire_Call) Return(_a0 *pgxpool.Conn, _a1 error) *MockLockDB_Acquire_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLockDB_Acquire_Call) RunAndReturn(run func(context.Context) (*pgxpool.Conn, error)) *MockLockDB_Acquire_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Exec provides a mock function with given fields: ctx, query, args
+func (_m *MockLockDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exec")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, query, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgconn.CommandTag); ok {
+		r0 = rf(ctx, query, args...)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...interface{}) error); ok {
+		r1 = rf(ctx, query, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLockDB_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
+type MockLockDB_Exec_Call struct {
+	*mock.Call
+}
+
+// Exec is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - args ...interface{}
+func (_e *MockLockDB_Expecter) Exec(ctx interface{}, query interface{}, args ...interface{}) *MockLockDB_Exec_Call {
+	return &MockLockDB_Exec_Call{Call: _e.mock.On("Exec",
+		append([]interface{}{ctx, query}, args...)...)}
+}
+
+func (_c *MockLockDB_Exec_Call) Run(run func(ctx context.Context, query string, args ...interface{})) *MockLockDB_Exec_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockLockDB_Exec_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockLockDB_Exec_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLockDB_Exec_Call) RunAndReturn(run func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)) *MockLockDB_Exec_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryRow provides a mock function with given fields: ctx, query, args
+func (_m *MockLockDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryRow")
+	}
+
+	var r0 pgx.Row
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgx.Row); ok {
+		r0 = rf(ctx, query, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(pgx.Row)
+		}
+	}
+
+	return r0
+}
+
+// MockLockDB_QueryRow_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryRow'
+type MockLockDB_QueryRow_Call struct {
+	*mock.Call
+}
+
+// QueryRow is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query string
+//   - args ...interface{}
+func (_e *MockLockDB_Expecter) QueryRow(ctx interface{}, query interface{}, args ...interface{}) *MockLockDB_QueryRow_Call {
+	return &MockLockDB_QueryRow_Call{Call: _e.mock.On("QueryRow",
+		append([]interface{}{ctx, query}, args...)...)}
+}
+
+func (_c *MockLockDB_QueryRow_Call) Run(run func(ctx context.Context, query string, args ...interface{})) *MockLockDB_QueryRow_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockLockDB_QueryRow_Call) Return(_a0 pgx.Row) *MockLockDB_QueryRow_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLockDB_QueryRow_Call) RunAndReturn(run func(context.Context, string, ...interface{}) pgx.Row) *MockLockDB_QueryRow_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Transactional provides a mock function with given fields: ctx, fn
+func (_m *MockLockDB) Transactional(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transactional")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLockDB_Transactional_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Transactional'
+type MockLockDB_Transactional_Call struct {
+	*mock.Call
+}
+
+// Transactional is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(context.Context) error
+func (_e *MockLockDB_Expecter) Transactional(ctx interface{}, fn interface{}) *MockLockDB_Transactional_Call {
+	return &MockLockDB_Transactional_Call{Call: _e.mock.On("Transactional", ctx, fn)}
+}
+
+func (_c *MockLockDB_Transactional_Call) Run(run func(ctx context.Context, fn func(context.Context) error)) *MockLockDB_Transactional_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(func(context.Context) error))
+	})
+	return _c
+}
+
+func (_c *MockLockDB_Transactional_Call) Return(out error) *MockLockDB_Transactional_Call {
+	_c.Call.Return(out)
+	return _c
+}
+
+func (_c *MockLockDB_Transactional_Call) RunAndReturn(run func(context.Context, func(context.Context) error) error) *MockLockDB_Transactional_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockLockDB creates a new instance of MockLockDB. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockLockDB(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockLockDB {
+	mock := &MockLockDB{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,53 @@
+package named
+
+import (
+	"container/list"
+	"sync"
+)
+
+// cache keeps limited count of compiled queries and evicts least recently used ones, so queries built at runtime
+// don't grow it unbounded.
+type cache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	query    string
+	compiled compiled
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *cache) get(query string) (compiled, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[query]
+	if !ok {
+		return compiled{}, false
+	}
+	c.order.MoveToFront(item)
+	return item.Value.(*entry).compiled, true
+}
+
+func (c *cache) put(query string, value compiled) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, ok := c.items[query]; ok {
+		c.order.MoveToFront(item)
+		return
+	}
+	c.items[query] = c.order.PushFront(&entry{query: query, compiled: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(*entry)
+		delete(c.items, oldest.query)
+	}
+}
@@ -0,0 +1,24 @@
+package named
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("should be able to keep first compiled query, when it's put twice", func(t *testing.T) {
+		sut := newCache(2)
+		sut.put("SELECT :a", compiled{sql: "SELECT $1", names: []string{"a"}})
+		sut.put("SELECT 1", compiled{sql: "SELECT 1"})
+
+		sut.put("SELECT :a", compiled{sql: "SELECT $1", names: []string{"a"}})
+		sut.put("SELECT 2", compiled{sql: "SELECT 2"})
+
+		_, ok := sut.get("SELECT 1")
+		assert.False(t, ok)
+		c, ok := sut.get("SELECT :a")
+		assert.True(t, ok)
+		assert.Equal(t, compiled{sql: "SELECT $1", names: []string{"a"}}, c)
+	})
+}
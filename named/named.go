@@ -0,0 +1,182 @@
+//go:generate mockery
+package named
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrMissingArg      = errors.New("named: argument is missing")
+	ErrUnsupportedArgs = errors.New("named: arguments must be map or struct")
+)
+
+type DB interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(_ ...any) error {
+	return r.err
+}
+
+const defaultCacheSize = 1024
+
+type Option func(n *Named)
+
+// WithCacheSize sets count of compiled queries, which are cached, 1024 by default.
+func WithCacheSize(size int) Option {
+	return func(n *Named) {
+		n.queries = newCache(max(size, 1))
+	}
+}
+
+// Named runs queries with :name and @name placeholders. Arguments are taken from map, pgx.NamedArgs or struct,
+// whose fields are named by `db` tag or by lowercase field name. Compiled queries are cached by text, least recently
+// used ones are evicted, when cache is full.
+type Named struct {
+	db      DB
+	queries *cache
+	structs sync.Map
+}
+
+func New(db DB, opts ...Option) *Named {
+	n := &Named{db: db, queries: newCache(defaultCacheSize)}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+func (n *Named) Query(ctx context.Context, query string, args any) (pgx.Rows, error) {
+	sql, values, err := n.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return n.db.Query(ctx, sql, values...)
+}
+
+func (n *Named) QueryRow(ctx context.Context, query string, args any) pgx.Row {
+	sql, values, err := n.bind(query, args)
+	if err != nil {
+		return failedRow{err: err}
+	}
+	return n.db.QueryRow(ctx, sql, values...)
+}
+
+func (n *Named) Exec(ctx context.Context, query string, args any) (pgconn.CommandTag, error) {
+	sql, values, err := n.bind(query, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return n.db.Exec(ctx, sql, values...)
+}
+
+func (n *Named) compile(query string) (compiled, error) {
+	if c, ok := n.queries.get(query); ok {
+		return c, nil
+	}
+	c, err := compile(query)
+	if err != nil {
+		return compiled{}, err
+	}
+	n.queries.put(query, c)
+	return c, nil
+}
+
+func (n *Named) bind(query string, args any) (string, []any, error) {
+	c, err := n.compile(query)
+	if err != nil {
+		return "", nil, err
+	}
+	lookup, err := n.lookup(args)
+	if err != nil {
+		return "", nil, err
+	}
+	values := make([]any, 0, len(c.names))
+	for _, name := range c.names {
+		value, ok := lookup(name)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrMissingArg, name)
+		}
+		values = append(values, value)
+	}
+	return c.sql, values, nil
+}
+
+func (n *Named) lookup(args any) (func(name string) (any, bool), error) {
+	switch args := args.(type) {
+	case nil:
+		return func(string) (any, bool) {
+			return nil, false
+		}, nil
+	case map[string]any:
+		return lookupMap(args), nil
+	case pgx.NamedArgs:
+		return lookupMap(args), nil
+	case pgx.StrictNamedArgs:
+		return lookupMap(args), nil
+	}
+
+	value := reflect.ValueOf(args)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedArgs, args)
+	}
+	fields := n.fields(value.Type())
+	return func(name string) (any, bool) {
+		index, ok := fields[name]
+		if !ok {
+			return nil, false
+		}
+		field, err := value.FieldByIndexErr(index)
+		if err != nil {
+			return nil, false
+		}
+		return field.Interface(), true
+	}, nil
+}
+
+func lookupMap[M ~map[string]any](args M) func(name string) (any, bool) {
+	return func(name string) (any, bool) {
+		value, ok := args[name]
+		return value, ok
+	}
+}
+
+// fields indexes exported fields of struct type, including promoted ones, by argument name.
+func (n *Named) fields(t reflect.Type) map[string][]int {
+	if fields, ok := n.structs.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || (field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		name, ok := field.Tag.Lookup("db")
+		if !ok {
+			name = strings.ToLower(field.Name)
+		}
+		if name == "-" {
+			continue
+		}
+		fields[name] = field.Index
+	}
+	n.structs.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,152 @@
+package named
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	namedQuery = "UPDATE users SET name = :name WHERE id = :id AND org_id = :org_id"
+	boundQuery = "UPDATE users SET name = $1 WHERE id = $2 AND org_id = $3"
+)
+
+type Org struct {
+	OrgID int64 `db:"org_id"`
+}
+
+type user struct {
+	*Org
+	ID      int64
+	Name    string
+	Comment string `db:"-"`
+	secret  string
+}
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func TestNamed_Exec(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args any
+	}{
+		{name: "map", args: map[string]any{"name": "root", "id": int64(1), "org_id": int64(2)}},
+		{name: "pgx named args", args: pgx.NamedArgs{"name": "root", "id": int64(1), "org_id": int64(2)}},
+		{name: "pgx strict named args", args: pgx.StrictNamedArgs{"name": "root", "id": int64(1), "org_id": int64(2)}},
+		{name: "struct", args: user{Org: &Org{OrgID: 2}, ID: 1, Name: "root", secret: "-"}},
+		{name: "pointer to struct", args: &user{Org: &Org{OrgID: 2}, ID: 1, Name: "root"}},
+	} {
+		t.Run("should be able to bind arguments of "+tc.name, func(t *testing.T) {
+			db := NewMockDB(t)
+			db.EXPECT().Exec(mock.Anything, boundQuery, "root", int64(1), int64(2)).
+				Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+			tag, err := New(db).Exec(context.Background(), namedQuery, tc.args)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), tag.RowsAffected())
+		})
+	}
+
+	t.Run("should be able to cache compiled queries and struct fields", func(t *testing.T) {
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.Anything, boundQuery, "root", int64(1), int64(2)).
+			Return(pgconn.CommandTag{}, nil).Twice()
+		sut := New(db)
+
+		for range 2 {
+			_, err := sut.Exec(context.Background(), namedQuery, user{Org: &Org{OrgID: 2}, ID: 1, Name: "root"})
+			require.NoError(t, err)
+		}
+		_, ok := sut.queries.get(namedQuery)
+		assert.True(t, ok)
+	})
+
+	t.Run("should be able to evict least recently used queries", func(t *testing.T) {
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, nil)
+		sut := New(db, WithCacheSize(2))
+
+		for _, query := range []string{"SELECT 1", "SELECT 2", "SELECT 1", "SELECT 1", "SELECT 3"} {
+			_, err := sut.Exec(context.Background(), query, nil)
+			require.NoError(t, err)
+		}
+
+		_, ok := sut.queries.get("SELECT 2")
+		assert.False(t, ok)
+		_, ok = sut.queries.get("SELECT 1")
+		assert.True(t, ok)
+		_, ok = sut.queries.get("SELECT 3")
+		assert.True(t, ok)
+	})
+
+	t.Run("should be able to fail on missing arguments", func(t *testing.T) {
+		for _, args := range []any{
+			nil,
+			map[string]any{"name": "root"},
+			user{ID: 1, Name: "root"},
+			struct{ Name string }{Name: "root"},
+		} {
+			_, err := New(NewMockDB(t)).Exec(context.Background(), namedQuery, args)
+			assert.ErrorIs(t, err, ErrMissingArg)
+		}
+	})
+
+	t.Run("should be able to fail on unsupported arguments", func(t *testing.T) {
+		_, err := New(NewMockDB(t)).Exec(context.Background(), namedQuery, []any{"root"})
+		assert.ErrorIs(t, err, ErrUnsupportedArgs)
+	})
+
+	t.Run("should be able to fail on invalid query", func(t *testing.T) {
+		_, err := New(NewMockDB(t)).Exec(context.Background(), "SELECT $1, :id", nil)
+		assert.ErrorIs(t, err, ErrMixedPlaceholders)
+	})
+
+	t.Run("should be able to pass query without placeholders", func(t *testing.T) {
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.Anything, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := New(db).Exec(context.Background(), "SELECT 1", nil)
+		require.NoError(t, err)
+	})
+}
+
+func TestNamed_Query(t *testing.T) {
+	t.Run("should be able to query with named arguments", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockDB(t)
+		db.EXPECT().Query(mock.Anything, "SELECT * FROM users WHERE id = $1", int64(1)).Return(nil, expErr)
+
+		_, err := New(db).Query(context.Background(), "SELECT * FROM users WHERE id = @id", pgx.NamedArgs{"id": int64(1)})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail on missing arguments", func(t *testing.T) {
+		_, err := New(NewMockDB(t)).Query(context.Background(), "SELECT :id", nil)
+		assert.ErrorIs(t, err, ErrMissingArg)
+	})
+}
+
+func TestNamed_QueryRow(t *testing.T) {
+	t.Run("should be able to query row with named arguments", func(t *testing.T) {
+		db := NewMockDB(t)
+		db.EXPECT().QueryRow(mock.Anything, "SELECT * FROM users WHERE id = $1", int64(1)).Return(nil)
+
+		row := New(db).QueryRow(context.Background(), "SELECT * FROM users WHERE id = :id", map[string]any{"id": int64(1)})
+		assert.Nil(t, row)
+	})
+
+	t.Run("should be able to return failed row on missing arguments", func(t *testing.T) {
+		var id int64
+		err := New(NewMockDB(t)).QueryRow(context.Background(), "SELECT :id", nil).Scan(&id)
+		assert.ErrorIs(t, err, ErrMissingArg)
+	})
+}
@@ -0,0 +1,167 @@
+package named
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrMixedPlaceholders = errors.New("named: query mixes named and positional placeholders")
+
+// compiled is query with named placeholders replaced by positional ones. Names are ordered by position.
+type compiled struct {
+	sql   string
+	names []string
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// operand reports, whether character before : or @ makes it part of identifier or operator like @@, not placeholder.
+func operand(c byte) bool {
+	return isIdentChar(c) || c == '@'
+}
+
+// escapeString reports, whether literal started at i is E'...' string, so E must be a separate word.
+func escapeString(query string, i int) bool {
+	if i == 0 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentChar(query[i-2])
+}
+
+// compile replaces :name and @name placeholders outside of literals, quoted identifiers and comments. Casts (::)
+// and operators like @> and <@ are kept as is.
+func compile(query string) (compiled, error) {
+	var (
+		out        strings.Builder
+		names      []string
+		positions  = make(map[string]int)
+		positional bool
+	)
+	out.Grow(len(query))
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			end := quoted(query, i, '\'', escapeString(query, i))
+			out.WriteString(query[i:end])
+			i = end
+		case c == '"':
+			end := quoted(query, i, '"', false)
+			out.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := blockComment(query, i)
+			out.WriteString(query[i:end])
+			i = end
+		case c == '$':
+			if i+1 < len(query) && isDigit(query[i+1]) {
+				positional = true
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			end := dollarQuoted(query, i)
+			out.WriteString(query[i:end])
+			i = end
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			out.WriteString("::")
+			i += 2
+		case (c == ':' || c == '@') && i+1 < len(query) && isIdentStart(query[i+1]) && !(i > 0 && operand(query[i-1])):
+			end := i + 1
+			for end < len(query) && isIdentChar(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			pos, ok := positions[name]
+			if !ok {
+				names = append(names, name)
+				pos = len(names)
+				positions[name] = pos
+			}
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(pos))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	if positional && len(names) > 0 {
+		return compiled{}, ErrMixedPlaceholders
+	}
+	return compiled{sql: out.String(), names: names}, nil
+}
+
+// quoted returns end of literal started at i. Quote is escaped by doubling it, and by backslash in escape strings.
+func quoted(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+func blockComment(query string, i int) int {
+	depth := 0
+	for j := i; j < len(query)-1; j++ {
+		switch query[j : j+2] {
+		case "/*":
+			depth++
+			j++
+		case "*/":
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// dollarQuoted returns end of $tag$...$tag$ string started at i, or position after $, when it isn't a tag.
+func dollarQuoted(query string, i int) int {
+	end := i + 1
+	for end < len(query) && isIdentChar(query[end]) {
+		end++
+	}
+	if end >= len(query) || query[end] != '$' {
+		return i + 1
+	}
+	tag := query[i : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return len(query)
+	}
+	return end + 1 + closing + len(tag)
+}
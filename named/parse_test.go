@@ -0,0 +1,124 @@
+package named
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		query string
+		sql   string
+		names []string
+	}{
+		{
+			name:  "colon and at placeholders",
+			query: "SELECT * FROM users WHERE id = :id AND org_id = @org_id",
+			sql:   "SELECT * FROM users WHERE id = $1 AND org_id = $2",
+			names: []string{"id", "org_id"},
+		},
+		{
+			name:  "repeated placeholder",
+			query: "SELECT :a, :b, :a",
+			sql:   "SELECT $1, $2, $1",
+			names: []string{"a", "b"},
+		},
+		{
+			name:  "casts",
+			query: "SELECT :id::text, '1'::int, x::jsonb",
+			sql:   "SELECT $1::text, '1'::int, x::jsonb",
+			names: []string{"id"},
+		},
+		{
+			name:  "string literals",
+			query: `SELECT ':no', 'it''s :no', E'\':no', :yes`,
+			sql:   `SELECT ':no', 'it''s :no', E'\':no', $1`,
+			names: []string{"yes"},
+		},
+		{
+			name:  "quoted identifiers",
+			query: `SELECT "col:no" FROM "t""@no" WHERE x = @yes`,
+			sql:   `SELECT "col:no" FROM "t""@no" WHERE x = $1`,
+			names: []string{"yes"},
+		},
+		{
+			name:  "comments",
+			query: "SELECT 1 -- :no\n, /* :no /* @no */ :no */ :yes",
+			sql:   "SELECT 1 -- :no\n, /* :no /* @no */ :no */ $1",
+			names: []string{"yes"},
+		},
+		{
+			name:  "dollar quoted strings",
+			query: "SELECT $$ :no $$, $fn$ @no $$ $fn$, :yes",
+			sql:   "SELECT $$ :no $$, $fn$ @no $$ $fn$, $1",
+			names: []string{"yes"},
+		},
+		{
+			name:  "operators and slices",
+			query: "SELECT tags @> :tags, :tags <@ tags, arr[1:2], @ -1",
+			sql:   "SELECT tags @> $1, $1 <@ tags, arr[1:2], @ -1",
+			names: []string{"tags"},
+		},
+		{
+			name:  "full text search operator",
+			query: "SELECT * FROM docs WHERE tsv @@to_tsquery(@q) AND tsv @@ :q",
+			sql:   "SELECT * FROM docs WHERE tsv @@to_tsquery($1) AND tsv @@ $1",
+			names: []string{"q"},
+		},
+		{
+			name:  "literals after words ending with e",
+			query: `SELECT CASE WHEN x THEN'a\' ELSE'b\' END, :yes, E'\':no', e'x'`,
+			sql:   `SELECT CASE WHEN x THEN'a\' ELSE'b\' END, $1, E'\':no', e'x'`,
+			names: []string{"yes"},
+		},
+		{
+			name:  "unterminated tails",
+			query: "SELECT :a, 'open",
+			sql:   "SELECT $1, 'open",
+			names: []string{"a"},
+		},
+		{
+			name:  "unterminated comment",
+			query: "SELECT :a /* open",
+			sql:   "SELECT $1 /* open",
+			names: []string{"a"},
+		},
+		{
+			name:  "unterminated dollar quote",
+			query: "SELECT :a, $tag$ open",
+			sql:   "SELECT $1, $tag$ open",
+			names: []string{"a"},
+		},
+		{
+			name:  "line comment at the end",
+			query: "SELECT :a -- tail",
+			sql:   "SELECT $1 -- tail",
+			names: []string{"a"},
+		},
+		{
+			name:  "positional placeholders",
+			query: "SELECT $1, $2, '$",
+			sql:   "SELECT $1, $2, '$",
+		},
+		{
+			name:  "trailing symbols",
+			query: "SELECT 1 $",
+			sql:   "SELECT 1 $",
+		},
+	} {
+		t.Run("should be able to compile "+tc.name, func(t *testing.T) {
+			c, err := compile(tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, tc.sql, c.sql)
+			assert.Equal(t, tc.names, c.names)
+		})
+	}
+
+	t.Run("should be able to reject mixed placeholders", func(t *testing.T) {
+		_, err := compile("SELECT $1, :id")
+		assert.ErrorIs(t, err, ErrMixedPlaceholders)
+	})
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const releaseTimeout = 5 * time.Second
+
+// Conn is dedicated connection, which holds LISTEN state.
+type Conn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Release()
+}
+
+type Dialer func(ctx context.Context) (Conn, error)
+
+type Acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// FromPool dials connections from pgxpool.Pool or elephant DB. Cluster gives connection of leader, sharded hive
+// gives connection of shard picked by context of Listener.Run.
+func FromPool(db Acquirer) Dialer {
+	return func(ctx context.Context) (Conn, error) {
+		conn, err := db.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return poolConn{conn: conn}, nil
+	}
+}
+
+type poolConn struct {
+	conn *pgxpool.Conn
+}
+
+func (c poolConn) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return c.conn.Exec(ctx, sql, arguments...)
+}
+
+func (c poolConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	return c.conn.Conn().WaitForNotification(ctx)
+}
+
+// Release returns connection to pool without subscriptions, or closes it when they can't be dropped.
+func (c poolConn) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if _, err := c.conn.Exec(ctx, "UNLISTEN *"); err != nil {
+		_ = c.conn.Conn().Close(ctx)
+	}
+	c.conn.Release()
+}
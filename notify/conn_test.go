@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+type failedAcquirer struct {
+	err error
+}
+
+func (a failedAcquirer) Acquire(_ context.Context) (*pgxpool.Conn, error) {
+	return nil, a.err
+}
+
+func TestFromPool(t *testing.T) {
+	t.Run("should be able to fail when connection can't be acquired", func(t *testing.T) {
+		expErr := errors.New("acquire failed")
+
+		_, err := FromPool(failedAcquirer{err: expErr})(context.Background())
+		assert.ErrorIs(t, err, expErr)
+	})
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/notify"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keptAcquirer keeps acquired connection, so test reaches it under notify connection.
+type keptAcquirer struct {
+	pool *pgxpool.Pool
+	conn *pgxpool.Conn
+}
+
+func (a *keptAcquirer) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := a.pool.Acquire(ctx)
+	a.conn = conn
+	return conn, err
+}
+
+func TestFromPool(t *testing.T) {
+	t.Run("should be able to receive notifications sent in committed transaction", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		db := singlepg.New(tcs.Deps.DB)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		events, sub := tcs.SUT.Chan("elephant events", 1)
+		defer sub.Close()
+		done := make(chan error, 1)
+		go func() {
+			done <- tcs.SUT.Run(ctx)
+		}()
+
+		require.Eventually(t, func() bool {
+			var listening bool
+			err := tcs.Deps.DB.QueryRow(ctx,
+				"SELECT count(*) > 0 FROM pg_stat_activity WHERE query LIKE 'LISTEN%elephant events%'",
+			).Scan(&listening)
+			return err == nil && listening
+		}, 5*time.Second, 10*time.Millisecond)
+
+		err := db.Transactional(ctx, func(ctx context.Context) error {
+			return notify.Notify(ctx, db, "elephant events", "payload")
+		})
+		require.NoError(t, err)
+
+		select {
+		case n := <-events:
+			assert.Equal(t, "elephant events", n.Channel)
+			assert.Equal(t, "payload", n.Payload)
+		case <-ctx.Done():
+			require.Fail(t, "notification wasn't delivered")
+		}
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("should be able to close connection which can't unlisten", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		pool := &keptAcquirer{pool: tcs.Deps.DB}
+		conn, err := notify.FromPool(pool)(context.Background())
+		require.NoError(t, err)
+		raw := pool.conn.Conn()
+		require.NoError(t, raw.Close(context.Background()))
+
+		conn.Release()
+		assert.True(t, raw.IsClosed())
+	})
+}
@@ -0,0 +1,42 @@
+// Package integration runs notify against postgres in container, apart from notify tests, which don't need it.
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/notify"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, *notify.Listener]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, *notify.Listener] {
+	return groat.New[Deps, State, *notify.Listener](t, func(t *testing.T, deps Deps) *notify.Listener {
+		return notify.New(notify.FromPool(singlepg.New(deps.DB)), notify.WithReconnectDelay(10*time.Millisecond))
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, *notify.Listener](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrator(func(context.Context, pgrx.MigratorConfig) error {
+				return nil
+			}),
+		),
+	)
+	os.Exit(suite.Go())
+}
@@ -0,0 +1,196 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package notify
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+)
+
+// MockConn is an autogenerated mock type for the Conn type
+type MockConn struct {
+	mock.Mock
+}
+
+type MockConn_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockConn) EXPECT() *MockConn_Expecter {
+	return &MockConn_Expecter{mock: &_m.Mock}
+}
+
+// Exec provides a mock function with given fields: ctx, sql, arguments
+func (_m *MockConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, sql)
+	_ca = append(_ca, arguments...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exec")
+	}
+
+	var r0 pgconn.CommandTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)); ok {
+		return rf(ctx, sql, arguments...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) pgconn.CommandTag); ok {
+		r0 = rf(ctx, sql, arguments...)
+	} else {
+		r0 = ret.Get(0).(pgconn.CommandTag)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...interface{}) error); ok {
+		r1 = rf(ctx, sql, arguments...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConn_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
+type MockConn_Exec_Call struct {
+	*mock.Call
+}
+
+// Exec is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sql string
+//   - arguments ...interface{}
+func (_e *MockConn_Expecter) Exec(ctx interface{}, sql interface{}, arguments ...interface{}) *MockConn_Exec_Call {
+	return &MockConn_Exec_Call{Call: _e.mock.On("Exec",
+		append([]interface{}{ctx, sql}, arguments...)...)}
+}
+
+func (_c *MockConn_Exec_Call) Run(run func(ctx context.Context, sql string, arguments ...interface{})) *MockConn_Exec_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockConn_Exec_Call) Return(_a0 pgconn.CommandTag, _a1 error) *MockConn_Exec_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConn_Exec_Call) RunAndReturn(run func(context.Context, string, ...interface{}) (pgconn.CommandTag, error)) *MockConn_Exec_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Release provides a mock function with given fields:
+func (_m *MockConn) Release() {
+	_m.Called()
+}
+
+// MockConn_Release_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Release'
+type MockConn_Release_Call struct {
+	*mock.Call
+}
+
+// Release is a helper method to define mock.On call
+func (_e *MockConn_Expecter) Release() *MockConn_Release_Call {
+	return &MockConn_Release_Call{Call: _e.mock.On("Release")}
+}
+
+func (_c *MockConn_Release_Call) Run(run func()) *MockConn_Release_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockConn_Release_Call) Return() *MockConn_Release_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockConn_Release_Call) RunAndReturn(run func()) *MockConn_Release_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WaitForNotification provides a mock function with given fields: ctx
+func (_m *MockConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WaitForNotification")
+	}
+
+	var r0 *pgconn.Notification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*pgconn.Notification, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *pgconn.Notification); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pgconn.Notification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockConn_WaitForNotification_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WaitForNotification'
+type MockConn_WaitForNotification_Call struct {
+	*mock.Call
+}
+
+// WaitForNotification is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockConn_Expecter) WaitForNotification(ctx interface{}) *MockConn_WaitForNotification_Call {
+	return &MockConn_WaitForNotification_Call{Call: _e.mock.On("WaitForNotification", ctx)}
+}
+
+func (_c *MockConn_WaitForNotification_Call) Run(run func(ctx context.Context)) *MockConn_WaitForNotification_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockConn_WaitForNotification_Call) Return(_a0 *pgconn.Notification, _a1 error) *MockConn_WaitForNotification_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockConn_WaitForNotification_Call) RunAndReturn(run func(context.Context) (*pgconn.Notification, error)) *MockConn_WaitForNotification_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockConn creates a new instance of MockConn. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockConn(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockConn {
+	mock := &MockConn{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
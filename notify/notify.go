@@ -0,0 +1,301 @@
+//go:generate mockery
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const notifyQuery = "SELECT pg_notify($1, $2)"
+
+var errInterrupted = errors.New("waiting interrupted by subscriptions change")
+
+type Notification = pgconn.Notification
+
+// Handler receives notifications of channel one by one. Listener doesn't read next notification until handler
+// returns, so slow handler slows down delivery instead of buffering it in memory.
+type Handler func(ctx context.Context, n *Notification)
+
+type Execer interface {
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// Notify sends notification through leader. Inside transaction from context it is delivered only on commit.
+func Notify(ctx context.Context, db Execer, channel, payload string) error {
+	if _, err := db.Exec(pgcontext.WithCanWrite(ctx), notifyQuery, channel, payload); err != nil {
+		return fmt.Errorf("can't notify channel %q: %w", channel, err)
+	}
+	return nil
+}
+
+type Config struct {
+	reconnectDelay time.Duration
+	onError        func(err error)
+}
+
+type Option func(cfg *Config)
+
+func WithReconnectDelay(delay time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.reconnectDelay = delay
+	}
+}
+
+// WithErrorHandler reports errors of connection, after which Listener reconnects.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onError = fn
+	}
+}
+
+type Subscription struct {
+	listener *Listener
+	channel  string
+	handler  Handler
+	done     chan struct{}
+	once     sync.Once
+	// stop closes Go channel of subscription made by Chan.
+	stop func()
+}
+
+// Close stops delivery to subscription. Listener stops listening channel when it has no subscriptions left.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.listener.remove(s)
+		if s.stop != nil {
+			s.stop()
+		}
+	})
+}
+
+type Listener struct {
+	dial Dialer
+	cfg  Config
+	mu   sync.Mutex
+	subs map[string][]*Subscription
+	wake chan struct{}
+}
+
+func New(dial Dialer, opts ...Option) *Listener {
+	cfg := Config{
+		reconnectDelay: time.Second,
+		onError:        func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Listener{
+		dial: dial,
+		cfg:  cfg,
+		subs: make(map[string][]*Subscription),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (l *Listener) Subscribe(channel string, handler Handler) *Subscription {
+	sub := &Subscription{
+		listener: l,
+		channel:  channel,
+		handler:  handler,
+		done:     make(chan struct{}),
+	}
+	l.add(sub)
+	return sub
+}
+
+// Chan delivers notifications of channel to Go channel with given buffer. When buffer is full, Listener waits for
+// reader. Channel is closed by Subscription.Close and when Run returns, so range over it ends with listener.
+func (l *Listener) Chan(channel string, buffer int) (<-chan *Notification, *Subscription) {
+	ch := make(chan *Notification, buffer)
+	sub := &Subscription{
+		listener: l,
+		channel:  channel,
+		done:     make(chan struct{}),
+	}
+	// sending guards channel from close while handler sends to it.
+	var sending sync.Mutex
+	sub.handler = func(ctx context.Context, n *Notification) {
+		sending.Lock()
+		defer sending.Unlock()
+		select {
+		case <-sub.done:
+			return
+		default:
+		}
+		select {
+		case ch <- n:
+		case <-ctx.Done():
+		case <-sub.done:
+		}
+	}
+	sub.stop = func() {
+		sending.Lock()
+		defer sending.Unlock()
+		close(ch)
+	}
+	l.add(sub)
+	return ch, sub
+}
+
+func (l *Listener) add(sub *Subscription) {
+	l.mu.Lock()
+	l.subs[sub.channel] = append(l.subs[sub.channel], sub)
+	l.mu.Unlock()
+	l.notifyChanged()
+}
+
+func (l *Listener) remove(sub *Subscription) {
+	l.mu.Lock()
+	subs := l.subs[sub.channel]
+	for i, item := range subs {
+		if item == sub {
+			subs = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(l.subs, sub.channel)
+	} else {
+		l.subs[sub.channel] = subs
+	}
+	l.mu.Unlock()
+	l.notifyChanged()
+}
+
+func (l *Listener) notifyChanged() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run listens subscribed channels until context is done. It reconnects after connection failures and subscribes
+// all channels again. Subscriptions made by Chan are closed, when Run returns.
+func (l *Listener) Run(ctx context.Context) error {
+	defer l.closeChans()
+	for {
+		err := l.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		l.cfg.onError(err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.cfg.reconnectDelay):
+		}
+	}
+}
+
+func (l *Listener) closeChans() {
+	l.mu.Lock()
+	var chans []*Subscription
+	for _, subs := range l.subs {
+		for _, sub := range subs {
+			if sub.stop != nil {
+				chans = append(chans, sub)
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	for _, sub := range chans {
+		sub.Close()
+	}
+}
+
+func (l *Listener) session(ctx context.Context) error {
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("can't dial listener connection: %w", err)
+	}
+	defer conn.Release()
+
+	listening := make(map[string]struct{})
+	for {
+		if err := l.sync(ctx, conn, listening); err != nil {
+			return err
+		}
+
+		n, err := l.wait(ctx, conn)
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("can't wait for notification: %w", err)
+		}
+		l.deliver(ctx, n)
+	}
+}
+
+// wait blocks until notification or change of subscriptions.
+func (l *Listener) wait(ctx context.Context, conn Conn) (*Notification, error) {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.wake:
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	n, err := conn.WaitForNotification(waitCtx)
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		return nil, errInterrupted
+	}
+	return n, err
+}
+
+func (l *Listener) sync(ctx context.Context, conn Conn, listening map[string]struct{}) error {
+	l.mu.Lock()
+	wanted := make(map[string]struct{}, len(l.subs))
+	for channel := range l.subs {
+		wanted[channel] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	for channel := range wanted {
+		if _, ok := listening[channel]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("can't listen channel %q: %w", channel, err)
+		}
+		listening[channel] = struct{}{}
+	}
+	for channel := range listening {
+		if _, ok := wanted[channel]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("can't unlisten channel %q: %w", channel, err)
+		}
+		delete(listening, channel)
+	}
+	return nil
+}
+
+func (l *Listener) deliver(ctx context.Context, n *Notification) {
+	l.mu.Lock()
+	subs := append([]*Subscription(nil), l.subs[n.Channel]...)
+	l.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case <-sub.done:
+			continue
+		default:
+		}
+		sub.handler(ctx, n)
+	}
+}
@@ -0,0 +1,294 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func canWrite(ctx context.Context) bool {
+	return pgcontext.CanWriteFrom(ctx)
+}
+
+func TestNotify(t *testing.T) {
+	t.Run("should be able to notify through leader", func(t *testing.T) {
+		db := NewMockExecer(t)
+		db.EXPECT().Exec(mock.MatchedBy(canWrite), notifyQuery, "events", "payload").
+			Return(pgconn.NewCommandTag("SELECT 1"), nil)
+
+		require.NoError(t, Notify(context.Background(), db, "events", "payload"))
+	})
+
+	t.Run("should be able to fail when notification can't be sent", func(t *testing.T) {
+		db := NewMockExecer(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		db.EXPECT().Exec(mock.MatchedBy(canWrite), notifyQuery, "events", "payload").
+			Return(pgconn.CommandTag{}, expErr)
+
+		assert.ErrorIs(t, Notify(context.Background(), db, "events", "payload"), expErr)
+	})
+}
+
+// fakeServer feeds notifications to mocked connections and records executed statements.
+type fakeServer struct {
+	feed  chan *Notification
+	execs chan string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		feed:  make(chan *Notification),
+		execs: make(chan string, 16),
+	}
+}
+
+func (s *fakeServer) conn(t *testing.T, execErr error, lost chan struct{}) *MockConn {
+	conn := NewMockConn(t)
+	conn.EXPECT().Exec(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+			s.execs <- sql
+			return pgconn.CommandTag{}, execErr
+		}).Maybe()
+	conn.EXPECT().WaitForNotification(mock.Anything).
+		RunAndReturn(func(ctx context.Context) (*Notification, error) {
+			select {
+			case n := <-s.feed:
+				return n, nil
+			case <-lost:
+				return nil, errors.New("connection lost")
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}).Maybe()
+	conn.EXPECT().Release().Return()
+	return conn
+}
+
+func expectExec(t *testing.T, execs chan string, sql string) {
+	t.Helper()
+	select {
+	case got := <-execs:
+		assert.Equal(t, sql, got)
+	case <-time.After(time.Second):
+		require.Fail(t, "statement wasn't executed", sql)
+	}
+}
+
+func runListener(l *Listener) (context.CancelFunc, chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+	return cancel, done
+}
+
+func TestListener_Run(t *testing.T) {
+	t.Run("should be able to deliver notifications to handler and channel", func(t *testing.T) {
+		server := newFakeServer()
+		conn := server.conn(t, nil, nil)
+		sut := New(func(context.Context) (Conn, error) {
+			return conn, nil
+		})
+		received := make(chan *Notification, 1)
+		sut.Subscribe("events", func(_ context.Context, n *Notification) {
+			received <- n
+		})
+		events, _ := sut.Chan("events", 1)
+
+		cancel, done := runListener(sut)
+		expectExec(t, server.execs, `LISTEN "events"`)
+		exp := &Notification{Channel: "events", Payload: "payload"}
+		server.feed <- exp
+
+		assert.Equal(t, exp, <-received)
+		assert.Equal(t, exp, <-events)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		_, ok := <-events
+		assert.False(t, ok)
+	})
+
+	t.Run("should be able to listen and unlisten channels while running", func(t *testing.T) {
+		server := newFakeServer()
+		conn := server.conn(t, nil, nil)
+		sut := New(func(context.Context) (Conn, error) {
+			return conn, nil
+		})
+
+		cancel, done := runListener(sut)
+		sub := sut.Subscribe("events", func(context.Context, *Notification) {})
+		expectExec(t, server.execs, `LISTEN "events"`)
+		sub.Close()
+		sub.Close()
+		expectExec(t, server.execs, `UNLISTEN "events"`)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("should be able to subscribe channels again after reconnect", func(t *testing.T) {
+		server := newFakeServer()
+		lost := make(chan struct{})
+		conns := []*MockConn{server.conn(t, nil, lost), server.conn(t, nil, nil)}
+		var dials int
+		errs := make(chan error, 1)
+		sut := New(func(context.Context) (Conn, error) {
+			dials++
+			return conns[dials-1], nil
+		}, WithReconnectDelay(time.Millisecond), WithErrorHandler(func(err error) {
+			errs <- err
+		}))
+		sut.Subscribe("events", func(context.Context, *Notification) {})
+
+		cancel, done := runListener(sut)
+		expectExec(t, server.execs, `LISTEN "events"`)
+		close(lost)
+		assert.ErrorContains(t, <-errs, "connection lost")
+		expectExec(t, server.execs, `LISTEN "events"`)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("should be able to report listen error and reconnect", func(t *testing.T) {
+		server := newFakeServer()
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		conns := []*MockConn{server.conn(t, expErr, nil), server.conn(t, nil, nil)}
+		var dials int
+		errs := make(chan error, 1)
+		sut := New(func(context.Context) (Conn, error) {
+			dials++
+			return conns[dials-1], nil
+		}, WithReconnectDelay(time.Millisecond), WithErrorHandler(func(err error) {
+			errs <- err
+		}))
+		sut.Subscribe("events", func(context.Context, *Notification) {})
+
+		cancel, done := runListener(sut)
+		expectExec(t, server.execs, `LISTEN "events"`)
+		assert.ErrorIs(t, <-errs, expErr)
+		expectExec(t, server.execs, `LISTEN "events"`)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("should be able to report unlisten error", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		conn := NewMockConn(t)
+		conn.EXPECT().Exec(mock.Anything, `UNLISTEN "events"`).Return(pgconn.CommandTag{}, expErr)
+		sut := New(nil)
+
+		err := sut.sync(context.Background(), conn, map[string]struct{}{"events": {}})
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to stop while waiting for reconnect", func(t *testing.T) {
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		ctx, cancel := context.WithCancel(context.Background())
+		var reported error
+		sut := New(func(context.Context) (Conn, error) {
+			return nil, expErr
+		}, WithReconnectDelay(time.Hour), WithErrorHandler(func(err error) {
+			reported = err
+			cancel()
+		}))
+
+		assert.ErrorIs(t, sut.Run(ctx), context.Canceled)
+		assert.ErrorIs(t, reported, expErr)
+	})
+
+	t.Run("should be able to ignore errors by default", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var dials int
+		sut := New(func(context.Context) (Conn, error) {
+			dials++
+			if dials > 1 {
+				cancel()
+			}
+			return nil, errors.New("dial failed")
+		}, WithReconnectDelay(time.Millisecond))
+
+		assert.ErrorIs(t, sut.Run(ctx), context.Canceled)
+	})
+}
+
+func TestListener_deliver(t *testing.T) {
+	t.Run("should be able to skip closed subscription", func(t *testing.T) {
+		sut := New(nil)
+		sub := sut.Subscribe("events", func(context.Context, *Notification) {
+			require.Fail(t, "closed subscription received notification")
+		})
+		close(sub.done)
+
+		sut.deliver(context.Background(), &Notification{Channel: "events"})
+	})
+
+	t.Run("should be able to keep other subscriptions of channel", func(t *testing.T) {
+		sut := New(nil)
+		first := sut.Subscribe("events", func(context.Context, *Notification) {})
+		var received int
+		sut.Subscribe("events", func(context.Context, *Notification) {
+			received++
+		})
+		first.Close()
+
+		sut.deliver(context.Background(), &Notification{Channel: "events"})
+		assert.Equal(t, 1, received)
+	})
+}
+
+func TestListener_Chan(t *testing.T) {
+	t.Run("should be able to stop waiting for reader when subscription is closed", func(t *testing.T) {
+		sut := New(nil)
+		_, sub := sut.Chan("events", 0)
+		sub.Close()
+
+		sub.handler(context.Background(), &Notification{Channel: "events"})
+	})
+
+	t.Run("should be able to close channel when subscription is closed", func(t *testing.T) {
+		sut := New(nil)
+		events, sub := sut.Chan("events", 1)
+		keep := sut.Subscribe("events", func(context.Context, *Notification) {})
+		sub.Close()
+		sub.Close()
+
+		_, ok := <-events
+		assert.False(t, ok)
+		assert.Equal(t, []*Subscription{keep}, sut.subs["events"])
+	})
+
+	t.Run("should be able to close channel of sender blocked by full buffer", func(t *testing.T) {
+		sut := New(nil)
+		events, sub := sut.Chan("events", 0)
+		sent := make(chan struct{})
+		go func() {
+			defer close(sent)
+			sub.handler(context.Background(), &Notification{Channel: "events"})
+		}()
+		sub.Close()
+
+		<-sent
+		_, ok := <-events
+		assert.False(t, ok)
+	})
+
+	t.Run("should be able to stop waiting for reader when context is done", func(t *testing.T) {
+		sut := New(nil)
+		_, sub := sut.Chan("events", 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		sub.handler(ctx, &Notification{Channel: "events"})
+	})
+}
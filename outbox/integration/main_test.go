@@ -0,0 +1,41 @@
+// Package integration runs outbox against postgres in container, apart from outbox tests, which don't need it.
+package integration
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/outbox"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, *outbox.Outbox]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, *outbox.Outbox] {
+	return groat.New[Deps, State, *outbox.Outbox](t, func(t *testing.T, deps Deps) *outbox.Outbox {
+		return outbox.New(singlepg.New(deps.DB), outbox.WithMaxAttempts(2), outbox.WithBackoff(func(int) time.Duration {
+			return 0
+		}))
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, *outbox.Outbox](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrationsPath("../sql"),
+		),
+	)
+	os.Exit(suite.Go())
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/godepo/elephant/outbox"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_Publication(t *testing.T) {
+	t.Run("should be able to publish committed messages in order of keys", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		db := singlepg.New(tcs.Deps.DB)
+		_, err := tcs.Deps.DB.Exec(ctx, "TRUNCATE outbox")
+		require.NoError(t, err)
+
+		require.NoError(t, db.Transactional(ctx, func(ctx context.Context) error {
+			for _, payload := range []string{"a1", "a2", "b1"} {
+				if err := tcs.SUT.Enqueue(ctx, "events", []byte(payload), outbox.WithKey(payload[:1])); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		require.Error(t, db.Transactional(ctx, func(ctx context.Context) error {
+			if err := tcs.SUT.Enqueue(ctx, "events", []byte("rolled back")); err != nil {
+				return err
+			}
+			return errors.New("rollback")
+		}))
+
+		var published []string
+		publish := func(_ context.Context, msg outbox.Message) error {
+			published = append(published, string(msg.Payload))
+			return nil
+		}
+		processed, err := tcs.SUT.Drain(ctx, publish)
+		require.NoError(t, err)
+		assert.Equal(t, 2, processed)
+		processed, err = tcs.SUT.Drain(ctx, publish)
+		require.NoError(t, err)
+		assert.Equal(t, 1, processed)
+		assert.Equal(t, []string{"a1", "b1", "a2"}, published)
+
+		deleted, err := tcs.SUT.Cleanup(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+	})
+
+	t.Run("should be able to retry and fail message", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		db := singlepg.New(tcs.Deps.DB)
+		_, err := tcs.Deps.DB.Exec(ctx, "TRUNCATE outbox")
+		require.NoError(t, err)
+		require.NoError(t, db.Transactional(ctx, func(ctx context.Context) error {
+			return tcs.SUT.Enqueue(ctx, "events", []byte("payload"), outbox.WithKey("a"))
+		}))
+
+		var attempts []int
+		publish := func(_ context.Context, msg outbox.Message) error {
+			attempts = append(attempts, msg.Attempts)
+			return errors.New("broker is down")
+		}
+		for range 3 {
+			_, err := tcs.SUT.Drain(ctx, publish)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []int{0, 1}, attempts)
+
+		var lastError string
+		require.NoError(t, tcs.Deps.DB.QueryRow(ctx,
+			"SELECT last_error FROM outbox WHERE failed_at IS NOT NULL",
+		).Scan(&lastError))
+		assert.Equal(t, "broker is down", lastError)
+	})
+}
@@ -0,0 +1,298 @@
+//go:generate mockery
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrNoTransaction = errors.New("outbox: enqueue requires transaction in context")
+
+type DB interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type Message struct {
+	ID        int64
+	Topic     string
+	Key       string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Publisher delivers message to broker. Message is published at least once, so consumers should be idempotent.
+type Publisher func(ctx context.Context, msg Message) error
+
+type Backoff func(attempts int) time.Duration
+
+type Config struct {
+	table           pgx.Identifier
+	batchSize       int
+	pollInterval    time.Duration
+	maxAttempts     int
+	backoff         Backoff
+	retention       time.Duration
+	cleanupInterval time.Duration
+	shards          []uint
+	onError         func(err error)
+}
+
+type Option func(cfg *Config)
+
+func WithTable(name ...string) Option {
+	return func(cfg *Config) {
+		cfg.table = name
+	}
+}
+
+func WithBatchSize(size int) Option {
+	return func(cfg *Config) {
+		cfg.batchSize = size
+	}
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithMaxAttempts marks message as failed after given number of failed publications. Zero means retry forever.
+func WithMaxAttempts(attempts int) Option {
+	return func(cfg *Config) {
+		cfg.maxAttempts = attempts
+	}
+}
+
+func WithBackoff(backoff Backoff) Option {
+	return func(cfg *Config) {
+		cfg.backoff = backoff
+	}
+}
+
+// WithCleanup deletes messages published earlier than retention ago, at most once per interval.
+func WithCleanup(retention, interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.retention = retention
+		cfg.cleanupInterval = interval
+	}
+}
+
+// WithShards makes relay drain outbox of every given shard of sharded.Hive.
+func WithShards(ids ...uint) Option {
+	return func(cfg *Config) {
+		cfg.shards = ids
+	}
+}
+
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onError = fn
+	}
+}
+
+func DefaultBackoff(attempts int) time.Duration {
+	return time.Second << min(attempts, 6)
+}
+
+type queries struct {
+	insert    string
+	fetch     string
+	published string
+	retry     string
+	failed    string
+	cleanup   string
+}
+
+type Outbox struct {
+	db      DB
+	cfg     Config
+	queries queries
+}
+
+func New(db DB, opts ...Option) *Outbox {
+	cfg := Config{
+		table:        pgx.Identifier{"outbox"},
+		batchSize:    100,
+		pollInterval: time.Second,
+		backoff:      DefaultBackoff,
+		onError:      func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	table := cfg.table.Sanitize()
+	return &Outbox{
+		db:  db,
+		cfg: cfg,
+		queries: queries{
+			insert: fmt.Sprintf("INSERT INTO %s (topic, key, payload) VALUES ($1, $2, $3)", table),
+			// message is available only when there are no earlier pending messages with the same key
+			fetch: fmt.Sprintf(`SELECT o.id, o.topic, o.key, o.payload, o.attempts, o.created_at FROM %[1]s o
+WHERE o.published_at IS NULL AND o.failed_at IS NULL AND o.next_attempt_at <= now()
+AND (o.key = '' OR NOT EXISTS (
+	SELECT 1 FROM %[1]s p
+	WHERE p.key = o.key AND p.id < o.id AND p.published_at IS NULL AND p.failed_at IS NULL
+))
+ORDER BY o.id LIMIT $1 FOR UPDATE SKIP LOCKED`, table),
+			published: fmt.Sprintf("UPDATE %s SET published_at = now(), attempts = attempts + 1 WHERE id = $1", table),
+			retry: fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = $2,
+next_attempt_at = now() + make_interval(secs => $3) WHERE id = $1`, table),
+			failed: fmt.Sprintf(
+				"UPDATE %s SET attempts = attempts + 1, last_error = $2, failed_at = now() WHERE id = $1", table,
+			),
+			cleanup: fmt.Sprintf("DELETE FROM %s WHERE published_at < now() - make_interval(secs => $1)", table),
+		},
+	}
+}
+
+type EnqueueOption func(msg *Message)
+
+// WithKey keeps order of messages with the same key: next one isn't published until previous is published or failed.
+func WithKey(key string) EnqueueOption {
+	return func(msg *Message) {
+		msg.Key = key
+	}
+}
+
+// Enqueue writes message in transaction from context, so it is published only when transaction commits.
+func (o *Outbox) Enqueue(ctx context.Context, topic string, payload []byte, opts ...EnqueueOption) error {
+	tx, ok := pgcontext.TransactionFrom(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	msg := Message{Topic: topic, Payload: payload}
+	for _, opt := range opts {
+		opt(&msg)
+	}
+	if _, err := tx.Exec(ctx, o.queries.insert, msg.Topic, msg.Key, msg.Payload); err != nil {
+		return fmt.Errorf("can't enqueue outbox message: %w", err)
+	}
+	return nil
+}
+
+// Drain publishes one batch of pending messages, holding them locked until publication is recorded.
+// It returns number of processed messages.
+func (o *Outbox) Drain(ctx context.Context, publish Publisher) (int, error) {
+	var processed int
+	err := o.db.Transactional(pgcontext.WithCanWrite(ctx), func(ctx context.Context) error {
+		messages, err := o.fetch(ctx)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if err := o.publish(ctx, publish, msg); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("can't drain outbox: %w", err)
+	}
+	return processed, nil
+}
+
+func (o *Outbox) fetch(ctx context.Context) ([]Message, error) {
+	rows, err := o.db.Query(ctx, o.queries.fetch, o.cfg.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0, o.cfg.batchSize)
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &msg.Payload, &msg.Attempts, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("can't scan outbox message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't fetch outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (o *Outbox) publish(ctx context.Context, publish Publisher, msg Message) error {
+	pubErr := publish(ctx, msg)
+	var err error
+	switch {
+	case pubErr == nil:
+		_, err = o.db.Exec(ctx, o.queries.published, msg.ID)
+	case o.cfg.maxAttempts > 0 && msg.Attempts+1 >= o.cfg.maxAttempts:
+		_, err = o.db.Exec(ctx, o.queries.failed, msg.ID, pubErr.Error())
+	default:
+		_, err = o.db.Exec(ctx, o.queries.retry, msg.ID, pubErr.Error(), o.cfg.backoff(msg.Attempts+1).Seconds())
+	}
+	if err != nil {
+		return fmt.Errorf("can't record publication of outbox message %d: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Cleanup deletes messages published earlier than retention ago.
+func (o *Outbox) Cleanup(ctx context.Context, retention time.Duration) (int64, error) {
+	tag, err := o.db.Exec(pgcontext.WithCanWrite(ctx), o.queries.cleanup, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("can't cleanup outbox: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (o *Outbox) scopes(ctx context.Context) []context.Context {
+	if len(o.cfg.shards) == 0 {
+		return []context.Context{ctx}
+	}
+	scopes := make([]context.Context, 0, len(o.cfg.shards))
+	for _, id := range o.cfg.shards {
+		scopes = append(scopes, pgcontext.With(ctx, pgcontext.WithShardID(id)))
+	}
+	return scopes
+}
+
+// Relay drains outbox until context is done. Full batch is followed by next one immediately, otherwise relay
+// waits for poll interval. Errors are reported to error handler and retried on next round.
+func (o *Outbox) Relay(ctx context.Context, publish Publisher) error {
+	var lastCleanup time.Time
+	for {
+		busy := false
+		for _, scope := range o.scopes(ctx) {
+			processed, err := o.Drain(scope, publish)
+			if err != nil {
+				o.cfg.onError(err)
+				continue
+			}
+			busy = busy || processed >= o.cfg.batchSize
+		}
+
+		if o.cfg.retention > 0 && time.Since(lastCleanup) >= o.cfg.cleanupInterval {
+			lastCleanup = time.Now()
+			for _, scope := range o.scopes(ctx) {
+				if _, err := o.Cleanup(scope, o.cfg.retention); err != nil {
+					o.cfg.onError(err)
+				}
+			}
+		}
+
+		if busy && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.cfg.pollInterval):
+		}
+	}
+}
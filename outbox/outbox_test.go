@@ -0,0 +1,271 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func inTransaction(db *MockDB) {
+	db.EXPECT().Transactional(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			if !pgcontext.CanWriteFrom(ctx) {
+				return errors.New("transaction isn't routed to leader")
+			}
+			return fn(ctx)
+		})
+}
+
+func expectFetch(t *testing.T, db *MockDB, messages ...Message) *MockRows {
+	t.Helper()
+	rows := NewMockRows(t)
+	db.EXPECT().Query(mock.Anything, mock.Anything, 100).Return(rows, nil).Once()
+	for _, msg := range messages {
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Scan(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			RunAndReturn(func(dest ...any) error {
+				*dest[0].(*int64) = msg.ID
+				*dest[1].(*string) = msg.Topic
+				*dest[2].(*string) = msg.Key
+				*dest[3].(*[]byte) = msg.Payload
+				*dest[4].(*int) = msg.Attempts
+				return nil
+			}).Once()
+	}
+	rows.EXPECT().Next().Return(false).Once()
+	rows.EXPECT().Err().Return(nil)
+	rows.EXPECT().Close().Return()
+	return rows
+}
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should be able to build queries for custom table", func(t *testing.T) {
+		sut := New(NewMockDB(t), WithTable("events", "outbox"))
+
+		assert.Equal(t, `INSERT INTO "events"."outbox" (topic, key, payload) VALUES ($1, $2, $3)`, sut.queries.insert)
+		assert.Contains(t, sut.queries.fetch, `FOR UPDATE SKIP LOCKED`)
+	})
+
+	t.Run("should be able to use exponential backoff by default", func(t *testing.T) {
+		assert.Equal(t, 2*time.Second, DefaultBackoff(1))
+		assert.Equal(t, 64*time.Second, DefaultBackoff(100))
+	})
+}
+
+func TestOutbox_Enqueue(t *testing.T) {
+	t.Run("should be able to insert message in context transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx))
+		sut := New(NewMockDB(t))
+		tx.EXPECT().Exec(ctx, sut.queries.insert, "events", "user-1", []byte("payload")).
+			Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+		require.NoError(t, sut.Enqueue(ctx, "events", []byte("payload"), WithKey("user-1")))
+	})
+
+	t.Run("should be able to fail without transaction", func(t *testing.T) {
+		err := New(NewMockDB(t)).Enqueue(context.Background(), "events", []byte("payload"))
+		assert.ErrorIs(t, err, ErrNoTransaction)
+	})
+
+	t.Run("should be able to fail when insert fails", func(t *testing.T) {
+		tx := NewMockTx(t)
+		expErr := newError()
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(tx))
+		sut := New(NewMockDB(t))
+		tx.EXPECT().Exec(ctx, sut.queries.insert, "events", "", []byte("payload")).Return(pgconn.CommandTag{}, expErr)
+
+		assert.ErrorIs(t, sut.Enqueue(ctx, "events", []byte("payload")), expErr)
+	})
+}
+
+func TestOutbox_Drain(t *testing.T) {
+	t.Run("should be able to record published, retried and failed messages", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db, WithMaxAttempts(3), WithBackoff(func(attempts int) time.Duration {
+			return time.Duration(attempts) * time.Second
+		}))
+		inTransaction(db)
+		expectFetch(t, db,
+			Message{ID: 1, Topic: "events", Payload: []byte("ok")},
+			Message{ID: 2, Topic: "events", Payload: []byte("retry"), Attempts: 1},
+			Message{ID: 3, Topic: "events", Payload: []byte("fail"), Attempts: 2},
+		)
+		pubErr := newError()
+		db.EXPECT().Exec(mock.Anything, sut.queries.published, int64(1)).Return(pgconn.CommandTag{}, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.retry, int64(2), pubErr.Error(), float64(2)).
+			Return(pgconn.CommandTag{}, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.failed, int64(3), pubErr.Error()).Return(pgconn.CommandTag{}, nil)
+
+		processed, err := sut.Drain(context.Background(), func(_ context.Context, msg Message) error {
+			if string(msg.Payload) == "ok" {
+				return nil
+			}
+			return pubErr
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, processed)
+	})
+
+	t.Run("should be able to fail when messages can't be fetched", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		inTransaction(db)
+		db.EXPECT().Query(mock.Anything, mock.Anything, 100).Return(nil, expErr)
+
+		_, err := New(db).Drain(context.Background(), nil)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when message can't be scanned", func(t *testing.T) {
+		db := NewMockDB(t)
+		rows := NewMockRows(t)
+		expErr := newError()
+		inTransaction(db)
+		db.EXPECT().Query(mock.Anything, mock.Anything, 100).Return(rows, nil)
+		rows.EXPECT().Next().Return(true)
+		rows.EXPECT().Scan(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(expErr)
+		rows.EXPECT().Close().Return()
+
+		_, err := New(db).Drain(context.Background(), nil)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when rows fail", func(t *testing.T) {
+		db := NewMockDB(t)
+		rows := NewMockRows(t)
+		expErr := newError()
+		inTransaction(db)
+		db.EXPECT().Query(mock.Anything, mock.Anything, 100).Return(rows, nil)
+		rows.EXPECT().Next().Return(false)
+		rows.EXPECT().Err().Return(expErr)
+		rows.EXPECT().Close().Return()
+
+		_, err := New(db).Drain(context.Background(), nil)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when publication can't be recorded", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		inTransaction(db)
+		expectFetch(t, db, Message{ID: 1})
+		db.EXPECT().Exec(mock.Anything, sut.queries.published, int64(1)).Return(pgconn.CommandTag{}, expErr)
+
+		_, err := sut.Drain(context.Background(), func(context.Context, Message) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestOutbox_Cleanup(t *testing.T) {
+	t.Run("should be able to delete published messages", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		db.EXPECT().Exec(mock.MatchedBy(pgcontext.CanWriteFrom), sut.queries.cleanup, float64(60)).
+			Return(pgconn.NewCommandTag("DELETE 5"), nil)
+
+		deleted, err := sut.Cleanup(context.Background(), time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), deleted)
+	})
+
+	t.Run("should be able to fail when delete fails", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		db.EXPECT().Exec(mock.Anything, sut.queries.cleanup, float64(60)).Return(pgconn.CommandTag{}, expErr)
+
+		_, err := sut.Cleanup(context.Background(), time.Minute)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestOutbox_Relay(t *testing.T) {
+	t.Run("should be able to drain every shard and cleanup until context is done", func(t *testing.T) {
+		db := NewMockDB(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		var errs []error
+		sut := New(db,
+			WithShards(0, 1),
+			WithBatchSize(1),
+			WithPollInterval(time.Millisecond),
+			WithCleanup(time.Hour, time.Hour),
+			WithErrorHandler(func(err error) {
+				errs = append(errs, err)
+			}),
+		)
+		drainErr := newError()
+		cleanupErr := newError()
+		var shards []uint
+		var round int
+		db.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+				id, _ := pgcontext.ShardIDFrom(ctx)
+				shards = append(shards, id)
+				round++
+				switch round {
+				case 1:
+					return fn(ctx)
+				case 2:
+					return drainErr
+				default:
+					cancel()
+					return nil
+				}
+			})
+		rows := NewMockRows(t)
+		db.EXPECT().Query(mock.Anything, mock.Anything, 1).Return(rows, nil)
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Scan(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		rows.EXPECT().Next().Return(false).Once()
+		rows.EXPECT().Err().Return(nil)
+		rows.EXPECT().Close().Return()
+		db.EXPECT().Exec(mock.Anything, sut.queries.published, int64(0)).Return(pgconn.CommandTag{}, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.cleanup, float64(3600)).Return(pgconn.CommandTag{}, nil).Once()
+		db.EXPECT().Exec(mock.Anything, sut.queries.cleanup, float64(3600)).Return(pgconn.CommandTag{}, cleanupErr).Once()
+
+		err := sut.Relay(ctx, func(context.Context, Message) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []uint{0, 1, 0, 1}, shards)
+		require.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[0], drainErr)
+		assert.ErrorIs(t, errs[1], cleanupErr)
+	})
+
+	t.Run("should be able to wait for poll interval when outbox is empty", func(t *testing.T) {
+		db := NewMockDB(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		sut := New(db, WithPollInterval(time.Millisecond))
+		var rounds int
+		db.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(context.Context, func(context.Context) error) error {
+				rounds++
+				if rounds == 2 {
+					cancel()
+				}
+				return nil
+			})
+
+		assert.ErrorIs(t, sut.Relay(ctx, nil), context.Canceled)
+		assert.Equal(t, 2, rounds)
+	})
+}
@@ -0,0 +1,16 @@
+CREATE TABLE outbox (
+    id              BIGSERIAL   PRIMARY KEY,
+    topic           TEXT        NOT NULL,
+    key             TEXT        NOT NULL DEFAULT '',
+    payload         BYTEA       NOT NULL,
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at    TIMESTAMPTZ,
+    failed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE INDEX outbox_pending_key_idx ON outbox (key, id) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE INDEX outbox_published_idx ON outbox (published_at) WHERE published_at IS NOT NULL;
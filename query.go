@@ -0,0 +1,94 @@
+package elephant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNotFound    = errors.New("row is not found")
+	ErrTooManyRows = errors.New("query returned more than one row")
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// Querier is satisfied by every elephant DB and by pgx.Tx.
+type Querier interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+}
+
+type Execer interface {
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// rowTo maps row to struct by column names, and to T itself for other types, time.Time and sql.Scanner.
+func rowTo[T any]() pgx.RowToFunc[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType) {
+		return pgx.RowTo[T]
+	}
+	return pgx.RowToStructByName[T]
+}
+
+// QueryAll collects all rows of query. Struct fields are matched with columns by name or `db` tag.
+func QueryAll[T any](ctx context.Context, db Querier, query string, args ...any) ([]T, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, err := pgx.CollectRows(rows, rowTo[T]())
+	if err != nil {
+		return nil, fmt.Errorf("can't collect rows: %w", err)
+	}
+	return items, nil
+}
+
+// QueryOne returns the only row of query, ErrNotFound without rows and ErrTooManyRows with more than one row.
+func QueryOne[T any](ctx context.Context, db Querier, query string, args ...any) (T, error) {
+	var zero T
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return zero, err
+	}
+	item, err := pgx.CollectExactlyOneRow(rows, rowTo[T]())
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return zero, fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, pgx.ErrTooManyRows):
+		return zero, fmt.Errorf("%w: %w", ErrTooManyRows, err)
+	case err != nil:
+		return zero, fmt.Errorf("can't collect row: %w", err)
+	}
+	return item, nil
+}
+
+// QueryOptional is QueryOne, which returns nil instead of ErrNotFound.
+func QueryOptional[T any](ctx context.Context, db Querier, query string, args ...any) (*T, error) {
+	item, err := QueryOne[T](ctx, db, query, args...)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil //nolint:nilnil // absent row isn't an error here.
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ExecAffected returns number of rows affected by statement.
+func ExecAffected(ctx context.Context, db Execer, query string, args ...any) (int64, error) {
+	tag, err := db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
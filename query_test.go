@@ -0,0 +1,194 @@
+package elephant
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID   int64
+	Name string `db:"login"`
+}
+
+// rowsOf feeds rows of values, every row is scanned into single destination or into fields of struct.
+func rowsOf(t *testing.T, scanErr error, rows ...[]any) *MockRows {
+	t.Helper()
+	mockRows := NewMockRows(t)
+	for _, row := range rows {
+		mockRows.EXPECT().Next().Return(true).Once()
+		scan := func(dest ...any) error {
+			if scanErr != nil {
+				return scanErr
+			}
+			for i, value := range row {
+				switch d := dest[i].(type) {
+				case *int64:
+					*d = value.(int64)
+				case *string:
+					*d = value.(string)
+				case *time.Time:
+					*d = value.(time.Time)
+				case *pgtype.Text:
+					*d = pgtype.Text{String: value.(string), Valid: true}
+				}
+			}
+			return nil
+		}
+		if len(row) == 1 {
+			mockRows.EXPECT().Scan(mock.Anything).RunAndReturn(scan).Once()
+			continue
+		}
+		mockRows.EXPECT().FieldDescriptions().
+			Return([]pgconn.FieldDescription{{Name: "id"}, {Name: "login"}}).Once()
+		mockRows.EXPECT().Scan(mock.Anything, mock.Anything).RunAndReturn(scan).Once()
+	}
+	if scanErr == nil {
+		mockRows.EXPECT().Next().Return(false).Maybe()
+	}
+	mockRows.EXPECT().Close().Return().Maybe()
+	mockRows.EXPECT().Err().Return(nil).Maybe()
+	return mockRows
+}
+
+func expectQuery(t *testing.T, rows pgx.Rows, err error) *MockQuerier {
+	t.Helper()
+	db := NewMockQuerier(t)
+	db.EXPECT().Query(mock.Anything, "SELECT", 1).Return(rows, err)
+	return db
+}
+
+func TestQueryAll(t *testing.T) {
+	t.Run("should be able to collect scalars", func(t *testing.T) {
+		db := expectQuery(t, rowsOf(t, nil, []any{int64(1)}, []any{int64(2)}), nil)
+
+		items, err := QueryAll[int64](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, items)
+	})
+	t.Run("should be able to collect structs by column names", func(t *testing.T) {
+		db := expectQuery(t, rowsOf(t, nil, []any{int64(1), "root"}), nil)
+
+		items, err := QueryAll[user](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []user{{ID: 1, Name: "root"}}, items)
+	})
+	t.Run("should be able to scan time and scanners as values", func(t *testing.T) {
+		now := time.Now()
+		db := expectQuery(t, rowsOf(t, nil, []any{now}), nil)
+		times, err := QueryAll[time.Time](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{now}, times)
+
+		db = expectQuery(t, rowsOf(t, nil, []any{"text"}), nil)
+		texts, err := QueryAll[pgtype.Text](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []pgtype.Text{{String: "text", Valid: true}}, texts)
+	})
+	t.Run("should be able to return error of query", func(t *testing.T) {
+		expErr := newError()
+		db := expectQuery(t, nil, expErr)
+
+		_, err := QueryAll[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, expErr)
+	})
+	t.Run("should be able to return error of scan", func(t *testing.T) {
+		expErr := newError()
+		db := expectQuery(t, rowsOf(t, expErr, []any{int64(1)}), nil)
+
+		_, err := QueryAll[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestQueryOne(t *testing.T) {
+	t.Run("should be able to return the only row", func(t *testing.T) {
+		db := expectQuery(t, rowsOf(t, nil, []any{int64(1), "root"}), nil)
+
+		item, err := QueryOne[user](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		assert.Equal(t, user{ID: 1, Name: "root"}, item)
+	})
+	t.Run("should be able to return error, when there are no rows", func(t *testing.T) {
+		db := expectQuery(t, rowsOf(t, nil), nil)
+
+		_, err := QueryOne[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+	t.Run("should be able to return error, when there are many rows", func(t *testing.T) {
+		rows := NewMockRows(t)
+		rows.EXPECT().Next().Return(true).Twice()
+		rows.EXPECT().Scan(mock.Anything).Return(nil).Once()
+		rows.EXPECT().Close().Return()
+		db := expectQuery(t, rows, nil)
+
+		_, err := QueryOne[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, ErrTooManyRows)
+	})
+	t.Run("should be able to return error of scan", func(t *testing.T) {
+		expErr := newError()
+		db := expectQuery(t, rowsOf(t, expErr, []any{int64(1)}), nil)
+
+		_, err := QueryOne[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, expErr)
+	})
+	t.Run("should be able to return error of query", func(t *testing.T) {
+		expErr := newError()
+		db := expectQuery(t, nil, expErr)
+
+		_, err := QueryOne[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestQueryOptional(t *testing.T) {
+	t.Run("should be able to return the only row", func(t *testing.T) {
+		db := expectQuery(t, rowsOf(t, nil, []any{int64(7)}), nil)
+
+		item, err := QueryOptional[int64](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		require.NotNil(t, item)
+		assert.Equal(t, int64(7), *item)
+	})
+	t.Run("should be able to return nil, when there are no rows", func(t *testing.T) {
+		db := expectQuery(t, rowsOf(t, nil), nil)
+
+		item, err := QueryOptional[int64](context.Background(), db, "SELECT", 1)
+		require.NoError(t, err)
+		assert.Nil(t, item)
+	})
+	t.Run("should be able to return error of query", func(t *testing.T) {
+		expErr := newError()
+		db := expectQuery(t, nil, expErr)
+
+		_, err := QueryOptional[int64](context.Background(), db, "SELECT", 1)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestExecAffected(t *testing.T) {
+	t.Run("should be able to return number of affected rows", func(t *testing.T) {
+		db := NewMockExecer(t)
+		db.EXPECT().Exec(mock.Anything, "DELETE", 1).Return(pgconn.NewCommandTag("DELETE 3"), nil)
+
+		affected, err := ExecAffected(context.Background(), db, "DELETE", 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), affected)
+	})
+	t.Run("should be able to return error of statement", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockExecer(t)
+		db.EXPECT().Exec(mock.Anything, "DELETE", 1).Return(pgconn.CommandTag{}, expErr)
+
+		_, err := ExecAffected(context.Background(), db, "DELETE", 1)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
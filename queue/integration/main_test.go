@@ -0,0 +1,45 @@
+// Package integration runs queue against postgres in container, apart from queue tests, which don't need it.
+package integration
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/queue"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/godepo/groat"
+	"github.com/godepo/groat/integration"
+	"github.com/godepo/pgrx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Deps struct {
+	DB *pgxpool.Pool `groat:"pgxpool"`
+}
+
+type State struct{}
+
+var suite *integration.Container[Deps, State, *queue.Queue]
+
+func mainProvider(t *testing.T) *groat.Case[Deps, State, *queue.Queue] {
+	return groat.New[Deps, State, *queue.Queue](t, func(t *testing.T, deps Deps) *queue.Queue {
+		return queue.New(singlepg.New(deps.DB),
+			queue.WithMaxAttempts(2),
+			queue.WithVisibilityTimeout(time.Second),
+			queue.WithBackoff(func(int) time.Duration {
+				return 0
+			}),
+		)
+	})
+}
+
+func TestMain(m *testing.M) {
+	suite = integration.New[Deps, State, *queue.Queue](m, mainProvider,
+		pgrx.New[Deps](
+			pgrx.WithContainerImage("docker.io/postgres:16"),
+			pgrx.WithMigrationsPath("../sql"),
+		),
+	)
+	os.Exit(suite.Go())
+}
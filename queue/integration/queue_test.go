@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/queue"
+	"github.com/godepo/elephant/singlepg"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jobStatus(t *testing.T, db *pgxpool.Pool, id int64) (string, int) {
+	t.Helper()
+	var (
+		status   string
+		attempts int
+	)
+	require.NoError(t, db.QueryRow(context.Background(),
+		"SELECT status, attempts FROM jobs WHERE id = $1", id,
+	).Scan(&status, &attempts))
+	return status, attempts
+}
+
+func TestQueue_Jobs(t *testing.T) {
+	t.Run("should be able to run committed job together with its changes", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		db := singlepg.New(tcs.Deps.DB)
+
+		var id int64
+		require.NoError(t, db.Transactional(ctx, func(ctx context.Context) error {
+			var err error
+			id, err = tcs.SUT.Enqueue(ctx, "mail", []byte("hello"), queue.WithUniqueKey("hello"))
+			return err
+		}))
+		_, err := tcs.SUT.Enqueue(ctx, "mail", []byte("hello"), queue.WithUniqueKey("hello"))
+		require.ErrorIs(t, err, queue.ErrDuplicateJob)
+
+		found, err := tcs.SUT.RunOne(ctx, "mail", func(ctx context.Context, job queue.Job) error {
+			assert.Equal(t, []byte("hello"), job.Payload)
+			assert.Equal(t, 1, job.Attempts)
+			_, err := db.Exec(ctx, "CREATE TABLE mail_sent (id BIGINT)")
+			return err
+		})
+		require.NoError(t, err)
+		require.True(t, found)
+
+		status, _ := jobStatus(t, tcs.Deps.DB, id)
+		assert.Equal(t, queue.StatusDone, status)
+		_, err = tcs.Deps.DB.Exec(ctx, "SELECT FROM mail_sent")
+		require.NoError(t, err)
+
+		found, err = tcs.SUT.RunOne(ctx, "mail", nil)
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("should be able to retry and dead-letter failed job", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		db := singlepg.New(tcs.Deps.DB)
+		id, err := tcs.SUT.Enqueue(ctx, "retry", []byte("payload"))
+		require.NoError(t, err)
+
+		handler := func(ctx context.Context, _ queue.Job) error {
+			if _, err := db.Exec(ctx, "CREATE TABLE retry_side_effect (id BIGINT)"); err != nil {
+				return err
+			}
+			return errors.New("handler failed")
+		}
+		for range 2 {
+			found, err := tcs.SUT.RunOne(ctx, "retry", handler)
+			require.NoError(t, err)
+			require.True(t, found)
+		}
+
+		status, attempts := jobStatus(t, tcs.Deps.DB, id)
+		assert.Equal(t, queue.StatusDead, status)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("should be able to postpone scheduled job", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		_, err := tcs.SUT.Enqueue(ctx, "scheduled", []byte("payload"), queue.WithRunAt(time.Now().Add(time.Hour)))
+		require.NoError(t, err)
+
+		found, err := tcs.SUT.RunOne(ctx, "scheduled", nil)
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("should be able to dead-letter job of crashed worker after visibility timeout", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		id, err := tcs.SUT.Enqueue(ctx, "crashed", []byte("payload"), queue.WithJobMaxAttempts(1))
+		require.NoError(t, err)
+		_, err = tcs.Deps.DB.Exec(ctx,
+			"UPDATE jobs SET attempts = 1, run_at = now() - interval '1 second' WHERE id = $1", id,
+		)
+		require.NoError(t, err)
+
+		reaped, err := tcs.SUT.Reap(ctx, "crashed")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), reaped)
+		status, _ := jobStatus(t, tcs.Deps.DB, id)
+		assert.Equal(t, queue.StatusDead, status)
+	})
+
+	t.Run("should be able to handle every job once by concurrent workers", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for range 20 {
+			_, err := tcs.SUT.Enqueue(ctx, "concurrent", []byte("payload"))
+			require.NoError(t, err)
+		}
+		sut := queue.New(singlepg.New(tcs.Deps.DB), queue.WithConcurrency(4), queue.WithPollInterval(10*time.Millisecond))
+
+		var (
+			mu      sync.Mutex
+			handled = make(map[int64]int)
+		)
+		go func() {
+			_ = sut.Work(ctx, "concurrent", func(_ context.Context, job queue.Job) error {
+				mu.Lock()
+				defer mu.Unlock()
+				handled[job.ID]++
+				if len(handled) == 20 {
+					cancel()
+				}
+				return nil
+			})
+		}()
+
+		<-ctx.Done()
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Len(t, handled, 20)
+		for _, times := range handled {
+			assert.Equal(t, 1, times)
+		}
+	})
+}
@@ -0,0 +1,325 @@
+//go:generate mockery
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+var (
+	ErrDuplicateJob = errors.New("queue: job with the same unique key is already pending")
+
+	errLeaseExpired = errors.New("visibility timeout exceeded")
+)
+
+type DB interface {
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type Job struct {
+	ID          int64
+	Queue       string
+	Payload     []byte
+	Attempts    int
+	MaxAttempts int
+	CreatedAt   time.Time
+}
+
+// Handler processes job in transaction, which is rolled back on error. Context is canceled when visibility
+// timeout is exceeded.
+type Handler func(ctx context.Context, job Job) error
+
+type Backoff func(attempts int) time.Duration
+
+type Config struct {
+	table             pgx.Identifier
+	concurrency       int
+	pollInterval      time.Duration
+	visibilityTimeout time.Duration
+	maxAttempts       int
+	backoff           Backoff
+	onError           func(err error)
+}
+
+type Option func(cfg *Config)
+
+func WithTable(name ...string) Option {
+	return func(cfg *Config) {
+		cfg.table = name
+	}
+}
+
+func WithConcurrency(workers int) Option {
+	return func(cfg *Config) {
+		cfg.concurrency = workers
+	}
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithVisibilityTimeout limits time of job handling. Job of crashed worker becomes available again after it.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.visibilityTimeout = timeout
+	}
+}
+
+// WithMaxAttempts sets default max attempts of enqueued jobs, after which job becomes dead.
+func WithMaxAttempts(attempts int) Option {
+	return func(cfg *Config) {
+		cfg.maxAttempts = attempts
+	}
+}
+
+func WithBackoff(backoff Backoff) Option {
+	return func(cfg *Config) {
+		cfg.backoff = backoff
+	}
+}
+
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onError = fn
+	}
+}
+
+func DefaultBackoff(attempts int) time.Duration {
+	return time.Second << min(attempts, 10)
+}
+
+type queries struct {
+	insert string
+	claim  string
+	lock   string
+	done   string
+	retry  string
+	dead   string
+	reap   string
+}
+
+type Queue struct {
+	db      DB
+	cfg     Config
+	queries queries
+}
+
+func New(db DB, opts ...Option) *Queue {
+	cfg := Config{
+		table:             pgx.Identifier{"jobs"},
+		concurrency:       1,
+		pollInterval:      time.Second,
+		visibilityTimeout: time.Minute,
+		maxAttempts:       10,
+		backoff:           DefaultBackoff,
+		onError:           func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	table := cfg.table.Sanitize()
+	return &Queue{
+		db:  db,
+		cfg: cfg,
+		queries: queries{
+			insert: fmt.Sprintf(`INSERT INTO %s (queue, payload, unique_key, max_attempts, run_at)
+VALUES ($1, $2, $3, $4, COALESCE($5, now()))
+ON CONFLICT (queue, unique_key) WHERE status = 'pending' DO NOTHING RETURNING id`, table),
+			// claimed job is hidden from other workers until visibility timeout is exceeded
+			claim: fmt.Sprintf(`UPDATE %[1]s SET attempts = attempts + 1, run_at = now() + make_interval(secs => $2)
+WHERE id = (
+	SELECT id FROM %[1]s
+	WHERE queue = $1 AND status = 'pending' AND run_at <= now() AND attempts < max_attempts
+	ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED
+)
+RETURNING id, queue, payload, attempts, max_attempts, created_at`, table),
+			lock: fmt.Sprintf(
+				"SELECT id FROM %s WHERE id = $1 AND attempts = $2 AND status = 'pending' FOR UPDATE", table,
+			),
+			done: fmt.Sprintf("UPDATE %s SET status = 'done', finished_at = now() WHERE id = $1", table),
+			retry: fmt.Sprintf(
+				"UPDATE %s SET last_error = $2, run_at = now() + make_interval(secs => $3) WHERE id = $1", table,
+			),
+			dead: fmt.Sprintf(
+				"UPDATE %s SET status = 'dead', last_error = $2, finished_at = now() WHERE id = $1", table,
+			),
+			reap: fmt.Sprintf(`UPDATE %s SET status = 'dead', last_error = $2, finished_at = now()
+WHERE queue = $1 AND status = 'pending' AND run_at <= now() AND attempts >= max_attempts`, table),
+		},
+	}
+}
+
+type enqueueParams struct {
+	uniqueKey   *string
+	maxAttempts int
+	runAt       *time.Time
+}
+
+type EnqueueOption func(params *enqueueParams)
+
+// WithUniqueKey skips enqueueing with ErrDuplicateJob while job with the same key is pending in queue.
+func WithUniqueKey(key string) EnqueueOption {
+	return func(params *enqueueParams) {
+		params.uniqueKey = &key
+	}
+}
+
+func WithRunAt(at time.Time) EnqueueOption {
+	return func(params *enqueueParams) {
+		params.runAt = &at
+	}
+}
+
+func WithJobMaxAttempts(attempts int) EnqueueOption {
+	return func(params *enqueueParams) {
+		params.maxAttempts = attempts
+	}
+}
+
+// Enqueue adds job to queue. It joins transaction from context, so job becomes visible on commit.
+func (q *Queue) Enqueue(ctx context.Context, queue string, payload []byte, opts ...EnqueueOption) (int64, error) {
+	params := enqueueParams{maxAttempts: q.cfg.maxAttempts}
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	var id int64
+	err := q.db.QueryRow(
+		pgcontext.WithCanWrite(ctx), q.queries.insert, queue, payload, params.uniqueKey, params.maxAttempts, params.runAt,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrDuplicateJob
+	}
+	if err != nil {
+		return 0, fmt.Errorf("can't enqueue job: %w", err)
+	}
+	return id, nil
+}
+
+// RunOne claims next available job of queue and handles it. It returns false when queue has no available jobs.
+func (q *Queue) RunOne(ctx context.Context, queue string, handler Handler) (bool, error) {
+	ctx = pgcontext.WithCanWrite(ctx)
+
+	var job Job
+	err := q.db.QueryRow(ctx, q.queries.claim, queue, q.cfg.visibilityTimeout.Seconds()).
+		Scan(&job.ID, &job.Queue, &job.Payload, &job.Attempts, &job.MaxAttempts, &job.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("can't claim job: %w", err)
+	}
+
+	err = q.db.Transactional(ctx, func(ctx context.Context) error {
+		var id int64
+		err := q.db.QueryRow(ctx, q.queries.lock, job.ID, job.Attempts).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errLeaseExpired
+		}
+		if err != nil {
+			return fmt.Errorf("can't lock job: %w", err)
+		}
+		return q.finish(ctx, job, q.handle(ctx, job, handler))
+	})
+	if err != nil {
+		return true, fmt.Errorf("can't run job %d: %w", job.ID, err)
+	}
+	return true, nil
+}
+
+// handle runs handler in savepoint, so its changes are rolled back on error without losing job lock.
+func (q *Queue) handle(ctx context.Context, job Job, handler Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+
+	return q.db.Transactional(ctx, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, q.cfg.visibilityTimeout)
+		defer cancel()
+		return handler(ctx, job)
+	})
+}
+
+func (q *Queue) finish(ctx context.Context, job Job, handleErr error) error {
+	var err error
+	switch {
+	case handleErr == nil:
+		_, err = q.db.Exec(ctx, q.queries.done, job.ID)
+	case job.Attempts >= job.MaxAttempts:
+		_, err = q.db.Exec(ctx, q.queries.dead, job.ID, handleErr.Error())
+	default:
+		_, err = q.db.Exec(ctx, q.queries.retry, job.ID, handleErr.Error(), q.cfg.backoff(job.Attempts).Seconds())
+	}
+	if err != nil {
+		return fmt.Errorf("can't record job result: %w", err)
+	}
+	if handleErr != nil {
+		q.cfg.onError(fmt.Errorf("job %d failed: %w", job.ID, handleErr))
+	}
+	return nil
+}
+
+// Reap marks as dead jobs, which exceeded visibility timeout at last attempt.
+func (q *Queue) Reap(ctx context.Context, queue string) (int64, error) {
+	tag, err := q.db.Exec(pgcontext.WithCanWrite(ctx), q.queries.reap, queue, errLeaseExpired.Error())
+	if err != nil {
+		return 0, fmt.Errorf("can't reap jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Work handles jobs of queue by concurrent workers until context is done.
+func (q *Queue) Work(ctx context.Context, queue string, handler Handler) error {
+	var wg sync.WaitGroup
+	for range q.cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, queue, handler)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (q *Queue) work(ctx context.Context, queue string, handler Handler) {
+	for ctx.Err() == nil {
+		found, err := q.RunOne(ctx, queue, handler)
+		switch {
+		case err != nil:
+			q.cfg.onError(err)
+		case found:
+			continue
+		default:
+			if _, err := q.Reap(ctx, queue); err != nil {
+				q.cfg.onError(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(q.cfg.pollInterval):
+		}
+	}
+}
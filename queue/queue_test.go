@@ -0,0 +1,299 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func scanRow(t *testing.T, err error, values ...any) *MockRow {
+	t.Helper()
+	row := NewMockRow(t)
+	row.EXPECT().Scan(mock.Anything).RunAndReturn(func(dest ...any) error {
+		return scan(err, values, dest)
+	}).Maybe()
+	row.EXPECT().Scan(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(dest ...any) error {
+			return scan(err, values, dest)
+		}).Maybe()
+	return row
+}
+
+func scan(err error, values, dest []any) error {
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		switch d := dest[i].(type) {
+		case *int64:
+			*d = value.(int64)
+		case *int:
+			*d = value.(int)
+		case *string:
+			*d = value.(string)
+		case *[]byte:
+			*d = value.([]byte)
+		}
+	}
+	return nil
+}
+
+func inTransaction(db *MockDB) {
+	db.EXPECT().Transactional(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		})
+}
+
+func expectClaim(t *testing.T, db *MockDB, sut *Queue, attempts int) {
+	t.Helper()
+	db.EXPECT().QueryRow(mock.MatchedBy(pgcontext.CanWriteFrom), sut.queries.claim, "mail", float64(60)).
+		Return(scanRow(t, nil, int64(1), "mail", []byte("payload"), attempts, 2)).Once()
+}
+
+func expectLock(t *testing.T, db *MockDB, sut *Queue, attempts int, err error) {
+	t.Helper()
+	db.EXPECT().QueryRow(mock.Anything, sut.queries.lock, int64(1), attempts).
+		Return(scanRow(t, err, int64(1))).Once()
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should be able to build queries for custom table", func(t *testing.T) {
+		sut := New(NewMockDB(t), WithTable("background", "jobs"))
+
+		assert.Contains(t, sut.queries.claim, `UPDATE "background"."jobs"`)
+		assert.Contains(t, sut.queries.claim, "FOR UPDATE SKIP LOCKED")
+	})
+
+	t.Run("should be able to use exponential backoff by default", func(t *testing.T) {
+		assert.Equal(t, 2*time.Second, DefaultBackoff(1))
+		assert.Equal(t, 1024*time.Second, DefaultBackoff(100))
+	})
+}
+
+func TestQueue_Enqueue(t *testing.T) {
+	t.Run("should be able to insert job with options", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		key := "user-1"
+		at := time.Now()
+		db.EXPECT().
+			QueryRow(mock.MatchedBy(pgcontext.CanWriteFrom), sut.queries.insert, "mail", []byte("payload"), &key, 3, &at).
+			Return(scanRow(t, nil, int64(7)))
+
+		id, err := sut.Enqueue(context.Background(), "mail", []byte("payload"),
+			WithUniqueKey(key), WithJobMaxAttempts(3), WithRunAt(at),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), id)
+	})
+
+	t.Run("should be able to reject duplicate job", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.insert, "mail", []byte("payload"), mock.Anything, 10, mock.Anything).
+			Return(scanRow(t, pgx.ErrNoRows))
+
+		_, err := sut.Enqueue(context.Background(), "mail", []byte("payload"), WithUniqueKey("user-1"))
+		assert.ErrorIs(t, err, ErrDuplicateJob)
+	})
+
+	t.Run("should be able to fail when insert fails", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.insert, "mail", []byte("payload"), mock.Anything, 10, mock.Anything).
+			Return(scanRow(t, expErr))
+
+		_, err := sut.Enqueue(context.Background(), "mail", []byte("payload"))
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestQueue_RunOne(t *testing.T) {
+	t.Run("should be able to mark handled job as done", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		expectClaim(t, db, sut, 1)
+		inTransaction(db)
+		expectLock(t, db, sut, 1, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.done, int64(1)).Return(pgconn.CommandTag{}, nil)
+
+		found, err := sut.RunOne(context.Background(), "mail", func(ctx context.Context, job Job) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+			assert.Equal(t, Job{ID: 1, Queue: "mail", Payload: []byte("payload"), Attempts: 1, MaxAttempts: 2}, job)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("should be able to schedule retry of failed job", func(t *testing.T) {
+		db := NewMockDB(t)
+		var reported error
+		sut := New(db, WithErrorHandler(func(err error) {
+			reported = err
+		}))
+		handleErr := newError()
+		expectClaim(t, db, sut, 1)
+		inTransaction(db)
+		expectLock(t, db, sut, 1, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.retry, int64(1), handleErr.Error(), float64(2)).
+			Return(pgconn.CommandTag{}, nil)
+
+		found, err := sut.RunOne(context.Background(), "mail", func(context.Context, Job) error {
+			return handleErr
+		})
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.ErrorIs(t, reported, handleErr)
+	})
+
+	t.Run("should be able to dead-letter job at last attempt", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db, WithMaxAttempts(2), WithBackoff(DefaultBackoff), WithVisibilityTimeout(time.Minute))
+		expectClaim(t, db, sut, 2)
+		inTransaction(db)
+		expectLock(t, db, sut, 2, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.dead, int64(1), "job handler panicked: boom").
+			Return(pgconn.CommandTag{}, nil)
+
+		found, err := sut.RunOne(context.Background(), "mail", func(context.Context, Job) error {
+			panic("boom")
+		})
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("should be able to return false for empty queue", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.claim, "mail", float64(60)).Return(scanRow(t, pgx.ErrNoRows))
+
+		found, err := sut.RunOne(context.Background(), "mail", nil)
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("should be able to fail when job can't be claimed", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.claim, "mail", float64(60)).Return(scanRow(t, expErr))
+
+		_, err := sut.RunOne(context.Background(), "mail", nil)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to skip job with expired lease", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		expectClaim(t, db, sut, 1)
+		inTransaction(db)
+		expectLock(t, db, sut, 1, pgx.ErrNoRows)
+
+		found, err := sut.RunOne(context.Background(), "mail", nil)
+		assert.True(t, found)
+		assert.ErrorIs(t, err, errLeaseExpired)
+	})
+
+	t.Run("should be able to fail when job can't be locked", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		expectClaim(t, db, sut, 1)
+		inTransaction(db)
+		expectLock(t, db, sut, 1, expErr)
+
+		_, err := sut.RunOne(context.Background(), "mail", nil)
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to fail when result can't be recorded", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		expectClaim(t, db, sut, 1)
+		inTransaction(db)
+		expectLock(t, db, sut, 1, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.done, int64(1)).Return(pgconn.CommandTag{}, expErr)
+
+		_, err := sut.RunOne(context.Background(), "mail", func(context.Context, Job) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestQueue_Reap(t *testing.T) {
+	t.Run("should be able to mark expired jobs as dead", func(t *testing.T) {
+		db := NewMockDB(t)
+		sut := New(db)
+		db.EXPECT().Exec(mock.MatchedBy(pgcontext.CanWriteFrom), sut.queries.reap, "mail", errLeaseExpired.Error()).
+			Return(pgconn.NewCommandTag("UPDATE 2"), nil)
+
+		reaped, err := sut.Reap(context.Background(), "mail")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), reaped)
+	})
+
+	t.Run("should be able to fail when update fails", func(t *testing.T) {
+		db := NewMockDB(t)
+		expErr := newError()
+		sut := New(db)
+		db.EXPECT().Exec(mock.Anything, sut.queries.reap, "mail", errLeaseExpired.Error()).
+			Return(pgconn.CommandTag{}, expErr)
+
+		_, err := sut.Reap(context.Background(), "mail")
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestQueue_Work(t *testing.T) {
+	t.Run("should be able to run jobs and reap idle queue until context is done", func(t *testing.T) {
+		db := NewMockDB(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		var errs []error
+		sut := New(db, WithConcurrency(1), WithPollInterval(time.Millisecond), WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}))
+		claimErr := newError()
+		reapErr := newError()
+		expectClaim(t, db, sut, 1)
+		inTransaction(db)
+		expectLock(t, db, sut, 1, nil)
+		db.EXPECT().Exec(mock.Anything, sut.queries.done, int64(1)).Return(pgconn.CommandTag{}, nil)
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.claim, "mail", float64(60)).
+			Return(scanRow(t, claimErr)).Once()
+		db.EXPECT().QueryRow(mock.Anything, sut.queries.claim, "mail", float64(60)).
+			Return(scanRow(t, pgx.ErrNoRows)).Once()
+		db.EXPECT().Exec(mock.Anything, sut.queries.reap, "mail", errLeaseExpired.Error()).
+			RunAndReturn(func(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+				cancel()
+				return pgconn.CommandTag{}, reapErr
+			})
+
+		err := sut.Work(ctx, "mail", func(context.Context, Job) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		require.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[0], claimErr)
+		assert.ErrorIs(t, errs[1], reapErr)
+	})
+}
@@ -0,0 +1,16 @@
+CREATE TABLE jobs (
+    id           BIGSERIAL   PRIMARY KEY,
+    queue        TEXT        NOT NULL,
+    payload      BYTEA       NOT NULL,
+    status       TEXT        NOT NULL DEFAULT 'pending',
+    unique_key   TEXT,
+    attempts     INT         NOT NULL DEFAULT 0,
+    max_attempts INT         NOT NULL,
+    last_error   TEXT,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at  TIMESTAMPTZ
+);
+
+CREATE INDEX jobs_pending_idx ON jobs (queue, run_at, id) WHERE status = 'pending';
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (queue, unique_key) WHERE status = 'pending';
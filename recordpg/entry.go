@@ -0,0 +1,241 @@
+package recordpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Kinds of recorded operations.
+const (
+	KindQuery             = "query"
+	KindExec              = "exec"
+	KindBatch             = "batch"
+	KindCopyFrom          = "copy_from"
+	KindCopyTo            = "copy_to"
+	KindBegin             = "begin"
+	KindCommit            = "commit"
+	KindRollback          = "rollback"
+	KindSavepoint         = "savepoint"
+	KindRelease           = "release"
+	KindRollbackSavepoint = "rollback_savepoint"
+)
+
+// sentinels survive replay, so errors.Is of replayed errors works as with recorded ones.
+var sentinels = []error{
+	context.Canceled,
+	context.DeadlineExceeded,
+	pgx.ErrNoRows,
+	pgx.ErrTxClosed,
+	pgx.ErrTxCommitRollback,
+	pgerr.ErrStatementTimeout,
+	pgerr.ErrLockTimeout,
+	pgerr.ErrLockNotAvailable,
+	pgerr.ErrAcquireNotSupported,
+}
+
+// Entry is one line of recording. Tx is id of transaction, which operation ran in, or which boundary opens or
+// closes. Nodes are names of nodes wrapped by Node, which pool routed operation to, replay doesn't match them.
+type Entry struct {
+	Seq   int64           `json:"seq"`
+	Kind  string          `json:"kind"`
+	Tx    int64           `json:"tx,omitempty"`
+	Hints Hints           `json:"hints"`
+	Nodes []string        `json:"nodes,omitempty"`
+	SQL   string          `json:"sql,omitempty"`
+	Args  json.RawMessage `json:"args,omitempty"`
+	Batch []Statement     `json:"batch,omitempty"`
+	Result
+	Results []Result `json:"results,omitempty"`
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("#%d %s", e.Seq, describe(e.Kind, e.SQL, e.Args, e.Batch, e.Hints))
+}
+
+// Hints are routing options of operation, taken from context. They aren't the node, which served operation: pool
+// picks follower or shard of sharding key itself, Entry.Nodes tell its choice.
+type Hints struct {
+	CanWrite    bool   `json:"can_write,omitempty"`
+	ShardID     *uint  `json:"shard_id,omitempty"`
+	ShardingKey string `json:"sharding_key,omitempty"`
+	Tenant      string `json:"tenant,omitempty"`
+}
+
+func hintsFrom(ctx context.Context) Hints {
+	hints := Hints{CanWrite: pgcontext.CanWriteFrom(ctx)}
+	if id, ok := pgcontext.ShardIDFrom(ctx); ok {
+		hints.ShardID = &id
+	}
+	hints.ShardingKey, _ = pgcontext.ShardingKeyFrom(ctx)
+	hints.Tenant, _ = pgcontext.TenantFrom(ctx)
+	return hints
+}
+
+func (h Hints) equal(other Hints) bool {
+	if (h.ShardID == nil) != (other.ShardID == nil) || h.ShardID != nil && *h.ShardID != *other.ShardID {
+		return false
+	}
+	return h.CanWrite == other.CanWrite && h.ShardingKey == other.ShardingKey && h.Tenant == other.Tenant
+}
+
+func (h Hints) String() string {
+	var parts []string
+	if h.CanWrite {
+		parts = append(parts, "can_write")
+	}
+	if h.ShardID != nil {
+		parts = append(parts, fmt.Sprintf("shard_id=%d", *h.ShardID))
+	}
+	if h.ShardingKey != "" {
+		parts = append(parts, "sharding_key="+h.ShardingKey)
+	}
+	if h.Tenant != "" {
+		parts = append(parts, "tenant="+h.Tenant)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Statement is query of batch.
+type Statement struct {
+	SQL  string          `json:"sql"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+func statements(b *pgx.Batch) []Statement {
+	out := make([]Statement, 0, len(b.QueuedQueries))
+	for _, qq := range b.QueuedQueries {
+		out = append(out, Statement{SQL: qq.SQL, Args: encodeArgs(qq.Arguments)})
+	}
+	return out
+}
+
+// Result of operation. Rows keep raw values in format of fields, so replay scans them as pgx does. Error is returned
+// by the call itself, RowsError by rows after iteration.
+type Result struct {
+	Fields    []Field    `json:"fields,omitempty"`
+	Rows      [][][]byte `json:"rows,omitempty"`
+	Data      []byte     `json:"data,omitempty"`
+	Tag       string     `json:"tag,omitempty"`
+	Error     *Error     `json:"error,omitempty"`
+	RowsError *Error     `json:"rows_error,omitempty"`
+}
+
+type Field struct {
+	Name   string `json:"name"`
+	OID    uint32 `json:"oid"`
+	Format int16  `json:"format,omitempty"`
+}
+
+func fieldsOf(descriptions []pgconn.FieldDescription) []Field {
+	if len(descriptions) == 0 {
+		return nil
+	}
+	out := make([]Field, 0, len(descriptions))
+	for _, fd := range descriptions {
+		out = append(out, Field{Name: fd.Name, OID: fd.DataTypeOID, Format: fd.Format})
+	}
+	return out
+}
+
+func descriptionsOf(fields []Field) []pgconn.FieldDescription {
+	out := make([]pgconn.FieldDescription, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, pgconn.FieldDescription{Name: f.Name, DataTypeOID: f.OID, Format: f.Format})
+	}
+	return out
+}
+
+// Error keeps message of recorded error, postgres error and known sentinels, which it wraps.
+type Error struct {
+	Message string          `json:"message"`
+	PgError *pgconn.PgError `json:"pg_error,omitempty"`
+	Is      []string        `json:"is,omitempty"`
+}
+
+func errorOf(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	out := &Error{Message: err.Error()}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		out.PgError = pgErr
+	}
+	for _, sentinel := range sentinels {
+		if errors.Is(err, sentinel) {
+			out.Is = append(out.Is, sentinel.Error())
+		}
+	}
+	return out
+}
+
+func (e *Error) err() error {
+	if e == nil {
+		return nil
+	}
+	out := &replayedError{message: e.Message}
+	if e.PgError != nil {
+		out.wrapped = append(out.wrapped, e.PgError)
+	}
+	for _, msg := range e.Is {
+		for _, sentinel := range sentinels {
+			if sentinel.Error() == msg {
+				out.wrapped = append(out.wrapped, sentinel)
+			}
+		}
+	}
+	return out
+}
+
+type replayedError struct {
+	message string
+	wrapped []error
+}
+
+func (e *replayedError) Error() string {
+	return e.message
+}
+
+func (e *replayedError) Unwrap() []error {
+	return e.wrapped
+}
+
+// encodeArgs encodes arguments as JSON, arguments without JSON form are encoded as text.
+func encodeArgs(args []any) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+	out, err := json.Marshal(args)
+	if err != nil {
+		out, _ = json.Marshal(fmt.Sprint(args))
+	}
+	return out
+}
+
+func describe(kind, sql string, args json.RawMessage, batch []Statement, hints Hints) string {
+	var buf bytes.Buffer
+	buf.WriteString(kind)
+	if sql != "" {
+		fmt.Fprintf(&buf, " %q", sql)
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(&buf, " %s", args)
+	}
+	for _, st := range batch {
+		fmt.Fprintf(&buf, " %q", st.SQL)
+		if len(st.Args) > 0 {
+			fmt.Fprintf(&buf, " %s", st.Args)
+		}
+	}
+	buf.WriteString(" " + hints.String())
+	return buf.String()
+}
@@ -0,0 +1,155 @@
+package recordpg
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type routeKey struct{}
+
+// route collects names of nodes, which pool routes operation to. Hive calls shards concurrently, so it's guarded.
+type route struct {
+	mu    sync.Mutex
+	nodes []string
+}
+
+func (r *route) add(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Contains(r.nodes, name) {
+		r.nodes = append(r.nodes, name)
+	}
+}
+
+func (r *route) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.nodes)
+}
+
+// routed returns context, which collects nodes of operation, and function, which reports them once pool returns.
+func routed(ctx context.Context) (context.Context, func() []string) {
+	rt := &route{}
+	return context.WithValue(ctx, routeKey{}, rt), rt.names
+}
+
+// unrouted hides route of transaction boundary from statements of fn.
+func unrouted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, (*route)(nil))
+}
+
+type healthChecker interface {
+	Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report
+}
+
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+type closer interface {
+	Close()
+}
+
+// node passes calls to pool and reports its name to route of recorded operation.
+type node struct {
+	name string
+	pool Pool
+}
+
+// Node wraps leader, follower or shard, so entries of operations, which pool routes to it, record its name in Nodes.
+// Wrap nodes before passing them to clusterpg or shardedpg builders and record the built pool.
+func Node(name string, pool Pool) Pool {
+	return &node{name: name, pool: pool}
+}
+
+func (n *node) mark(ctx context.Context) {
+	if rt, _ := ctx.Value(routeKey{}).(*route); rt != nil {
+		rt.add(n.name)
+	}
+}
+
+func (n *node) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	n.mark(ctx)
+	return n.pool.BeginTx(ctx, opts)
+}
+
+func (n *node) Begin(ctx context.Context) (pgx.Tx, error) {
+	n.mark(ctx)
+	return n.pool.Begin(ctx)
+}
+
+func (n *node) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	n.mark(ctx)
+	return n.pool.Query(ctx, query, args...)
+}
+
+func (n *node) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	n.mark(ctx)
+	return n.pool.QueryRow(ctx, query, args...)
+}
+
+func (n *node) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	n.mark(ctx)
+	return n.pool.Exec(ctx, query, args...)
+}
+
+func (n *node) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	n.mark(ctx)
+	return n.pool.SendBatch(ctx, b)
+}
+
+func (n *node) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	n.mark(ctx)
+	return n.pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (n *node) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	n.mark(ctx)
+	return n.pool.CopyTo(ctx, w, query)
+}
+
+func (n *node) Transactional(ctx context.Context, fn func(ctx context.Context) error) error {
+	n.mark(ctx)
+	return n.pool.Transactional(ctx, fn)
+}
+
+// Acquire, Health, Shutdown and Close keep optional methods of pool, nodes without them behave as in cluster.
+
+func (n *node) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	pool, ok := n.pool.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	return pool.Acquire(ctx)
+}
+
+func (n *node) Health(ctx context.Context, rules ...pghealth.Rule) pghealth.Report {
+	if checker, ok := n.pool.(healthChecker); ok {
+		return checker.Health(ctx, rules...)
+	}
+	return pghealth.Aggregate([]pghealth.Node{pghealth.Probe(ctx, n.pool)}, rules...)
+}
+
+func (n *node) Shutdown(ctx context.Context) error {
+	if pool, ok := n.pool.(shutdowner); ok {
+		return pool.Shutdown(ctx)
+	}
+	n.Close()
+	return nil
+}
+
+func (n *node) Close() {
+	if pool, ok := n.pool.(closer); ok {
+		pool.Close()
+	}
+}
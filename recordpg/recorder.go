@@ -0,0 +1,334 @@
+//go:generate mockery
+package recordpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pool is satisfied by elephant databases and by pools of clusterpg and shardedpg.
+type Pool interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+type Config struct {
+	onError func(err error)
+	strict  bool
+}
+
+type Option func(cfg *Config)
+
+// WithErrorHandler receives errors of writing recording, by default they are ignored.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *Config) {
+		cfg.onError = fn
+	}
+}
+
+// WithStrictOrder makes replayer match calls in recorded order, by default a call takes the first recorded entry
+// equal to it, so concurrent calls replay too.
+func WithStrictOrder() Option {
+	return func(cfg *Config) {
+		cfg.strict = true
+	}
+}
+
+// Recorder wraps pool and writes its operations with results to JSON lines. Entry of query is written, when its rows
+// are closed, entry of batch - when batch is closed, so entries are ordered by Seq, not by lines.
+type Recorder struct {
+	pool Pool
+	cfg  Config
+	mu   sync.Mutex
+	enc  *json.Encoder
+	seq  atomic.Int64
+	txs  atomic.Int64
+}
+
+func NewRecorder(pool Pool, w io.Writer, opts ...Option) *Recorder {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Recorder{pool: pool, cfg: cfg, enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) entry(ctx context.Context, kind string, tx int64) Entry {
+	return Entry{Seq: r.seq.Add(1), Kind: kind, Tx: tx, Hints: hintsFrom(ctx)}
+}
+
+func (r *Recorder) write(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(entry); err != nil && r.cfg.onError != nil {
+		r.cfg.onError(fmt.Errorf("can't write recording: %w", err))
+	}
+}
+
+// inTx reports, that context has recorded transaction, which records statements routed to it by pool.
+func (r *Recorder) inTx(ctx context.Context) bool {
+	tx, _ := pgcontext.TransactionFrom(ctx)
+	return r.owns(tx)
+}
+
+func (r *Recorder) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	entry := r.entry(ctx, KindBegin, 0)
+	ctx, nodes := routed(ctx)
+	tx, err := r.pool.BeginTx(ctx, opts)
+	entry.Nodes = nodes()
+	return r.begun(entry, tx, err, false)
+}
+
+func (r *Recorder) Begin(ctx context.Context) (pgx.Tx, error) {
+	entry := r.entry(ctx, KindBegin, 0)
+	ctx, nodes := routed(ctx)
+	tx, err := r.pool.Begin(ctx)
+	entry.Nodes = nodes()
+	return r.begun(entry, tx, err, false)
+}
+
+func (r *Recorder) begun(entry Entry, tx pgx.Tx, err error, nested bool) (pgx.Tx, error) {
+	if err != nil {
+		entry.Error = errorOf(err)
+		r.write(entry)
+		return nil, err
+	}
+	wrapped := r.wrapTx(tx, nested)
+	entry.Tx = wrapped.id
+	r.write(entry)
+	return wrapped, nil
+}
+
+// Acquire passes dedicated connection of pool as is, it isn't recorded.
+func (r *Recorder) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	pool, ok := r.pool.(acquirer)
+	if !ok {
+		return nil, pgerr.ErrAcquireNotSupported
+	}
+	return pool.Acquire(ctx)
+}
+
+func (r *Recorder) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	if r.inTx(ctx) {
+		return r.pool.Query(ctx, query, args...)
+	}
+	return r.query(ctx, r.pool, 0, query, args)
+}
+
+func (r *Recorder) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	rows, err := r.Query(ctx, query, args...)
+	return &row{rows: rows, err: err}
+}
+
+func (r *Recorder) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	if r.inTx(ctx) {
+		return r.pool.Exec(ctx, query, args...)
+	}
+	return r.exec(ctx, r.pool, 0, query, args)
+}
+
+func (r *Recorder) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if r.inTx(ctx) {
+		return r.pool.SendBatch(ctx, b)
+	}
+	return r.sendBatch(ctx, r.pool, 0, b)
+}
+
+func (r *Recorder) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	if r.inTx(ctx) {
+		return r.pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+	return r.copyFrom(ctx, r.pool, 0, tableName, columnNames, rowSrc)
+}
+
+// CopyTo records exported data, transaction doesn't run COPY TO itself, so it's recorded here also in transaction.
+func (r *Recorder) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	var id int64
+	if tx, _ := pgcontext.TransactionFrom(ctx); r.owns(tx) {
+		id = tx.(*recordingTx).id
+	}
+	entry := r.entry(ctx, KindCopyTo, id)
+	entry.SQL = query
+	var data bytes.Buffer
+	ctx, nodes := routed(ctx)
+	tag, err := r.pool.CopyTo(ctx, io.MultiWriter(w, &data), query)
+	entry.Nodes = nodes()
+	entry.Data, entry.Tag, entry.Error = data.Bytes(), tag.String(), errorOf(err)
+	r.write(entry)
+	return tag, err
+}
+
+// Transactional records boundaries of transaction or savepoint, which pool runs fn in. Pool gets unwrapped
+// transaction from context, so statements of pool itself, like settings of transaction, aren't recorded and replay
+// doesn't depend on them.
+func (r *Recorder) Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error) {
+	kind, parent := KindBegin, int64(0)
+	if tx, _ := pgcontext.TransactionFrom(ctx); r.owns(tx) {
+		outer := tx.(*recordingTx)
+		kind, parent = KindSavepoint, outer.id
+		ctx = pgcontext.With(ctx, pgcontext.WithTransaction(outer.Tx))
+	}
+	var (
+		begun  *recordingTx
+		called bool
+		fnErr  error
+		begin  = r.entry(ctx, kind, parent)
+	)
+	routedCtx, nodes := routed(ctx)
+	err := r.pool.Transactional(routedCtx, func(ctx context.Context) error {
+		called = true
+		ctx = unrouted(ctx)
+		tx, ok := pgcontext.TransactionFrom(ctx)
+		if !ok {
+			fnErr = fn(ctx)
+			return fnErr
+		}
+		begun = r.wrapTx(tx, kind == KindSavepoint)
+		begin.Tx, begin.Nodes = begun.id, nodes()
+		r.write(begin)
+		fnErr = fn(pgcontext.With(ctx, pgcontext.WithTransaction(begun)))
+		return fnErr
+	})
+	if begun == nil {
+		if !called && err != nil {
+			begin.Nodes, begin.Error = nodes(), errorOf(err)
+			r.write(begin)
+		}
+		return err
+	}
+
+	end := r.entry(ctx, begun.boundary(KindCommit, KindRelease), begun.id)
+	if fnErr != nil && !passes(ctx, fnErr) {
+		end.Kind = begun.boundary(KindRollback, KindRollbackSavepoint)
+	} else if err != nil && !errors.Is(err, fnErr) {
+		end.Error = errorOf(err)
+	}
+	r.write(end)
+	return err
+}
+
+func passes(ctx context.Context, err error) bool {
+	matcher, ok := pgcontext.TxPassMatcherFrom(ctx)
+	return ok && matcher(ctx, err)
+}
+
+// db is the part of pool and transaction, which runs statements.
+type db interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+func (r *Recorder) query(ctx context.Context, db db, tx int64, query string, args []any) (pgx.Rows, error) {
+	entry := r.entry(ctx, KindQuery, tx)
+	entry.SQL, entry.Args = query, encodeArgs(args)
+	ctx, nodes := routed(ctx)
+	rows, err := db.Query(ctx, query, args...)
+	entry.Nodes = nodes()
+	if err != nil {
+		entry.Error = errorOf(err)
+		r.write(entry)
+		return nil, err
+	}
+	return &recordingRows{Rows: rows, done: func(result Result) {
+		entry.Result = result
+		r.write(entry)
+	}}, nil
+}
+
+func (r *Recorder) exec(ctx context.Context, db db, tx int64, query string, args []any) (pgconn.CommandTag, error) {
+	entry := r.entry(ctx, KindExec, tx)
+	entry.SQL, entry.Args = query, encodeArgs(args)
+	ctx, nodes := routed(ctx)
+	tag, err := db.Exec(ctx, query, args...)
+	entry.Nodes = nodes()
+	entry.Tag, entry.Error = tag.String(), errorOf(err)
+	r.write(entry)
+	return tag, err
+}
+
+func (r *Recorder) sendBatch(ctx context.Context, db db, tx int64, b *pgx.Batch) pgx.BatchResults {
+	entry := r.entry(ctx, KindBatch, tx)
+	entry.Batch = statements(b)
+	ctx, nodes := routed(ctx)
+	results := db.SendBatch(ctx, b)
+	entry.Nodes = nodes()
+	done := func(results []Result, err error) {
+		entry.Results, entry.Error = results, errorOf(err)
+		r.write(entry)
+	}
+	return &recordingBatch{results: results, queued: b.QueuedQueries, done: done}
+}
+
+func (r *Recorder) copyFrom(
+	ctx context.Context, db db, tx int64, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	entry := r.entry(ctx, KindCopyFrom, tx)
+	entry.SQL = copySQL(tableName, columnNames)
+	src := &copySource{CopyFromSource: rowSrc}
+	ctx, nodes := routed(ctx)
+	n, err := db.CopyFrom(ctx, tableName, columnNames, src)
+	entry.Nodes = nodes()
+	entry.Args = encodeRows(src.rows)
+	entry.Tag, entry.Error = fmt.Sprintf("COPY %d", n), errorOf(err)
+	r.write(entry)
+	return n, err
+}
+
+func copySQL(tableName pgx.Identifier, columnNames []string) string {
+	columns := make([]string, 0, len(columnNames))
+	for _, name := range columnNames {
+		columns = append(columns, pgx.Identifier{name}.Sanitize())
+	}
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", tableName.Sanitize(), strings.Join(columns, ", "))
+}
+
+func encodeRows(rows [][]any) json.RawMessage {
+	args := make([]any, 0, len(rows))
+	for _, row := range rows {
+		args = append(args, row)
+	}
+	return encodeArgs(args)
+}
+
+// copySource keeps rows, which pool reads from source, in memory until COPY ends, as they are written in one entry.
+type copySource struct {
+	pgx.CopyFromSource
+	rows [][]any
+}
+
+func (s *copySource) Values() ([]any, error) {
+	values, err := s.CopyFromSource.Values()
+	if err == nil {
+		s.rows = append(s.rows, slices.Clone(values))
+	}
+	return values, err
+}
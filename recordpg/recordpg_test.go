@@ -0,0 +1,1020 @@
+package recordpg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/clusterpg"
+	"github.com/godepo/elephant/elephanttest"
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+type user struct {
+	ID    int64
+	Login *string
+	Score int32
+}
+
+func usersResult() Result {
+	return Result{
+		Fields: []Field{
+			{Name: "id", OID: pgtype.Int8OID},
+			{Name: "login", OID: pgtype.TextOID},
+			{Name: "score", OID: pgtype.Int4OID, Format: pgtype.BinaryFormatCode},
+		},
+		Rows: [][][]byte{
+			{[]byte("1"), []byte("admin"), {0, 0, 0, 7}},
+			{[]byte("2"), nil, {0, 0, 0, 9}},
+		},
+		Tag: "SELECT 2",
+	}
+}
+
+// rowsOf serves result as rows of pool under recording.
+func rowsOf(result Result) pgx.Rows {
+	return newReplayRows(pgtype.NewMap(), result)
+}
+
+func collectUsers(rows pgx.Rows, err error) ([]user, error) {
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[user])
+}
+
+func replay(t *testing.T, recording *bytes.Buffer, opts ...Option) *Replayer {
+	t.Helper()
+	rp, err := Load(bytes.NewReader(recording.Bytes()), opts...)
+	require.NoError(t, err)
+	return rp
+}
+
+func entries(t *testing.T, recording *bytes.Buffer) []Entry {
+	t.Helper()
+	rp := replay(t, recording)
+	out := make([]Entry, 0, len(rp.entries))
+	for _, entry := range rp.entries {
+		out = append(out, *entry)
+	}
+	return out
+}
+
+func kinds(t *testing.T, recording *bytes.Buffer) []string {
+	t.Helper()
+	var out []string
+	for _, entry := range entries(t, recording) {
+		out = append(out, entry.Kind)
+	}
+	return out
+}
+
+func TestRecorder_Query(t *testing.T) {
+	ctx := pgcontext.With(pgcontext.WithCanWrite(context.Background()),
+		pgcontext.WithShardID(2), pgcontext.WithTenant("acme"))
+
+	t.Run("should be able to record and replay rows", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Query(mock.Anything, "SELECT * FROM users WHERE id > $1", int64(0)).Return(rowsOf(usersResult()), nil)
+		var recording bytes.Buffer
+		recorded, err := collectUsers(NewRecorder(pool, &recording).Query(ctx, "SELECT * FROM users WHERE id > $1", int64(0)))
+		require.NoError(t, err)
+		require.Len(t, recorded, 2)
+		assert.Equal(t, "admin", *recorded[0].Login)
+		assert.Equal(t, int32(9), recorded[1].Score)
+
+		got := entries(t, &recording)
+		require.Len(t, got, 1)
+		assert.Equal(t, KindQuery, got[0].Kind)
+		assert.Equal(t, `[0]`, string(got[0].Args))
+		assert.True(t, got[0].Hints.CanWrite)
+		assert.Equal(t, uint(2), *got[0].Hints.ShardID)
+		assert.Equal(t, "acme", got[0].Hints.Tenant)
+		assert.Equal(t, "SELECT 2", got[0].Tag)
+
+		rp := replay(t, &recording)
+		replayed, err := collectUsers(rp.Query(ctx, "SELECT * FROM users WHERE id > $1", int64(0)))
+		require.NoError(t, err)
+		assert.Equal(t, recorded, replayed)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record and replay error of query", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().Query(mock.Anything, "SELECT").Return(nil, expErr)
+		var recording bytes.Buffer
+		_, err := NewRecorder(pool, &recording).Query(ctx, "SELECT")
+		require.ErrorIs(t, err, expErr)
+
+		rp := replay(t, &recording)
+		_, err = rp.Query(ctx, "SELECT")
+		assert.EqualError(t, err, expErr.Error())
+		require.NoError(t, rp.Verify())
+
+		_, err = rp.Query(ctx, "SELECT")
+		require.ErrorIs(t, err, ErrMismatch)
+	})
+
+	t.Run("should be able to record and replay error of rows", func(t *testing.T) {
+		result := usersResult()
+		result.RowsError = errorOf(&pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"})
+		pool := NewMockPool(t)
+		pool.EXPECT().Query(mock.Anything, "SELECT").Return(rowsOf(result), nil)
+		var recording bytes.Buffer
+		_, err := collectUsers(NewRecorder(pool, &recording).Query(ctx, "SELECT"))
+		require.Error(t, err)
+
+		_, err = collectUsers(replay(t, &recording).Query(ctx, "SELECT"))
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		assert.Equal(t, "57014", pgErr.Code)
+	})
+
+	t.Run("should be able to record and replay row", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Query(mock.Anything, "SELECT id FROM users").
+			Return(rowsOf(Result{Fields: usersResult().Fields[:1], Rows: [][][]byte{{[]byte("1")}}}), nil)
+		pool.EXPECT().Query(mock.Anything, "SELECT id FROM users WHERE false").
+			Return(rowsOf(Result{Fields: usersResult().Fields[:1]}), nil)
+		var recording bytes.Buffer
+		sut := NewRecorder(pool, &recording)
+		var id int64
+		require.NoError(t, sut.QueryRow(ctx, "SELECT id FROM users").Scan(&id))
+		assert.Equal(t, int64(1), id)
+		require.ErrorIs(t, sut.QueryRow(ctx, "SELECT id FROM users WHERE false").Scan(&id), pgx.ErrNoRows)
+
+		rp := replay(t, &recording)
+		id = 0
+		require.NoError(t, rp.QueryRow(ctx, "SELECT id FROM users").Scan(&id))
+		assert.Equal(t, int64(1), id)
+		require.ErrorIs(t, rp.QueryRow(ctx, "SELECT id FROM users WHERE false").Scan(&id), pgx.ErrNoRows)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to fail row", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().Query(mock.Anything, "SELECT").Return(nil, expErr)
+		pool.EXPECT().Query(mock.Anything, "SELECT 1").Return(rowsOf(usersResult()), nil)
+		pool.EXPECT().Query(mock.Anything, "SELECT 2").Return(rowsOf(Result{RowsError: errorOf(expErr)}), nil)
+		sut := NewRecorder(pool, &bytes.Buffer{})
+		require.ErrorIs(t, sut.QueryRow(ctx, "SELECT").Scan(), expErr)
+		var id int64
+		require.Error(t, sut.QueryRow(ctx, "SELECT 1").Scan(&id))
+		assert.EqualError(t, sut.QueryRow(ctx, "SELECT 2").Scan(&id), expErr.Error())
+	})
+}
+
+func TestRecorder_Exec(t *testing.T) {
+	t.Run("should be able to record and replay tag", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Exec(mock.Anything, "UPDATE users SET login = $1", "root").
+			Return(pgconn.NewCommandTag("UPDATE 3"), nil)
+		var recording bytes.Buffer
+		_, err := NewRecorder(pool, &recording).Exec(context.Background(), "UPDATE users SET login = $1", "root")
+		require.NoError(t, err)
+
+		rp := replay(t, &recording)
+		tag, err := rp.Exec(context.Background(), "UPDATE users SET login = $1", "root")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), tag.RowsAffected())
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to replay postgres error and sentinels", func(t *testing.T) {
+		pgErr := &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout",
+			ConstraintName: "users_pkey"}
+		pool := NewMockPool(t)
+		timed := pgcontext.With(context.Background(), pgcontext.WithStatementTimeout(time.Second))
+		pool.EXPECT().Exec(mock.Anything, "INSERT").Return(pgconn.CommandTag{}, pgerr.Map(timed, pgErr))
+		var recording bytes.Buffer
+		_, recErr := NewRecorder(pool, &recording).Exec(context.Background(), "INSERT")
+
+		_, err := replay(t, &recording).Exec(context.Background(), "INSERT")
+		assert.EqualError(t, err, recErr.Error())
+		assert.ErrorIs(t, err, pgerr.ErrStatementTimeout)
+		var replayed *pgconn.PgError
+		require.ErrorAs(t, err, &replayed)
+		assert.Equal(t, "users_pkey", replayed.ConstraintName)
+	})
+
+	t.Run("should be able to fail on mismatch of args and hints", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Exec(mock.Anything, "DELETE", 1).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+		var recording bytes.Buffer
+		_, err := NewRecorder(pool, &recording).Exec(context.Background(), "DELETE", 1)
+		require.NoError(t, err)
+
+		rp := replay(t, &recording)
+		_, err = rp.Exec(context.Background(), "DELETE", 2)
+		require.ErrorIs(t, err, ErrMismatch)
+		_, err = rp.Exec(pgcontext.WithCanWrite(context.Background()), "DELETE", 1)
+		require.ErrorIs(t, err, ErrMismatch)
+		assert.Contains(t, err.Error(), `exec "DELETE" [1] [can_write]`)
+
+		err = rp.Verify()
+		require.ErrorIs(t, err, ErrMismatch)
+		require.ErrorIs(t, err, ErrNotReplayed)
+		assert.Contains(t, err.Error(), `#1 exec "DELETE" [1] []`)
+	})
+
+	t.Run("should be able to report write errors", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Exec(mock.Anything, "SELECT").Return(pgconn.CommandTag{}, nil)
+		var got error
+		sut := NewRecorder(pool, failingWriter{}, WithErrorHandler(func(err error) {
+			got = err
+		}))
+		_, err := sut.Exec(context.Background(), "SELECT")
+		require.NoError(t, err)
+		require.ErrorIs(t, got, errWrite)
+	})
+
+	t.Run("should be able to encode args without JSON form", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Exec(mock.Anything, "SELECT", mock.Anything).Return(pgconn.CommandTag{}, nil)
+		var recording bytes.Buffer
+		_, err := NewRecorder(pool, &recording).Exec(context.Background(), "SELECT", func() {})
+		require.NoError(t, err)
+		var args string
+		require.NoError(t, json.Unmarshal(entries(t, &recording)[0].Args, &args))
+		assert.True(t, strings.HasPrefix(args, "[0x"))
+	})
+}
+
+var errWrite = errors.New("write failed")
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestRecorder_Transactional(t *testing.T) {
+	t.Run("should be able to record and replay boundaries of transactions and savepoints", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`INSERT`).Times(2)
+		expErr := newError()
+		run := func(db Pool) error {
+			return db.Transactional(context.Background(), func(ctx context.Context) error {
+				if _, err := db.Exec(ctx, "INSERT 1"); err != nil {
+					return err
+				}
+				err := db.Transactional(ctx, func(ctx context.Context) error {
+					_, err := db.Exec(ctx, "INSERT 2")
+					require.NoError(t, err)
+					return expErr
+				})
+				require.ErrorIs(t, err, expErr)
+				return db.Transactional(ctx, func(ctx context.Context) error {
+					return nil
+				})
+			})
+		}
+		var recording bytes.Buffer
+		require.NoError(t, run(NewRecorder(fake, &recording)))
+		assert.Equal(t, []string{
+			KindBegin, KindExec, KindSavepoint, KindExec, KindRollbackSavepoint, KindSavepoint, KindRelease, KindCommit,
+		}, kinds(t, &recording))
+		got := entries(t, &recording)
+		assert.Equal(t, got[0].Tx, got[1].Tx)
+		assert.Equal(t, got[2].Tx, got[3].Tx)
+		assert.NotEqual(t, got[0].Tx, got[2].Tx)
+
+		rp := replay(t, &recording, WithStrictOrder())
+		require.NoError(t, run(rp))
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record passed error as commit", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		expErr := newError()
+		ctx := pgcontext.With(context.Background(), pgcontext.WithFnTxPassMatcher(func(context.Context, error) bool {
+			return true
+		}))
+		run := func(db Pool) error {
+			return db.Transactional(ctx, func(ctx context.Context) error {
+				return expErr
+			})
+		}
+		var recording bytes.Buffer
+		require.ErrorIs(t, run(NewRecorder(fake, &recording)), expErr)
+		assert.Equal(t, []string{KindBegin, KindCommit}, kinds(t, &recording))
+
+		rp := replay(t, &recording)
+		require.ErrorIs(t, run(rp), expErr)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record failed commit", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+				require.NoError(t, fn(pgcontext.With(ctx, pgcontext.WithTransaction(NewMockTx(t)))))
+				return expErr
+			})
+		run := func(db Pool) error {
+			return db.Transactional(context.Background(), func(ctx context.Context) error {
+				return nil
+			})
+		}
+		var recording bytes.Buffer
+		require.ErrorIs(t, run(NewRecorder(pool, &recording)), expErr)
+		assert.Equal(t, []string{KindBegin, KindCommit}, kinds(t, &recording))
+
+		rp := replay(t, &recording)
+		assert.EqualError(t, run(rp), expErr.Error())
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record failed begin", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().Transactional(mock.Anything, mock.Anything).Return(expErr)
+		run := func(db Pool) error {
+			return db.Transactional(context.Background(), func(ctx context.Context) error {
+				t.Fatal("fn must not be called")
+				return nil
+			})
+		}
+		var recording bytes.Buffer
+		require.ErrorIs(t, run(NewRecorder(pool, &recording)), expErr)
+		assert.Equal(t, []string{KindBegin}, kinds(t, &recording))
+
+		rp := replay(t, &recording)
+		assert.EqualError(t, run(rp), expErr.Error())
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to skip pool without transaction in context", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+				return fn(ctx)
+			})
+		var recording bytes.Buffer
+		require.NoError(t, NewRecorder(pool, &recording).Transactional(context.Background(),
+			func(ctx context.Context) error {
+				return nil
+			}))
+		assert.Empty(t, recording.String())
+	})
+
+	t.Run("should be able to fail replay of missed transaction", func(t *testing.T) {
+		rp := replay(t, &bytes.Buffer{})
+		err := rp.Transactional(context.Background(), func(ctx context.Context) error {
+			t.Fatal("fn must not be called")
+			return nil
+		})
+		require.ErrorIs(t, err, ErrMismatch)
+	})
+}
+
+func TestRecorder_Tx(t *testing.T) {
+	t.Run("should be able to record and replay statements of transaction", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`.*`).Times(5)
+		run := func(db Pool) {
+			ctx := context.Background()
+			tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+			require.NoError(t, err)
+			_, err = tx.Exec(ctx, "INSERT 1")
+			require.NoError(t, err)
+			rows, err := tx.Query(ctx, "SELECT 1")
+			require.NoError(t, err)
+			rows.Close()
+			require.ErrorIs(t, tx.QueryRow(ctx, "SELECT 2").Scan(), pgx.ErrNoRows)
+
+			b := &pgx.Batch{}
+			b.Queue("INSERT 2")
+			require.NoError(t, tx.SendBatch(ctx, b).Close())
+			_, err = tx.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, pgx.CopyFromRows([][]any{{1}}))
+			require.NoError(t, err)
+
+			nested, err := tx.Begin(ctx)
+			require.NoError(t, err)
+			require.NoError(t, nested.Commit(ctx))
+			require.ErrorIs(t, nested.Rollback(ctx), pgx.ErrTxClosed)
+			require.NoError(t, tx.Rollback(ctx))
+
+			tx, err = db.Begin(pgcontext.WithCanWrite(ctx))
+			require.NoError(t, err)
+			require.NoError(t, tx.Commit(ctx))
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(fake, &recording))
+		assert.Equal(t, []string{
+			KindBegin, KindExec, KindQuery, KindQuery, KindBatch, KindCopyFrom, KindSavepoint, KindRelease,
+			KindRollback, KindBegin, KindCommit,
+		}, kinds(t, &recording))
+
+		rp := replay(t, &recording, WithStrictOrder())
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record and replay statements routed by pool to transaction", func(t *testing.T) {
+		fake := elephanttest.NewFake(t)
+		fake.Expect(`.*`).Times(4)
+		run := func(db Pool) error {
+			return db.Transactional(context.Background(), func(ctx context.Context) error {
+				_, err := db.Exec(ctx, "INSERT 1")
+				require.NoError(t, err)
+				require.ErrorIs(t, db.QueryRow(ctx, "SELECT 1").Scan(), pgx.ErrNoRows)
+				b := &pgx.Batch{}
+				b.Queue("INSERT 2")
+				require.NoError(t, db.SendBatch(ctx, b).Close())
+				_, err = db.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, pgx.CopyFromRows([][]any{{1}}))
+				return err
+			})
+		}
+		var recording bytes.Buffer
+		require.NoError(t, run(NewRecorder(fake, &recording)))
+		got := entries(t, &recording)
+		require.Len(t, got, 6)
+		for _, entry := range got {
+			assert.Equal(t, got[0].Tx, entry.Tx)
+		}
+
+		rp := replay(t, &recording)
+		require.NoError(t, run(rp))
+		require.NoError(t, rp.Verify())
+
+		_, err := replay(t, &recording).Exec(context.Background(), "INSERT 1")
+		require.ErrorIs(t, err, ErrMismatch)
+	})
+
+	t.Run("should be able to record and replay failed begin", func(t *testing.T) {
+		expErr := newError()
+		tx := NewMockTx(t)
+		tx.EXPECT().Begin(mock.Anything).Return(nil, expErr)
+		pool := NewMockPool(t)
+		pool.EXPECT().Begin(mock.Anything).Return(tx, nil)
+		pool.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(nil, expErr)
+		run := func(db Pool) {
+			ctx := context.Background()
+			_, err := db.BeginTx(ctx, pgx.TxOptions{})
+			assert.EqualError(t, err, expErr.Error())
+			outer, err := db.Begin(ctx)
+			require.NoError(t, err)
+			_, err = outer.Begin(ctx)
+			assert.EqualError(t, err, expErr.Error())
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+		assert.Equal(t, []string{KindBegin, KindBegin, KindSavepoint}, kinds(t, &recording))
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record and replay failed commit and rollback of savepoint", func(t *testing.T) {
+		expErr := newError()
+		nested := NewMockTx(t)
+		nested.EXPECT().Rollback(mock.Anything).Return(expErr)
+		tx := NewMockTx(t)
+		tx.EXPECT().Begin(mock.Anything).Return(nested, nil)
+		tx.EXPECT().Commit(mock.Anything).Return(expErr)
+		pool := NewMockPool(t)
+		pool.EXPECT().Begin(mock.Anything).Return(tx, nil)
+		run := func(db Pool) {
+			ctx := context.Background()
+			outer, err := db.Begin(ctx)
+			require.NoError(t, err)
+			sp, err := outer.Begin(ctx)
+			require.NoError(t, err)
+			assert.EqualError(t, sp.Rollback(ctx), expErr.Error())
+			assert.EqualError(t, outer.Commit(ctx), expErr.Error())
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+		assert.Equal(t, []string{KindBegin, KindSavepoint, KindRollbackSavepoint, KindCommit}, kinds(t, &recording))
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to fail replay of missed boundary", func(t *testing.T) {
+		tx := &replayTx{replayer: replay(t, &bytes.Buffer{})}
+		require.ErrorIs(t, tx.Commit(context.Background()), ErrMismatch)
+		require.ErrorIs(t, tx.Rollback(context.Background()), pgx.ErrTxClosed)
+	})
+
+	t.Run("should be able to serve parts of transaction without connection", func(t *testing.T) {
+		tx := &replayTx{}
+		assert.Equal(t, pgx.LargeObjects{}, tx.LargeObjects())
+		assert.Nil(t, tx.Conn())
+		sd, err := tx.Prepare(context.Background(), "name", "SELECT")
+		require.NoError(t, err)
+		assert.Equal(t, &pgconn.StatementDescription{Name: "name", SQL: "SELECT"}, sd)
+	})
+}
+
+func TestRecorder_SendBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should be able to record and replay results of batch with callbacks", func(t *testing.T) {
+		results := NewMockBatchResults(t)
+		results.EXPECT().Exec().Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Twice()
+		results.EXPECT().Query().Return(rowsOf(usersResult()), nil).Once()
+		ids := Result{Fields: usersResult().Fields[:1], Rows: [][][]byte{{[]byte("5")}}}
+		results.EXPECT().Query().Return(rowsOf(ids), nil).Once()
+		results.EXPECT().Close().Return(nil)
+		pool := NewMockPool(t)
+		pool.EXPECT().SendBatch(mock.Anything, mock.Anything).Return(results)
+
+		run := func(db Pool) {
+			var id int64
+			b := &pgx.Batch{}
+			b.Queue("INSERT 1", 1)
+			b.Queue("SELECT users")
+			b.Queue("SELECT id").QueryRow(func(row pgx.Row) error {
+				return row.Scan(&id)
+			})
+			b.Queue("INSERT 2")
+			br := db.SendBatch(ctx, b)
+			tag, err := br.Exec()
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), tag.RowsAffected())
+			users, err := collectUsers(br.Query())
+			require.NoError(t, err)
+			assert.Len(t, users, 2)
+			require.NoError(t, br.Close())
+			require.NoError(t, br.Close())
+			assert.Equal(t, int64(5), id)
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+		got := entries(t, &recording)
+		require.Len(t, got, 1)
+		assert.Len(t, got[0].Batch, 4)
+		assert.Len(t, got[0].Results, 4)
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record and replay failed items", func(t *testing.T) {
+		expErr := newError()
+		results := NewMockBatchResults(t)
+		results.EXPECT().Query().Return(nil, expErr).Once()
+		results.EXPECT().Exec().Return(pgconn.CommandTag{}, expErr).Once()
+		results.EXPECT().Close().Return(expErr)
+		pool := NewMockPool(t)
+		pool.EXPECT().SendBatch(mock.Anything, mock.Anything).Return(results)
+
+		run := func(db Pool) {
+			b := &pgx.Batch{}
+			b.Queue("SELECT 1")
+			b.Queue("INSERT 1")
+			b.Queue("INSERT 2")
+			br := db.SendBatch(ctx, b)
+			assert.EqualError(t, br.QueryRow().Scan(), expErr.Error())
+			assert.EqualError(t, br.Close(), expErr.Error())
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+		assert.Len(t, entries(t, &recording)[0].Results, 2)
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to fail replay of missed batch and results", func(t *testing.T) {
+		b := &pgx.Batch{}
+		b.Queue("SELECT")
+		br := replay(t, &bytes.Buffer{}).SendBatch(ctx, b)
+		_, err := br.Exec()
+		require.ErrorIs(t, err, ErrMismatch)
+		_, err = br.Query()
+		require.ErrorIs(t, err, ErrMismatch)
+		require.ErrorIs(t, br.QueryRow().Scan(), ErrMismatch)
+		require.ErrorIs(t, br.Close(), ErrMismatch)
+
+		replayed := &replayBatch{queued: b.QueuedQueries}
+		_, err = replayed.Exec()
+		require.ErrorIs(t, err, ErrMismatch)
+		require.ErrorIs(t, replayed.Close(), ErrMismatch)
+		_, err = replayed.Query()
+		require.ErrorIs(t, err, errBatchClosed)
+	})
+}
+
+func TestRecorder_Copy(t *testing.T) {
+	ctx := pgcontext.WithCanWrite(context.Background())
+
+	t.Run("should be able to record and replay copy from", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().CopyFrom(mock.Anything, pgx.Identifier{"public", "users"}, []string{"id", "login"}, mock.Anything).
+			RunAndReturn(func(
+				_ context.Context, _ pgx.Identifier, _ []string, src pgx.CopyFromSource,
+			) (int64, error) {
+				var n int64
+				for src.Next() {
+					_, err := src.Values()
+					require.NoError(t, err)
+					n++
+				}
+				return n, src.Err()
+			})
+		run := func(db Pool) {
+			n, err := db.CopyFrom(ctx, pgx.Identifier{"public", "users"}, []string{"id", "login"},
+				pgx.CopyFromRows([][]any{{1, "admin"}, {2, "root"}}))
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), n)
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+		got := entries(t, &recording)
+		assert.Equal(t, `COPY "public"."users" ("id", "login") FROM STDIN`, got[0].SQL)
+		assert.Equal(t, `[[1,"admin"],[2,"root"]]`, string(got[0].Args))
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to record and replay failed source", func(t *testing.T) {
+		expErr := newError()
+		pool := NewMockPool(t)
+		pool.EXPECT().CopyFrom(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			RunAndReturn(func(
+				_ context.Context, _ pgx.Identifier, _ []string, src pgx.CopyFromSource,
+			) (int64, error) {
+				for src.Next() {
+					if _, err := src.Values(); err != nil {
+						return 0, err
+					}
+				}
+				return 0, src.Err()
+			})
+		run := func(db Pool) {
+			src := pgx.CopyFromFunc(func() ([]any, error) {
+				return nil, expErr
+			})
+			_, err := db.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, src)
+			assert.EqualError(t, err, expErr.Error())
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+
+		_, err := rp.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, pgx.CopyFromRows(nil))
+		require.ErrorIs(t, err, ErrMismatch)
+		_, err = rp.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id"}, failedSource{err: newError()})
+		require.ErrorIs(t, err, ErrMismatch)
+	})
+
+	t.Run("should be able to record and replay copy to", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().CopyTo(mock.Anything, mock.Anything, "COPY users TO STDOUT").
+			RunAndReturn(func(_ context.Context, w io.Writer, _ string) (pgconn.CommandTag, error) {
+				_, err := w.Write([]byte("1\tadmin\n"))
+				return pgconn.NewCommandTag("COPY 1"), err
+			})
+		run := func(db Pool) {
+			var out bytes.Buffer
+			tag, err := db.CopyTo(ctx, &out, "COPY users TO STDOUT")
+			require.NoError(t, err)
+			assert.Equal(t, "1\tadmin\n", out.String())
+			assert.Equal(t, int64(1), tag.RowsAffected())
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+
+		_, err := rp.CopyTo(ctx, &bytes.Buffer{}, "COPY users TO STDOUT")
+		require.ErrorIs(t, err, ErrMismatch)
+	})
+
+	t.Run("should be able to record copy to in transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		pool := NewMockPool(t)
+		pool.EXPECT().Begin(mock.Anything).Return(tx, nil)
+		pool.EXPECT().CopyTo(mock.Anything, mock.Anything, "COPY").Return(pgconn.NewCommandTag("COPY 0"), nil)
+		run := func(db Pool) {
+			tx, err := db.Begin(ctx)
+			require.NoError(t, err)
+			_, err = db.CopyTo(pgcontext.With(ctx, pgcontext.WithTransaction(tx)), &bytes.Buffer{}, "COPY")
+			require.NoError(t, err)
+		}
+		var recording bytes.Buffer
+		run(NewRecorder(pool, &recording))
+		got := entries(t, &recording)
+		assert.Equal(t, got[0].Tx, got[1].Tx)
+
+		rp := replay(t, &recording)
+		run(rp)
+		require.NoError(t, rp.Verify())
+	})
+
+	t.Run("should be able to fail writing of replayed data", func(t *testing.T) {
+		pool := NewMockPool(t)
+		pool.EXPECT().CopyTo(mock.Anything, mock.Anything, "COPY").
+			RunAndReturn(func(_ context.Context, w io.Writer, _ string) (pgconn.CommandTag, error) {
+				_, err := w.Write([]byte("data"))
+				return pgconn.CommandTag{}, err
+			})
+		var recording bytes.Buffer
+		_, err := NewRecorder(pool, &recording).CopyTo(ctx, &bytes.Buffer{}, "COPY")
+		require.NoError(t, err)
+
+		_, err = replay(t, &recording).CopyTo(ctx, failingWriter{}, "COPY")
+		require.ErrorIs(t, err, errWrite)
+	})
+}
+
+type failedSource struct {
+	err error
+}
+
+func (s failedSource) Next() bool {
+	return true
+}
+
+func (s failedSource) Values() ([]any, error) {
+	return nil, s.err
+}
+
+func (s failedSource) Err() error {
+	return s.err
+}
+
+type acquirerPool struct {
+	*MockPool
+	conn *pgxpool.Conn
+}
+
+func (p acquirerPool) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return p.conn, nil
+}
+
+func TestAcquire(t *testing.T) {
+	t.Run("should be able to pass connection of pool", func(t *testing.T) {
+		conn := &pgxpool.Conn{}
+		got, err := NewRecorder(acquirerPool{MockPool: NewMockPool(t), conn: conn}, &bytes.Buffer{}).
+			Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Same(t, conn, got)
+	})
+	t.Run("should be able to return error, when pool can't acquire", func(t *testing.T) {
+		_, err := NewRecorder(NewMockPool(t), &bytes.Buffer{}).Acquire(context.Background())
+		require.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+		_, err = replay(t, &bytes.Buffer{}).Acquire(context.Background())
+		require.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+	})
+}
+
+type closerPool struct {
+	*MockPool
+	closed bool
+}
+
+func (p *closerPool) Close() {
+	p.closed = true
+}
+
+func TestNode(t *testing.T) {
+	newCluster := func(t *testing.T) (clusterpg.Cluster, *MockPool, *MockPool) {
+		leader, follower := NewMockPool(t), NewMockPool(t)
+		cls, err := clusterpg.New().
+			Leader(func() (clusterpg.Pool, error) {
+				return Node("leader", leader), nil
+			}).
+			Follower(func() (clusterpg.Pool, error) {
+				return Node("follower", follower), nil
+			}).
+			Go()
+		require.NoError(t, err)
+		return cls, leader, follower
+	}
+
+	t.Run("should be able to record nodes, which cluster routes statements to", func(t *testing.T) {
+		cls, leader, follower := newCluster(t)
+		follower.EXPECT().Exec(mock.Anything, "SELECT 1").Return(pgconn.NewCommandTag("SELECT 1"), nil)
+		leader.EXPECT().Exec(mock.Anything, "UPDATE users").Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+		var recording bytes.Buffer
+		rec := NewRecorder(cls, &recording)
+
+		_, err := rec.Exec(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+		_, err = rec.Exec(pgcontext.WithCanWrite(context.Background()), "UPDATE users")
+		require.NoError(t, err)
+
+		recorded := entries(t, &recording)
+		require.Len(t, recorded, 2)
+		assert.Equal(t, []string{"follower"}, recorded[0].Nodes)
+		assert.Equal(t, []string{"leader"}, recorded[1].Nodes)
+	})
+
+	t.Run("should be able to record node of transaction only at its begin", func(t *testing.T) {
+		cls, leader, _ := newCluster(t)
+		tx := NewMockTx(t)
+		leader.EXPECT().Transactional(mock.Anything, mock.Anything).
+			RunAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+				return fn(pgcontext.With(ctx, pgcontext.WithTransaction(tx)))
+			})
+		tx.EXPECT().Exec(mock.Anything, "UPDATE users").Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+		var recording bytes.Buffer
+		rec := NewRecorder(cls, &recording)
+
+		err := rec.Transactional(pgcontext.WithCanWrite(context.Background()), func(ctx context.Context) error {
+			_, err := rec.Exec(ctx, "UPDATE users")
+			return err
+		})
+		require.NoError(t, err)
+
+		recorded := entries(t, &recording)
+		require.Len(t, recorded, 3)
+		assert.Equal(t, KindBegin, recorded[0].Kind)
+		assert.Equal(t, []string{"leader"}, recorded[0].Nodes)
+		assert.Empty(t, recorded[1].Nodes)
+		assert.Empty(t, recorded[2].Nodes)
+	})
+
+	t.Run("should be able to keep optional methods of node", func(t *testing.T) {
+		conn := &pgxpool.Conn{}
+		got, err := Node("leader", acquirerPool{MockPool: NewMockPool(t), conn: conn}).(acquirer).
+			Acquire(context.Background())
+		require.NoError(t, err)
+		assert.Same(t, conn, got)
+
+		_, err = Node("leader", NewMockPool(t)).(acquirer).Acquire(context.Background())
+		require.ErrorIs(t, err, pgerr.ErrAcquireNotSupported)
+
+		pool := &closerPool{MockPool: NewMockPool(t)}
+		require.NoError(t, Node("leader", pool).(shutdowner).Shutdown(context.Background()))
+		assert.True(t, pool.closed)
+	})
+
+	t.Run("should be able to probe node without health check", func(t *testing.T) {
+		pool := NewMockPool(t)
+		expErr := newError()
+		pool.EXPECT().QueryRow(mock.Anything, mock.Anything).Return(&row{err: expErr})
+
+		report := Node("leader", pool).(healthChecker).Health(context.Background())
+		require.Len(t, report.Nodes, 1)
+		assert.False(t, report.Nodes[0].Reachable)
+		assert.ErrorIs(t, report.Err(), expErr)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should be able to return error of malformed recording", func(t *testing.T) {
+		_, err := Load(strings.NewReader(`{"seq":`))
+		require.Error(t, err)
+	})
+	t.Run("should be able to order entries by seq", func(t *testing.T) {
+		rp, err := Load(strings.NewReader(`{"seq":2,"kind":"exec","sql":"B"}`+"\n"+`{"seq":1,"kind":"exec","sql":"A"}`),
+			WithStrictOrder())
+		require.NoError(t, err)
+		_, err = rp.Exec(context.Background(), "B")
+		require.ErrorIs(t, err, ErrMismatch)
+		_, err = rp.Exec(context.Background(), "A")
+		require.NoError(t, err)
+		_, err = rp.Exec(context.Background(), "B")
+		require.NoError(t, err)
+	})
+	t.Run("should be able to match entries out of order by default", func(t *testing.T) {
+		rp, err := Load(strings.NewReader(`{"seq":1,"kind":"exec","sql":"A"}` + "\n" + `{"seq":2,"kind":"exec","sql":"B"}`))
+		require.NoError(t, err)
+		_, err = rp.Exec(context.Background(), "B")
+		require.NoError(t, err)
+		_, err = rp.Exec(context.Background(), "A")
+		require.NoError(t, err)
+		require.NoError(t, rp.Verify())
+	})
+}
+
+func TestHints(t *testing.T) {
+	t.Run("should be able to compare hints", func(t *testing.T) {
+		one, two := uint(1), uint(2)
+		assert.True(t, Hints{ShardID: &one}.equal(Hints{ShardID: &one}))
+		assert.False(t, Hints{ShardID: &one}.equal(Hints{ShardID: &two}))
+		assert.False(t, Hints{ShardID: &one}.equal(Hints{}))
+		assert.False(t, Hints{ShardingKey: "a"}.equal(Hints{ShardingKey: "b"}))
+	})
+	t.Run("should be able to describe hints", func(t *testing.T) {
+		id := uint(1)
+		assert.Equal(t, "[can_write shard_id=1 sharding_key=key tenant=acme]",
+			Hints{CanWrite: true, ShardID: &id, ShardingKey: "key", Tenant: "acme"}.String())
+	})
+	t.Run("should be able to describe batch call in transaction", func(t *testing.T) {
+		c := call{kind: KindBatch, batch: []Statement{{SQL: "A", Args: json.RawMessage(`[1]`)}, {SQL: "B"}}, inTx: true}
+		assert.Equal(t, `batch "A" [1] "B" [] in transaction`, c.String())
+	})
+	t.Run("should be able to distinguish batches", func(t *testing.T) {
+		c := call{kind: KindBatch, batch: []Statement{{SQL: "A"}}}
+		assert.False(t, c.match(&Entry{Kind: KindBatch}))
+		assert.False(t, c.match(&Entry{Kind: KindBatch, Batch: []Statement{{SQL: "B"}}}))
+		assert.True(t, c.match(&Entry{Kind: KindBatch, Batch: []Statement{{SQL: "A"}}}))
+	})
+}
+
+func TestReplayRows(t *testing.T) {
+	newRows := func() pgx.Rows {
+		rows := rowsOf(usersResult())
+		require.True(t, rows.Next())
+		return rows
+	}
+
+	t.Run("should be able to decode values", func(t *testing.T) {
+		rows := newRows()
+		values, err := rows.Values()
+		require.NoError(t, err)
+		assert.Equal(t, []any{int64(1), "admin", int32(7)}, values)
+		require.True(t, rows.Next())
+		values, err = rows.Values()
+		require.NoError(t, err)
+		assert.Nil(t, values[1])
+		assert.False(t, rows.Next())
+		assert.Nil(t, rows.Conn())
+	})
+
+	t.Run("should be able to keep values of unknown types", func(t *testing.T) {
+		rows := rowsOf(Result{
+			Fields: []Field{{Name: "a", OID: 1}, {Name: "b", OID: 1, Format: pgtype.BinaryFormatCode}},
+			Rows:   [][][]byte{{[]byte("text"), {1, 2}}},
+		})
+		require.True(t, rows.Next())
+		values, err := rows.Values()
+		require.NoError(t, err)
+		assert.Equal(t, []any{"text", []byte{1, 2}}, values)
+	})
+
+	t.Run("should be able to return error of decoding", func(t *testing.T) {
+		rows := rowsOf(Result{Fields: []Field{{Name: "a", OID: pgtype.Int8OID}}, Rows: [][][]byte{{[]byte("x")}}})
+		require.True(t, rows.Next())
+		_, err := rows.Values()
+		require.Error(t, err)
+		var id int64
+		var argErr pgx.ScanArgError
+		require.ErrorAs(t, rows.Scan(&id), &argErr)
+	})
+
+	t.Run("should be able to scan with row scanner and skip nil destinations", func(t *testing.T) {
+		var login string
+		require.NoError(t, newRows().Scan(nil, &login, nil))
+		assert.Equal(t, "admin", login)
+
+		var got map[string]any
+		require.NoError(t, newRows().Scan(rowScanner(func(rows pgx.Rows) error {
+			var err error
+			got, err = pgx.RowToMap(rows)
+			return err
+		})))
+		assert.Equal(t, "admin", got["login"])
+	})
+
+	t.Run("should be able to return error of destinations count", func(t *testing.T) {
+		var id int64
+		require.Error(t, newRows().Scan(&id))
+	})
+
+	t.Run("should be able to have no values before the first row", func(t *testing.T) {
+		assert.Nil(t, rowsOf(usersResult()).RawValues())
+	})
+
+	t.Run("should be able to stop after close", func(t *testing.T) {
+		rows := newRows()
+		rows.Close()
+		assert.False(t, rows.Next())
+		assert.NoError(t, rows.Err())
+	})
+}
+
+type rowScanner func(rows pgx.Rows) error
+
+func (fn rowScanner) ScanRow(rows pgx.Rows) error {
+	return fn(rows)
+}
@@ -0,0 +1,358 @@
+package recordpg
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrMismatch    = errors.New("recordpg: call doesn't match recording")
+	ErrNotReplayed = errors.New("recordpg: recorded entry isn't replayed")
+)
+
+// call is operation of replayer, which is matched with recorded entries.
+type call struct {
+	kind  string
+	sql   string
+	args  json.RawMessage
+	batch []Statement
+	hints Hints
+	inTx  bool
+}
+
+func (c call) match(e *Entry) bool {
+	if c.kind != e.Kind || c.sql != e.SQL || !bytes.Equal(c.args, e.Args) || !c.hints.equal(e.Hints) {
+		return false
+	}
+	if len(c.batch) != len(e.Batch) {
+		return false
+	}
+	for i, st := range c.batch {
+		if st.SQL != e.Batch[i].SQL || !bytes.Equal(st.Args, e.Batch[i].Args) {
+			return false
+		}
+	}
+	switch c.kind {
+	case KindBegin, KindSavepoint, KindCommit, KindRelease, KindRollback, KindRollbackSavepoint:
+		return true
+	}
+	return c.inTx == (e.Tx != 0)
+}
+
+func (c call) String() string {
+	out := describe(c.kind, c.sql, c.args, c.batch, c.hints)
+	if c.inTx {
+		out += " in transaction"
+	}
+	return out
+}
+
+// Replayer serves recorded results back as DB without postgres. Calls, which don't match recording, fail with
+// ErrMismatch, Verify reports them together with recorded entries, which aren't replayed.
+type Replayer struct {
+	cfg        Config
+	typeMap    *pgtype.Map
+	mu         sync.Mutex
+	entries    []*Entry
+	used       []bool
+	mismatches []string
+}
+
+// Load reads recording of Recorder.
+func Load(r io.Reader, opts ...Option) (*Replayer, error) {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var entries []*Entry
+	dec := json.NewDecoder(r)
+	for {
+		var entry Entry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read recording: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	slices.SortStableFunc(entries, func(a, b *Entry) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	return &Replayer{
+		cfg:     cfg,
+		typeMap: pgtype.NewMap(),
+		entries: entries,
+		used:    make([]bool, len(entries)),
+	}, nil
+}
+
+func (r *Replayer) match(c call) (*Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, entry := range r.entries {
+		if r.used[i] {
+			continue
+		}
+		if c.match(entry) {
+			r.used[i] = true
+			return entry, nil
+		}
+		if r.cfg.strict {
+			break
+		}
+	}
+	r.mismatches = append(r.mismatches, c.String())
+	return nil, fmt.Errorf("%w: %s", ErrMismatch, c)
+}
+
+// Verify returns report of calls, which don't match recording, and of recorded entries, which aren't replayed.
+func (r *Replayer) Verify() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	errs := make([]error, 0, len(r.mismatches))
+	for _, mismatch := range r.mismatches {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrMismatch, mismatch))
+	}
+	for i, entry := range r.entries {
+		if !r.used[i] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrNotReplayed, entry))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Replayer) inTx(ctx context.Context) bool {
+	tx, _ := pgcontext.TransactionFrom(ctx)
+	return r.owns(tx)
+}
+
+func (r *Replayer) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return r.begin(ctx, false)
+}
+
+func (r *Replayer) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.begin(ctx, false)
+}
+
+func (r *Replayer) begin(ctx context.Context, nested bool) (pgx.Tx, error) {
+	kind := KindBegin
+	if nested {
+		kind = KindSavepoint
+	}
+	entry, err := r.match(call{kind: kind, hints: hintsFrom(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != nil {
+		return nil, entry.Error.err()
+	}
+	return &replayTx{replayer: r, nested: nested}, nil
+}
+
+// Acquire isn't supported, because replay has no connections.
+func (r *Replayer) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return nil, pgerr.ErrAcquireNotSupported
+}
+
+func (r *Replayer) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return r.query(ctx, r.inTx(ctx), query, args)
+}
+
+func (r *Replayer) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	rows, err := r.Query(ctx, query, args...)
+	return &row{rows: rows, err: err}
+}
+
+func (r *Replayer) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return r.exec(ctx, r.inTx(ctx), query, args)
+}
+
+func (r *Replayer) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return r.sendBatch(ctx, r.inTx(ctx), b)
+}
+
+func (r *Replayer) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return r.copyFrom(ctx, r.inTx(ctx), tableName, columnNames, rowSrc)
+}
+
+func (r *Replayer) CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error) {
+	entry, err := r.match(call{kind: KindCopyTo, sql: query, hints: hintsFrom(ctx), inTx: r.inTx(ctx)})
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if _, err := w.Write(entry.Data); err != nil {
+		return pgconn.CommandTag{}, fmt.Errorf("can't write copy data: %w", err)
+	}
+	return pgconn.NewCommandTag(entry.Tag), entry.Error.err()
+}
+
+// Transactional replays boundaries of transaction, or of savepoint, when context has replayed transaction. Settings
+// of transaction aren't applied, as they aren't recorded.
+func (r *Replayer) Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error) {
+	tx, err := r.begin(ctx, r.inTx(ctx))
+	if err != nil {
+		return err
+	}
+	if err := fn(pgcontext.With(ctx, pgcontext.WithTransaction(tx))); err != nil {
+		if !passes(ctx, err) {
+			_ = tx.Rollback(ctx)
+			return err
+		}
+		out = err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	return out
+}
+
+func (r *Replayer) query(ctx context.Context, inTx bool, query string, args []any) (pgx.Rows, error) {
+	entry, err := r.match(call{kind: KindQuery, sql: query, args: encodeArgs(args), hints: hintsFrom(ctx), inTx: inTx})
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != nil {
+		return nil, entry.Error.err()
+	}
+	return newReplayRows(r.typeMap, entry.Result), nil
+}
+
+func (r *Replayer) exec(ctx context.Context, inTx bool, query string, args []any) (pgconn.CommandTag, error) {
+	entry, err := r.match(call{kind: KindExec, sql: query, args: encodeArgs(args), hints: hintsFrom(ctx), inTx: inTx})
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(entry.Tag), entry.Error.err()
+}
+
+func (r *Replayer) sendBatch(ctx context.Context, inTx bool, b *pgx.Batch) pgx.BatchResults {
+	entry, err := r.match(call{kind: KindBatch, batch: statements(b), hints: hintsFrom(ctx), inTx: inTx})
+	if err != nil {
+		return failedBatchResults{err: err}
+	}
+	return &replayBatch{typeMap: r.typeMap, queued: b.QueuedQueries, results: entry.Results, err: entry.Error.err()}
+}
+
+// copyFrom reads source as postgres does and matches its rows with recording.
+func (r *Replayer) copyFrom(
+	ctx context.Context, inTx bool, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	var rows [][]any
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			break
+		}
+		rows = append(rows, slices.Clone(values))
+	}
+	entry, err := r.match(call{
+		kind:  KindCopyFrom,
+		sql:   copySQL(tableName, columnNames),
+		args:  encodeRows(rows),
+		hints: hintsFrom(ctx),
+		inTx:  inTx,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pgconn.NewCommandTag(entry.Tag).RowsAffected(), entry.Error.err()
+}
+
+// replayTx replays statements and boundaries of transaction, nested transactions are savepoints.
+type replayTx struct {
+	replayer *Replayer
+	nested   bool
+	mu       sync.Mutex
+	closed   bool
+}
+
+func (r *Replayer) owns(tx pgx.Tx) bool {
+	replayed, ok := tx.(*replayTx)
+	return ok && replayed.replayer == r
+}
+
+func (tx *replayTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return tx.replayer.begin(ctx, true)
+}
+
+func (tx *replayTx) Commit(ctx context.Context) error {
+	if tx.nested {
+		return tx.end(ctx, KindRelease)
+	}
+	return tx.end(ctx, KindCommit)
+}
+
+func (tx *replayTx) Rollback(ctx context.Context) error {
+	if tx.nested {
+		return tx.end(ctx, KindRollbackSavepoint)
+	}
+	return tx.end(ctx, KindRollback)
+}
+
+func (tx *replayTx) end(ctx context.Context, kind string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	entry, err := tx.replayer.match(call{kind: kind, hints: hintsFrom(ctx)})
+	if err != nil {
+		return err
+	}
+	return entry.Error.err()
+}
+
+func (tx *replayTx) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return tx.replayer.copyFrom(ctx, true, tableName, columnNames, rowSrc)
+}
+
+func (tx *replayTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return tx.replayer.sendBatch(ctx, true, b)
+}
+
+func (tx *replayTx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (tx *replayTx) Prepare(_ context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+func (tx *replayTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return tx.replayer.exec(ctx, true, sql, arguments)
+}
+
+func (tx *replayTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return tx.replayer.query(ctx, true, sql, args)
+}
+
+func (tx *replayTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := tx.Query(ctx, sql, args...)
+	return &row{rows: rows, err: err}
+}
+
+// Conn is nil, because replay has no connections.
+func (tx *replayTx) Conn() *pgx.Conn {
+	return nil
+}
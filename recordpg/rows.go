@@ -0,0 +1,312 @@
+package recordpg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var errBatchClosed = errors.New("batch already closed")
+
+// row reads the first row of rows as pgx does, so QueryRow is recorded and replayed as Query.
+type row struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+// recordingRows keeps raw values of rows and passes result to done on close.
+type recordingRows struct {
+	pgx.Rows
+	result Result
+	done   func(result Result)
+	closed bool
+}
+
+func (r *recordingRows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+	values := r.Rows.RawValues()
+	raw := make([][]byte, 0, len(values))
+	for _, value := range values {
+		raw = append(raw, bytes.Clone(value))
+	}
+	r.result.Rows = append(r.result.Rows, raw)
+	return true
+}
+
+func (r *recordingRows) Close() {
+	if r.closed {
+		r.Rows.Close()
+		return
+	}
+	r.closed = true
+	r.result.Fields = fieldsOf(r.Rows.FieldDescriptions())
+	r.Rows.Close()
+	r.result.Tag, r.result.RowsError = r.Rows.CommandTag().String(), errorOf(r.Rows.Err())
+	r.done(r.result)
+}
+
+// recordingBatch keeps results of batch in order they are read and passes them to done on close.
+type recordingBatch struct {
+	results  pgx.BatchResults
+	queued   []*pgx.QueuedQuery
+	recorded []Result
+	done     func(results []Result, err error)
+	closed   bool
+}
+
+func (b *recordingBatch) Exec() (pgconn.CommandTag, error) {
+	tag, err := b.results.Exec()
+	b.recorded = append(b.recorded, Result{Tag: tag.String(), Error: errorOf(err)})
+	return tag, err
+}
+
+func (b *recordingBatch) Query() (pgx.Rows, error) {
+	i := len(b.recorded)
+	b.recorded = append(b.recorded, Result{})
+	rows, err := b.results.Query()
+	if err != nil {
+		b.recorded[i].Error = errorOf(err)
+		return rows, err
+	}
+	return &recordingRows{Rows: rows, done: func(result Result) {
+		b.recorded[i] = result
+	}}, nil
+}
+
+func (b *recordingBatch) QueryRow() pgx.Row {
+	rows, err := b.Query()
+	return &row{rows: rows, err: err}
+}
+
+// Close reads results left, like pgx does, through recording batch, so callbacks of queued queries are recorded too.
+func (b *recordingBatch) Close() error {
+	if b.closed {
+		return b.results.Close()
+	}
+	b.closed = true
+	err := readLeft(b, b.queued, len(b.recorded))
+	if closeErr := b.results.Close(); err == nil {
+		err = closeErr
+	}
+	b.done(b.recorded, err)
+	return err
+}
+
+func readLeft(br pgx.BatchResults, queued []*pgx.QueuedQuery, read int) error {
+	for _, qq := range queued[min(read, len(queued)):] {
+		var err error
+		if qq.Fn != nil {
+			err = qq.Fn(br)
+		} else {
+			_, err = br.Exec()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayRows serves recorded rows, values are decoded by types of recorded fields.
+type replayRows struct {
+	typeMap *pgtype.Map
+	fields  []pgconn.FieldDescription
+	rows    [][][]byte
+	tag     pgconn.CommandTag
+	err     error
+	pos     int
+	closed  bool
+}
+
+func newReplayRows(typeMap *pgtype.Map, result Result) *replayRows {
+	return &replayRows{
+		typeMap: typeMap,
+		fields:  descriptionsOf(result.Fields),
+		rows:    result.Rows,
+		tag:     pgconn.NewCommandTag(result.Tag),
+		err:     result.RowsError.err(),
+	}
+}
+
+func (r *replayRows) Close() {
+	r.closed = true
+}
+
+func (r *replayRows) Err() error {
+	return r.err
+}
+
+func (r *replayRows) CommandTag() pgconn.CommandTag {
+	return r.tag
+}
+
+func (r *replayRows) FieldDescriptions() []pgconn.FieldDescription {
+	return r.fields
+}
+
+func (r *replayRows) Next() bool {
+	if r.closed || r.pos >= len(r.rows) {
+		r.closed = true
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *replayRows) Scan(dest ...any) error {
+	values := r.RawValues()
+	if len(dest) == 1 {
+		if scanner, ok := dest[0].(pgx.RowScanner); ok {
+			return scanner.ScanRow(r)
+		}
+	}
+	if len(dest) != len(values) {
+		return fmt.Errorf("number of field descriptions must equal number of destinations, got %d and %d",
+			len(values), len(dest))
+	}
+	for i, dst := range dest {
+		if dst == nil {
+			continue
+		}
+		if err := r.typeMap.Scan(r.fields[i].DataTypeOID, r.fields[i].Format, values[i], dst); err != nil {
+			return pgx.ScanArgError{ColumnIndex: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func (r *replayRows) Values() ([]any, error) {
+	values := make([]any, len(r.fields))
+	for i, raw := range r.RawValues() {
+		if raw == nil {
+			continue
+		}
+		fd := r.fields[i]
+		if dt, ok := r.typeMap.TypeForOID(fd.DataTypeOID); ok {
+			value, err := dt.Codec.DecodeValue(r.typeMap, fd.DataTypeOID, fd.Format, raw)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+			continue
+		}
+		if fd.Format == pgtype.TextFormatCode {
+			values[i] = string(raw)
+		} else {
+			values[i] = bytes.Clone(raw)
+		}
+	}
+	return values, nil
+}
+
+func (r *replayRows) RawValues() [][]byte {
+	if r.pos == 0 {
+		return nil
+	}
+	return r.rows[r.pos-1]
+}
+
+// Conn is nil, because replay has no connections.
+func (r *replayRows) Conn() *pgx.Conn {
+	return nil
+}
+
+// replayBatch serves recorded results of batch in order.
+type replayBatch struct {
+	typeMap *pgtype.Map
+	queued  []*pgx.QueuedQuery
+	results []Result
+	err     error
+	read    int
+	closed  bool
+}
+
+func (b *replayBatch) next() (Result, error) {
+	if b.closed {
+		return Result{}, errBatchClosed
+	}
+	if b.read >= len(b.results) {
+		return Result{}, fmt.Errorf("%w: result %d of batch", ErrMismatch, b.read)
+	}
+	b.read++
+	return b.results[b.read-1], nil
+}
+
+func (b *replayBatch) Exec() (pgconn.CommandTag, error) {
+	result, err := b.next()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(result.Tag), result.Error.err()
+}
+
+func (b *replayBatch) Query() (pgx.Rows, error) {
+	result, err := b.next()
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error.err()
+	}
+	return newReplayRows(b.typeMap, result), nil
+}
+
+func (b *replayBatch) QueryRow() pgx.Row {
+	rows, err := b.Query()
+	return &row{rows: rows, err: err}
+}
+
+func (b *replayBatch) Close() error {
+	if b.closed {
+		return b.err
+	}
+	if err := readLeft(b, b.queued, b.read); err != nil && b.err == nil {
+		b.err = err
+	}
+	b.closed = true
+	return b.err
+}
+
+type failedBatchResults struct {
+	err error
+}
+
+func (r failedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+func (r failedBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+func (r failedBatchResults) QueryRow() pgx.Row {
+	return &row{err: r.err}
+}
+
+func (r failedBatchResults) Close() error {
+	return r.err
+}
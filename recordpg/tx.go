@@ -0,0 +1,84 @@
+package recordpg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordingTx records statements and boundaries of transaction, nested transactions are savepoints.
+type recordingTx struct {
+	pgx.Tx
+	rec    *Recorder
+	id     int64
+	nested bool
+}
+
+func (r *Recorder) wrapTx(tx pgx.Tx, nested bool) *recordingTx {
+	return &recordingTx{Tx: tx, rec: r, id: r.txs.Add(1), nested: nested}
+}
+
+func (r *Recorder) owns(tx pgx.Tx) bool {
+	wrapped, ok := tx.(*recordingTx)
+	return ok && wrapped.rec == r
+}
+
+func (tx *recordingTx) boundary(kind, nestedKind string) string {
+	if tx.nested {
+		return nestedKind
+	}
+	return kind
+}
+
+func (tx *recordingTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	entry := tx.rec.entry(ctx, KindSavepoint, tx.id)
+	nested, err := tx.Tx.Begin(ctx)
+	return tx.rec.begun(entry, nested, err, true)
+}
+
+func (tx *recordingTx) Commit(ctx context.Context) error {
+	err := tx.Tx.Commit(ctx)
+	tx.end(ctx, tx.boundary(KindCommit, KindRelease), err)
+	return err
+}
+
+func (tx *recordingTx) Rollback(ctx context.Context) error {
+	err := tx.Tx.Rollback(ctx)
+	tx.end(ctx, tx.boundary(KindRollback, KindRollbackSavepoint), err)
+	return err
+}
+
+// end records boundary, except of closing of already closed transaction, which doesn't reach database.
+func (tx *recordingTx) end(ctx context.Context, kind string, err error) {
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return
+	}
+	entry := tx.rec.entry(ctx, kind, tx.id)
+	entry.Error = errorOf(err)
+	tx.rec.write(entry)
+}
+
+func (tx *recordingTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return tx.rec.query(ctx, tx.Tx, tx.id, sql, args)
+}
+
+func (tx *recordingTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := tx.Query(ctx, sql, args...)
+	return &row{rows: rows, err: err}
+}
+
+func (tx *recordingTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return tx.rec.exec(ctx, tx.Tx, tx.id, sql, arguments)
+}
+
+func (tx *recordingTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return tx.rec.sendBatch(ctx, tx.Tx, tx.id, b)
+}
+
+func (tx *recordingTx) CopyFrom(
+	ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return tx.rec.copyFrom(ctx, tx.Tx, tx.id, tableName, columnNames, rowSrc)
+}
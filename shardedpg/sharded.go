@@ -3,8 +3,11 @@ package shardedpg
 import (
 	"context"
 	"errors"
+	"hash/fnv"
+	"io"
 	"reflect"
 
+	"github.com/godepo/elephant/internal/pkg/pgbreaker"
 	"github.com/godepo/elephant/internal/sharded"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -15,6 +18,9 @@ var (
 	ErrNoShardPickerProvided   = errors.New("sharded pg: no sharded picker provided")
 	ErrNotEnoughShardsProvided = errors.New("sharded pg: provided less shards than pool size")
 	ErrNilShardProvided        = errors.New("sharded pg: nil shard provided")
+
+	ErrPartitionedCopyInTransaction = sharded.ErrPartitionedCopyInTransaction
+	ErrShardCountMismatch           = sharded.ErrShardCountMismatch
 )
 
 type Pool interface {
@@ -23,21 +29,54 @@ type Pool interface {
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
 	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
 }
 
 type Builder interface {
 	Picker(pickFn ShardPicker) Builder
 	Shard(key uint, shard Pool) Builder
-	Go() (*sharded.Hive, error)
+	// CircuitBreaker guards every shard by circuit breaker, so calls to shard with open circuit fail fast.
+	CircuitBreaker(cfg pgbreaker.Config) Builder
+	Go() (*Hive, error)
+}
+
+// Hive is sharded database, which Builder builds.
+type Hive struct {
+	*sharded.Hive
 }
 
+// SwapShards atomically replaces shards, count of shards is kept, so shards are picked by the same keys. Queries in
+// flight finish on previous shards, which are shut down or closed, once they are drained, unless they stay in hive.
+func (h *Hive) SwapShards(ctx context.Context, shards []Pool) error {
+	pools := make([]sharded.Pool, 0, len(shards))
+	for _, shard := range shards {
+		pools = append(pools, shard)
+	}
+	return h.Hive.SwapShards(ctx, pools)
+}
+
+// RowKey extracts sharding key from row values for Hive.CopyFromPartitioned.
+type RowKey = sharded.RowKey
+
 type ShardPicker func(ctx context.Context, key string) uint
 
+// HashPicker spreads sharding keys over size shards by FNV-1a hash of key.
+func HashPicker(size uint) ShardPicker {
+	return func(_ context.Context, key string) uint {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		return uint(h.Sum32()) % size
+	}
+}
+
 type builder struct {
 	size   uint
 	shards map[uint]Pool
 	picker ShardPicker
+	opts   []sharded.Option
 }
 
 func New(poolSize uint) Builder {
@@ -57,7 +96,12 @@ func (b *builder) Shard(key uint, shard Pool) Builder {
 	return b
 }
 
-func (b *builder) Go() (*sharded.Hive, error) {
+func (b *builder) CircuitBreaker(cfg pgbreaker.Config) Builder {
+	b.opts = append(b.opts, sharded.WithCircuitBreaker(cfg))
+	return b
+}
+
+func (b *builder) Go() (*Hive, error) {
 	if b.size == 0 {
 		return nil, ErrWrongShardsPoolSize
 	}
@@ -75,5 +119,5 @@ func (b *builder) Go() (*sharded.Hive, error) {
 		}
 		shards = append(shards, shard)
 	}
-	return sharded.New(shards, sharded.Picker(b.picker)), nil
+	return &Hive{Hive: sharded.New(shards, sharded.Picker(b.picker), b.opts...)}, nil
 }
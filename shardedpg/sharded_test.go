@@ -2,8 +2,12 @@ package shardedpg
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
 
+	"github.com/godepo/elephant"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -412,3 +416,59 @@ func TestNew(t *testing.T) {
 			})
 	})
 }
+
+func TestHashPicker(t *testing.T) {
+	picker := HashPicker(4)
+
+	assert.Equal(t, picker(context.Background(), "tenant"), picker(context.Background(), "tenant"))
+	assert.Equal(t, uint(0), picker(context.Background(), "a"))
+	assert.Equal(t, uint(1), picker(context.Background(), "b"))
+	assert.Equal(t, uint(2), picker(context.Background(), "c"))
+}
+
+func TestBuilder_CircuitBreaker(t *testing.T) {
+	t.Run("should be able to guard shards by circuit breaker", func(t *testing.T) {
+		ctx := elephant.With(context.Background(), elephant.WithShardID(0))
+		shard := NewMockPool(t)
+		shard.EXPECT().Exec(ctx, "SELECT 1").
+			Return(pgconn.CommandTag{}, &net.OpError{Op: "dial", Err: errors.New("connection refused")}).Once()
+		hive, err := New(1).
+			Shard(0, shard).
+			Picker(HashPicker(1)).
+			CircuitBreaker(elephant.CircuitBreaker{ConsecutiveFailures: 1}).
+			Go()
+		require.NoError(t, err)
+		_, err = hive.Exec(ctx, "SELECT 1")
+		require.Error(t, err)
+
+		_, err = hive.Exec(ctx, "SELECT 1")
+
+		var openErr *elephant.CircuitOpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, "shard [0]", openErr.Node)
+	})
+}
+
+func TestHive_SwapShards(t *testing.T) {
+	t.Run("should be able to route queries to swapped shards", func(t *testing.T) {
+		ctx := elephant.With(context.Background(), elephant.WithShardID(0))
+		next := NewMockPool(t)
+		next.EXPECT().Exec(ctx, "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		hive, err := New(1).Shard(0, NewMockPool(t)).Picker(HashPicker(1)).Go()
+		require.NoError(t, err)
+
+		require.NoError(t, hive.SwapShards(ctx, []Pool{next}))
+
+		_, err = hive.Exec(ctx, "SELECT 1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("should be able to reject changed count of shards", func(t *testing.T) {
+		hive, err := New(1).Shard(0, NewMockPool(t)).Picker(HashPicker(1)).Go()
+		require.NoError(t, err)
+
+		err = hive.SwapShards(context.Background(), []Pool{NewMockPool(t), NewMockPool(t)})
+
+		assert.ErrorIs(t, err, ErrShardCountMismatch)
+	})
+}
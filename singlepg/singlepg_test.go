@@ -2,8 +2,14 @@ package singlepg
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/godepo/elephant/internal/pkg/pgdrain"
+	"github.com/godepo/elephant/internal/pkg/pgerr"
+	"github.com/godepo/elephant/internal/pkg/pghealth"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -25,3 +31,38 @@ func TestNew(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestDB_Shutdown(t *testing.T) {
+	t.Run("should be able to shut down and reject new transactions", func(t *testing.T) {
+		db := New(NewMockPool(t))
+
+		require.NoError(t, db.Shutdown(context.Background()))
+
+		assert.Equal(t, pgdrain.StageClosed, db.ShutdownProgress().Stage)
+		assert.ErrorIs(t, db.Transactional(context.Background(), func(context.Context) error {
+			return nil
+		}), pgerr.ErrShutdown)
+	})
+}
+
+type failedRow struct {
+	err error
+}
+
+func (r failedRow) Scan(...any) error {
+	return r.err
+}
+
+func TestDB_Health(t *testing.T) {
+	t.Run("should be able to report unreachable node", func(t *testing.T) {
+		pool := NewMockPool(t)
+		expErr := errors.New(faker.New().RandomStringWithLength(10))
+		pool.EXPECT().QueryRow(mock.Anything, mock.Anything).Return(failedRow{err: expErr})
+		db := New(pool)
+
+		err := db.Ping(context.Background())
+
+		assert.ErrorIs(t, err, pghealth.ErrNotReady)
+		assert.ErrorIs(t, err, expErr)
+	})
+}
@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/sqlpg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenDB_Integration(t *testing.T) {
+	t.Run("should be able to scan postgres types", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+
+		var (
+			id      int64
+			amount  string
+			payload []byte
+			key     string
+			at      time.Time
+		)
+		err := sqlpg.OpenDB(tcs.SUT).QueryRowContext(context.Background(),
+			`SELECT $1::bigint, 12.50::numeric, '{"a":1}'::jsonb, gen_random_uuid(), now()`, int64(7),
+		).Scan(&id, &amount, &payload, &key, &at)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), id)
+		assert.Equal(t, "12.50", amount)
+		assert.JSONEq(t, `{"a":1}`, string(payload))
+		assert.Len(t, key, 36)
+		assert.False(t, at.IsZero())
+	})
+
+	t.Run("should be able to join elephant transaction from context", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		ctx := context.Background()
+		sqlDB := sqlpg.OpenDB(tcs.SUT)
+		_, err := tcs.SUT.Exec(ctx, "CREATE TABLE sqlpg_items (name text PRIMARY KEY)")
+		require.NoError(t, err)
+
+		expErr := errors.New("rollback")
+		err = tcs.SUT.Transactional(ctx, func(ctx context.Context) error {
+			if _, err := sqlDB.ExecContext(ctx, "INSERT INTO sqlpg_items VALUES ($1)", "joined"); err != nil {
+				return err
+			}
+			tx, err := sqlDB.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("INSERT INTO sqlpg_items VALUES ($1)", "savepoint"); err != nil {
+				return err
+			}
+			if err := tx.Rollback(); err != nil {
+				return err
+			}
+			var count int
+			if err := sqlDB.QueryRowContext(ctx, "SELECT count(*) FROM sqlpg_items").Scan(&count); err != nil {
+				return err
+			}
+			assert.Equal(t, 1, count)
+			return expErr
+		})
+		require.ErrorIs(t, err, expErr)
+
+		var count int
+		require.NoError(t, sqlDB.QueryRow("SELECT count(*) FROM sqlpg_items").Scan(&count))
+		assert.Zero(t, count)
+	})
+
+	t.Run("should be able to commit own transaction", func(t *testing.T) {
+		tcs := suite.Case(t)
+		tcs.Go()
+		sqlDB := sqlpg.OpenDB(tcs.SUT)
+		_, err := sqlDB.Exec("CREATE TABLE sqlpg_commits (name text PRIMARY KEY)")
+		require.NoError(t, err)
+
+		tx, err := sqlDB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+		require.NoError(t, err)
+		_, err = tx.Exec("INSERT INTO sqlpg_commits VALUES ($1)", "committed")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		var name string
+		require.NoError(t, sqlDB.QueryRow("SELECT name FROM sqlpg_commits").Scan(&name))
+		assert.Equal(t, "committed", name)
+	})
+}
@@ -0,0 +1,80 @@
+package sqlpg
+
+import (
+	"database/sql/driver"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type rows struct {
+	rows    pgx.Rows
+	typeMap *pgtype.Map
+}
+
+func newRows(pgRows pgx.Rows) *rows {
+	r := &rows{rows: pgRows}
+	if conn := pgRows.Conn(); conn != nil {
+		r.typeMap = conn.TypeMap()
+	} else {
+		r.typeMap = pgtype.NewMap()
+	}
+	return r
+}
+
+func (r *rows) Columns() []string {
+	fields := r.rows.FieldDescriptions()
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, field.Name)
+	}
+	return columns
+}
+
+func (r *rows) Close() error {
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	values, err := r.rows.Values()
+	if err != nil {
+		return err
+	}
+	fields := r.rows.FieldDescriptions()
+	for i, value := range values {
+		if dest[i], err = r.value(fields[i].DataTypeOID, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value converts value decoded by pgx to driver value. Types unknown to database/sql are passed as text.
+func (r *rows) value(oid uint32, value any) (driver.Value, error) {
+	switch value := value.(type) {
+	case nil, bool, string, []byte, time.Time, int64, float64:
+		return value, nil
+	case int16:
+		return int64(value), nil
+	case int32:
+		return int64(value), nil
+	case uint32:
+		return int64(value), nil
+	case float32:
+		return float64(value), nil
+	}
+	text, err := r.typeMap.Encode(oid, pgtype.TextFormatCode, value, nil)
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
@@ -0,0 +1,216 @@
+//go:generate mockery
+package sqlpg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrOpenNotSupported     = errors.New("sqlpg: driver can't open connection by name, use connector")
+	ErrNamedArgs            = errors.New("sqlpg: named arguments aren't supported")
+	ErrUnsupportedIsolation = errors.New("sqlpg: isolation level isn't supported")
+)
+
+type DB interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// OpenDB exposes elephant DB as *sql.DB. Statements are routed through context in the same way as in elephant DB:
+// transaction from context is joined, WithCanWrite routes to leader, shard options route to shard.
+func OpenDB(db DB) *sql.DB {
+	return sql.OpenDB(NewConnector(db))
+}
+
+type Connector struct {
+	db DB
+}
+
+func NewConnector(db DB) *Connector {
+	return &Connector{db: db}
+}
+
+// Connect returns virtual connection, which routes every statement through elephant DB. Only transaction, started
+// through database/sql, is bound to connection.
+func (c *Connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{db: c.db}, nil
+}
+
+func (c *Connector) Driver() driver.Driver {
+	return sqlDriver{}
+}
+
+type sqlDriver struct{}
+
+func (sqlDriver) Open(string) (driver.Conn, error) {
+	return nil, ErrOpenNotSupported
+}
+
+type conn struct {
+	db DB
+	tx pgx.Tx
+}
+
+func (c *conn) scope(ctx context.Context) context.Context {
+	if c.tx == nil {
+		return ctx
+	}
+	return pgcontext.With(ctx, pgcontext.WithTransaction(c.tx))
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return c.Prepare(query)
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts savepoint, when context has transaction, otherwise starts transaction through elephant DB.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	txOpts, err := txOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	var tx pgx.Tx
+	if outer, ok := pgcontext.TransactionFrom(ctx); ok {
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = c.db.BeginTx(ctx, txOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	return &sqlTx{conn: c, tx: tx}, nil
+}
+
+func txOptions(opts driver.TxOptions) (pgx.TxOptions, error) {
+	var txOpts pgx.TxOptions
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		txOpts.IsoLevel = pgx.ReadUncommitted
+	case sql.LevelReadCommitted:
+		txOpts.IsoLevel = pgx.ReadCommitted
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		txOpts.IsoLevel = pgx.RepeatableRead
+	case sql.LevelSerializable:
+		txOpts.IsoLevel = pgx.Serializable
+	default:
+		return txOpts, fmt.Errorf("%w: %s", ErrUnsupportedIsolation, sql.IsolationLevel(opts.Isolation))
+	}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx.ReadOnly
+	}
+	return txOpts, nil
+}
+
+func args(named []driver.NamedValue) ([]any, error) {
+	values := make([]any, 0, len(named))
+	for _, arg := range named {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("%w: %s", ErrNamedArgs, arg.Name)
+		}
+		values = append(values, arg.Value)
+	}
+	return values, nil
+}
+
+// CheckNamedValue passes arguments to pgx as is, so pgx types and codecs are available to database/sql callers.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	values, err := args(named)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := c.db.Exec(c.scope(ctx), query, values...)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(tag.RowsAffected()), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	values, err := args(named)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.db.Query(c.scope(ctx), query, values...)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(rows), nil
+}
+
+type sqlTx struct {
+	conn *conn
+	tx   pgx.Tx
+}
+
+func (t *sqlTx) Commit() error {
+	t.conn.tx = nil
+	return t.tx.Commit(context.Background())
+}
+
+func (t *sqlTx) Rollback() error {
+	t.conn.tx = nil
+	return t.tx.Rollback(context.Background())
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func named(values []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, 0, len(values))
+	for i, value := range values {
+		out = append(out, driver.NamedValue{Ordinal: i + 1, Value: value})
+	}
+	return out
+}
+
+func (s *stmt) Exec(values []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(values))
+}
+
+func (s *stmt) Query(values []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(values))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
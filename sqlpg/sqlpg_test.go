@@ -0,0 +1,284 @@
+package sqlpg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jaswdr/faker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newError() error {
+	return errors.New(faker.New().RandomStringWithLength(10))
+}
+
+func withTx(tx pgx.Tx) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		got, ok := pgcontext.TransactionFrom(ctx)
+		return ok && got == tx
+	})
+}
+
+func TestConnector(t *testing.T) {
+	t.Run("should be able to refuse opening by name", func(t *testing.T) {
+		_, err := NewConnector(NewMockDB(t)).Driver().Open("postgres://")
+		assert.ErrorIs(t, err, ErrOpenNotSupported)
+	})
+}
+
+func TestDB_Exec(t *testing.T) {
+	t.Run("should be able to exec through elephant DB", func(t *testing.T) {
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.MatchedBy(pgcontext.CanWriteFrom), "DELETE FROM users WHERE id = $1", int64(1)).
+			Return(pgconn.NewCommandTag("DELETE 1"), nil)
+
+		res, err := OpenDB(db).ExecContext(pgcontext.WithCanWrite(context.Background()),
+			"DELETE FROM users WHERE id = $1", int64(1),
+		)
+		require.NoError(t, err)
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+	})
+
+	t.Run("should be able to join transaction from context", func(t *testing.T) {
+		tx := NewMockTx(t)
+		db := NewMockDB(t)
+		db.EXPECT().Exec(withTx(tx), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := OpenDB(db).ExecContext(pgcontext.With(context.Background(), pgcontext.WithTransaction(tx)), "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to return error of statement", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.Anything, "SELECT 1").Return(pgconn.CommandTag{}, expErr)
+
+		_, err := OpenDB(db).Exec("SELECT 1")
+		assert.ErrorIs(t, err, expErr)
+	})
+
+	t.Run("should be able to reject named arguments", func(t *testing.T) {
+		sqlDB := OpenDB(NewMockDB(t))
+
+		_, err := sqlDB.Exec("SELECT @id", sql.Named("id", 1))
+		assert.ErrorIs(t, err, ErrNamedArgs)
+		_, err = sqlDB.Query("SELECT @id", sql.Named("id", 1))
+		assert.ErrorIs(t, err, ErrNamedArgs)
+	})
+}
+
+func TestDB_Query(t *testing.T) {
+	t.Run("should be able to convert values", func(t *testing.T) {
+		now := time.Now()
+		rows := NewMockRows(t)
+		rows.EXPECT().Conn().Return(nil)
+		rows.EXPECT().FieldDescriptions().Return([]pgconn.FieldDescription{
+			{Name: "a", DataTypeOID: pgtype.Int2OID},
+			{Name: "b", DataTypeOID: pgtype.Int4OID},
+			{Name: "c", DataTypeOID: pgtype.OIDOID},
+			{Name: "d", DataTypeOID: pgtype.Float4OID},
+			{Name: "e", DataTypeOID: pgtype.TimestamptzOID},
+			{Name: "f", DataTypeOID: pgtype.UUIDOID},
+			{Name: "g", DataTypeOID: pgtype.TextOID},
+		})
+		rows.EXPECT().Next().Return(true).Once()
+		rows.EXPECT().Values().Return([]any{
+			int16(1), int32(2), uint32(3), float32(4), now, [16]byte{1}, nil,
+		}, nil).Once()
+		rows.EXPECT().Next().Return(false).Once()
+		rows.EXPECT().Err().Return(nil)
+		rows.EXPECT().Close().Return()
+		db := NewMockDB(t)
+		db.EXPECT().Query(mock.Anything, "SELECT", 1).Return(rows, nil)
+
+		sqlRows, err := OpenDB(db).Query("SELECT", 1)
+		require.NoError(t, err)
+		columns, err := sqlRows.Columns()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e", "f", "g"}, columns)
+
+		require.True(t, sqlRows.Next())
+		var (
+			a, b, c int64
+			d       float64
+			e       time.Time
+			f       string
+			g       sql.NullString
+		)
+		require.NoError(t, sqlRows.Scan(&a, &b, &c, &d, &e, &f, &g))
+		assert.Equal(t, []int64{1, 2, 3}, []int64{a, b, c})
+		assert.InDelta(t, 4.0, d, 0)
+		assert.Equal(t, now, e)
+		assert.Equal(t, "01000000-0000-0000-0000-000000000000", f)
+		assert.False(t, g.Valid)
+		assert.False(t, sqlRows.Next())
+		require.NoError(t, sqlRows.Err())
+	})
+
+	t.Run("should be able to return errors of rows", func(t *testing.T) {
+		for name, setup := range map[string]func(rows *MockRows, expErr error){
+			"iteration": func(rows *MockRows, expErr error) {
+				rows.EXPECT().Next().Return(false)
+				rows.EXPECT().Err().Return(expErr)
+			},
+			"values": func(rows *MockRows, expErr error) {
+				rows.EXPECT().Next().Return(true).Once()
+				rows.EXPECT().Values().Return(nil, expErr)
+				rows.EXPECT().Err().Return(nil)
+			},
+			"encoding": func(rows *MockRows, _ error) {
+				rows.EXPECT().Next().Return(true).Once()
+				rows.EXPECT().FieldDescriptions().Return([]pgconn.FieldDescription{{DataTypeOID: pgtype.Int8OID}})
+				rows.EXPECT().Values().Return([]any{struct{}{}}, nil)
+				rows.EXPECT().Err().Return(nil)
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				expErr := newError()
+				rows := NewMockRows(t)
+				rows.EXPECT().Conn().Return(nil)
+				rows.EXPECT().FieldDescriptions().Return([]pgconn.FieldDescription{{Name: "id"}}).Maybe()
+				rows.EXPECT().Close().Return()
+				setup(rows, expErr)
+				db := NewMockDB(t)
+				db.EXPECT().Query(mock.Anything, "SELECT").Return(rows, nil)
+
+				sqlRows, err := OpenDB(db).Query("SELECT")
+				require.NoError(t, err)
+				assert.False(t, sqlRows.Next())
+				require.Error(t, sqlRows.Err())
+				if name != "encoding" {
+					assert.ErrorIs(t, sqlRows.Err(), expErr)
+				}
+			})
+		}
+	})
+
+	t.Run("should be able to return error of query", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockDB(t)
+		db.EXPECT().Query(mock.Anything, "SELECT").Return(nil, expErr)
+
+		_, err := OpenDB(db).Query("SELECT")
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestDB_Tx(t *testing.T) {
+	t.Run("should be able to run statements in transaction", func(t *testing.T) {
+		tx := NewMockTx(t)
+		db := NewMockDB(t)
+		db.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly}).
+			Return(tx, nil)
+		db.EXPECT().Exec(withTx(tx), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+		tx.EXPECT().Commit(mock.Anything).Return(nil)
+
+		sqlTx, err := OpenDB(db).BeginTx(context.Background(),
+			&sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true},
+		)
+		require.NoError(t, err)
+		_, err = sqlTx.Exec("SELECT 1")
+		require.NoError(t, err)
+		require.NoError(t, sqlTx.Commit())
+	})
+
+	t.Run("should be able to start savepoint in transaction from context", func(t *testing.T) {
+		outer := NewMockTx(t)
+		tx := NewMockTx(t)
+		outer.EXPECT().Begin(mock.Anything).Return(tx, nil)
+		tx.EXPECT().Rollback(mock.Anything).Return(nil)
+
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTransaction(outer))
+		sqlTx, err := OpenDB(NewMockDB(t)).BeginTx(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, sqlTx.Rollback())
+	})
+
+	t.Run("should be able to map isolation levels", func(t *testing.T) {
+		for level, exp := range map[sql.IsolationLevel]pgx.TxIsoLevel{
+			sql.LevelDefault:         "",
+			sql.LevelReadUncommitted: pgx.ReadUncommitted,
+			sql.LevelReadCommitted:   pgx.ReadCommitted,
+			sql.LevelRepeatableRead:  pgx.RepeatableRead,
+			sql.LevelSnapshot:        pgx.RepeatableRead,
+		} {
+			opts, err := txOptions(driver.TxOptions{Isolation: driver.IsolationLevel(level)})
+			require.NoError(t, err)
+			assert.Equal(t, exp, opts.IsoLevel)
+		}
+	})
+
+	t.Run("should be able to reject unsupported isolation level", func(t *testing.T) {
+		_, err := OpenDB(NewMockDB(t)).BeginTx(context.Background(),
+			&sql.TxOptions{Isolation: sql.LevelLinearizable},
+		)
+		assert.ErrorIs(t, err, ErrUnsupportedIsolation)
+	})
+
+	t.Run("should be able to return error of begin", func(t *testing.T) {
+		expErr := newError()
+		db := NewMockDB(t)
+		db.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(nil, expErr)
+
+		_, err := OpenDB(db).Begin()
+		assert.ErrorIs(t, err, expErr)
+	})
+}
+
+func TestStmt(t *testing.T) {
+	t.Run("should be able to run prepared statements", func(t *testing.T) {
+		rows := NewMockRows(t)
+		rows.EXPECT().Conn().Return(nil)
+		rows.EXPECT().Close().Return()
+		rows.EXPECT().Err().Return(nil)
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.Anything, "SELECT $1", int64(1)).Return(pgconn.CommandTag{}, nil)
+		db.EXPECT().Query(mock.Anything, "SELECT $1", int64(1)).Return(rows, nil)
+
+		stmt, err := OpenDB(db).Prepare("SELECT $1")
+		require.NoError(t, err)
+		_, err = stmt.Exec(int64(1))
+		require.NoError(t, err)
+		sqlRows, err := stmt.Query(int64(1))
+		require.NoError(t, err)
+		require.NoError(t, sqlRows.Close())
+		require.NoError(t, stmt.Close())
+	})
+
+	t.Run("should be able to run legacy driver interface", func(t *testing.T) {
+		rows := NewMockRows(t)
+		rows.EXPECT().Conn().Return(nil)
+		db := NewMockDB(t)
+		db.EXPECT().Exec(mock.Anything, "SELECT $1", int64(1)).Return(pgconn.CommandTag{}, nil)
+		db.EXPECT().Query(mock.Anything, "SELECT $1", int64(1)).Return(rows, nil)
+		tx := NewMockTx(t)
+		tx.EXPECT().Rollback(mock.Anything).Return(nil)
+		db.EXPECT().BeginTx(mock.Anything, pgx.TxOptions{}).Return(tx, nil)
+
+		conn, err := NewConnector(db).Connect(context.Background())
+		require.NoError(t, err)
+		stmt, err := conn.Prepare("SELECT $1")
+		require.NoError(t, err)
+		assert.Equal(t, -1, stmt.NumInput())
+		_, err = stmt.Exec([]driver.Value{int64(1)}) //nolint:staticcheck // legacy interface is covered on purpose.
+		require.NoError(t, err)
+		_, err = stmt.Query([]driver.Value{int64(1)}) //nolint:staticcheck // legacy interface is covered on purpose.
+		require.NoError(t, err)
+		driverTx, err := conn.Begin() //nolint:staticcheck // legacy interface is covered on purpose.
+		require.NoError(t, err)
+		require.NoError(t, driverTx.Rollback())
+		require.NoError(t, conn.Close())
+	})
+}
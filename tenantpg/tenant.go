@@ -0,0 +1,61 @@
+package tenantpg
+
+import (
+	"context"
+	"io"
+
+	"github.com/godepo/elephant/internal/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNoTenant      = tenant.ErrNoTenant
+	ErrInvalidSchema = tenant.ErrInvalidSchema
+	ErrTenantSwitch  = tenant.ErrTenantSwitch
+)
+
+type Pool interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	CopyTo(ctx context.Context, w io.Writer, query string) (pgconn.CommandTag, error)
+	Transactional(ctx context.Context, fn func(ctx context.Context) error) (out error)
+}
+
+type (
+	Option         = tenant.Option
+	SchemaResolver func(ctx context.Context, tenant string) (string, error)
+	PoolResolver   func(ctx context.Context, tenant string) (Pool, error)
+)
+
+func WithStrict() Option {
+	return tenant.WithStrict()
+}
+
+func WithShardingByTenant() Option {
+	return tenant.WithShardingByTenant()
+}
+
+func WithSharedSchemas(schemas ...string) Option {
+	return tenant.WithSharedSchemas(schemas...)
+}
+
+func WithSchemaResolver(resolver SchemaResolver) Option {
+	return tenant.WithSchemaResolver(tenant.SchemaResolver(resolver))
+}
+
+func WithPoolResolver(resolver PoolResolver) Option {
+	return tenant.WithPoolResolver(func(ctx context.Context, name string) (tenant.Pool, error) {
+		return resolver(ctx, name)
+	})
+}
+
+// New wraps any elephant database to run queries of tenant from context with search_path of tenant schema.
+func New(db Pool, opts ...Option) Pool {
+	return tenant.New(db, opts...)
+}
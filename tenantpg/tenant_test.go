@@ -0,0 +1,44 @@
+package tenantpg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godepo/elephant/internal/pkg/pgcontext"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should be able to route tenant to resolved pool and schema", func(t *testing.T) {
+		db, tenantDB := NewMockPool(t), NewMockPool(t)
+		sut := New(db,
+			WithSchemaResolver(func(_ context.Context, tenant string) (string, error) {
+				return "tenant_" + tenant, nil
+			}),
+			WithPoolResolver(func(_ context.Context, _ string) (Pool, error) {
+				return tenantDB, nil
+			}),
+			WithSharedSchemas("public"),
+			WithShardingByTenant(),
+		)
+		ctx := pgcontext.With(context.Background(), pgcontext.WithTenant("acme"))
+		tenantDB.EXPECT().Exec(mock.MatchedBy(func(ctx context.Context) bool {
+			vars, _ := pgcontext.SessionVarsFrom(ctx)
+			key, _ := pgcontext.ShardingKeyFrom(ctx)
+			return vars["search_path"] == `"tenant_acme", "public"` && key == "acme"
+		}), "SELECT 1").Return(pgconn.CommandTag{}, nil)
+
+		_, err := sut.Exec(ctx, "SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("should be able to reject queries without tenant in strict mode", func(t *testing.T) {
+		sut := New(NewMockPool(t), WithStrict())
+
+		_, err := sut.Exec(context.Background(), "SELECT 1")
+		assert.ErrorIs(t, err, ErrNoTenant)
+	})
+}